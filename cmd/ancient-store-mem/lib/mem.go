@@ -22,6 +22,9 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
 )
 
 const (
@@ -30,11 +33,15 @@ const (
 	freezerRemoteBodiesTable     = "bodies"
 	freezerRemoteReceiptTable    = "receipts"
 	freezerRemoteDifficultyTable = "diffs"
+
+	// freezerRemoteChecksumLimit mirrors rawdb.FreezerRemoteChecksumLimit.
+	freezerRemoteChecksumLimit = 65536
 )
 
 var (
-	errOutOfBounds = errors.New("out of bounds")
-	errOutOfOrder  = errors.New("out of order")
+	errOutOfBounds   = errors.New("out of bounds")
+	errOutOfOrder    = errors.New("out of order")
+	errRangeTooLarge = errors.New("range too large")
 )
 
 // MemFreezerRemoteServerAPI is a mock freezer server implementation.
@@ -117,6 +124,85 @@ func (f *MemFreezerRemoteServerAPI) AppendAncient(number uint64, hash, header, b
 	return nil
 }
 
+// freezerRemoteItem mirrors rawdb.FreezerRemoteItem, which can't be imported here
+// since the rawdb tests use this package.
+type freezerRemoteItem struct {
+	Number   uint64 `json:"number"`
+	Hash     []byte `json:"hash"`
+	Header   []byte `json:"header"`
+	Body     []byte `json:"body"`
+	Receipts []byte `json:"receipts"`
+	Td       []byte `json:"td"`
+}
+
+func (f *MemFreezerRemoteServerAPI) AncientRange(kind string, start, count, maxBytes uint64) ([][]byte, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if start >= f.count {
+		return nil, errOutOfBounds
+	}
+	if f.count-start < count {
+		count = f.count - start
+	}
+	var (
+		res  [][]byte
+		size uint64
+	)
+	for i := uint64(0); i < count; i++ {
+		v, ok := f.store[f.storeKey(kind, start+i)]
+		if !ok {
+			return nil, errOutOfBounds
+		}
+		if maxBytes != 0 && i > 0 && size+uint64(len(v)) > maxBytes {
+			break
+		}
+		res = append(res, v)
+		size += uint64(len(v))
+	}
+	return res, nil
+}
+
+func (f *MemFreezerRemoteServerAPI) AppendAncients(items []*freezerRemoteItem) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, item := range items {
+		if item.Number != f.count+uint64(i) {
+			return errOutOfOrder
+		}
+	}
+	for _, item := range items {
+		f.store[f.storeKey(freezerRemoteHashTable, item.Number)] = item.Hash
+		f.store[f.storeKey(freezerRemoteHeaderTable, item.Number)] = item.Header
+		f.store[f.storeKey(freezerRemoteBodiesTable, item.Number)] = item.Body
+		f.store[f.storeKey(freezerRemoteReceiptTable, item.Number)] = item.Receipts
+		f.store[f.storeKey(freezerRemoteDifficultyTable, item.Number)] = item.Td
+	}
+	f.count += uint64(len(items))
+	return nil
+}
+
+// AncientChecksum returns the keccak256 hash of the concatenated keccak256 hashes
+// of the items, like rawdb.FreezerRemoteChecksum.
+func (f *MemFreezerRemoteServerAPI) AncientChecksum(kind string, start, count uint64) (common.Hash, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if start > f.count || count > f.count-start {
+		return common.Hash{}, errOutOfBounds
+	}
+	if count > freezerRemoteChecksumLimit {
+		return common.Hash{}, errRangeTooLarge
+	}
+	hashes := make([][]byte, count)
+	for i := range hashes {
+		v, ok := f.store[f.storeKey(kind, start+uint64(i))]
+		if !ok {
+			return common.Hash{}, errOutOfBounds
+		}
+		hashes[i] = crypto.Keccak256(v)
+	}
+	return crypto.Keccak256Hash(hashes...), nil
+}
+
 func (f *MemFreezerRemoteServerAPI) TruncateAncients(n uint64) error {
 	// fmt.Println("mock server called", "method=TruncateAncients")
 	f.count = n
@@ -0,0 +1,303 @@
+// Copyright 2020 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package lib
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const s3MetaObject = "meta.json"
+
+var (
+	errOutOfBounds  = errors.New("out of bounds")
+	errOutOfOrder   = errors.New("out of order")
+	errUnknownTable = errors.New("unknown table")
+)
+
+// s3Tables are the ancient data kinds, in the order they are passed to AppendAncient.
+var s3Tables = []string{
+	rawdb.FreezerRemoteHashTable,
+	rawdb.FreezerRemoteHeaderTable,
+	rawdb.FreezerRemoteBodiesTable,
+	rawdb.FreezerRemoteReceiptTable,
+	rawdb.FreezerRemoteDifficultyTable,
+}
+
+// S3Config configures the connection to an S3-compatible object store.
+type S3Config struct {
+	Endpoint  string // Custom endpoint URL, eg. of a self-hosted store; empty for AWS
+	Region    string
+	AccessKey string // If empty, credentials are read from the environment
+	SecretKey string
+	PathStyle bool // Use path-style bucket addressing, as required by most self-hosted stores
+}
+
+// NewS3API creates an S3 API client.
+func NewS3API(cfg S3Config) (s3iface.S3API, error) {
+	config := &aws.Config{
+		Region:           aws.String(cfg.Region),
+		S3ForcePathStyle: aws.Bool(cfg.PathStyle),
+	}
+	if cfg.Endpoint != "" {
+		config.Endpoint = aws.String(cfg.Endpoint)
+	}
+	if cfg.AccessKey != "" {
+		config.Credentials = credentials.NewStaticCredentials(cfg.AccessKey, cfg.SecretKey, "")
+	}
+	sess, err := session.NewSession(config)
+	if err != nil {
+		return nil, err
+	}
+	return s3.New(sess), nil
+}
+
+// s3Meta is the persisted metadata of an S3 ancient store.
+type s3Meta struct {
+	Items uint64            `json:"items"`
+	Sizes map[string]uint64 `json:"sizes"`
+}
+
+// S3AncientStore is an ancient store keeping each item as an object in an
+// S3-compatible bucket, under <prefix>/<kind>/<number>.
+//
+// The number of items and the table sizes are kept in a metadata object, which
+// is written on Sync, TruncateAncients and Close. Objects appended after the
+// last metadata write are disregarded (and eventually overwritten) on reopening,
+// just like unsynced data in the file-based freezer.
+type S3AncientStore struct {
+	api    s3iface.S3API
+	bucket string
+	prefix string
+
+	meta s3Meta
+	lock sync.RWMutex
+}
+
+// NewS3AncientStore opens the ancient store in the given bucket and key prefix.
+func NewS3AncientStore(api s3iface.S3API, bucket, prefix string) (*S3AncientStore, error) {
+	store := &S3AncientStore{
+		api:    api,
+		bucket: bucket,
+		prefix: prefix,
+		meta:   s3Meta{Sizes: make(map[string]uint64)},
+	}
+	data, err := store.get(store.key(s3MetaObject))
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); !ok || aerr.Code() != s3.ErrCodeNoSuchKey {
+			return nil, err
+		}
+		log.Info("Initializing S3 ancient store", "bucket", bucket, "prefix", prefix)
+		return store, nil
+	}
+	if err := json.Unmarshal(data, &store.meta); err != nil {
+		return nil, fmt.Errorf("invalid ancient store metadata: %v", err)
+	}
+	if store.meta.Sizes == nil {
+		store.meta.Sizes = make(map[string]uint64)
+	}
+	log.Info("Opened S3 ancient store", "bucket", bucket, "prefix", prefix, "items", store.meta.Items)
+	return store, nil
+}
+
+func (s *S3AncientStore) key(elems ...string) string {
+	return path.Join(append([]string{s.prefix}, elems...)...)
+}
+
+func (s *S3AncientStore) itemKey(kind string, number uint64) string {
+	return s.key(kind, fmt.Sprintf("%016x", number))
+}
+
+func (s *S3AncientStore) get(key string) ([]byte, error) {
+	out, err := s.api.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer out.Body.Close()
+	return ioutil.ReadAll(out.Body)
+}
+
+func (s *S3AncientStore) put(key string, data []byte) error {
+	_, err := s.api.PutObject(&s3.PutObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+		Body:   bytes.NewReader(data),
+	})
+	return err
+}
+
+func (s *S3AncientStore) delete(key string) error {
+	_, err := s.api.DeleteObject(&s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	return err
+}
+
+func (s *S3AncientStore) size(key string) (uint64, error) {
+	out, err := s.api.HeadObject(&s3.HeadObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return 0, err
+	}
+	return uint64(aws.Int64Value(out.ContentLength)), nil
+}
+
+// writeMeta persists the metadata. The caller must hold the write lock.
+func (s *S3AncientStore) writeMeta() error {
+	data, err := json.Marshal(s.meta)
+	if err != nil {
+		return err
+	}
+	return s.put(s.key(s3MetaObject), data)
+}
+
+func knownTable(kind string) bool {
+	for _, t := range s3Tables {
+		if t == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// HasAncient returns an indicator whether the specified ancient data exists.
+func (s *S3AncientStore) HasAncient(kind string, number uint64) (bool, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return knownTable(kind) && number < s.meta.Items, nil
+}
+
+// Ancient retrieves an ancient binary blob.
+func (s *S3AncientStore) Ancient(kind string, number uint64) ([]byte, error) {
+	if !knownTable(kind) {
+		return nil, errUnknownTable
+	}
+	s.lock.RLock()
+	items := s.meta.Items
+	s.lock.RUnlock()
+	if number >= items {
+		return nil, errOutOfBounds
+	}
+	return s.get(s.itemKey(kind, number))
+}
+
+// Ancients returns the number of frozen items.
+func (s *S3AncientStore) Ancients() (uint64, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.meta.Items, nil
+}
+
+// AncientSize returns the ancient size of the specified category.
+func (s *S3AncientStore) AncientSize(kind string) (uint64, error) {
+	if !knownTable(kind) {
+		return 0, errUnknownTable
+	}
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.meta.Sizes[kind], nil
+}
+
+// AppendAncient stores all binary blobs belonging to a block after the last item.
+func (s *S3AncientStore) AppendAncient(number uint64, hash, header, body, receipt, td []byte) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if number != s.meta.Items {
+		return errOutOfOrder
+	}
+	for i, blob := range [][]byte{hash, header, body, receipt, td} {
+		if err := s.put(s.itemKey(s3Tables[i], number), blob); err != nil {
+			return err
+		}
+	}
+	for i, blob := range [][]byte{hash, header, body, receipt, td} {
+		s.meta.Sizes[s3Tables[i]] += uint64(len(blob))
+	}
+	s.meta.Items++
+	return nil
+}
+
+// TruncateAncients discards any recent data above the provided threshold number.
+func (s *S3AncientStore) TruncateAncients(items uint64) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if items >= s.meta.Items {
+		return nil
+	}
+	sizes := make(map[string]uint64, len(s.meta.Sizes))
+	for kind, size := range s.meta.Sizes {
+		sizes[kind] = size
+	}
+	for number := items; number < s.meta.Items; number++ {
+		for _, kind := range s3Tables {
+			size, err := s.size(s.itemKey(kind, number))
+			if err != nil {
+				return err
+			}
+			sizes[kind] -= size
+		}
+	}
+	// Mark the items as gone before deleting them, so that a failure part way
+	// through only leaves orphaned objects behind.
+	last := s.meta.Items
+	s.meta = s3Meta{Items: items, Sizes: sizes}
+	if err := s.writeMeta(); err != nil {
+		return err
+	}
+	for number := items; number < last; number++ {
+		for _, kind := range s3Tables {
+			if err := s.delete(s.itemKey(kind, number)); err != nil {
+				log.Warn("Failed to delete truncated ancient", "kind", kind, "number", number, "err", err)
+			}
+		}
+	}
+	return nil
+}
+
+// Sync persists the store metadata, committing all appended items.
+func (s *S3AncientStore) Sync() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.writeMeta()
+}
+
+// Close persists the store metadata.
+func (s *S3AncientStore) Close() error {
+	return s.Sync()
+}
@@ -0,0 +1,147 @@
+// Copyright 2020 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package lib
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/rawdb"
+)
+
+// fakeS3 is a minimal stand-in for an S3-compatible object store, supporting
+// path-style object GET, HEAD, PUT and DELETE requests.
+type fakeS3 struct {
+	objects map[string][]byte
+	lock    sync.Mutex
+}
+
+func newFakeS3() *fakeS3 {
+	return &fakeS3{objects: make(map[string][]byte)}
+}
+
+func (s *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	key := r.URL.Path
+	switch r.Method {
+	case http.MethodPut:
+		data, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		s.objects[key] = data
+		w.WriteHeader(http.StatusOK)
+	case http.MethodGet, http.MethodHead:
+		data, ok := s.objects[key]
+		if !ok {
+			w.Header().Set("Content-Type", "application/xml")
+			w.WriteHeader(http.StatusNotFound)
+			if r.Method == http.MethodGet {
+				w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?><Error><Code>NoSuchKey</Code><Message>The specified key does not exist.</Message></Error>`))
+			}
+			return
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		w.WriteHeader(http.StatusOK)
+		if r.Method == http.MethodGet {
+			w.Write(data)
+		}
+	case http.MethodDelete:
+		delete(s.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func newTestS3Store(t *testing.T, url string) *S3AncientStore {
+	api, err := NewS3API(S3Config{
+		Endpoint:  url,
+		Region:    "us-east-1",
+		AccessKey: "test",
+		SecretKey: "test",
+		PathStyle: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := NewS3AncientStore(api, "bucket", "ancients")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func TestS3AncientStore(t *testing.T) {
+	backend := newFakeS3()
+	srv := httptest.NewServer(backend)
+	defer srv.Close()
+
+	store := newTestS3Store(t, srv.URL)
+	client := newTestClient(t, store, false)
+
+	appendTestItems(t, client, 0, 20)
+	if err := client.Call(nil, rawdb.FreezerMethodAppendAncient, 30, []byte{}, []byte{}, []byte{}, []byte{}, []byte{}); err == nil {
+		t.Fatal("out of order append succeeded")
+	}
+	checkTestItems(t, client, 20)
+
+	var size uint64
+	if err := client.Call(&size, rawdb.FreezerMethodAncientSize, rawdb.FreezerRemoteBodiesTable); err != nil {
+		t.Fatal(err)
+	}
+	if want := uint64(20 * len(testBlob(rawdb.FreezerRemoteBodiesTable, 0))); size != want {
+		t.Fatalf("ancient size mismatch: have %d, want %d", size, want)
+	}
+	if _, ok := backend.objects["/bucket/ancients/bodies/0000000000000013"]; !ok {
+		t.Fatal("missing object for the last item")
+	}
+
+	// Items appended after the last sync are discarded on reopening.
+	if err := client.Call(nil, rawdb.FreezerMethodSync); err != nil {
+		t.Fatal(err)
+	}
+	appendTestItems(t, client, 20, 25)
+	store = newTestS3Store(t, srv.URL)
+	client = newTestClient(t, store, false)
+	checkTestItems(t, client, 20)
+
+	// Truncation removes the items and their objects, and is persisted.
+	appendTestItems(t, client, 20, 25)
+	if err := client.Call(nil, rawdb.FreezerMethodTruncateAncients, 10); err != nil {
+		t.Fatal(err)
+	}
+	checkTestItems(t, client, 10)
+	if _, ok := backend.objects["/bucket/ancients/bodies/0000000000000010"]; ok {
+		t.Fatal("object of truncated item not deleted")
+	}
+	if err := client.Call(&size, rawdb.FreezerMethodAncientSize, rawdb.FreezerRemoteBodiesTable); err != nil {
+		t.Fatal(err)
+	}
+	if want := uint64(10 * len(testBlob(rawdb.FreezerRemoteBodiesTable, 0))); size != want {
+		t.Fatalf("ancient size after truncation mismatch: have %d, want %d", size, want)
+	}
+	store = newTestS3Store(t, srv.URL)
+	checkTestItems(t, newTestClient(t, store, true), 10)
+}
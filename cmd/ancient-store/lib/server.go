@@ -0,0 +1,187 @@
+// Copyright 2020 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package lib
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+var (
+	errReadOnly      = errors.New("read-only freezer")
+	errRangeTooLarge = errors.New("range too large")
+)
+
+// FreezerRemoteServerAPI serves the remote freezer protocol (the freezer_* RPC
+// methods used by rawdb.FreezerRemoteClient) on top of an ancient store.
+//
+// Any number of clients may read concurrently. Writes are serialized, and can be
+// disabled altogether to share a store with nodes that must not modify it.
+type FreezerRemoteServerAPI struct {
+	store    ethdb.AncientStore
+	readonly bool
+	writeMu  sync.Mutex
+}
+
+// NewFreezerRemoteServerAPI creates a remote freezer server API for the given store.
+// If readonly is set, all methods modifying the store return an error.
+func NewFreezerRemoteServerAPI(store ethdb.AncientStore, readonly bool) *FreezerRemoteServerAPI {
+	return &FreezerRemoteServerAPI{store: store, readonly: readonly}
+}
+
+// HasAncient returns an indicator whether the specified ancient data exists.
+func (f *FreezerRemoteServerAPI) HasAncient(kind string, number uint64) (bool, error) {
+	return f.store.HasAncient(kind, number)
+}
+
+// Ancient retrieves an ancient binary blob.
+func (f *FreezerRemoteServerAPI) Ancient(kind string, number uint64) ([]byte, error) {
+	return f.store.Ancient(kind, number)
+}
+
+// AncientRange retrieves up to count consecutive ancient binary blobs of a kind,
+// starting at number start. The result is cut short at the end of the store,
+// and once the total size exceeds maxBytes (if non-zero), but holds at least
+// one item.
+func (f *FreezerRemoteServerAPI) AncientRange(kind string, start, count, maxBytes uint64) ([][]byte, error) {
+	items, err := f.store.Ancients()
+	if err != nil {
+		return nil, err
+	}
+	if start >= items {
+		return nil, errOutOfBounds
+	}
+	if items-start < count {
+		count = items - start
+	}
+	var (
+		res  [][]byte
+		size uint64
+	)
+	for i := uint64(0); i < count; i++ {
+		blob, err := f.store.Ancient(kind, start+i)
+		if err != nil {
+			return nil, err
+		}
+		if maxBytes != 0 && i > 0 && size+uint64(len(blob)) > maxBytes {
+			break
+		}
+		res = append(res, blob)
+		size += uint64(len(blob))
+	}
+	return res, nil
+}
+
+// AncientChecksum returns the checksum of count consecutive ancient binary blobs
+// of a kind, starting at number start, as computed by rawdb.FreezerRemoteChecksum.
+func (f *FreezerRemoteServerAPI) AncientChecksum(kind string, start, count uint64) (common.Hash, error) {
+	items, err := f.store.Ancients()
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if start > items || count > items-start {
+		return common.Hash{}, errOutOfBounds
+	}
+	if count > rawdb.FreezerRemoteChecksumLimit {
+		return common.Hash{}, errRangeTooLarge
+	}
+	blobs := make([][]byte, count)
+	for i := range blobs {
+		if blobs[i], err = f.store.Ancient(kind, start+uint64(i)); err != nil {
+			return common.Hash{}, err
+		}
+	}
+	return rawdb.FreezerRemoteChecksum(blobs), nil
+}
+
+// Ancients returns the number of frozen items.
+func (f *FreezerRemoteServerAPI) Ancients() (uint64, error) {
+	return f.store.Ancients()
+}
+
+// AncientSize returns the ancient size of the specified category.
+func (f *FreezerRemoteServerAPI) AncientSize(kind string) (uint64, error) {
+	return f.store.AncientSize(kind)
+}
+
+// AppendAncient injects all binary blobs belonging to a block at the end of the store.
+func (f *FreezerRemoteServerAPI) AppendAncient(number uint64, hash, header, body, receipt, td []byte) error {
+	if f.readonly {
+		return errReadOnly
+	}
+	f.writeMu.Lock()
+	defer f.writeMu.Unlock()
+	return f.store.AppendAncient(number, hash, header, body, receipt, td)
+}
+
+// AppendAncients injects the blobs of consecutive blocks at the end of the store.
+// Batches not following the last item are rejected without appending anything.
+func (f *FreezerRemoteServerAPI) AppendAncients(items []*rawdb.FreezerRemoteItem) error {
+	if f.readonly {
+		return errReadOnly
+	}
+	f.writeMu.Lock()
+	defer f.writeMu.Unlock()
+
+	frozen, err := f.store.Ancients()
+	if err != nil {
+		return err
+	}
+	for i, item := range items {
+		if item.Number != frozen+uint64(i) {
+			return errOutOfOrder
+		}
+	}
+	for _, item := range items {
+		if err := f.store.AppendAncient(item.Number, item.Hash, item.Header, item.Body, item.Receipts, item.Td); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TruncateAncients discards any recent data above the provided threshold number.
+func (f *FreezerRemoteServerAPI) TruncateAncients(items uint64) error {
+	if f.readonly {
+		return errReadOnly
+	}
+	f.writeMu.Lock()
+	defer f.writeMu.Unlock()
+	return f.store.TruncateAncients(items)
+}
+
+// Sync flushes all data to the backing storage.
+// It is a no-op for read-only servers, since clients sync after every freezing
+// attempt, whether or not anything was appended.
+func (f *FreezerRemoteServerAPI) Sync() error {
+	if f.readonly {
+		return nil
+	}
+	f.writeMu.Lock()
+	defer f.writeMu.Unlock()
+	return f.store.Sync()
+}
+
+// Close is called by clients when they shut down. The store is shared between
+// clients, so it is left open; it is closed when the server shuts down.
+func (f *FreezerRemoteServerAPI) Close() error {
+	return nil
+}
@@ -0,0 +1,226 @@
+// Copyright 2020 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package lib
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+func newTestClient(t *testing.T, store ethdb.AncientStore, readonly bool) *rpc.Client {
+	server := rpc.NewServer()
+	if err := server.RegisterName("freezer", NewFreezerRemoteServerAPI(store, readonly)); err != nil {
+		t.Fatal(err)
+	}
+	return rpc.DialInProc(server)
+}
+
+func testBlob(kind string, number uint64) []byte {
+	return append([]byte(kind), byte(number), byte(number>>8))
+}
+
+func appendTestItems(t *testing.T, client *rpc.Client, from, to uint64) {
+	for n := from; n < to; n++ {
+		err := client.Call(nil, rawdb.FreezerMethodAppendAncient, n,
+			testBlob(rawdb.FreezerRemoteHashTable, n),
+			testBlob(rawdb.FreezerRemoteHeaderTable, n),
+			testBlob(rawdb.FreezerRemoteBodiesTable, n),
+			testBlob(rawdb.FreezerRemoteReceiptTable, n),
+			testBlob(rawdb.FreezerRemoteDifficultyTable, n),
+		)
+		if err != nil {
+			t.Fatalf("append %d: %v", n, err)
+		}
+	}
+}
+
+func checkTestItems(t *testing.T, client *rpc.Client, items uint64) {
+	var have uint64
+	if err := client.Call(&have, rawdb.FreezerMethodAncients); err != nil {
+		t.Fatal(err)
+	}
+	if have != items {
+		t.Fatalf("ancients mismatch: have %d, want %d", have, items)
+	}
+	for n := uint64(0); n < items; n++ {
+		for _, kind := range s3Tables {
+			var blob []byte
+			if err := client.Call(&blob, rawdb.FreezerMethodAncient, kind, n); err != nil {
+				t.Fatalf("ancient %s %d: %v", kind, n, err)
+			}
+			if want := testBlob(kind, n); !bytes.Equal(blob, want) {
+				t.Fatalf("ancient %s %d: have %x, want %x", kind, n, blob, want)
+			}
+		}
+	}
+	var has bool
+	if err := client.Call(&has, rawdb.FreezerMethodHasAncient, rawdb.FreezerRemoteHeaderTable, items); err != nil {
+		t.Fatal(err)
+	}
+	if has {
+		t.Fatalf("item %d beyond the ancients exists", items)
+	}
+}
+
+func TestFreezerRemoteServerFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ancient-store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store, err := rawdb.NewFreezer(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	writer := newTestClient(t, store, false)
+	reader := newTestClient(t, store, true)
+
+	// Readers may read concurrently while the writer appends.
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			var items uint64
+			if err := reader.Call(&items, rawdb.FreezerMethodAncients); err != nil {
+				t.Error(err)
+				return
+			}
+			if items > 0 {
+				var blob []byte
+				if err := reader.Call(&blob, rawdb.FreezerMethodAncient, rawdb.FreezerRemoteBodiesTable, items-1); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}
+	}()
+	appendTestItems(t, writer, 0, 100)
+	wg.Wait()
+
+	if err := writer.Call(nil, rawdb.FreezerMethodSync); err != nil {
+		t.Fatal(err)
+	}
+	checkTestItems(t, reader, 100)
+
+	// Read-only clients may not modify the store, but may sync (a no-op).
+	if err := reader.Call(nil, rawdb.FreezerMethodAppendAncient, 100, []byte{}, []byte{}, []byte{}, []byte{}, []byte{}); err == nil {
+		t.Fatal("read-only append succeeded")
+	}
+	if err := reader.Call(nil, rawdb.FreezerMethodTruncateAncients, 50); err == nil {
+		t.Fatal("read-only truncate succeeded")
+	}
+	if err := reader.Call(nil, rawdb.FreezerMethodSync); err != nil {
+		t.Fatalf("read-only sync failed: %v", err)
+	}
+	// Clients closing must not close the shared store.
+	if err := writer.Call(nil, rawdb.FreezerMethodClose); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Call(nil, rawdb.FreezerMethodTruncateAncients, 60); err != nil {
+		t.Fatal(err)
+	}
+	checkTestItems(t, reader, 60)
+
+	// The store persists across restarts.
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+	store, err = rawdb.NewFreezer(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	checkTestItems(t, newTestClient(t, store, true), 60)
+}
+
+func TestFreezerRemoteServerBatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ancient-store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store, err := rawdb.NewFreezer(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	writer := newTestClient(t, store, false)
+	reader := newTestClient(t, store, true)
+
+	var items []*rawdb.FreezerRemoteItem
+	for n := uint64(0); n < 50; n++ {
+		items = append(items, &rawdb.FreezerRemoteItem{
+			Number:   n,
+			Hash:     testBlob(rawdb.FreezerRemoteHashTable, n),
+			Header:   testBlob(rawdb.FreezerRemoteHeaderTable, n),
+			Body:     testBlob(rawdb.FreezerRemoteBodiesTable, n),
+			Receipts: testBlob(rawdb.FreezerRemoteReceiptTable, n),
+			Td:       testBlob(rawdb.FreezerRemoteDifficultyTable, n),
+		})
+	}
+	if err := reader.Call(nil, rawdb.FreezerMethodAppendAncients, items); err == nil {
+		t.Fatal("read-only batch append succeeded")
+	}
+	if err := writer.Call(nil, rawdb.FreezerMethodAppendAncients, items[:30]); err != nil {
+		t.Fatal(err)
+	}
+	// Batches with gaps are rejected as a whole.
+	gapped := append(append([]*rawdb.FreezerRemoteItem{}, items[30:35]...), items[36:]...)
+	if err := writer.Call(nil, rawdb.FreezerMethodAppendAncients, gapped); err == nil {
+		t.Fatal("gapped batch append succeeded")
+	}
+	checkTestItems(t, reader, 30)
+	if err := writer.Call(nil, rawdb.FreezerMethodAppendAncients, items[30:]); err != nil {
+		t.Fatal(err)
+	}
+	checkTestItems(t, reader, 50)
+
+	var blobs [][]byte
+	if err := reader.Call(&blobs, rawdb.FreezerMethodAncientRange, rawdb.FreezerRemoteHeaderTable, 40, 20, 0); err != nil {
+		t.Fatal(err)
+	}
+	if len(blobs) != 10 {
+		t.Fatalf("range length mismatch: have %d, want 10", len(blobs))
+	}
+	for i, blob := range blobs {
+		if want := testBlob(rawdb.FreezerRemoteHeaderTable, uint64(40+i)); !bytes.Equal(blob, want) {
+			t.Fatalf("range item %d: have %x, want %x", i, blob, want)
+		}
+	}
+	var sum common.Hash
+	if err := reader.Call(&sum, rawdb.FreezerMethodAncientChecksum, rawdb.FreezerRemoteHeaderTable, 40, 10); err != nil {
+		t.Fatal(err)
+	}
+	if want := rawdb.FreezerRemoteChecksum(blobs); sum != want {
+		t.Fatalf("checksum mismatch: have %x, want %x", sum, want)
+	}
+	if err := reader.Call(&sum, rawdb.FreezerMethodAncientChecksum, rawdb.FreezerRemoteHeaderTable, 40, uint64(math.MaxUint64-10)); err == nil {
+		t.Fatal("overflowing checksum range succeeded")
+	}
+}
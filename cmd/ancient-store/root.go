@@ -0,0 +1,184 @@
+// Copyright 2020 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/ethereum/go-ethereum/cmd/ancient-store/lib"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/spf13/cobra"
+)
+
+var (
+	datadir     string
+	s3Bucket    string
+	s3Prefix    string
+	s3Endpoint  string
+	s3Region    string
+	s3PathStyle bool
+
+	ipcPath          string
+	httpAddr         string
+	readonlyIPCPath  string
+	readonlyHTTPAddr string
+
+	verbosity int
+)
+
+// rootCmd represents the base command when called without any subcommands
+var rootCmd = &cobra.Command{
+	Use:   "ancient-store",
+	Short: "Persistent remote ancient store application",
+	Long: `Serves a persistent ancient store over the remote freezer RPC protocol (--ancient.rpc).
+
+Ancient data is stored either in append-only flat files in a directory (--datadir),
+using the same format as the built-in freezer, or as objects in an S3-compatible
+bucket (--s3.bucket). S3 credentials are read from the environment
+(AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY).
+
+The read-write endpoints (--ipc, --http) must be used by a single node.
+Any number of nodes may share the store through the read-only endpoints
+(--ro.ipc, --ro.http), which reject appends and truncations. Such nodes must
+be started with --ancient.rpc.readonly.
+`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		log.Root().SetHandler(log.LvlFilterHandler(log.Lvl(verbosity), log.StreamHandler(os.Stderr, log.TerminalFormat(false))))
+
+		if ipcPath == "" && httpAddr == "" && readonlyIPCPath == "" && readonlyHTTPAddr == "" {
+			return errors.New("no endpoint configured")
+		}
+		store, err := openStore()
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err := store.Close(); err != nil {
+				log.Error("Failed to close ancient store", "err", err)
+			}
+		}()
+		var (
+			servers   []*rpc.Server
+			listeners []net.Listener
+		)
+		defer func() {
+			for _, l := range listeners {
+				l.Close()
+			}
+			for _, s := range servers {
+				s.Stop()
+			}
+		}()
+		for _, endpoint := range []struct {
+			ipc, http string
+			readonly  bool
+		}{
+			{ipcPath, httpAddr, false},
+			{readonlyIPCPath, readonlyHTTPAddr, true},
+		} {
+			if endpoint.ipc == "" && endpoint.http == "" {
+				continue
+			}
+			apis := []rpc.API{{
+				Namespace: "freezer",
+				Service:   lib.NewFreezerRemoteServerAPI(store, endpoint.readonly),
+				Public:    true,
+			}}
+			if endpoint.ipc != "" {
+				l, server, err := rpc.StartIPCEndpoint(endpoint.ipc, apis)
+				if err != nil {
+					return err
+				}
+				listeners, servers = append(listeners, l), append(servers, server)
+				log.Info("IPC endpoint opened", "url", endpoint.ipc, "readonly", endpoint.readonly)
+			}
+			if endpoint.http != "" {
+				server := rpc.NewServer()
+				servers = append(servers, server)
+				if err := server.RegisterName(apis[0].Namespace, apis[0].Service); err != nil {
+					return err
+				}
+				l, err := net.Listen("tcp", endpoint.http)
+				if err != nil {
+					return err
+				}
+				listeners = append(listeners, l)
+				go http.Serve(l, server)
+				log.Info("HTTP endpoint opened", "url", fmt.Sprintf("http://%v", l.Addr()), "readonly", endpoint.readonly)
+			}
+		}
+		sigc := make(chan os.Signal, 1)
+		signal.Notify(sigc, syscall.SIGINT, syscall.SIGTERM)
+		<-sigc
+		log.Info("Got interrupt, shutting down...")
+		return nil
+	},
+}
+
+func openStore() (ethdb.AncientStore, error) {
+	switch {
+	case datadir != "" && s3Bucket != "":
+		return nil, errors.New("--datadir and --s3.bucket are mutually exclusive")
+	case datadir != "":
+		return rawdb.NewFreezer(datadir, "")
+	case s3Bucket != "":
+		api, err := lib.NewS3API(lib.S3Config{
+			Endpoint:  s3Endpoint,
+			Region:    s3Region,
+			PathStyle: s3PathStyle,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return lib.NewS3AncientStore(api, s3Bucket, s3Prefix)
+	}
+	return nil, errors.New("either --datadir or --s3.bucket is required")
+}
+
+func init() {
+	flags := rootCmd.Flags()
+	flags.StringVar(&datadir, "datadir", "", "Directory of the ancient store flat files")
+	flags.StringVar(&s3Bucket, "s3.bucket", "", "S3 bucket of the ancient store")
+	flags.StringVar(&s3Prefix, "s3.prefix", "", "Key prefix of the ancient store objects within the S3 bucket")
+	flags.StringVar(&s3Endpoint, "s3.endpoint", "", "S3 endpoint URL, for S3-compatible stores other than AWS")
+	flags.StringVar(&s3Region, "s3.region", "us-east-1", "S3 region")
+	flags.BoolVar(&s3PathStyle, "s3.pathstyle", false, "Use path-style S3 bucket addressing")
+	flags.StringVar(&ipcPath, "ipc", "", "IPC path of the read-write endpoint")
+	flags.StringVar(&httpAddr, "http", "", "Listening address (host:port) of the read-write HTTP endpoint")
+	flags.StringVar(&readonlyIPCPath, "ro.ipc", "", "IPC path of the read-only endpoint")
+	flags.StringVar(&readonlyHTTPAddr, "ro.http", "", "Listening address (host:port) of the read-only HTTP endpoint")
+	flags.IntVar(&verbosity, "verbosity", int(log.LvlInfo), "Logging verbosity: 0=silent, 1=error, 2=warn, 3=info, 4=debug, 5=detail")
+}
+
+// Execute adds all child commands to the root command and sets flags appropriately.
+// This is called by main.main(). It only needs to happen once to the rootCmd.
+func Execute() {
+	if err := rootCmd.Execute(); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/params/types/ctypes"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var diffCommand = cli.Command{
+	Name:  "diff",
+	Usage: "Compare the configuration against another, printing differing fields",
+	Description: `Compares the global chain configuration (A) against a second one (B) over the ChainConfigurator getters.
+B is established with the command's own --default, or --inputf and --file (or standard input) options.
+Each differing field is printed as: <field> <A value> <B value>; unset values are printed as '-'.
+Reward and difficulty bomb delay schedules are compared per block.
+
+Exits 0 if the configurations do not differ, 1 if they do.`,
+	Flags: []cli.Flag{
+		formatInFlag,
+		fileInFlag,
+		defaultValueFlag,
+	},
+	Action: diff,
+}
+
+// diffChainspecValue establishes the B side of a diff from the command's flags.
+// The input format defaults to the global one if not given.
+func diffChainspecValue(ctx *cli.Context) (ctypes.Configurator, error) {
+	if ctx.IsSet(defaultValueFlag.Name) {
+		v, ok := defaultChainspecValues[ctx.String(defaultValueFlag.Name)]
+		if !ok {
+			return nil, fmt.Errorf("error: %v, name: %s", errInvalidDefaultValue, ctx.String(defaultValueFlag.Name))
+		}
+		return v, nil
+	}
+	var (
+		data []byte
+		err  error
+	)
+	if ctx.IsSet(fileInFlag.Name) {
+		data, err = ioutil.ReadFile(ctx.String(fileInFlag.Name))
+	} else {
+		data, err = ioutil.ReadAll(os.Stdin)
+	}
+	if err != nil {
+		return nil, err
+	}
+	format := ctx.String(formatInFlag.Name)
+	if format == "" {
+		format = ctx.GlobalString(formatInFlag.Name)
+	}
+	return unmarshalChainSpec(format, data)
+}
+
+func diff(ctx *cli.Context) error {
+	b, err := diffChainspecValue(ctx)
+	if err != nil {
+		return err
+	}
+	diffs := diffConfigurators(globalChainspecValue, b)
+	for _, d := range diffs {
+		fmt.Println(d.field, d.a, d.b)
+	}
+	if len(diffs) > 0 {
+		return cli.NewExitError(fmt.Sprintf("configurations differ in %d fields", len(diffs)), 1)
+	}
+	return nil
+}
+
+type fieldDiff struct {
+	field string
+	a, b  string
+}
+
+// diffConfigurators compares the values returned by all argument-less getters
+// of the ChainConfigurator interface. Map values (eg. block reward schedules)
+// are compared key by key.
+func diffConfigurators(a, b ctypes.ChainConfigurator) []fieldDiff {
+	diffs := []fieldDiff{}
+	iface := reflect.TypeOf((*ctypes.ChainConfigurator)(nil)).Elem()
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	for i := 0; i < iface.NumMethod(); i++ {
+		method := iface.Method(i)
+		if !strings.HasPrefix(method.Name, "Get") || method.Type.NumIn() != 0 || method.Type.NumOut() != 1 {
+			continue
+		}
+		name := strings.TrimPrefix(method.Name, "Get")
+		name = strings.TrimSuffix(name, "Transition")
+
+		ra := va.MethodByName(method.Name).Call(nil)[0]
+		rb := vb.MethodByName(method.Name).Call(nil)[0]
+
+		if ra.Kind() == reflect.Map {
+			fa, fb := formatMapValue(ra), formatMapValue(rb)
+			for _, k := range unionKeys(fa, fb) {
+				sa, ok := fa[k]
+				if !ok {
+					sa = "-"
+				}
+				sb, ok := fb[k]
+				if !ok {
+					sb = "-"
+				}
+				if sa != sb {
+					diffs = append(diffs, fieldDiff{fmt.Sprintf("%s[%d]", name, k), sa, sb})
+				}
+			}
+			continue
+		}
+		if sa, sb := formatValue(ra), formatValue(rb); sa != sb {
+			diffs = append(diffs, fieldDiff{name, sa, sb})
+		}
+	}
+	return diffs
+}
+
+// formatValue formats a getter return value for printing, dereferencing
+// non-nil pointers and representing nil ones as '-'.
+func formatValue(v reflect.Value) string {
+	if v.Kind() == reflect.Ptr && v.IsNil() {
+		return "-"
+	}
+	// Prefer String methods, eg. *big.Int and common.Hash.
+	if s, ok := v.Interface().(fmt.Stringer); ok {
+		return s.String()
+	}
+	if v.Kind() == reflect.Ptr {
+		return fmt.Sprintf("%v", v.Elem().Interface())
+	}
+	return fmt.Sprintf("%v", v.Interface())
+}
+
+// formatMapValue formats the values of a uint64-keyed map.
+func formatMapValue(v reflect.Value) map[uint64]string {
+	m := make(map[uint64]string, v.Len())
+	iter := v.MapRange()
+	for iter.Next() {
+		m[iter.Key().Uint()] = formatValue(iter.Value())
+	}
+	return m
+}
+
+func unionKeys(a, b map[uint64]string) []uint64 {
+	keys := []uint64{}
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i] < keys[j]
+	})
+	return keys
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"testing"
+
+	"gopkg.in/urfave/cli.v1"
+)
+
+func TestDiffConfigurators(t *testing.T) {
+	if diffs := diffConfigurators(defaultChainspecValues["classic"], defaultChainspecValues["classic"]); len(diffs) != 0 {
+		t.Errorf("identical configurations differ: %v", diffs)
+	}
+	diffs := diffConfigurators(defaultChainspecValues["classic"], defaultChainspecValues["mordor"])
+	found := false
+	for _, d := range diffs {
+		if d.field == "EIP7" {
+			found = true
+			if d.a != "1150000" || d.b != "0" {
+				t.Errorf("EIP7 diff mismatch: have %s %s, want 1150000 0", d.a, d.b)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("EIP7 difference not reported: %v", diffs)
+	}
+}
+
+func TestDiffExitCode(t *testing.T) {
+	exited := -1
+	defer func(exiter func(int)) { cli.OsExiter = exiter }(cli.OsExiter)
+	cli.OsExiter = func(code int) { exited = code }
+
+	for _, tt := range []struct {
+		other string
+		code  int
+	}{
+		{"classic", -1},
+		{"mordor", 1},
+	} {
+		exited = -1
+		err := app.Run([]string{"echainspec", "--default", "classic", "diff", "--default", tt.other})
+		if exited != tt.code {
+			t.Errorf("diff against %s: exit code mismatch: have %d, want %d", tt.other, exited, tt.code)
+		}
+		if (err != nil) != (tt.code != -1) {
+			t.Errorf("diff against %s: unexpected error: %v", tt.other, err)
+		}
+	}
+}
@@ -9,11 +9,13 @@ import (
 
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/params/confp"
+	"github.com/ethereum/go-ethereum/params/types/besu"
 	"github.com/ethereum/go-ethereum/params/types/coregeth"
 	"github.com/ethereum/go-ethereum/params/types/ctypes"
 	"github.com/ethereum/go-ethereum/params/types/genesisT"
 	"github.com/ethereum/go-ethereum/params/types/goethereum"
 	"github.com/ethereum/go-ethereum/params/types/multigeth"
+	"github.com/ethereum/go-ethereum/params/types/nethermind"
 	"github.com/ethereum/go-ethereum/params/types/parity"
 	"gopkg.in/urfave/cli.v1"
 )
@@ -32,7 +34,11 @@ var (
 		"geth": &genesisT.Genesis{
 			Config: &goethereum.ChainConfig{},
 		},
-		"parity": &parity.ParityChainSpec{},
+		"besu": &genesisT.Genesis{
+			Config: &besu.ChainConfig{},
+		},
+		"parity":     &parity.ParityChainSpec{},
+		"nethermind": &nethermind.NethermindChainSpec{},
 		// TODO
 		// "aleth"
 		// "retesteth"
@@ -211,6 +217,14 @@ EXAMPLES:
 	
 		> {{.Name}} --default kotti validate 3000000
 
+	Compare a default Ethereum Classic network chain configuration against an external Parity chainspec:
+
+		> {{.Name}} --default classic diff --inputf parity --file my-parity-spec.json
+
+	List the IPs and fork IDs of a default Mordor network chain configuration up to block #3000000:
+
+		> {{.Name}} --default mordor schedule 0 3000000
+
 VERSION:
    {{.Version}}
 
@@ -234,6 +248,8 @@ GLOBAL OPTIONS:
 		validateCommand,
 		forksCommand,
 		ipsCommand,
+		diffCommand,
+		scheduleCommand,
 	}
 	app.Before = mustGetChainspecValue
 	app.Action = convertf
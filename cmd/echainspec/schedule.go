@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/forkid"
+	"github.com/ethereum/go-ethereum/params/confp"
+	"github.com/ethereum/go-ethereum/params/types/coregeth"
+	"github.com/ethereum/go-ethereum/params/types/ctypes"
+	"github.com/ethereum/go-ethereum/params/types/genesisT"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var scheduleCommand = cli.Command{
+	Name:  "schedule",
+	Usage: "List the IPs activating at each fork block and the resulting fork ID",
+	Description: `Prints, for each fork block in the (inclusive) range, the block number, the fork ID (hash and
+next fork) at that block, and the names of the IPs activating at it.
+Fork blocks are those listed by the forks command. The range defaults to all of them.`,
+	ArgsUsage: "[<from>] [<to>]",
+	Action:    schedule,
+}
+
+func parseBlockArg(s string) (uint64, error) {
+	var n math.HexOrDecimal64
+	if err := n.UnmarshalText([]byte(s)); err != nil {
+		return 0, err
+	}
+	return uint64(n), nil
+}
+
+// chainspecGenesisHash returns the hash of the genesis block defined by the
+// configuration, converting it to a Genesis value first if necessary.
+func chainspecGenesisHash(conf ctypes.Configurator) (common.Hash, error) {
+	g, ok := conf.(*genesisT.Genesis)
+	if !ok {
+		g = &genesisT.Genesis{Config: &coregeth.CoreGethChainConfig{}}
+		if err := confp.Convert(conf, g); err != nil {
+			return common.Hash{}, err
+		}
+	}
+	return core.GenesisToBlock(g, nil).Hash(), nil
+}
+
+// scheduleEntry describes the IPs activating at a fork block.
+type scheduleEntry struct {
+	block uint64
+	id    forkid.ID
+	names []string
+}
+
+// forkSchedule returns the fork blocks of the configuration in the (inclusive)
+// range, along with their fork IDs and the IPs activating at them.
+func forkSchedule(conf ctypes.ChainConfigurator, genesis common.Hash, from, to uint64) []scheduleEntry {
+	entries := []scheduleEntry{}
+	fns, names := confp.Transitions(conf)
+	for _, n := range confp.Forks(conf) {
+		if n < from || n > to {
+			continue
+		}
+		entry := scheduleEntry{block: n, id: forkid.NewID(conf, genesis, n)}
+		for i, fn := range fns {
+			if v := fn(); v != nil && *v == n {
+				name := strings.TrimPrefix(names[i], "Get")
+				entry.names = append(entry.names, strings.TrimSuffix(name, "Transition"))
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func schedule(ctx *cli.Context) error {
+	var from, to uint64 = 0, math.MaxUint64
+	var err error
+	if ctx.NArg() > 0 {
+		if from, err = parseBlockArg(ctx.Args().Get(0)); err != nil {
+			return err
+		}
+	}
+	if ctx.NArg() > 1 {
+		if to, err = parseBlockArg(ctx.Args().Get(1)); err != nil {
+			return err
+		}
+	}
+	genesis, err := chainspecGenesisHash(globalChainspecValue)
+	if err != nil {
+		return err
+	}
+	for _, entry := range forkSchedule(globalChainspecValue, genesis, from, to) {
+		fmt.Println(entry.block, fmt.Sprintf("%#x", entry.id.Hash), entry.id.Next, strings.Join(entry.names, ","))
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/params/confp"
+)
+
+func TestForkSchedule(t *testing.T) {
+	conf := defaultChainspecValues["classic"]
+	genesis, err := chainspecGenesisHash(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if genesis != params.MainnetGenesisHash {
+		t.Fatalf("genesis hash mismatch: have %x, want %x", genesis, params.MainnetGenesisHash)
+	}
+
+	entries := forkSchedule(conf, genesis, 0, 3000000)
+	if len(entries) != 3 {
+		t.Fatalf("entry count mismatch: have %d, want 3", len(entries))
+	}
+	homestead := entries[0]
+	if homestead.block != 1150000 || homestead.id.Next != 2500000 || homestead.id.Hash != [4]byte{0x97, 0xc2, 0xc3, 0x4c} {
+		t.Errorf("homestead entry mismatch: block %d, id %x/%d", homestead.block, homestead.id.Hash, homestead.id.Next)
+	}
+	for _, want := range []string{"EIP2", "EIP7"} {
+		found := false
+		for _, name := range homestead.names {
+			found = found || name == want
+		}
+		if !found {
+			t.Errorf("homestead entry missing %s: %v", want, homestead.names)
+		}
+	}
+	if entries[2].block != 3000000 {
+		t.Errorf("range end not inclusive: last entry at %d", entries[2].block)
+	}
+
+	// The fork blocks are exactly those of the forks command, which excludes
+	// the genesis, disabled transitions and compatible-by-name ones (ECBP1100).
+	var blocks []uint64
+	for _, entry := range forkSchedule(conf, genesis, 0, ^uint64(0)) {
+		blocks = append(blocks, entry.block)
+	}
+	if want := confp.Forks(conf); !reflect.DeepEqual(blocks, want) {
+		t.Errorf("fork blocks mismatch: have %v, want %v", blocks, want)
+	}
+}
@@ -2,14 +2,12 @@ package main
 
 import (
 	"encoding/json"
-	"fmt"
 	"io/ioutil"
 	"os"
-	"strings"
+	"reflect"
 
 	"github.com/ethereum/go-ethereum/params/types/ctypes"
 	"github.com/ethereum/go-ethereum/params/types/genesisT"
-	"github.com/ethereum/go-ethereum/params/types/parity"
 	"gopkg.in/urfave/cli.v1"
 )
 
@@ -21,46 +19,41 @@ func readInputData(ctx *cli.Context) ([]byte, error) {
 }
 
 func unmarshalChainSpec(format string, data []byte) (conf ctypes.Configurator, err error) {
-	conf, ok := chainspecFormatTypes[format]
+	proto, ok := chainspecFormatTypes[format]
 	if !ok {
 		return nil, errInvalidChainspecValue
 	}
-	err = json.Unmarshal(data, conf)
-	if err != nil {
-		return conf, err
-	}
-	if !strings.Contains(format, "geth") {
+	// Decode into fresh values rather than the format prototypes,
+	// since a command may read more than one chain specification.
+	pt, ok := proto.(*genesisT.Genesis)
+	if !ok {
+		// Chainspec types like Parity's already conform to ChainConfigurator.
+		conf = reflect.New(reflect.TypeOf(proto).Elem()).Interface().(ctypes.Configurator)
+		err = json.Unmarshal(data, conf)
 		return
 	}
+	configType := reflect.TypeOf(pt.Config).Elem()
+	t := &genesisT.Genesis{}
+	err = json.Unmarshal(data, t)
+	if err != nil {
+		return t, err
+	}
 	// Logic in params/types/gen_genesis.go already "auto-magically"
 	// handles genesis Config unmarshaling, and IT PREFERS COREGETH,
-	// and the two data types are not mutually exclusive (are overlapping).
+	// and the data types are not mutually exclusive (are overlapping).
 	// So we need to redo custom unmarshaling logic to enforce data type
 	// preference based on passed format value.
 	type dec struct {
 		Config ctypes.ChainConfigurator `json:"config"`
 	}
 	var d dec
-	configurator, ok := chainspecFormatTypes[format]
-	if !ok {
-		return nil, fmt.Errorf("unknown chainspec format type: %v", format)
-	}
-	switch t := configurator.(type) {
-	case *genesisT.Genesis:
-		d.Config = t.Config
-	case *parity.ParityChainSpec:
-		// Don't need to do anything here; the Parity type already conforms to ChainConfigurator.
-	default:
-		return nil, fmt.Errorf("unhandled chainspec type: %v %v", format, t)
-	}
-	t := chainspecFormatTypes[format].(*genesisT.Genesis)
+	d.Config = reflect.New(configType).Interface().(ctypes.ChainConfigurator)
 	err = json.Unmarshal(data, &d)
 	if err != nil {
-		return conf, err
+		return t, err
 	}
 	t.Config = d.Config
-	conf = t
-	return
+	return t, nil
 }
 
 func jsonMarshalPretty(i interface{}) ([]byte, error) {
@@ -18,6 +18,7 @@ package t8ntool
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"math/big"
@@ -202,6 +203,10 @@ func Main(ctx *cli.Context) error {
 		return err
 	}
 
+	// Sanity check, to not `panic` in state_transition
+	if chainConfig.IsEnabled(chainConfig.GetEIP1559Transition, new(big.Int).SetUint64(prestate.Env.Number)) && prestate.Env.BaseFee == nil {
+		return NewError(ErrorVMConfig, errors.New("EIP-1559 config but missing 'currentBaseFee' in env section"))
+	}
 	// Run the test and aggregate the result
 	state, result, err := prestate.Apply(vmConfig, chainConfig, txs, ctx.Int64(RewardFlag.Name), getTracer)
 	if err != nil {
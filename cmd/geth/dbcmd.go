@@ -0,0 +1,717 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/olekukonko/tablewriter"
+	"gopkg.in/urfave/cli.v1"
+)
+
+const (
+	// migrationSamples is the number of key-value pairs compared one by one
+	// between the source and destination databases after a migration.
+	migrationSamples = 10000
+
+	// migrationCheckpointInterval is the minimum time between two persisted
+	// checkpoints of a running migration.
+	migrationCheckpointInterval = 5 * time.Second
+)
+
+// Stages of a database migration, persisted in its checkpoint.
+const (
+	migrationCopying   = "copying"   // Keys are being copied into the new database
+	migrationCopied    = "copied"    // All keys were copied, verification pending
+	migrationVerified  = "verified"  // The copy was verified, the old database is being moved away
+	migrationReplacing = "replacing" // The old database was moved away, the new one is being moved in
+)
+
+var (
+	dbCommand = cli.Command{
+		Name:        "db",
+		Usage:       "Low level database operations",
+		ArgsUsage:   "",
+		Category:    "DATABASE COMMANDS",
+		Description: "",
+		Subcommands: []cli.Command{
+			{
+				Name:      "migrate",
+				Usage:     "Migrate the chain database to another key-value store engine",
+				ArgsUsage: "<engine>",
+				Action:    utils.MigrateFlags(migrateDB),
+				Category:  "DATABASE COMMANDS",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.CacheFlag,
+					utils.SyncModeFlag,
+					utils.ClassicFlag,
+					utils.MordorFlag,
+					utils.KottiFlag,
+					utils.SocialFlag,
+					utils.EthersocialFlag,
+					utils.LegacyTestnetFlag,
+					utils.RopstenFlag,
+					utils.RinkebyFlag,
+					utils.GoerliFlag,
+					utils.YoloV1Flag,
+				},
+				Description: `
+geth db migrate <engine>
+will copy all the keys of the chain database into a new database backed by
+the given engine ('leveldb' or 'pebble'), verify the copy and replace the
+original database with it. The ancient chain segments are not touched.
+
+The new database is built next to the original one, so enough free disk
+space is needed to hold both. Once replaced, the original database is kept
+in a 'chaindata.<engine>.bak' folder and can be removed after checking the
+node runs fine on the new one.
+
+If the migration is interrupted, it's resumed the next time this command
+is run with the same engine.
+`,
+			},
+			{
+				Name:      "freezer-check",
+				Usage:     "Check the integrity of the ancient chain segments",
+				ArgsUsage: "",
+				Action:    utils.MigrateFlags(checkFreezer),
+				Category:  "DATABASE COMMANDS",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.AncientFlag,
+					utils.SyncModeFlag,
+					utils.ClassicFlag,
+					utils.MordorFlag,
+					utils.KottiFlag,
+					utils.SocialFlag,
+					utils.EthersocialFlag,
+					utils.LegacyTestnetFlag,
+					utils.RopstenFlag,
+					utils.RinkebyFlag,
+					utils.GoerliFlag,
+					utils.YoloV1Flag,
+				},
+				Description: `
+geth db freezer-check
+will validate the ancient database (freezer) without modifying it. For every
+table (hashes, headers, bodies, receipts and difficulties) the index file is
+cross checked against the data files, and every item is read back, verifying
+its checksum if the table stores any and decompressing it if the table is
+compressed. The items of every block are also checked against each other:
+the header has to match the canonical hash, and the body and receipts have
+to match the roots committed to in the header.
+
+Per-item checksums are only stored by tables created with --ancient.checksum.
+`,
+			},
+			{
+				Name:      "freezer-export",
+				Usage:     "Export the raw ancient chain segments into a file",
+				ArgsUsage: "<filename> [<blockNumFirst> <blockNumLast>]",
+				Action:    utils.MigrateFlags(exportFreezer),
+				Category:  "DATABASE COMMANDS",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.AncientFlag,
+					utils.SyncModeFlag,
+					utils.ClassicFlag,
+					utils.MordorFlag,
+					utils.KottiFlag,
+					utils.SocialFlag,
+					utils.EthersocialFlag,
+					utils.LegacyTestnetFlag,
+					utils.RopstenFlag,
+					utils.RinkebyFlag,
+					utils.GoerliFlag,
+					utils.YoloV1Flag,
+				},
+				Description: `
+geth db freezer-export <filename> [<blockNumFirst> <blockNumLast>]
+will export the raw ancient data of the given blocks, all of them by default,
+into a file which can be used to rebuild a damaged ancient database with
+'geth db freezer-repair'. If the file ends with .gz, the output will be
+gzipped.
+`,
+			},
+			{
+				Name:      "freezer-repair",
+				Usage:     "Rebuild the damaged tail of the ancient chain segments",
+				ArgsUsage: "[<filename>]",
+				Action:    utils.MigrateFlags(repairFreezer),
+				Category:  "DATABASE COMMANDS",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.AncientFlag,
+					utils.SyncModeFlag,
+					utils.ClassicFlag,
+					utils.MordorFlag,
+					utils.KottiFlag,
+					utils.SocialFlag,
+					utils.EthersocialFlag,
+					utils.LegacyTestnetFlag,
+					utils.RopstenFlag,
+					utils.RinkebyFlag,
+					utils.GoerliFlag,
+					utils.YoloV1Flag,
+				},
+				Description: `
+geth db freezer-repair [<filename>]
+will check the ancient database like 'geth db freezer-check' does, and if
+it's damaged, truncate it to the last block intact in every table.
+
+If a file exported by 'geth db freezer-export' is given, the discarded blocks
+are rebuilt from it after being verified.
+
+This command does not download anything from peers itself. Any block which
+can't be rebuilt from a file stays missing: on the next start the node rewinds
+the chain database to the truncated ancient database, and the missing blocks
+(along with every more recent one) are only fetched again by the regular
+sync. Depending on how far back the damage is, that means resyncing a large
+part of the chain.
+`,
+			},
+		},
+	}
+)
+
+// migrationCheckpoint tracks the progress of a database migration. It's stored
+// next to the databases to allow resuming an interrupted migration.
+type migrationCheckpoint struct {
+	Source string        `json:"source"`           // Engine of the source database
+	Engine string        `json:"engine"`           // Engine of the destination database
+	Stage  string        `json:"stage"`            // Current stage of the migration
+	Marker hexutil.Bytes `json:"marker,omitempty"` // Last key copied, nil if none yet
+	Keys   uint64        `json:"keys"`             // Number of keys copied so far
+	Size   uint64        `json:"size"`             // Size of the key-value pairs copied so far
+}
+
+// loadMigrationCheckpoint reads a migration checkpoint from disk, returning nil
+// if there's no migration in progress.
+func loadMigrationCheckpoint(path string) (*migrationCheckpoint, error) {
+	blob, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var checkpoint migrationCheckpoint
+	if err := json.Unmarshal(blob, &checkpoint); err != nil {
+		return nil, fmt.Errorf("invalid migration checkpoint %s: %v", path, err)
+	}
+	return &checkpoint, nil
+}
+
+// save atomically persists the migration checkpoint to disk.
+func (c *migrationCheckpoint) save(path string) error {
+	blob, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(path+".tmp", blob, 0644); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+func migrateDB(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return errors.New("need the engine to migrate to")
+	}
+	engine := ctx.Args()[0]
+	if engine != rawdb.DBLeveldb && engine != rawdb.DBPebble {
+		return fmt.Errorf("invalid engine '%s', allowed 'leveldb' or 'pebble'", engine)
+	}
+	stack, config := makeConfigNode(ctx)
+	defer stack.Close()
+
+	name := "chaindata"
+	if ctx.GlobalString(utils.SyncModeFlag.Name) == "light" {
+		name = "lightchaindata"
+	}
+	var (
+		srcPath        = stack.ResolvePath(name)
+		dstPath        = stack.ResolvePath(name + ".migrating")
+		checkpointPath = stack.ResolvePath(name + ".migrating.json")
+		cache          = ctx.GlobalInt(utils.CacheFlag.Name) * ctx.GlobalInt(utils.CacheDatabaseFlag.Name) / 100
+	)
+	checkpoint, err := loadMigrationCheckpoint(checkpointPath)
+	if err != nil {
+		return err
+	}
+	if checkpoint != nil && checkpoint.Engine != engine {
+		return fmt.Errorf("interrupted migration to %s found, remove %s and %s to start over", checkpoint.Engine, dstPath, checkpointPath)
+	}
+	if checkpoint == nil {
+		// Refuse pointless migrations and ones left over from a previous run
+		switch source := rawdb.PreexistingDatabase(srcPath); source {
+		case "":
+			return fmt.Errorf("no database found at %s", srcPath)
+		case engine:
+			return fmt.Errorf("database %s already uses %s", srcPath, engine)
+		default:
+			checkpoint = &migrationCheckpoint{Source: source, Engine: engine, Stage: migrationCopying}
+		}
+		if common.FileExist(dstPath) {
+			return fmt.Errorf("stale migration database found, remove %s to start over", dstPath)
+		}
+	}
+	if config.Node.DBEngine != "" && config.Node.DBEngine != checkpoint.Source {
+		return fmt.Errorf("db.engine choice was %s but found pre-existing %s database", config.Node.DBEngine, checkpoint.Source)
+	}
+	backupPath := stack.ResolvePath(fmt.Sprintf("%s.%s.bak", name, checkpoint.Source))
+	if checkpoint.Stage == migrationCopying && common.FileExist(backupPath) {
+		return fmt.Errorf("database backup %s already exists, move it away before migrating", backupPath)
+	}
+
+	// Copy over and verify the database contents, unless already done
+	if checkpoint.Stage == migrationCopying || checkpoint.Stage == migrationCopied {
+		var (
+			srcOpts = rawdb.OpenOptions{Type: checkpoint.Source, Directory: srcPath, Cache: cache / 2, Handles: 256}
+			dstOpts = rawdb.OpenOptions{Type: engine, Directory: dstPath, Cache: cache / 2, Handles: 256}
+		)
+		if err := copyDatabase(srcOpts, dstOpts, checkpoint, checkpointPath); err != nil {
+			return err
+		}
+		if common.FileExist(backupPath) {
+			return fmt.Errorf("database backup %s already exists, move it away to finish the migration", backupPath)
+		}
+		checkpoint.Stage = migrationVerified
+		if err := checkpoint.save(checkpointPath); err != nil {
+			return err
+		}
+	}
+	// Swap the databases, leaving any ancient store in its folder untouched
+	if checkpoint.Stage == migrationVerified {
+		if err := moveDatabaseFiles(srcPath, backupPath); err != nil {
+			return err
+		}
+		checkpoint.Stage = migrationReplacing
+		if err := checkpoint.save(checkpointPath); err != nil {
+			return err
+		}
+	}
+	if err := moveDatabaseFiles(dstPath, srcPath); err != nil {
+		return err
+	}
+	if err := os.Remove(dstPath); err != nil {
+		return err
+	}
+	if err := os.Remove(checkpointPath); err != nil {
+		return err
+	}
+	log.Info("Database migrated", "engine", engine, "path", srcPath, "backup", backupPath)
+	return nil
+}
+
+// copyDatabase copies the source key-value store into the destination one and
+// verifies the copy, resuming from the given checkpoint.
+func copyDatabase(srcOpts, dstOpts rawdb.OpenOptions, checkpoint *migrationCheckpoint, checkpointPath string) error {
+	src, err := rawdb.OpenKeyValueStore(srcOpts)
+	if err != nil {
+		return fmt.Errorf("failed to open source database: %v", err)
+	}
+	defer src.Close()
+
+	dst, err := rawdb.OpenKeyValueStore(dstOpts)
+	if err != nil {
+		return fmt.Errorf("failed to open destination database: %v", err)
+	}
+	defer dst.Close()
+
+	if checkpoint.Stage == migrationCopying {
+		log.Info("Migrating database", "source", srcOpts.Type, "destination", dstOpts.Type, "path", srcOpts.Directory)
+		if err := copyKeyValueStore(src, dst, checkpoint, func() error { return checkpoint.save(checkpointPath) }); err != nil {
+			return err
+		}
+	}
+	if err := verifyKeyValueStore(src, dst, migrationSamples); err != nil {
+		log.Error("Migrated database is inconsistent", "error", err)
+		return fmt.Errorf("%v, remove %s and %s to start over", err, dstOpts.Directory, checkpointPath)
+	}
+	return nil
+}
+
+// copyKeyValueStore copies all the keys from the source database into the
+// destination one, resuming after the marker of the checkpoint. The checkpoint
+// is updated as the copy progresses, and committed periodically after the keys
+// it covers were flushed into the destination.
+//
+// Copying a key multiple times is harmless, so only the keys after the last
+// committed checkpoint are redone after an interruption.
+func copyKeyValueStore(src ethdb.Iteratee, dst ethdb.Batcher, checkpoint *migrationCheckpoint, commit func() error) error {
+	var (
+		start     = time.Now()
+		logged    = time.Now()
+		committed = time.Now()
+		batch     = dst.NewBatch()
+	)
+	it := src.NewIterator(nil, checkpoint.Marker)
+	defer it.Release()
+
+	for it.Next() {
+		// The iterator starts at the marker, which was already copied
+		if checkpoint.Marker != nil && bytes.Equal(it.Key(), checkpoint.Marker) {
+			continue
+		}
+		if err := batch.Put(it.Key(), it.Value()); err != nil {
+			return err
+		}
+		checkpoint.Marker = common.CopyBytes(it.Key())
+		checkpoint.Keys++
+		checkpoint.Size += uint64(len(it.Key()) + len(it.Value()))
+
+		if batch.ValueSize() > ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return err
+			}
+			batch.Reset()
+
+			if time.Since(committed) > migrationCheckpointInterval {
+				if err := commit(); err != nil {
+					return err
+				}
+				committed = time.Now()
+			}
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Migrating database", "keys", checkpoint.Keys, "size", common.StorageSize(checkpoint.Size), "at", hexutil.Bytes(checkpoint.Marker), "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	if err := it.Error(); err != nil {
+		return err
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	checkpoint.Stage = migrationCopied
+	if err := commit(); err != nil {
+		return err
+	}
+	log.Info("Copied database", "keys", checkpoint.Keys, "size", common.StorageSize(checkpoint.Size), "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+// verifyKeyValueStore ensures the destination database holds the same number
+// of keys as the source one, and that a random sample of the source key-value
+// pairs is present in the destination.
+func verifyKeyValueStore(src, dst ethdb.KeyValueStore, samples int) error {
+	var (
+		start = time.Now()
+		keys  [][]byte
+		vals  []common.Hash
+	)
+	// Count the source keys, sampling them along the way
+	srcKeys, err := countKeys(src, "source", func(n uint64, key, value []byte) {
+		switch {
+		case len(keys) < samples:
+			keys = append(keys, common.CopyBytes(key))
+			vals = append(vals, common.BytesToHash(crypto.Keccak256(value)))
+		default:
+			// Reservoir sampling to keep a uniform sample over all the keys
+			if i := rand.Int63n(int64(n)); i < int64(samples) {
+				keys[i] = common.CopyBytes(key)
+				vals[i] = common.BytesToHash(crypto.Keccak256(value))
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+	dstKeys, err := countKeys(dst, "destination", nil)
+	if err != nil {
+		return err
+	}
+	if srcKeys != dstKeys {
+		return fmt.Errorf("key count mismatch: source %d, destination %d", srcKeys, dstKeys)
+	}
+	for i, key := range keys {
+		value, err := dst.Get(key)
+		if err != nil {
+			return fmt.Errorf("sampled key %x missing: %v", key, err)
+		}
+		if common.BytesToHash(crypto.Keccak256(value)) != vals[i] {
+			return fmt.Errorf("sampled key %x value mismatch", key)
+		}
+	}
+	log.Info("Verified migrated database", "keys", dstKeys, "samples", len(keys), "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+// countKeys iterates over all the keys of a database, invoking the callback for
+// each with the number of keys seen so far including it.
+func countKeys(db ethdb.Iteratee, kind string, onKey func(n uint64, key, value []byte)) (uint64, error) {
+	var (
+		start  = time.Now()
+		logged = time.Now()
+		count  uint64
+	)
+	it := db.NewIterator(nil, nil)
+	defer it.Release()
+
+	for it.Next() {
+		count++
+		if onKey != nil {
+			onKey(count, it.Key(), it.Value())
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Counting database keys", "database", kind, "keys", count, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	return count, it.Error()
+}
+
+// moveDatabaseFiles moves all the files of a database folder into another one,
+// creating it if needed. Subfolders, such as the default location of the ancient
+// store, are left in place.
+func moveDatabaseFiles(from, to string) error {
+	if err := os.MkdirAll(to, 0755); err != nil {
+		return err
+	}
+	entries, err := ioutil.ReadDir(from)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		if err := os.Rename(filepath.Join(from, entry.Name()), filepath.Join(to, entry.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ancientPath returns the directory of the ancient database, resolved the same
+// way the node resolves it when opening the chain database.
+func ancientPath(ctx *cli.Context, stack *node.Node) string {
+	name := "chaindata"
+	if ctx.GlobalString(utils.SyncModeFlag.Name) == "light" {
+		name = "lightchaindata"
+	}
+	path := ctx.GlobalString(utils.AncientFlag.Name)
+	switch {
+	case path == "":
+		path = filepath.Join(stack.ResolvePath(name), "ancient")
+	case !filepath.IsAbs(path):
+		path = stack.ResolvePath(path)
+	}
+	return path
+}
+
+// verifyAncient checks the ancient data of a block against each other: the
+// header has to hash to the canonical hash, and the body and receipts have to
+// match the roots committed to in the header.
+func verifyAncient(number uint64, items map[string][]byte) error {
+	hash := items[rawdb.FreezerRemoteHashTable]
+	if len(hash) != common.HashLength {
+		return fmt.Errorf("invalid hash length %d", len(hash))
+	}
+	if have := crypto.Keccak256Hash(items[rawdb.FreezerRemoteHeaderTable]); !bytes.Equal(have[:], hash) {
+		return fmt.Errorf("header hash mismatch: have %x, want %x", have, hash)
+	}
+	header := new(types.Header)
+	if err := rlp.DecodeBytes(items[rawdb.FreezerRemoteHeaderTable], header); err != nil {
+		return fmt.Errorf("invalid header: %v", err)
+	}
+	if header.Number == nil || header.Number.Uint64() != number {
+		return fmt.Errorf("header number mismatch: have %v, want %d", header.Number, number)
+	}
+	body := new(types.Body)
+	if err := rlp.DecodeBytes(items[rawdb.FreezerRemoteBodiesTable], body); err != nil {
+		return fmt.Errorf("invalid body: %v", err)
+	}
+	if root := types.DeriveSha(types.Transactions(body.Transactions), trie.NewStackTrie(nil)); root != header.TxHash {
+		return fmt.Errorf("transaction root mismatch: have %x, want %x", root, header.TxHash)
+	}
+	if uncles := types.CalcUncleHash(body.Uncles); uncles != header.UncleHash {
+		return fmt.Errorf("uncle hash mismatch: have %x, want %x", uncles, header.UncleHash)
+	}
+	var stored []*types.ReceiptForStorage
+	if err := rlp.DecodeBytes(items[rawdb.FreezerRemoteReceiptTable], &stored); err != nil {
+		return fmt.Errorf("invalid receipts: %v", err)
+	}
+	if len(stored) != len(body.Transactions) {
+		return fmt.Errorf("receipt count mismatch: have %d, want %d", len(stored), len(body.Transactions))
+	}
+	// The storage encoding drops the receipt type, restore it from the transactions
+	receipts := make(types.Receipts, len(stored))
+	for i, receipt := range stored {
+		receipts[i] = (*types.Receipt)(receipt)
+		receipts[i].Type = body.Transactions[i].Type()
+	}
+	if root := types.DeriveSha(receipts, trie.NewStackTrie(nil)); root != header.ReceiptHash {
+		return fmt.Errorf("receipt root mismatch: have %x, want %x", root, header.ReceiptHash)
+	}
+	if err := rlp.DecodeBytes(items[rawdb.FreezerRemoteDifficultyTable], new(big.Int)); err != nil {
+		return fmt.Errorf("invalid total difficulty: %v", err)
+	}
+	return nil
+}
+
+// printFreezerReport prints the outcome of a freezer check.
+func printFreezerReport(report *rawdb.FreezerReport) {
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Table", "Items", "Intact", "Size", "Compressed", "Checksummed", "Status"})
+	for _, t := range report.Tables {
+		status := "OK"
+		if t.Err != nil {
+			status = t.Err.Error()
+		}
+		table.Append([]string{t.Name, fmt.Sprint(t.Items), fmt.Sprint(t.Intact), t.Size.String(), fmt.Sprint(t.Compressed), fmt.Sprint(t.Checksummed), status})
+	}
+	table.Render()
+
+	if report.VerifyErr != nil {
+		log.Error("Ancient block verification failed", "err", report.VerifyErr)
+	}
+}
+
+func checkFreezer(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	path := ancientPath(ctx, stack)
+	log.Info("Checking ancient database", "path", path)
+
+	report, err := rawdb.CheckFreezer(path, verifyAncient)
+	if err != nil {
+		return err
+	}
+	printFreezerReport(report)
+
+	if !report.Healthy() {
+		return fmt.Errorf("ancient database intact up to block #%d only, run 'geth db freezer-repair' to rebuild it", report.Intact)
+	}
+	log.Info("Ancient database is healthy", "blocks", report.Items)
+	return nil
+}
+
+func exportFreezer(ctx *cli.Context) error {
+	if ctx.NArg() != 1 && ctx.NArg() != 3 {
+		return errors.New("need the export file, optionally followed by the first and last block")
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	path := ancientPath(ctx, stack)
+	if !common.FileExist(path) {
+		return fmt.Errorf("no ancient database found at %s", path)
+	}
+	freezer, err := rawdb.NewFreezer(path, "")
+	if err != nil {
+		return err
+	}
+	defer freezer.Close()
+
+	frozen, err := freezer.Ancients()
+	if err != nil {
+		return err
+	}
+	if frozen == 0 {
+		return errors.New("ancient database is empty")
+	}
+	first, last := uint64(0), frozen-1
+	if ctx.NArg() == 3 {
+		if first, err = strconv.ParseUint(ctx.Args().Get(1), 10, 64); err != nil {
+			return fmt.Errorf("invalid first block: %v", err)
+		}
+		if last, err = strconv.ParseUint(ctx.Args().Get(2), 10, 64); err != nil {
+			return fmt.Errorf("invalid last block: %v", err)
+		}
+		if first > last || last >= frozen {
+			return fmt.Errorf("invalid block range #%d-#%d, ancient database holds #0-#%d", first, last, frozen-1)
+		}
+	}
+	return utils.ExportAncients(freezer, ctx.Args().First(), first, last)
+}
+
+func repairFreezer(ctx *cli.Context) error {
+	if ctx.NArg() > 1 {
+		return errors.New("too many arguments")
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	path := ancientPath(ctx, stack)
+	log.Info("Checking ancient database", "path", path)
+
+	report, err := rawdb.CheckFreezer(path, verifyAncient)
+	if err != nil {
+		return err
+	}
+	if report.Healthy() {
+		log.Info("Ancient database is healthy, nothing to repair", "blocks", report.Items)
+		return nil
+	}
+	printFreezerReport(report)
+
+	// Discard everything from the first damaged block onwards
+	freezer, err := rawdb.NewFreezer(path, "")
+	if err != nil {
+		return err
+	}
+	defer freezer.Close()
+
+	log.Warn("Truncating damaged ancient database", "intact", report.Intact, "blocks", report.Items)
+	if err := freezer.TruncateAncients(report.Intact); err != nil {
+		return err
+	}
+	if err := freezer.Sync(); err != nil {
+		return err
+	}
+	// Rebuild the discarded blocks from the export file, if any
+	if ctx.NArg() == 1 {
+		if _, err := utils.ImportAncients(freezer, ctx.Args().First(), report.Items, verifyAncient); err != nil {
+			return err
+		}
+	}
+	frozen, err := freezer.Ancients()
+	if err != nil {
+		return err
+	}
+	if frozen < report.Items {
+		log.Warn("Ancient database truncated, the chain will be rewound and resynced from this block on the next start", "blocks", frozen, "missing", report.Items-frozen)
+	} else {
+		log.Info("Ancient database repaired", "blocks", frozen)
+	}
+	return nil
+}
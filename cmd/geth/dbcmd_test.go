@@ -0,0 +1,209 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/params/types/genesisT"
+	"github.com/ethereum/go-ethereum/params/vars"
+)
+
+// Tests that an interrupted database copy is resumed from its checkpoint, and
+// that the verification catches missing and modified entries.
+func TestMigrateKeyValueStore(t *testing.T) {
+	src := memorydb.New()
+	for i := 0; i < 1000; i++ {
+		key := make([]byte, 8)
+		binary.BigEndian.PutUint64(key, uint64(i))
+		src.Put(key, make([]byte, 1024))
+	}
+	// Pretend the first half was copied before an interruption
+	dst := memorydb.New()
+	it := src.NewIterator(nil, nil)
+	for i := 0; i < 500 && it.Next(); i++ {
+		dst.Put(it.Key(), it.Value())
+	}
+	checkpoint := &migrationCheckpoint{Stage: migrationCopying, Marker: it.Key(), Keys: 500}
+	it.Release()
+
+	if err := verifyKeyValueStore(src, dst, 100); err == nil {
+		t.Fatalf("verified partial copy")
+	}
+	var commits int
+	if err := copyKeyValueStore(src, dst, checkpoint, func() error { commits++; return nil }); err != nil {
+		t.Fatalf("failed to resume copy: %v", err)
+	}
+	if checkpoint.Stage != migrationCopied || checkpoint.Keys != 1000 || commits == 0 {
+		t.Errorf("checkpoint mismatch: stage %s, keys %d, commits %d", checkpoint.Stage, checkpoint.Keys, commits)
+	}
+	if err := verifyKeyValueStore(src, dst, 100); err != nil {
+		t.Fatalf("failed to verify copy: %v", err)
+	}
+	// Corrupt every entry, so any sample catches it
+	it = dst.NewIterator(nil, nil)
+	for it.Next() {
+		dst.Put(it.Key(), []byte{0x01})
+	}
+	it.Release()
+	if err := verifyKeyValueStore(src, dst, 100); err == nil {
+		t.Fatalf("verified corrupted copy")
+	}
+}
+
+// Tests that moving a database around leaves its subfolders, such as the ancient
+// store, in place.
+func TestMoveDatabaseFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "geth-migrate-")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	var (
+		from = filepath.Join(dir, "chaindata")
+		to   = filepath.Join(dir, "chaindata.bak")
+	)
+	os.MkdirAll(filepath.Join(from, "ancient"), 0755)
+	ioutil.WriteFile(filepath.Join(from, "CURRENT"), nil, 0644)
+	ioutil.WriteFile(filepath.Join(from, "ancient", "headers.cidx"), nil, 0644)
+
+	if err := moveDatabaseFiles(from, to); err != nil {
+		t.Fatalf("failed to move database: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(to, "CURRENT")); err != nil {
+		t.Errorf("database file not moved: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(from, "ancient", "headers.cidx")); err != nil {
+		t.Errorf("ancient store moved: %v", err)
+	}
+}
+
+// Tests that a damaged ancient database is detected by the block verification
+// and rebuilt from an export.
+func TestRepairFreezerFromExport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "geth-freezer-")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Freeze a chain with legacy and typed transactions and export it
+	config := *params.TestChainConfig
+	config.BerlinBlock = big.NewInt(0)
+
+	var (
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		address = crypto.PubkeyToAddress(key.PublicKey)
+		signer  = types.LatestSigner(&config)
+		db      = rawdb.NewMemoryDatabase()
+		gspec   = &genesisT.Genesis{
+			Config: &config,
+			Alloc:  genesisT.GenesisAlloc{address: {Balance: big.NewInt(1000000000000000)}},
+		}
+		genesis = core.MustCommitGenesis(db, gspec)
+	)
+	blocks, receipts := core.GenerateChain(gspec.Config, genesis, ethash.NewFaker(), db, 20, func(i int, block *core.BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(block.TxNonce(address), common.Address{byte(i)}, big.NewInt(1), vars.TxGas, nil, nil), signer, key)
+		block.AddTx(tx)
+
+		tx, _ = types.SignNewTx(key, signer, &types.AccessListTx{
+			ChainID:  config.GetChainID(),
+			Nonce:    block.TxNonce(address),
+			To:       &common.Address{byte(i)},
+			Gas:      vars.TxGas + vars.TxAccessListAddressGas,
+			GasPrice: big.NewInt(1),
+			Value:    big.NewInt(1),
+			AccessList: types.AccessList{{
+				Address: common.Address{byte(i)},
+			}},
+		})
+		block.AddTx(tx)
+	})
+	freezer, err := rawdb.NewFreezer(dir, "")
+	if err != nil {
+		t.Fatalf("failed to create freezer: %v", err)
+	}
+	rawdb.WriteAncientBlock(freezer, genesis, nil, genesis.Difficulty())
+	for i, block := range blocks {
+		rawdb.WriteAncientBlock(freezer, block, receipts[i], big.NewInt(int64(i+2)))
+	}
+	export := filepath.Join(dir, "ancients.rlp")
+	if err := utils.ExportAncients(freezer, export, 0, 20); err != nil {
+		t.Fatalf("failed to export ancients: %v", err)
+	}
+	freezer.Close()
+
+	if report, err := rawdb.CheckFreezer(dir, verifyAncient); err != nil || !report.Healthy() {
+		t.Fatalf("intact freezer reported damaged: %v", err)
+	}
+	// Damage the body of block 10, which isn't checksummed
+	index, err := ioutil.ReadFile(filepath.Join(dir, "bodies.cidx"))
+	if err != nil {
+		t.Fatalf("failed to read index: %v", err)
+	}
+	var (
+		start = binary.BigEndian.Uint32(index[10*6+2:])
+		end   = binary.BigEndian.Uint32(index[11*6+2:])
+	)
+	file, err := os.OpenFile(filepath.Join(dir, "bodies.0000.cdat"), os.O_RDWR, 0644)
+	if err != nil {
+		t.Fatalf("failed to open data file: %v", err)
+	}
+	file.WriteAt(make([]byte, end-start), int64(start))
+	file.Close()
+
+	report, err := rawdb.CheckFreezer(dir, verifyAncient)
+	if err != nil {
+		t.Fatalf("failed to check freezer: %v", err)
+	}
+	if report.Healthy() || report.Intact != 10 || report.Items != 21 {
+		t.Fatalf("damage not detected: healthy %v, intact %d, items %d", report.Healthy(), report.Intact, report.Items)
+	}
+	// Rebuild the damaged tail from the export
+	if freezer, err = rawdb.NewFreezer(dir, ""); err != nil {
+		t.Fatalf("failed to open freezer: %v", err)
+	}
+	if err := freezer.TruncateAncients(report.Intact); err != nil {
+		t.Fatalf("failed to truncate freezer: %v", err)
+	}
+	imported, err := utils.ImportAncients(freezer, export, report.Items, verifyAncient)
+	if err != nil {
+		t.Fatalf("failed to import ancients: %v", err)
+	}
+	freezer.Close()
+	if imported != 11 {
+		t.Errorf("imported items mismatch: have %d, want 11", imported)
+	}
+	if report, err := rawdb.CheckFreezer(dir, verifyAncient); err != nil || !report.Healthy() {
+		t.Fatalf("rebuilt freezer reported damaged: %v", err)
+	}
+}
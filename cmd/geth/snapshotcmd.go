@@ -0,0 +1,661 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"time"
+
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/state/pruner"
+	"github.com/ethereum/go-ethereum/core/state/snapshot"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	// emptyRoot is the known root hash of an empty trie.
+	emptyRoot = common.HexToHash("56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421")
+
+	// emptyCode is the known hash of the empty EVM bytecode.
+	emptyCode = crypto.Keccak256(nil)
+)
+
+var (
+	snapshotCommand = cli.Command{
+		Name:        "snapshot",
+		Usage:       "A set of commands based on the snapshot",
+		Category:    "MISCELLANEOUS COMMANDS",
+		Description: "",
+		Subcommands: []cli.Command{
+			{
+				Name:      "prune-state",
+				Usage:     "Prune stale ethereum state data based on the snapshot",
+				ArgsUsage: "<root>",
+				Action:    utils.MigrateFlags(pruneState),
+				Category:  "MISCELLANEOUS COMMANDS",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.AncientFlag,
+					utils.ClassicFlag,
+					utils.MordorFlag,
+					utils.KottiFlag,
+					utils.SocialFlag,
+					utils.EthersocialFlag,
+					utils.LegacyTestnetFlag,
+					utils.RopstenFlag,
+					utils.RinkebyFlag,
+					utils.GoerliFlag,
+					utils.YoloV1Flag,
+					utils.CacheTrieJournalFlag,
+					utils.BloomFilterSizeFlag,
+				},
+				Description: `
+geth snapshot prune-state <state-root>
+will prune historical state data with the help of the state snapshot.
+All trie nodes and contract codes that do not belong to the specified
+version state will be deleted from the database. After pruning, only
+two version states are available: genesis and the specific one.
+
+The default pruning target is the HEAD-127 state.
+
+WARNING: It's necessary to delete the trie clean cache after the pruning.
+If you specify another directory for the trie clean cache via "--cache.trie.journal"
+during the use of Geth, please also specify it here for correct deletion. Otherwise
+the trie clean cache with default directory will be deleted.
+
+If the pruning is interrupted, it's resumed the next time this command or
+the node itself is started.
+`,
+			},
+			{
+				Name:      "verify-state",
+				Usage:     "Verify that a state is complete in the database",
+				ArgsUsage: "<root>",
+				Action:    utils.MigrateFlags(verifyState),
+				Category:  "MISCELLANEOUS COMMANDS",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.AncientFlag,
+					utils.ClassicFlag,
+					utils.MordorFlag,
+					utils.KottiFlag,
+					utils.SocialFlag,
+					utils.EthersocialFlag,
+					utils.LegacyTestnetFlag,
+					utils.RopstenFlag,
+					utils.RinkebyFlag,
+					utils.GoerliFlag,
+					utils.YoloV1Flag,
+				},
+				Description: `
+geth snapshot verify-state <state-root>
+will traverse the whole state from the given root, ensuring that every
+trie node and contract code is present in the database and matches its
+hash. It's meant to be run after pruning, to check that no live data
+was deleted.
+
+If no root is given, the state of the head block is verified, or the
+state of the snapshot if the head state was pruned.
+`,
+			},
+			{
+				Name:      "verify",
+				Usage:     "Recalculate the state hash based on the snapshot for verification",
+				ArgsUsage: "<root>",
+				Action:    utils.MigrateFlags(verifySnapshot),
+				Category:  "MISCELLANEOUS COMMANDS",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.AncientFlag,
+					utils.ClassicFlag,
+					utils.MordorFlag,
+					utils.KottiFlag,
+					utils.SocialFlag,
+					utils.EthersocialFlag,
+					utils.LegacyTestnetFlag,
+					utils.RopstenFlag,
+					utils.RinkebyFlag,
+					utils.GoerliFlag,
+					utils.YoloV1Flag,
+					utils.SnapshotOnlyFlag,
+				},
+				Description: `
+geth snapshot verify <state-root>
+will traverse the whole accounts and storages set based on the specified
+snapshot and recalculate the root hash of state for verification.
+The root must be present in the state trie, failing otherwise. If the trie
+is missing, e.g. after pruning, --snapshot-only verifies the snapshot on its
+own, checking only that it hashes to the given root.
+
+If no root is given, the head of the snapshot is verified.
+`,
+			},
+			{
+				Name:      "dump",
+				Usage:     "Dump a specific state from the snapshot as JSON lines",
+				ArgsUsage: "<root>",
+				Action:    utils.MigrateFlags(dumpSnapshot),
+				Category:  "MISCELLANEOUS COMMANDS",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.AncientFlag,
+					utils.ClassicFlag,
+					utils.MordorFlag,
+					utils.KottiFlag,
+					utils.SocialFlag,
+					utils.EthersocialFlag,
+					utils.LegacyTestnetFlag,
+					utils.RopstenFlag,
+					utils.RinkebyFlag,
+					utils.GoerliFlag,
+					utils.YoloV1Flag,
+					utils.ExcludeCodeFlag,
+					utils.ExcludeStorageFlag,
+					utils.IncludeIncompletesFlag,
+					utils.StartKeyFlag,
+					utils.DumpLimitFlag,
+				},
+				Description: `
+geth snapshot dump <state-root>
+will stream the accounts of the specified state from the snapshot, one JSON
+object per line, without touching the state trie. The first line holds the
+state root. Accounts are ordered by the hash of their address; --start and
+--limit can be used to dump a range of them.
+
+If no root is given, the head of the snapshot is dumped.
+`,
+			},
+			{
+				Name:     "inspect",
+				Usage:    "Inspect the layers, generation progress and disk usage of the snapshot",
+				Action:   utils.MigrateFlags(inspectSnapshot),
+				Category: "MISCELLANEOUS COMMANDS",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.AncientFlag,
+					utils.ClassicFlag,
+					utils.MordorFlag,
+					utils.KottiFlag,
+					utils.SocialFlag,
+					utils.EthersocialFlag,
+					utils.LegacyTestnetFlag,
+					utils.RopstenFlag,
+					utils.RinkebyFlag,
+					utils.GoerliFlag,
+					utils.YoloV1Flag,
+				},
+				Description: `
+geth snapshot inspect
+will print the diff layers journalled on top of the persistent snapshot, the
+progress of the snapshot generation and the disk space used by the snapshot.
+`,
+			},
+		},
+	}
+)
+
+func pruneState(ctx *cli.Context) error {
+	stack, config := makeConfigNode(ctx)
+	defer stack.Close()
+
+	chaindb := utils.MakeChainDatabase(ctx, stack)
+	defer chaindb.Close()
+
+	// Finish any previously interrupted pruning first, the state of the chain
+	// may be incomplete until then
+	datadir, trieCachePath := stack.ResolvePath(""), stack.ResolvePath(config.Eth.TrieCleanCacheJournal)
+	if err := pruner.RecoverPruning(datadir, chaindb, trieCachePath); err != nil {
+		log.Error("Failed to resume state pruning", "error", err)
+		return err
+	}
+	pruner, err := pruner.NewPruner(chaindb, datadir, trieCachePath, ctx.Uint64(utils.BloomFilterSizeFlag.Name))
+	if err != nil {
+		log.Error("Failed to open snapshot tree", "error", err)
+		return err
+	}
+	if ctx.NArg() > 1 {
+		log.Error("Too many arguments given")
+		return errors.New("too many arguments")
+	}
+	var targetRoot common.Hash
+	if ctx.NArg() == 1 {
+		targetRoot, err = parseRoot(ctx.Args()[0])
+		if err != nil {
+			log.Error("Failed to resolve state root", "error", err)
+			return err
+		}
+	}
+	if err = pruner.Prune(targetRoot); err != nil {
+		log.Error("Failed to prune state", "error", err)
+		return err
+	}
+	return nil
+}
+
+func verifyState(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	chaindb := utils.MakeChainDatabase(ctx, stack)
+	defer chaindb.Close()
+
+	if ctx.NArg() > 1 {
+		log.Error("Too many arguments given")
+		return errors.New("too many arguments")
+	}
+	var (
+		root common.Hash
+		err  error
+	)
+	if ctx.NArg() == 1 {
+		root, err = parseRoot(ctx.Args()[0])
+		if err != nil {
+			log.Error("Failed to resolve state root", "error", err)
+			return err
+		}
+	} else {
+		headBlock := rawdb.ReadHeadBlock(chaindb)
+		if headBlock == nil {
+			log.Error("Failed to load head block")
+			return errors.New("no head block")
+		}
+		root = headBlock.Root()
+
+		// After pruning, the head state is gone until the node rewinds its head
+		// to the pruning target, which is retained by the snapshot
+		if len(rawdb.ReadTrieNode(chaindb, root)) == 0 {
+			if snapRoot := rawdb.ReadSnapshotRoot(chaindb); snapRoot != (common.Hash{}) {
+				log.Info("Head state missing, verifying snapshot state", "head", root, "snapshot", snapRoot)
+				root = snapRoot
+			}
+		}
+	}
+	if err := traverseState(chaindb, root); err != nil {
+		log.Error("Failed to verify state", "root", root, "error", err)
+		return err
+	}
+	return nil
+}
+
+// openSnapshot loads the persisted snapshot as it is, without regenerating or
+// repairing it, and returns it along with a summary of its journal.
+func openSnapshot(chaindb ethdb.Database) (*snapshot.Tree, *snapshot.JournalInfo, error) {
+	info, err := snapshot.ReadJournalInfo(chaindb)
+	if err != nil {
+		return nil, nil, err
+	}
+	snaptree, err := snapshot.Load(chaindb, trie.NewDatabase(chaindb), 256, info.Head())
+	if err != nil {
+		return nil, nil, err
+	}
+	return snaptree, info, nil
+}
+
+// snapshotRoot resolves the state root a snapshot command operates on, either
+// given as the only argument or defaulting to the head of the snapshot.
+func snapshotRoot(ctx *cli.Context, info *snapshot.JournalInfo) (common.Hash, error) {
+	switch ctx.NArg() {
+	case 0:
+		return info.Head(), nil
+	case 1:
+		return parseRoot(ctx.Args()[0])
+	default:
+		return common.Hash{}, errors.New("too many arguments")
+	}
+}
+
+func verifySnapshot(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	chaindb := utils.MakeChainDatabase(ctx, stack)
+	defer chaindb.Close()
+
+	snaptree, info, err := openSnapshot(chaindb)
+	if err != nil {
+		log.Error("Failed to open snapshot tree", "error", err)
+		return err
+	}
+	root, err := snapshotRoot(ctx, info)
+	if err != nil {
+		log.Error("Failed to resolve state root", "error", err)
+		return err
+	}
+	// The recalculated root is only meaningful if the state trie has the same
+	// one, skip resolving it only if explicitly requested
+	snapshotOnly := ctx.Bool(utils.SnapshotOnlyFlag.Name)
+	if !snapshotOnly {
+		if _, err := trie.New(root, trie.NewDatabase(chaindb)); err != nil {
+			log.Error("Failed to resolve state trie, use --snapshot-only to verify the snapshot alone", "root", root, "error", err)
+			return err
+		}
+	}
+	if err := snapshot.VerifyState(snaptree, root); err != nil {
+		log.Error("Failed to verify state", "root", root, "error", err)
+		return err
+	}
+	if snapshotOnly {
+		log.Info("Verified snapshot state", "root", root)
+		return nil
+	}
+	log.Info("Verified snapshot state against the trie", "root", root)
+	return nil
+}
+
+func dumpSnapshot(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	chaindb := utils.MakeChainDatabase(ctx, stack)
+	defer chaindb.Close()
+
+	snaptree, info, err := openSnapshot(chaindb)
+	if err != nil {
+		log.Error("Failed to open snapshot tree", "error", err)
+		return err
+	}
+	root, err := snapshotRoot(ctx, info)
+	if err != nil {
+		log.Error("Failed to resolve state root", "error", err)
+		return err
+	}
+	start, err := parseStartKey(ctx.String(utils.StartKeyFlag.Name))
+	if err != nil {
+		log.Error("Failed to resolve start key", "error", err)
+		return err
+	}
+	accIt, err := snaptree.AccountIterator(root, start)
+	if err != nil {
+		log.Error("Failed to open account iterator", "root", root, "error", err)
+		return err
+	}
+	defer accIt.Release()
+
+	var (
+		excludeCode    = ctx.Bool(utils.ExcludeCodeFlag.Name)
+		excludeStorage = ctx.Bool(utils.ExcludeStorageFlag.Name)
+		includeMissing = ctx.Bool(utils.IncludeIncompletesFlag.Name)
+		limit          = ctx.Uint64(utils.DumpLimitFlag.Name)
+		enc            = json.NewEncoder(os.Stdout)
+		accounts       uint64
+	)
+	enc.Encode(struct {
+		Root common.Hash `json:"root"`
+	}{root})
+
+	for accIt.Next() {
+		if limit > 0 && accounts >= limit {
+			break
+		}
+		account, err := snapshot.FullAccount(accIt.Account())
+		if err != nil {
+			return err
+		}
+		dump := &state.DumpAccount{
+			Balance:  account.Balance.String(),
+			Nonce:    account.Nonce,
+			Root:     common.Bytes2Hex(account.Root),
+			CodeHash: common.Bytes2Hex(account.CodeHash),
+		}
+		if addr := rawdb.ReadPreimage(chaindb, accIt.Hash()); len(addr) == common.AddressLength {
+			address := common.BytesToAddress(addr)
+			dump.Address = &address
+		} else {
+			if !includeMissing {
+				continue
+			}
+			dump.SecureKey = accIt.Hash().Bytes()
+		}
+		if !excludeCode && !bytes.Equal(account.CodeHash, emptyCode) {
+			dump.Code = common.Bytes2Hex(rawdb.ReadCode(chaindb, common.BytesToHash(account.CodeHash)))
+		}
+		if !excludeStorage {
+			dump.Storage, err = dumpSnapshotStorage(snaptree, chaindb, root, accIt.Hash())
+			if err != nil {
+				return err
+			}
+		}
+		enc.Encode(dump)
+		accounts++
+	}
+	return accIt.Error()
+}
+
+// dumpSnapshotStorage collects the storage of an account from the snapshot,
+// keyed by the slot preimages if available and by the slot hashes otherwise.
+func dumpSnapshotStorage(snaptree *snapshot.Tree, db ethdb.KeyValueReader, root, account common.Hash) (map[common.Hash]string, error) {
+	stIt, err := snaptree.StorageIterator(root, account, common.Hash{})
+	if err != nil {
+		return nil, err
+	}
+	defer stIt.Release()
+
+	storage := make(map[common.Hash]string)
+	for stIt.Next() {
+		_, content, _, err := rlp.Split(stIt.Slot())
+		if err != nil {
+			return nil, fmt.Errorf("invalid storage slot %x of account %x: %v", stIt.Hash(), account, err)
+		}
+		key := stIt.Hash()
+		if preimage := rawdb.ReadPreimage(db, key); len(preimage) == common.HashLength {
+			key = common.BytesToHash(preimage)
+		}
+		storage[key] = common.Bytes2Hex(content)
+	}
+	return storage, stIt.Error()
+}
+
+func inspectSnapshot(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	chaindb := utils.MakeChainDatabase(ctx, stack)
+	defer chaindb.Close()
+
+	info, err := snapshot.ReadJournalInfo(chaindb)
+	if err != nil {
+		log.Error("Failed to read snapshot journal", "error", err)
+		return err
+	}
+	fmt.Printf("Disk layer root:     %s\n", info.DiskRoot.Hex())
+	switch {
+	case info.Done:
+		fmt.Printf("Generation:          done\n")
+	case info.Wiping:
+		fmt.Printf("Generation:          wiping\n")
+	default:
+		fmt.Printf("Generation:          %.2f%% (marker %#x)\n", generationProgress(info.Marker), info.Marker)
+	}
+	fmt.Printf("Generated accounts:  %d\n", info.Accounts)
+	fmt.Printf("Generated slots:     %d\n", info.Slots)
+	fmt.Printf("Generated size:      %v\n", info.Storage)
+	fmt.Printf("Diff layers:         %d\n", len(info.Layers))
+	for i, layer := range info.Layers {
+		fmt.Printf("  #%-3d %s: %d destructs, %d accounts, %d slots\n", i, layer.Root.Hex(), layer.Destructs, layer.Accounts, layer.Slots)
+	}
+	accounts, accountSize := snapshotUsage(chaindb, rawdb.SnapshotAccountPrefix, len(rawdb.SnapshotAccountPrefix)+common.HashLength)
+	slots, slotSize := snapshotUsage(chaindb, rawdb.SnapshotStoragePrefix, len(rawdb.SnapshotStoragePrefix)+2*common.HashLength)
+
+	fmt.Printf("Disk accounts:       %d (%v)\n", accounts, accountSize)
+	fmt.Printf("Disk slots:          %d (%v)\n", slots, slotSize)
+	fmt.Printf("Journal size:        %v\n", info.Size)
+	fmt.Printf("Total disk usage:    %v\n", accountSize+slotSize+info.Size)
+	return nil
+}
+
+// generationProgress estimates the percentage of the account space already
+// covered by the snapshot generator from its progress marker.
+func generationProgress(marker []byte) float64 {
+	if len(marker) == 0 {
+		return 0
+	}
+	var head [8]byte
+	copy(head[:], marker)
+	return float64(binary.BigEndian.Uint64(head[:])) / math.MaxUint64 * 100
+}
+
+// snapshotUsage counts the snapshot entries with the given prefix and key length
+// in the database, along with their total size.
+func snapshotUsage(db ethdb.Iteratee, prefix []byte, keylen int) (uint64, common.StorageSize) {
+	var (
+		start  = time.Now()
+		logged = time.Now()
+		count  uint64
+		size   common.StorageSize
+	)
+	it := db.NewIterator(prefix, nil)
+	defer it.Release()
+
+	for it.Next() {
+		if len(it.Key()) != keylen {
+			continue
+		}
+		count++
+		size += common.StorageSize(len(it.Key()) + len(it.Value()))
+
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Inspecting snapshot", "prefix", string(prefix), "count", count, "size", size, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	return count, size
+}
+
+// traverseState iterates over the whole state trie at the given root, including
+// all storage tries and contract codes, verifying that every referenced trie
+// node and code is present and consistent with its hash.
+func traverseState(db ethdb.Database, root common.Hash) error {
+	var (
+		start    = time.Now()
+		logged   = time.Now()
+		triedb   = trie.NewDatabase(db)
+		accounts int
+		slots    int
+		codes    int
+		nodes    int
+	)
+	// checkNode ensures a hashed trie node is present and uncorrupted. Embedded
+	// nodes don't have a hash and live inside their parents.
+	checkNode := func(hash common.Hash) error {
+		if hash == (common.Hash{}) {
+			return nil
+		}
+		blob := rawdb.ReadTrieNode(db, hash)
+		if len(blob) == 0 {
+			return fmt.Errorf("missing trie node %x", hash)
+		}
+		if crypto.Keccak256Hash(blob) != hash {
+			return fmt.Errorf("corrupted trie node %x", hash)
+		}
+		nodes++
+		return nil
+	}
+	t, err := trie.NewSecure(root, triedb)
+	if err != nil {
+		return err
+	}
+	accIter := t.NodeIterator(nil)
+	for accIter.Next(true) {
+		if err := checkNode(accIter.Hash()); err != nil {
+			return err
+		}
+		if !accIter.Leaf() {
+			continue
+		}
+		accounts++
+
+		var acc state.Account
+		if err := rlp.DecodeBytes(accIter.LeafBlob(), &acc); err != nil {
+			return fmt.Errorf("invalid account %x: %v", accIter.LeafKey(), err)
+		}
+		if acc.Root != emptyRoot {
+			storageTrie, err := trie.NewSecure(acc.Root, triedb)
+			if err != nil {
+				return fmt.Errorf("missing storage trie %x of account %x: %v", acc.Root, accIter.LeafKey(), err)
+			}
+			storageIter := storageTrie.NodeIterator(nil)
+			for storageIter.Next(true) {
+				if err := checkNode(storageIter.Hash()); err != nil {
+					return err
+				}
+				if storageIter.Leaf() {
+					slots++
+				}
+			}
+			if err := storageIter.Error(); err != nil {
+				return fmt.Errorf("failed to traverse storage of account %x: %v", accIter.LeafKey(), err)
+			}
+		}
+		if !bytes.Equal(acc.CodeHash, emptyCode) {
+			code := rawdb.ReadCode(db, common.BytesToHash(acc.CodeHash))
+			if len(code) == 0 {
+				return fmt.Errorf("missing code %x of account %x", acc.CodeHash, accIter.LeafKey())
+			}
+			codes++
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Verifying state", "accounts", accounts, "slots", slots, "codes", codes, "nodes", nodes, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	if err := accIter.Error(); err != nil {
+		return err
+	}
+	log.Info("State is complete", "root", root, "accounts", accounts, "slots", slots, "codes", codes, "nodes", nodes, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+// parseStartKey parses the position to start a dump from, given either as an
+// account hash or as an address, which is hashed.
+func parseStartKey(input string) (common.Hash, error) {
+	raw, err := hexutil.Decode(input)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	switch len(raw) {
+	case common.HashLength:
+		return common.BytesToHash(raw), nil
+	case common.AddressLength:
+		return crypto.Keccak256Hash(raw), nil
+	default:
+		return common.Hash{}, fmt.Errorf("invalid start key length %d", len(raw))
+	}
+}
+
+// parseRoot parses a state root given on the command line.
+func parseRoot(input string) (common.Hash, error) {
+	var h common.Hash
+	if err := h.UnmarshalText([]byte(input)); err != nil {
+		return h, err
+	}
+	return h, nil
+}
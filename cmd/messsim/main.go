@@ -0,0 +1,60 @@
+// Copyright 2020 The core-geth Authors
+// This file is part of core-geth.
+//
+// core-geth is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// core-geth is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with core-geth. If not, see <http://www.gnu.org/licenses/>.
+
+// messsim evaluates the ECBP1100 (MESS) artificial finality antigravity curves
+// against competing chain segments, without running a node.
+//
+// The segments can be read from exported files:
+//
+//	messsim segments --local local.rlp --proposed attack.rlp
+//
+// or generated from synthetic attack parameters:
+//
+//	messsim synthetic --share 0.6 --duration 3600
+//
+// For each curve, the tool reports whether the proposed segment would be accepted
+// as a reorganization, and at which proposed block.
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ethereum/go-ethereum/internal/flags"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	// Git SHA1 commit hash of the release (set via linker flags)
+	gitCommit = ""
+	gitDate   = ""
+
+	app = flags.NewApp(gitCommit, gitDate, "ECBP1100 (MESS) artificial finality simulator")
+)
+
+func init() {
+	app.Commands = []cli.Command{
+		segmentsCommand,
+		syntheticCommand,
+	}
+}
+
+func main() {
+	if err := app.Run(os.Args); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
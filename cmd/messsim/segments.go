@@ -0,0 +1,134 @@
+// Copyright 2020 The core-geth Authors
+// This file is part of core-geth.
+//
+// core-geth is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// core-geth is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with core-geth. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	localFlag = cli.StringFlag{
+		Name:  "local",
+		Usage: "File containing the local chain segment, including the common ancestor",
+	}
+	proposedFlag = cli.StringFlag{
+		Name:  "proposed",
+		Usage: "File containing the proposed chain segment",
+	}
+	formatFlag = cli.StringFlag{
+		Name:  "format",
+		Usage: "Segment file format [rlp|json]",
+		Value: "rlp",
+	}
+
+	segmentsCommand = cli.Command{
+		Name:      "segments",
+		Usage:     "Evaluate exported competing chain segments",
+		ArgsUsage: " ",
+		Flags: []cli.Flag{
+			localFlag,
+			proposedFlag,
+			formatFlag,
+		},
+		Description: `
+Evaluates a proposed chain segment against a local one.
+
+Segment files hold either an RLP stream of blocks (as written by 'geth export')
+or headers, or a JSON array of headers (or blocks, as returned by eth_getBlockByNumber).
+Headers must be ordered by number. The local segment must include the common ancestor.`,
+		Action: segments,
+	}
+)
+
+var errUnknownFormat = errors.New("unknown segment format")
+
+func segments(ctx *cli.Context) error {
+	if !ctx.IsSet(localFlag.Name) || !ctx.IsSet(proposedFlag.Name) {
+		return fmt.Errorf("both --%s and --%s are required", localFlag.Name, proposedFlag.Name)
+	}
+	local, err := readSegment(ctx.String(localFlag.Name), ctx.String(formatFlag.Name))
+	if err != nil {
+		return fmt.Errorf("local segment: %v", err)
+	}
+	proposed, err := readSegment(ctx.String(proposedFlag.Name), ctx.String(formatFlag.Name))
+	if err != nil {
+		return fmt.Errorf("proposed segment: %v", err)
+	}
+	s, err := newScenario(local, proposed)
+	if err != nil {
+		return err
+	}
+	return s.report(os.Stdout, s.evaluate())
+}
+
+// readSegment reads the headers of a chain segment from a file.
+func readSegment(path string, format string) ([]*types.Header, error) {
+	switch format {
+	case "rlp":
+		fh, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer fh.Close()
+		return decodeRLPSegment(fh)
+	case "json":
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var headers []*types.Header
+		if err := json.Unmarshal(data, &headers); err != nil {
+			return nil, err
+		}
+		return headers, nil
+	}
+	return nil, fmt.Errorf("%w: %s", errUnknownFormat, format)
+}
+
+// decodeRLPSegment decodes a stream of RLP encoded headers or blocks.
+func decodeRLPSegment(r io.Reader) ([]*types.Header, error) {
+	stream := rlp.NewStream(r, 0)
+	headers := []*types.Header{}
+	for {
+		raw, err := stream.Raw()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		header := new(types.Header)
+		if err := rlp.DecodeBytes(raw, header); err == nil {
+			headers = append(headers, header)
+			continue
+		}
+		block := new(types.Block)
+		if err := rlp.DecodeBytes(raw, block); err != nil {
+			return nil, fmt.Errorf("item %d is neither a header nor a block: %v", len(headers), err)
+		}
+		headers = append(headers, block.Header())
+	}
+	return headers, nil
+}
@@ -0,0 +1,165 @@
+// Copyright 2020 The core-geth Authors
+// This file is part of core-geth.
+//
+// core-geth is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// core-geth is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with core-geth. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// tdRuleName names the plain total difficulty rule, ie. no artificial finality.
+const tdRuleName = "td"
+
+var errNoCommonAncestor = errors.New("proposed segment does not descend from the local segment")
+
+// scenario is a pair of competing chain segments descending from a common ancestor.
+type scenario struct {
+	ancestor *types.Header
+	local    []*types.Header // local (current) chain after the ancestor
+	proposed []*types.Header // proposed chain after the ancestor
+}
+
+// newScenario finds the common ancestor of the local and proposed segments.
+// The local segment must contain the common ancestor; the proposed segment
+// may begin with headers it shares with the local segment.
+func newScenario(local, proposed []*types.Header) (*scenario, error) {
+	index := make(map[common.Hash]int, len(local))
+	for i, h := range local {
+		index[h.Hash()] = i
+	}
+	for i, h := range proposed {
+		if _, ok := index[h.Hash()]; ok {
+			continue
+		}
+		pos, ok := index[h.ParentHash]
+		if !ok {
+			return nil, fmt.Errorf("%w: parent %x of proposed block #%d not found", errNoCommonAncestor, h.ParentHash, h.Number)
+		}
+		return &scenario{
+			ancestor: local[pos],
+			local:    local[pos+1:],
+			proposed: proposed[i:],
+		}, nil
+	}
+	return nil, errors.New("proposed segment is contained in the local segment")
+}
+
+// current returns the head of the local chain.
+func (s *scenario) current() *types.Header {
+	if len(s.local) == 0 {
+		return s.ancestor
+	}
+	return s.local[len(s.local)-1]
+}
+
+// result is the outcome of evaluating a scenario with a reorganization rule.
+type result struct {
+	rule     string
+	accepted bool
+	block    *types.Header // first accepted proposed block, if accepted
+	tdRatio  *big.Rat      // proposed over local subchain TD at the accepted (or last) proposed block
+	required *big.Rat      // required ratio, nil for the plain TD rule
+}
+
+// evaluate assesses the scenario with the plain total difficulty rule and each
+// of the ECBP1100 curves.
+//
+// The proposed blocks are assumed to be revealed in order while the local head
+// is the tip of the local segment, so the time delta evaluated by the curves is
+// that between the common ancestor and the local tip.
+func (s *scenario) evaluate() []*result {
+	localTD := new(big.Int)
+	for _, h := range s.local {
+		localTD.Add(localTD, h.Difficulty)
+	}
+	timeDelta := s.current().Time - s.ancestor.Time
+
+	names := []string{}
+	for name := range core.ECBP1100Curves {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	results := []*result{s.evaluateRule(tdRuleName, localTD, nil)}
+	for _, name := range names {
+		results = append(results, s.evaluateRule(name, localTD, core.ECBP1100Curves[name](timeDelta)))
+	}
+	return results
+}
+
+func (s *scenario) evaluateRule(name string, localTD *big.Int, required *big.Rat) *result {
+	res := &result{rule: name, required: required}
+	proposedTD := new(big.Int)
+	for _, h := range s.proposed {
+		proposedTD.Add(proposedTD, h.Difficulty)
+		if localTD.Sign() > 0 {
+			res.tdRatio = new(big.Rat).SetFrac(proposedTD, localTD)
+		}
+		// The artificial finality rule only overrides reorganizations which
+		// the total difficulty rule would otherwise make.
+		if proposedTD.Cmp(localTD) <= 0 {
+			continue
+		}
+		if required != nil && res.tdRatio != nil && res.tdRatio.Cmp(required) < 0 {
+			continue
+		}
+		res.accepted, res.block = true, h
+		break
+	}
+	return res
+}
+
+func formatRat(r *big.Rat) string {
+	if r == nil {
+		return "-"
+	}
+	return r.FloatString(6)
+}
+
+// report writes a human readable report of the scenario evaluation.
+func (s *scenario) report(w io.Writer, results []*result) error {
+	current := s.current()
+	timeDelta := current.Time - s.ancestor.Time
+
+	fmt.Fprintf(w, "common ancestor:  #%d [%x]\n", s.ancestor.Number, s.ancestor.Hash().Bytes()[:4])
+	fmt.Fprintf(w, "local head:       #%d [%x] (%d blocks)\n", current.Number, current.Hash().Bytes()[:4], len(s.local))
+	if len(s.proposed) > 0 {
+		head := s.proposed[len(s.proposed)-1]
+		fmt.Fprintf(w, "proposed head:    #%d [%x] (%d blocks)\n", head.Number, head.Hash().Bytes()[:4], len(s.proposed))
+	}
+	fmt.Fprintf(w, "time delta:       %ds (%v)\n\n", timeDelta, common.PrettyDuration(time.Duration(timeDelta)*time.Second))
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "RULE\tACCEPTED\tBLOCK\tTD RATIO\tREQUIRED")
+	for _, r := range results {
+		block := "-"
+		if r.block != nil {
+			block = fmt.Sprintf("#%d", r.block.Number)
+		}
+		fmt.Fprintf(tw, "%s\t%v\t%s\t%s\t%s\n", r.rule, r.accepted, block, formatRat(r.tdRatio), formatRat(r.required))
+	}
+	return tw.Flush()
+}
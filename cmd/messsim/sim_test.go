@@ -0,0 +1,114 @@
+// Copyright 2020 The core-geth Authors
+// This file is part of core-geth.
+//
+// core-geth is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// core-geth is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with core-geth. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+func TestScenarioEvaluate(t *testing.T) {
+	difficulty := big.NewInt(1000000)
+
+	// A short attack by a majority attacker is accepted by every rule,
+	// a long one only by the plain total difficulty rule.
+	for _, c := range []struct {
+		duration uint64
+		accepted map[string]bool
+	}{
+		{60, map[string]bool{tdRuleName: true, "polynomialV": true, "sinusoidalA": true, "expA": true, "expB": true}},
+		{86400, map[string]bool{tdRuleName: true, "polynomialV": false, "sinusoidalA": false, "expA": false, "expB": false}},
+	} {
+		s := syntheticScenario(100, 0.9, c.duration, 13, difficulty)
+		for _, r := range s.evaluate() {
+			want, ok := c.accepted[r.rule]
+			if !ok {
+				t.Errorf("duration %d: unexpected rule %s", c.duration, r.rule)
+				continue
+			}
+			if r.accepted != want {
+				t.Errorf("duration %d: rule %s accepted %v, want %v", c.duration, r.rule, r.accepted, want)
+			}
+			if r.accepted && r.block.Number.Uint64() <= 100 {
+				t.Errorf("duration %d: rule %s accepted at #%d, before the proposed segment", c.duration, r.rule, r.block.Number)
+			}
+		}
+	}
+	// A minority attacker never gets a reorg.
+	s := syntheticScenario(0, 0.3, 3600, 13, difficulty)
+	for _, r := range s.evaluate() {
+		if r.accepted {
+			t.Errorf("minority attack accepted by rule %s", r.rule)
+		}
+	}
+}
+
+func TestNewScenario(t *testing.T) {
+	s := syntheticScenario(0, 0.6, 130, 13, big.NewInt(1000))
+	local := append([]*types.Header{s.ancestor}, s.local...)
+
+	// The proposed segment may include shared headers.
+	proposed := append([]*types.Header{s.ancestor}, s.proposed...)
+	got, err := newScenario(local, proposed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ancestor.Hash() != s.ancestor.Hash() {
+		t.Errorf("wrong ancestor: have #%d, want #%d", got.ancestor.Number, s.ancestor.Number)
+	}
+	if len(got.local) != len(s.local) || len(got.proposed) != len(s.proposed) {
+		t.Errorf("wrong segment lengths: have %d/%d, want %d/%d", len(got.local), len(got.proposed), len(s.local), len(s.proposed))
+	}
+	if _, err := newScenario(s.local, s.proposed); !errors.Is(err, errNoCommonAncestor) {
+		t.Errorf("missing ancestor: have error %v, want %v", err, errNoCommonAncestor)
+	}
+}
+
+func TestDecodeRLPSegment(t *testing.T) {
+	s := syntheticScenario(0, 0.5, 65, 13, big.NewInt(1000))
+
+	// Headers and blocks may be mixed in a single stream.
+	var buf bytes.Buffer
+	for i, h := range s.local {
+		var err error
+		if i%2 == 0 {
+			err = rlp.Encode(&buf, h)
+		} else {
+			err = rlp.Encode(&buf, types.NewBlockWithHeader(h))
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	headers, err := decodeRLPSegment(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(headers) != len(s.local) {
+		t.Fatalf("wrong number of headers: have %d, want %d", len(headers), len(s.local))
+	}
+	for i, h := range headers {
+		if h.Hash() != s.local[i].Hash() {
+			t.Errorf("header %d: hash mismatch", i)
+		}
+	}
+}
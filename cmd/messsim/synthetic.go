@@ -0,0 +1,127 @@
+// Copyright 2020 The core-geth Authors
+// This file is part of core-geth.
+//
+// core-geth is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// core-geth is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with core-geth. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"math/big"
+	"os"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	shareFlag = cli.Float64Flag{
+		Name:  "share",
+		Usage: "Attacker share of the total network hashrate (0 < share < 1)",
+		Value: 0.5,
+	}
+	durationFlag = cli.Uint64Flag{
+		Name:  "duration",
+		Usage: "Duration of the attack in seconds",
+		Value: 3600,
+	}
+	blockTimeFlag = cli.Uint64Flag{
+		Name:  "blocktime",
+		Usage: "Target block time in seconds",
+		Value: 13,
+	}
+	difficultyFlag = cli.Uint64Flag{
+		Name:  "difficulty",
+		Usage: "Block difficulty at the full network hashrate",
+		Value: 1e12,
+	}
+	ancestorFlag = cli.Uint64Flag{
+		Name:  "ancestor",
+		Usage: "Block number of the common ancestor",
+	}
+
+	syntheticCommand = cli.Command{
+		Name:      "synthetic",
+		Usage:     "Evaluate a synthetic attack",
+		ArgsUsage: " ",
+		Flags: []cli.Flag{
+			shareFlag,
+			durationFlag,
+			blockTimeFlag,
+			difficultyFlag,
+			ancestorFlag,
+		},
+		Description: `
+Evaluates a private chain attack, where an attacker with the given share of the
+network hashrate mines a competing segment for the given duration, while the
+remaining hashrate extends the local chain.
+
+Both chains are assumed to have adjusted their difficulty to the target block time,
+so their block difficulties are proportional to their hashrates.`,
+		Action: synthetic,
+	}
+)
+
+func synthetic(ctx *cli.Context) error {
+	share := ctx.Float64(shareFlag.Name)
+	if share <= 0 || share >= 1 {
+		return errors.New("hashrate share must be between 0 and 1")
+	}
+	blockTime := ctx.Uint64(blockTimeFlag.Name)
+	if blockTime == 0 {
+		return errors.New("block time must be positive")
+	}
+	s := syntheticScenario(
+		ctx.Uint64(ancestorFlag.Name),
+		share,
+		ctx.Uint64(durationFlag.Name),
+		blockTime,
+		new(big.Int).SetUint64(ctx.Uint64(difficultyFlag.Name)),
+	)
+	return s.report(os.Stdout, s.evaluate())
+}
+
+// syntheticScenario generates the chain segments of an attacker with the given
+// hashrate share mining privately for the given duration.
+func syntheticScenario(ancestorNumber uint64, share float64, duration, blockTime uint64, difficulty *big.Int) *scenario {
+	ancestor := &types.Header{
+		Number:     new(big.Int).SetUint64(ancestorNumber),
+		Difficulty: new(big.Int).Set(difficulty),
+	}
+	return &scenario{
+		ancestor: ancestor,
+		local:    syntheticSegment(ancestor, "local", 1-share, duration, blockTime, difficulty),
+		proposed: syntheticSegment(ancestor, "proposed", share, duration, blockTime, difficulty),
+	}
+}
+
+func syntheticSegment(parent *types.Header, extra string, share float64, duration, blockTime uint64, difficulty *big.Int) []*types.Header {
+	d, _ := new(big.Float).Mul(new(big.Float).SetInt(difficulty), big.NewFloat(share)).Int(nil)
+	if d.Sign() <= 0 {
+		d.SetUint64(1)
+	}
+	headers := []*types.Header{}
+	for elapsed := blockTime; elapsed <= duration; elapsed += blockTime {
+		h := &types.Header{
+			ParentHash: parent.Hash(),
+			Number:     new(big.Int).Add(parent.Number, big.NewInt(1)),
+			Difficulty: new(big.Int).Set(d),
+			Time:       parent.Time + blockTime,
+			Extra:      []byte(extra),
+		}
+		headers = append(headers, h)
+		parent = h
+	}
+	return headers
+}
@@ -26,6 +26,7 @@ import (
 	"runtime"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core"
@@ -312,3 +313,130 @@ func ExportPreimages(db ethdb.Database, fn string) error {
 	log.Info("Exported preimages", "file", fn)
 	return nil
 }
+
+// ancientItems returns the ancient data of a block keyed by freezer table.
+func ancientItems(item *rawdb.FreezerRemoteItem) map[string][]byte {
+	return map[string][]byte{
+		rawdb.FreezerRemoteHashTable:       item.Hash,
+		rawdb.FreezerRemoteHeaderTable:     item.Header,
+		rawdb.FreezerRemoteBodiesTable:     item.Body,
+		rawdb.FreezerRemoteReceiptTable:    item.Receipts,
+		rawdb.FreezerRemoteDifficultyTable: item.Td,
+	}
+}
+
+// ExportAncients exports the raw ancient data of the blocks in the [first, last]
+// range into the specified file, truncating any data already present in it.
+func ExportAncients(db ethdb.AncientReader, fn string, first uint64, last uint64) error {
+	log.Info("Exporting ancients", "file", fn, "first", first, "last", last)
+
+	// Open the file handle and potentially wrap with a gzip stream
+	fh, err := os.OpenFile(fn, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.ModePerm)
+	if err != nil {
+		return err
+	}
+	defer fh.Close()
+
+	var writer io.Writer = fh
+	if strings.HasSuffix(fn, ".gz") {
+		writer = gzip.NewWriter(writer)
+		defer writer.(*gzip.Writer).Close()
+	}
+	// Iterate over the ancient items and export them
+	var (
+		start  = time.Now()
+		logged = time.Now()
+	)
+	for number := first; number <= last; number++ {
+		item := &rawdb.FreezerRemoteItem{Number: number}
+		for kind, blob := range map[string]*[]byte{
+			rawdb.FreezerRemoteHashTable:       &item.Hash,
+			rawdb.FreezerRemoteHeaderTable:     &item.Header,
+			rawdb.FreezerRemoteBodiesTable:     &item.Body,
+			rawdb.FreezerRemoteReceiptTable:    &item.Receipts,
+			rawdb.FreezerRemoteDifficultyTable: &item.Td,
+		} {
+			if *blob, err = db.Ancient(kind, number); err != nil {
+				return fmt.Errorf("failed to read ancient %s #%d: %v", kind, number, err)
+			}
+		}
+		if err := rlp.Encode(writer, item); err != nil {
+			return err
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Exporting ancients", "exported", number-first, "total", last-first+1, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	log.Info("Exported ancients", "file", fn)
+	return nil
+}
+
+// ImportAncients appends the raw ancient data of blocks exported by ExportAncients
+// to the ancient store, until it holds limit items. Items already present in
+// the store are skipped, the rest have to follow each other without gaps. Every
+// item is passed to verify, if non-nil, before being appended. The number of
+// imported items is returned.
+func ImportAncients(db ethdb.AncientStore, fn string, limit uint64, verify func(number uint64, items map[string][]byte) error) (uint64, error) {
+	log.Info("Importing ancients", "file", fn)
+
+	// Open the file handle and potentially unwrap the gzip stream
+	fh, err := os.Open(fn)
+	if err != nil {
+		return 0, err
+	}
+	defer fh.Close()
+
+	var reader io.Reader = fh
+	if strings.HasSuffix(fn, ".gz") {
+		if reader, err = gzip.NewReader(reader); err != nil {
+			return 0, err
+		}
+	}
+	stream := rlp.NewStream(reader, 0)
+
+	next, err := db.Ancients()
+	if err != nil {
+		return 0, err
+	}
+	var (
+		first  = next
+		start  = time.Now()
+		logged = time.Now()
+	)
+	for next < limit {
+		// Read the next entry and ensure it's the one needed
+		var item rawdb.FreezerRemoteItem
+		if err := stream.Decode(&item); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return next - first, err
+		}
+		if item.Number < next {
+			continue
+		}
+		if item.Number > next {
+			return next - first, fmt.Errorf("missing ancient #%d in export, found #%d", next, item.Number)
+		}
+		if verify != nil {
+			if err := verify(item.Number, ancientItems(&item)); err != nil {
+				return next - first, fmt.Errorf("invalid ancient #%d in export: %v", item.Number, err)
+			}
+		}
+		if err := db.AppendAncient(item.Number, item.Hash, item.Header, item.Body, item.Receipts, item.Td); err != nil {
+			return next - first, err
+		}
+		next++
+
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Importing ancients", "imported", next-first, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	if err := db.Sync(); err != nil {
+		return next - first, err
+	}
+	log.Info("Imported ancients", "file", fn, "count", next-first)
+	return next - first, nil
+}
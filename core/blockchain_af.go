@@ -9,7 +9,9 @@ import (
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/log"
 )
 
@@ -87,10 +89,27 @@ func (bc *BlockChain) ecbp1100(commonAncestor, current, proposed *types.Header)
 
 	xBig := big.NewInt(int64(current.Time - commonAncestor.Time))
 	eq := ecbp1100PolynomialV(xBig)
+	antiGravity := new(big.Int).Set(eq)
 	want := eq.Mul(eq, localSubchainTD)
 
 	got := new(big.Int).Mul(proposedSubchainTD, ecbp1100PolynomialVCurveFunctionDenominator)
 
+	bc.recordArtificialFinalityDecision(&types.ArtificialFinalityDecision{
+		CommonAncestorNumber:   commonAncestor.Number.Uint64(),
+		CommonAncestorHash:     commonAncestor.Hash(),
+		CurrentNumber:          current.Number.Uint64(),
+		CurrentHash:            current.Hash(),
+		ProposedNumber:         proposed.Number.Uint64(),
+		ProposedHash:           proposed.Hash(),
+		TimeDelta:              xBig.Uint64(),
+		CurrentSubchainTD:      localSubchainTD,
+		ProposedSubchainTD:     proposedSubchainTD,
+		AntiGravityNumerator:   antiGravity,
+		AntiGravityDenominator: new(big.Int).Set(ecbp1100PolynomialVCurveFunctionDenominator),
+		Accepted:               got.Cmp(want) >= 0,
+		EvaluatedAt:            uint64(time.Now().Unix()),
+	})
+
 	if got.Cmp(want) < 0 {
 		prettyRatio, _ := new(big.Float).Quo(
 			new(big.Float).SetInt(got),
@@ -110,6 +129,53 @@ func (bc *BlockChain) ecbp1100(commonAncestor, current, proposed *types.Header)
 	return nil
 }
 
+// recordArtificialFinalityDecision persists an artificial finality decision to
+// the database, keyed by the proposed block, and notifies any subscribers.
+func (bc *BlockChain) recordArtificialFinalityDecision(decision *types.ArtificialFinalityDecision) {
+	rawdb.WriteArtificialFinalityDecision(bc.db, decision)
+	bc.afDecisionFeed.Send(ArtificialFinalityDecisionEvent{Decision: decision})
+}
+
+// SubscribeArtificialFinalityDecisionEvent registers a subscription of ArtificialFinalityDecisionEvent.
+func (bc *BlockChain) SubscribeArtificialFinalityDecisionEvent(ch chan<- ArtificialFinalityDecisionEvent) event.Subscription {
+	return bc.scope.Track(bc.afDecisionFeed.Subscribe(ch))
+}
+
+// ECBP1100Curve is an antigravity function, returning the minimum ratio of
+// proposed over current chain segment total difficulty (both measured from their
+// common ancestor) required to accept a reorganization, given the time in seconds
+// between the common ancestor and the current head.
+type ECBP1100Curve func(timeDelta uint64) *big.Rat
+
+// ECBP1100Curves are the antigravity curves proposed for ECBP1100, by name.
+// Only "polynomialV" is used by the blockchain; the others are kept for evaluation.
+var ECBP1100Curves = map[string]ECBP1100Curve{
+	"polynomialV": func(timeDelta uint64) *big.Rat {
+		return new(big.Rat).SetFrac(
+			ecbp1100PolynomialV(new(big.Int).SetUint64(timeDelta)),
+			ecbp1100PolynomialVCurveFunctionDenominator,
+		)
+	},
+	"sinusoidalA": func(timeDelta uint64) *big.Rat {
+		return ecbp1100FloatCurveRat(ecbp1100AGSinusoidalA(float64(timeDelta)))
+	},
+	"expA": func(timeDelta uint64) *big.Rat {
+		return ecbp1100FloatCurveRat(ecbp1100AGExpA(float64(timeDelta)))
+	},
+	"expB": func(timeDelta uint64) *big.Rat {
+		return ecbp1100FloatCurveRat(ecbp1100AGExpB(float64(timeDelta)))
+	},
+}
+
+// ecbp1100FloatCurveRat converts a floating point curve value to a rational,
+// limiting overflowed values to the maximum float64.
+func ecbp1100FloatCurveRat(y float64) *big.Rat {
+	if math.IsInf(y, 1) || math.IsNaN(y) {
+		y = math.MaxFloat64
+	}
+	return new(big.Rat).SetFloat64(y)
+}
+
 /*
 ecbp1100PolynomialV is a cubic function that looks a lot like Option 3's sin function,
 but adds the benefit that the calculation can be done with integers (instead of yucky floating points).
@@ -199,7 +265,6 @@ ecbp1100AGSinusoidalA is a sinusoidal function.
 
 OPTION 3: Yet slower takeoff, yet steeper eventual ascent. Has a differentiable ceiling transition.
 h(x)=15 sin((x+12000 π)/(8000))+15+1
-
 */
 func ecbp1100AGSinusoidalA(x float64) (antiGravity float64) {
 	ampl := float64(15)   // amplitude
@@ -242,6 +242,97 @@ func TestAFKnownBlock(t *testing.T) {
 	}
 }
 
+// TestAFDecisionLog tests that artificial finality decisions are persisted
+// and posted to subscribers.
+func TestAFDecisionLog(t *testing.T) {
+	engine := ethash.NewFaker()
+
+	db := rawdb.NewMemoryDatabase()
+	genesis := params.DefaultMessNetGenesisBlock()
+	genesisB := MustCommitGenesis(db, genesis)
+
+	chain, err := NewBlockChain(db, nil, genesis.Config, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer chain.Stop()
+	chain.EnableArtificialFinality(true)
+
+	events := make(chan ArtificialFinalityDecisionEvent, 1024)
+	sub := chain.SubscribeArtificialFinalityDecisionEvent(events)
+	defer sub.Unsubscribe()
+
+	easy, _ := GenerateChain(genesis.Config, genesisB, engine, db, 1000, func(i int, gen *BlockGen) {
+		gen.OffsetTime(0)
+	})
+	if _, err := chain.InsertChain(easy); err != nil {
+		t.Fatal(err)
+	}
+	if decisions := rawdb.ReadArtificialFinalityDecisions(db, 0, 1000, 0); len(decisions) != 0 {
+		t.Fatalf("unexpected decisions without reorgs: %d", len(decisions))
+	}
+	commonAncestor := easy[969]
+	hard, _ := GenerateChain(genesis.Config, commonAncestor, engine, db, 30, func(i int, gen *BlockGen) {
+		gen.OffsetTime(-2)
+	})
+	if _, err := chain.InsertChain(hard); err != nil {
+		t.Fatal(err)
+	}
+	if chain.CurrentBlock().Hash() == hard[len(hard)-1].Hash() {
+		t.Fatal("hard block got chain head, should be side")
+	}
+
+	decisions := rawdb.ReadArtificialFinalityDecisions(db, 0, 2000, 0)
+	if len(decisions) == 0 {
+		t.Fatal("no decisions recorded")
+	}
+	for i, d := range decisions {
+		if i > 0 && d.ProposedNumber < decisions[i-1].ProposedNumber {
+			t.Errorf("decisions not ordered by proposed number: %d < %d", d.ProposedNumber, decisions[i-1].ProposedNumber)
+		}
+		if d.CommonAncestorHash != commonAncestor.Hash() {
+			t.Errorf("wrong common ancestor: have %x, want %x", d.CommonAncestorHash, commonAncestor.Hash())
+		}
+		if d.Accepted != (d.TDRatio() >= d.RequiredRatio()) {
+			t.Errorf("decision inconsistent with ratios: accepted=%v tdr=%f required=%f", d.Accepted, d.TDRatio(), d.RequiredRatio())
+		}
+	}
+	last := decisions[len(decisions)-1]
+	if last.Accepted {
+		t.Error("last proposed block was accepted, should be rejected")
+	}
+	if got := rawdb.ReadArtificialFinalityDecision(db, last.ProposedHash, last.ProposedNumber); got == nil || got.Accepted != last.Accepted {
+		t.Error("missing decision for proposed block")
+	}
+	if limited := rawdb.ReadArtificialFinalityDecisions(db, 0, 2000, 1); len(limited) != 1 {
+		t.Errorf("limit not respected: have %d decisions, want 1", len(limited))
+	}
+	if len(events) != len(decisions) {
+		t.Errorf("wrong number of decision events: have %d, want %d", len(events), len(decisions))
+	}
+}
+
+// TestECBP1100Curves tests that the exported curves agree with the functions they wrap.
+func TestECBP1100Curves(t *testing.T) {
+	poly := ECBP1100Curves["polynomialV"]
+	if got := poly(0); got.Cmp(big.NewRat(1, 1)) != 0 {
+		t.Errorf("polynomialV(0): have %v, want 1", got)
+	}
+	if got := poly(ecbp1100PolynomialVXCap.Uint64() * 2); got.Cmp(big.NewRat(31, 1)) != 0 {
+		t.Errorf("polynomialV(2*xcap): have %v, want 31", got)
+	}
+	for _, x := range []uint64{0, 600, 3600, 86400} {
+		want := new(big.Rat).SetFrac(ecbp1100PolynomialV(new(big.Int).SetUint64(x)), ecbp1100PolynomialVCurveFunctionDenominator)
+		if got := poly(x); got.Cmp(want) != 0 {
+			t.Errorf("polynomialV(%d): have %v, want %v", x, got, want)
+		}
+	}
+	// Overflowing curve values are limited.
+	if got := ECBP1100Curves["expA"](math.MaxUint32); got == nil || got.Sign() <= 0 {
+		t.Errorf("expA(MaxUint32): have %v, want positive limit", got)
+	}
+}
+
 // TestEcbp1100PolynomialV tests the general shape and return values of the ECBP1100 polynomial curve.
 // It makes sure domain values above the 'cap' do indeed get limited, as well
 // as sanity check some normal domain values.
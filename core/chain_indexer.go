@@ -229,6 +229,8 @@ func (c *ChainIndexer) eventLoop(currentHeader *types.Header, events chan ChainH
 				if rawdb.ReadCanonicalHash(c.chainDb, prevHeader.Number.Uint64()) != prevHash {
 					if h := rawdb.FindCommonAncestor(c.chainDb, prevHeader, header); h != nil {
 						c.newHead(h.Number.Uint64(), true)
+					} else {
+						c.rewind()
 					}
 				}
 			}
@@ -299,6 +301,22 @@ func (c *ChainIndexer) newHead(head uint64, reorg bool) {
 	}
 }
 
+// rewind rolls the indexer back to the last stored section still matching the
+// canonical chain. It's used when the previous head is gone from the database,
+// e.g. after a SetHead, so the reorg point can't be looked up.
+func (c *ChainIndexer) rewind() {
+	c.lock.Lock()
+	c.verifyLastHead()
+	sections := c.storedSections
+	c.lock.Unlock()
+
+	var head uint64
+	if sections > 0 {
+		head = sections*c.sectionSize - 1
+	}
+	c.newHead(head, true)
+}
+
 // updateLoop is the main event loop of the indexer which pushes chain segments
 // down into the processing backend.
 func (c *ChainIndexer) updateLoop() {
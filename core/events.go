@@ -41,3 +41,9 @@ type ChainSideEvent struct {
 }
 
 type ChainHeadEvent struct{ Block *types.Block }
+
+// ArtificialFinalityDecisionEvent is posted when the artificial finality (ECBP1100)
+// mechanism evaluates a proposed chain reorganization.
+type ArtificialFinalityDecisionEvent struct {
+	Decision *types.ArtificialFinalityDecision
+}
@@ -0,0 +1,83 @@
+// Copyright 2020 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"encoding/binary"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// ReadArtificialFinalityDecision retrieves the artificial finality decision
+// made about the proposed block with the given hash and number.
+func ReadArtificialFinalityDecision(db ethdb.KeyValueReader, hash common.Hash, number uint64) *types.ArtificialFinalityDecision {
+	data, _ := db.Get(artificialFinalityDecisionKey(number, hash))
+	if len(data) == 0 {
+		return nil
+	}
+	decision := new(types.ArtificialFinalityDecision)
+	if err := rlp.DecodeBytes(data, decision); err != nil {
+		log.Error("Invalid artificial finality decision RLP", "hash", hash, "err", err)
+		return nil
+	}
+	return decision
+}
+
+// ReadArtificialFinalityDecisions retrieves the artificial finality decisions
+// made about proposed blocks numbered in the inclusive range [from, to],
+// ordered by block number. At most limit decisions are returned, unless limit is 0.
+func ReadArtificialFinalityDecisions(db ethdb.Iteratee, from, to uint64, limit int) []*types.ArtificialFinalityDecision {
+	it := db.NewIterator(artificialFinalityDecisionPrefix, encodeBlockNumber(from))
+	defer it.Release()
+
+	decisions := []*types.ArtificialFinalityDecision{}
+	for it.Next() {
+		key := it.Key()
+		if len(key) != len(artificialFinalityDecisionPrefix)+8+common.HashLength {
+			continue
+		}
+		if binary.BigEndian.Uint64(key[len(artificialFinalityDecisionPrefix):]) > to {
+			break
+		}
+		decision := new(types.ArtificialFinalityDecision)
+		if err := rlp.DecodeBytes(it.Value(), decision); err != nil {
+			log.Error("Invalid artificial finality decision RLP", "key", key, "err", err)
+			continue
+		}
+		decisions = append(decisions, decision)
+		if limit > 0 && len(decisions) >= limit {
+			break
+		}
+	}
+	return decisions
+}
+
+// WriteArtificialFinalityDecision stores an artificial finality decision,
+// keyed by the proposed block.
+func WriteArtificialFinalityDecision(db ethdb.KeyValueWriter, decision *types.ArtificialFinalityDecision) {
+	data, err := rlp.EncodeToBytes(decision)
+	if err != nil {
+		log.Crit("Failed to RLP encode artificial finality decision", "err", err)
+	}
+	if err := db.Put(artificialFinalityDecisionKey(decision.ProposedNumber, decision.ProposedHash), data); err != nil {
+		log.Crit("Failed to store artificial finality decision", "err", err)
+	}
+}
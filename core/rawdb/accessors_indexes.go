@@ -18,6 +18,7 @@ package rawdb
 
 import (
 	"bytes"
+	"encoding/binary"
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -175,3 +176,85 @@ func DeleteBloombits(db ethdb.Database, bit uint, from uint64, to uint64) {
 		log.Crit("Failed to delete bloom bits", "err", it.Error())
 	}
 }
+
+// AddressTxEntry is a reference from an address index to a transaction that
+// touched the address.
+type AddressTxEntry struct {
+	Address     common.Address
+	BlockNumber uint64
+	Index       uint64
+	Hash        common.Hash
+}
+
+// ReadAddressTxEntries retrieves, in chain order, up to limit references to the
+// transactions touching an address within the given inclusive block range. A
+// non-positive limit retrieves all of them.
+func ReadAddressTxEntries(db ethdb.Iteratee, address common.Address, from uint64, to uint64, limit int) []AddressTxEntry {
+	prefix := append(addressTxPrefix, address.Bytes()...)
+	it := db.NewIterator(prefix, encodeBlockNumber(from))
+	defer it.Release()
+
+	var entries []AddressTxEntry
+	for (limit <= 0 || len(entries) < limit) && it.Next() {
+		key := it.Key()
+		if len(key) != len(prefix)+12 || len(it.Value()) != common.HashLength {
+			continue
+		}
+		number := binary.BigEndian.Uint64(key[len(prefix):])
+		if number > to {
+			break
+		}
+		entries = append(entries, AddressTxEntry{
+			Address:     address,
+			BlockNumber: number,
+			Index:       uint64(binary.BigEndian.Uint32(key[len(prefix)+8:])),
+			Hash:        common.BytesToHash(it.Value()),
+		})
+	}
+	return entries
+}
+
+// ReadAddressTxBlockEntries retrieves all the address index entries stored for
+// a block.
+func ReadAddressTxBlockEntries(db ethdb.KeyValueReader, number uint64) []AddressTxEntry {
+	data, _ := db.Get(addressTxBlockKey(number))
+	if len(data) == 0 {
+		return nil
+	}
+	var entries []AddressTxEntry
+	if err := rlp.DecodeBytes(data, &entries); err != nil {
+		log.Error("Invalid address index entries", "number", number, "err", err)
+		return nil
+	}
+	return entries
+}
+
+// WriteAddressTxIndex stores the address index entries of a block, along with
+// the list of them needed to delete them again.
+func WriteAddressTxIndex(db ethdb.KeyValueWriter, number uint64, entries []AddressTxEntry) {
+	for _, entry := range entries {
+		if err := db.Put(addressTxKey(entry.Address, number, entry.Index), entry.Hash.Bytes()); err != nil {
+			log.Crit("Failed to store address index entry", "err", err)
+		}
+	}
+	data, err := rlp.EncodeToBytes(entries)
+	if err != nil {
+		log.Crit("Failed to RLP encode address index entries", "err", err)
+	}
+	if err := db.Put(addressTxBlockKey(number), data); err != nil {
+		log.Crit("Failed to store address index entries", "err", err)
+	}
+}
+
+// DeleteAddressTxIndex removes the given address index entries of a block, as
+// returned by ReadAddressTxBlockEntries.
+func DeleteAddressTxIndex(db ethdb.KeyValueWriter, number uint64, entries []AddressTxEntry) {
+	for _, entry := range entries {
+		if err := db.Delete(addressTxKey(entry.Address, number, entry.Index)); err != nil {
+			log.Crit("Failed to delete address index entry", "err", err)
+		}
+	}
+	if err := db.Delete(addressTxBlockKey(number)); err != nil {
+		log.Crit("Failed to delete address index entries", "err", err)
+	}
+}
@@ -177,3 +177,59 @@ func TestDeleteBloomBits(t *testing.T) {
 	check(1, 1, params.MainnetGenesisHash, true)
 	check(1, 1, params.RinkebyGenesisHash, true)
 }
+
+// Tests that address index entries can be stored, retrieved in chain order and
+// deleted per block.
+func TestAddressTxIndexStorage(t *testing.T) {
+	db := NewMemoryDatabase()
+
+	var (
+		alice = common.Address{0xaa}
+		bob   = common.Address{0xbb}
+	)
+	blocks := map[uint64][]AddressTxEntry{
+		1: {
+			{Address: alice, BlockNumber: 1, Index: 0, Hash: common.Hash{0x01}},
+			{Address: bob, BlockNumber: 1, Index: 0, Hash: common.Hash{0x01}},
+		},
+		2: {
+			{Address: bob, BlockNumber: 2, Index: 0, Hash: common.Hash{0x02}},
+			{Address: alice, BlockNumber: 2, Index: 1, Hash: common.Hash{0x03}},
+		},
+		3: {
+			{Address: alice, BlockNumber: 3, Index: 2, Hash: common.Hash{0x04}},
+		},
+	}
+	for number, entries := range blocks {
+		WriteAddressTxIndex(db, number, entries)
+	}
+	check := func(address common.Address, from, to uint64, limit int, want []common.Hash) {
+		t.Helper()
+
+		entries := ReadAddressTxEntries(db, address, from, to, limit)
+		if len(entries) != len(want) {
+			t.Fatalf("%x [%d-%d]: entry count mismatch: have %d, want %d", address, from, to, len(entries), len(want))
+		}
+		for i, entry := range entries {
+			if entry.Address != address || entry.Hash != want[i] {
+				t.Errorf("%x [%d-%d]: entry %d mismatch: have %x/%x, want %x/%x", address, from, to, i, entry.Address, entry.Hash, address, want[i])
+			}
+		}
+	}
+	check(alice, 0, 10, 0, []common.Hash{{0x01}, {0x03}, {0x04}})
+	check(alice, 2, 10, 0, []common.Hash{{0x03}, {0x04}})
+	check(alice, 0, 2, 0, []common.Hash{{0x01}, {0x03}})
+	check(alice, 0, 10, 2, []common.Hash{{0x01}, {0x03}})
+	check(bob, 0, 10, 0, []common.Hash{{0x01}, {0x02}})
+	check(common.Address{0xcc}, 0, 10, 0, nil)
+
+	if entries := ReadAddressTxBlockEntries(db, 2); len(entries) != 2 {
+		t.Fatalf("block entry count mismatch: have %d, want %d", len(entries), 2)
+	}
+	DeleteAddressTxIndex(db, 2, blocks[2])
+	if entries := ReadAddressTxBlockEntries(db, 2); entries != nil {
+		t.Fatalf("block entries not deleted: %v", entries)
+	}
+	check(alice, 0, 10, 0, []common.Hash{{0x01}, {0x04}})
+	check(bob, 0, 10, 0, []common.Hash{{0x01}})
+}
@@ -21,6 +21,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 	"sync/atomic"
 	"time"
@@ -29,6 +30,7 @@ import (
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/ethdb/leveldb"
 	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/ethereum/go-ethereum/ethdb/pebble"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/olekukonko/tablewriter"
 )
@@ -122,11 +124,15 @@ func NewDatabase(db ethdb.KeyValueStore) ethdb.Database {
 // NewDatabaseWithFreezerRemote creates a high level database on top of a given key-
 // value data store with a freezer moving immutable chain segments into cold
 // storage.
-func NewDatabaseWithFreezerRemote(db ethdb.KeyValueStore, freezerURL string) (ethdb.Database, error) {
+//
+// If readonly is set, the remote freezer is shared with another node which
+// maintains it: ancient data is read from it, but nothing is ever frozen into
+// it nor truncated from it, so all new chain data stays in the key-value store.
+func NewDatabaseWithFreezerRemote(db ethdb.KeyValueStore, freezerURL string, readonly bool) (ethdb.Database, error) {
 	// Create the idle freezer instance
-	log.Info("New remote freezer", "freezer", freezerURL)
+	log.Info("New remote freezer", "freezer", freezerURL, "readonly", readonly)
 
-	frdb, err := newFreezerRemoteClient(freezerURL)
+	frdb, err := newFreezerRemoteClient(freezerURL, readonly)
 	if err != nil {
 		log.Error("NewDatabaseWithFreezerRemote error", "error", err)
 		return nil, err
@@ -203,7 +209,9 @@ Please set --ancient.rpc to the correct path, and/or review the remote freezer's
 		}
 	}
 	// Freezer is consistent with the key-value database, permit combining the two
-	go freezeRemote(db, frdb, frdb.threshold, frdb.quit, frdb.trigger)
+	if !readonly {
+		go freezeRemote(db, frdb, frdb.threshold, frdb.quit, frdb.trigger)
+	}
 
 	return &freezerdb{
 		KeyValueStore: db,
@@ -215,8 +223,15 @@ Please set --ancient.rpc to the correct path, and/or review the remote freezer's
 // value data store with a freezer moving immutable chain segments into cold
 // storage.
 func NewDatabaseWithFreezer(db ethdb.KeyValueStore, freezerStr string, namespace string) (ethdb.Database, error) {
+	return newDatabaseWithFreezer(db, freezerStr, namespace, false)
+}
+
+// newDatabaseWithFreezer creates a high level database on top of a given key-
+// value data store with a freezer, optionally checksumming the items of newly
+// created freezer tables.
+func newDatabaseWithFreezer(db ethdb.KeyValueStore, freezerStr string, namespace string, checksum bool) (ethdb.Database, error) {
 	// Create the idle freezer instance
-	frdb, err := newFreezer(freezerStr, namespace)
+	frdb, err := newFreezer(freezerStr, namespace, checksum)
 	if err != nil {
 		return nil, err
 	}
@@ -272,6 +287,7 @@ func NewDatabaseWithFreezer(db ethdb.KeyValueStore, freezerStr string, namespace
 	}
 
 	// Freezer is consistent with the key-value database, permit combining the two
+	frdb.freezing = true
 	go frdb.freeze(db)
 
 	return &freezerdb{
@@ -305,12 +321,12 @@ func NewLevelDBDatabase(file string, cache int, handles int, namespace string) (
 
 // NewLevelDBDatabaseWithFreezer creates a persistent key-value database with a
 // freezer moving immutable chain segments into cold storage.
-func NewLevelDBDatabaseWithFreezerRemote(file string, cache int, handles int, freezerURL string) (ethdb.Database, error) {
+func NewLevelDBDatabaseWithFreezerRemote(file string, cache int, handles int, freezerURL string, readonly bool) (ethdb.Database, error) {
 	kvdb, err := leveldb.New(file, cache, handles, "eth/db/chaindata")
 	if err != nil {
 		return nil, err
 	}
-	frdb, err := NewDatabaseWithFreezerRemote(kvdb, freezerURL)
+	frdb, err := NewDatabaseWithFreezerRemote(kvdb, freezerURL, readonly)
 	if err != nil {
 		kvdb.Close()
 		return nil, err
@@ -333,6 +349,91 @@ func NewLevelDBDatabaseWithFreezer(file string, cache int, handles int, freezer
 	return frdb, nil
 }
 
+const (
+	// DBLeveldb is the identifier of the leveldb key-value store engine.
+	DBLeveldb = "leveldb"
+
+	// DBPebble is the identifier of the pebble key-value store engine.
+	DBPebble = "pebble"
+)
+
+// PreexistingDatabase checks the given directory whether a database is already
+// instantiated at that location, and if so, returns the engine it was created
+// with. An empty string is returned if there is no database yet.
+//
+// The engine is deduced from the files the engines maintain: both keep their
+// manifest pointer in CURRENT, but only pebble persists its options.
+func PreexistingDatabase(path string) string {
+	if _, err := os.Stat(filepath.Join(path, "CURRENT")); err != nil {
+		return "" // No pre-existing db
+	}
+	if matches, _ := filepath.Glob(filepath.Join(path, "OPTIONS*")); len(matches) > 0 {
+		return DBPebble
+	}
+	return DBLeveldb
+}
+
+// OpenOptions contains the options to apply when opening a persistent database.
+type OpenOptions struct {
+	Type              string // Engine to use, empty to use the pre-existing or default one
+	Directory         string // Directory of the key-value store
+	AncientsDirectory string // Directory of the freezer, empty for no freezer
+	AncientsRemote    string // URL of a remote freezer, takes precedence over the directory
+	AncientsReadOnly  bool   // Whether the remote freezer is maintained by another node and only read
+	AncientsChecksum  bool   // Whether newly created freezer tables checksum their items
+	Namespace         string // Prefix of the metrics reported by the database
+	Cache             int    // Megabytes of memory allocated to internal caching
+	Handles           int    // Number of file handles allocated to the database
+}
+
+// OpenKeyValueStore opens a persistent key-value store with the configured
+// engine. If no engine is configured, the one of the pre-existing database is
+// used, defaulting to leveldb for new databases. Opening an existing database
+// with a different engine is refused.
+func OpenKeyValueStore(o OpenOptions) (ethdb.KeyValueStore, error) {
+	existing := PreexistingDatabase(o.Directory)
+	if o.Type != "" && existing != "" && o.Type != existing {
+		return nil, fmt.Errorf("db.engine choice was %s but found pre-existing %s database in specified data directory", o.Type, existing)
+	}
+	engine := o.Type
+	if engine == "" {
+		engine = existing
+	}
+	switch engine {
+	case DBPebble:
+		log.Info("Using pebble as the backing database")
+		return pebble.New(o.Directory, o.Cache, o.Handles, o.Namespace)
+	case DBLeveldb, "":
+		log.Info("Using leveldb as the backing database")
+		return leveldb.New(o.Directory, o.Cache, o.Handles, o.Namespace)
+	default:
+		return nil, fmt.Errorf("unknown db.engine %q", engine)
+	}
+}
+
+// Open opens a persistent key-value store with the configured engine and, if
+// any is configured, attaches a local or remote freezer to it.
+func Open(o OpenOptions) (ethdb.Database, error) {
+	kvdb, err := OpenKeyValueStore(o)
+	if err != nil {
+		return nil, err
+	}
+	var frdb ethdb.Database
+	switch {
+	case o.AncientsRemote != "":
+		frdb, err = NewDatabaseWithFreezerRemote(kvdb, o.AncientsRemote, o.AncientsReadOnly)
+	case o.AncientsDirectory != "":
+		frdb, err = newDatabaseWithFreezer(kvdb, o.AncientsDirectory, o.Namespace, o.AncientsChecksum)
+	default:
+		return NewDatabase(kvdb), nil
+	}
+	if err != nil {
+		kvdb.Close()
+		return nil, err
+	}
+	return frdb, nil
+}
+
 type counter uint64
 
 func (c counter) String() string {
@@ -388,7 +489,9 @@ func InspectDatabase(db ethdb.Database) error {
 		storageSnaps    stat
 		preimages       stat
 		bloomBits       stat
+		addressTxs      stat
 		cliqueSnaps     stat
+		afDecisions     stat
 
 		// Ancient store statistics
 		ancientHeadersSize  common.StorageSize
@@ -442,8 +545,14 @@ func InspectDatabase(db ethdb.Database) error {
 			preimages.Add(size)
 		case bytes.HasPrefix(key, bloomBitsPrefix) && len(key) == (len(bloomBitsPrefix)+10+common.HashLength):
 			bloomBits.Add(size)
+		case bytes.HasPrefix(key, addressTxPrefix) && len(key) == (len(addressTxPrefix)+common.AddressLength+12):
+			addressTxs.Add(size)
+		case bytes.HasPrefix(key, addressTxBlockPrefix) && len(key) == (len(addressTxBlockPrefix)+8):
+			addressTxs.Add(size)
 		case bytes.HasPrefix(key, []byte("clique-")) && len(key) == 7+common.HashLength:
 			cliqueSnaps.Add(size)
+		case bytes.HasPrefix(key, artificialFinalityDecisionPrefix) && len(key) == (len(artificialFinalityDecisionPrefix)+8+common.HashLength):
+			afDecisions.Add(size)
 		case bytes.HasPrefix(key, []byte("cht-")) && len(key) == 4+common.HashLength:
 			chtTrieNodes.Add(size)
 		case bytes.HasPrefix(key, []byte("blt-")) && len(key) == 4+common.HashLength:
@@ -490,12 +599,14 @@ func InspectDatabase(db ethdb.Database) error {
 		{"Key-Value store", "Block hash->number", hashNumPairings.Size(), hashNumPairings.Count()},
 		{"Key-Value store", "Transaction index", txLookups.Size(), txLookups.Count()},
 		{"Key-Value store", "Bloombit index", bloomBits.Size(), bloomBits.Count()},
+		{"Key-Value store", "Address index", addressTxs.Size(), addressTxs.Count()},
 		{"Key-Value store", "Contract codes", codes.Size(), codes.Count()},
 		{"Key-Value store", "Trie nodes", tries.Size(), tries.Count()},
 		{"Key-Value store", "Trie preimages", preimages.Size(), preimages.Count()},
 		{"Key-Value store", "Account snapshot", accountSnaps.Size(), accountSnaps.Count()},
 		{"Key-Value store", "Storage snapshot", storageSnaps.Size(), storageSnaps.Count()},
 		{"Key-Value store", "Clique snapshots", cliqueSnaps.Size(), cliqueSnaps.Count()},
+		{"Key-Value store", "MESS decisions", afDecisions.Size(), afDecisions.Count()},
 		{"Key-Value store", "Singleton metadata", metadata.Size(), metadata.Count()},
 		{"Ancient store", "Headers", ancientHeadersSize.String(), ancients.String()},
 		{"Ancient store", "Bodies", ancientBodiesSize.String(), ancients.String()},
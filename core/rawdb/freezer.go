@@ -78,13 +78,15 @@ type freezer struct {
 
 	trigger chan chan struct{} // Manual blocking freeze trigger, test determinism
 
+	freezing  bool // Whether a freeze loop was started, which has to be stopped on close
 	quit      chan struct{}
 	closeOnce sync.Once
 }
 
 // newFreezer creates a chain freezer that moves ancient chain data into
-// append-only flat file containers.
-func newFreezer(datadir string, namespace string) (*freezer, error) {
+// append-only flat file containers. If checksum is set, tables created by the
+// freezer store a checksum alongside every item.
+func newFreezer(datadir string, namespace string, checksum bool) (*freezer, error) {
 	// Create the initial freezer object
 	var (
 		readMeter  = metrics.NewRegisteredMeter(namespace+"ancient/read", nil)
@@ -113,7 +115,7 @@ func newFreezer(datadir string, namespace string) (*freezer, error) {
 		quit:         make(chan struct{}),
 	}
 	for name, disableSnappy := range freezerNoSnappy {
-		table, err := newTable(datadir, name, readMeter, writeMeter, sizeGauge, disableSnappy)
+		table, err := newTable(datadir, name, readMeter, writeMeter, sizeGauge, disableSnappy, checksum)
 		if err != nil {
 			for _, table := range freezer.tables {
 				table.Close()
@@ -134,11 +136,25 @@ func newFreezer(datadir string, namespace string) (*freezer, error) {
 	return freezer, nil
 }
 
+// NewFreezer creates a standalone chain freezer backed by append-only flat files
+// in the given directory. Unlike the freezer of a database created by
+// NewDatabaseWithFreezer, it does not move data from any key-value store;
+// it is intended for remote freezer server implementations.
+func NewFreezer(datadir string, namespace string) (ethdb.AncientStore, error) {
+	f, err := newFreezer(datadir, namespace, false)
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
 // Close terminates the chain freezer, unmapping all the data files.
 func (f *freezer) Close() error {
 	var errs []error
 	f.closeOnce.Do(func() {
-		f.quit <- struct{}{}
+		if f.freezing {
+			f.quit <- struct{}{}
+		}
 		for _, table := range f.tables {
 			if err := table.Close(); err != nil {
 				errs = append(errs, err)
@@ -0,0 +1,356 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/golang/snappy"
+	"github.com/prometheus/tsdb/fileutil"
+)
+
+// FreezerTableReport contains the outcome of checking a single freezer table.
+type FreezerTableReport struct {
+	Name        string             // Name of the table
+	Tail        uint64             // Number of items deleted from the tail of the table
+	Items       uint64             // Number of items referenced by the index, including deleted ones
+	Intact      uint64             // Number of leading items that passed every check
+	Compressed  bool               // Whether the items are snappy compressed
+	Checksummed bool               // Whether the items are stored with a checksum
+	Size        common.StorageSize // Total size of the index and data files
+	Err         error              // First inconsistency found in the table, nil if none
+}
+
+// FreezerReport contains the outcome of checking all the tables of a freezer.
+type FreezerReport struct {
+	Tables    []*FreezerTableReport // Reports of the individual tables, sorted by name
+	Items     uint64                // Number of items present in every table
+	Intact    uint64                // Number of leading items intact in every table
+	VerifyErr error                 // First cross-table verification failure, nil if none
+}
+
+// Healthy returns whether the freezer passed every check, with all its tables
+// holding the same number of items.
+func (r *FreezerReport) Healthy() bool {
+	if r.VerifyErr != nil {
+		return false
+	}
+	for _, table := range r.Tables {
+		if table.Err != nil || table.Items != r.Items || table.Intact != r.Items {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckFreezer validates the freezer in the given directory without modifying
+// it. The index of every table is cross checked against its data files, and
+// every item is read back, checking its checksum if the table stores any and
+// decompressing it if the table is compressed.
+//
+// If verify is non-nil, it's invoked in ascending order with the items of every
+// table for each number present in all of them, allowing the caller to validate
+// the contents across tables. Verification stops at the first error.
+func CheckFreezer(datadir string, verify func(number uint64, items map[string][]byte) error) (*FreezerReport, error) {
+	if _, err := os.Stat(datadir); err != nil {
+		return nil, err
+	}
+	// Prevent a running node from modifying the freezer under our feet
+	lock, _, err := fileutil.Flock(filepath.Join(datadir, "FLOCK"))
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Release()
+
+	names := make([]string, 0, len(freezerNoSnappy))
+	for name := range freezerNoSnappy {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var (
+		report   = new(FreezerReport)
+		checkers = make(map[string]*freezerTableChecker)
+		first    = uint64(0)
+		last     = uint64(0)
+	)
+	for i, name := range names {
+		checker, err := newFreezerTableChecker(datadir, name, freezerNoSnappy[name])
+		if err != nil {
+			for _, checker := range checkers {
+				checker.close()
+			}
+			return nil, err
+		}
+		defer checker.close()
+
+		checkers[name] = checker
+		report.Tables = append(report.Tables, checker.report)
+
+		if i == 0 || checker.report.Items < report.Items {
+			report.Items = checker.report.Items
+		}
+		if i == 0 || checker.report.Tail < first {
+			first = checker.report.Tail
+		}
+		if checker.report.Items > last {
+			last = checker.report.Items
+		}
+	}
+	// Read every item of every table, verifying the ones all tables have
+	var (
+		start  = time.Now()
+		logged = time.Now()
+	)
+	report.Intact = report.Items
+	for number := first; number < last; number++ {
+		items := make(map[string][]byte)
+		for name, checker := range checkers {
+			if checker.failed() || number < checker.report.Tail || number >= checker.report.Items {
+				continue
+			}
+			blob, err := checker.next()
+			if err != nil {
+				checker.fail(number, err)
+				if number < report.Intact {
+					report.Intact = number
+				}
+				continue
+			}
+			items[name] = blob
+		}
+		if verify != nil && report.VerifyErr == nil && len(items) == len(checkers) {
+			if err := verify(number, items); err != nil {
+				report.VerifyErr = fmt.Errorf("item %d: %v", number, err)
+				if number < report.Intact {
+					report.Intact = number
+				}
+			}
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Checking freezer", "checked", number-first, "total", last-first, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	for _, checker := range checkers {
+		checker.finish()
+	}
+	return report, nil
+}
+
+// freezerTableChecker reads through the index and data files of a freezer table
+// item by item, validating them along the way.
+type freezerTableChecker struct {
+	report *FreezerTableReport
+	path   string
+
+	index  *bufio.Reader // Buffered reader positioned at the next index entry
+	indexF *os.File      // Index file of the table
+	prev   indexEntry    // Index entry ending the previously read item
+
+	data     *os.File // Data file currently being read
+	dataSize int64    // Size of the data file currently being read
+}
+
+// newFreezerTableChecker opens the index of a freezer table and reads the
+// table layout from it.
+func newFreezerTableChecker(path string, name string, noCompression bool) (*freezerTableChecker, error) {
+	report := &FreezerTableReport{
+		Name:       name,
+		Compressed: !noCompression,
+	}
+	meta, err := readFreezerTableMeta(filepath.Join(path, freezerMetaName(name)))
+	if err != nil {
+		return nil, err
+	}
+	report.Checksummed = meta != nil && meta.Checksum
+
+	checker := &freezerTableChecker{report: report, path: path}
+	checker.indexF, err = os.Open(filepath.Join(path, freezerIndexName(name, noCompression, report.Checksummed)))
+	if os.IsNotExist(err) {
+		report.Err = errors.New("missing index file")
+		return checker, nil
+	} else if err != nil {
+		return nil, err
+	}
+	stat, err := checker.indexF.Stat()
+	if err != nil {
+		checker.indexF.Close()
+		return nil, err
+	}
+	report.Size = common.StorageSize(stat.Size())
+
+	// The first index entry holds the tail file and the number of deleted items
+	if stat.Size() < indexEntrySize {
+		report.Err = fmt.Errorf("index file too short (%d bytes)", stat.Size())
+		return checker, nil
+	}
+	checker.index = bufio.NewReader(checker.indexF)
+	if err := checker.readEntry(&checker.prev); err != nil {
+		checker.indexF.Close()
+		return nil, err
+	}
+	report.Tail = uint64(checker.prev.offset)
+	report.Items = report.Tail + uint64(stat.Size()/indexEntrySize) - 1
+	report.Intact = report.Items
+
+	// Sum up the sizes of the referenced data files
+	for num := checker.prev.filenum; ; num++ {
+		stat, err := os.Stat(filepath.Join(path, freezerDataName(name, num, noCompression, report.Checksummed)))
+		if err != nil {
+			break
+		}
+		report.Size += common.StorageSize(stat.Size())
+	}
+	// Items before the tail were deleted along with their data files, so start
+	// reading from the beginning of the tail file.
+	checker.prev.offset = 0
+
+	if overflow := stat.Size() % indexEntrySize; overflow != 0 {
+		report.Err = fmt.Errorf("index file has %d dangling bytes", overflow)
+	}
+	return checker, nil
+}
+
+// readEntry reads the next entry from the index file.
+func (c *freezerTableChecker) readEntry(entry *indexEntry) error {
+	buffer := make([]byte, indexEntrySize)
+	if _, err := io.ReadFull(c.index, buffer); err != nil {
+		return err
+	}
+	return entry.unmarshalBinary(buffer)
+}
+
+// openData switches to reading the data file with the given number.
+func (c *freezerTableChecker) openData(num uint32) error {
+	if c.data != nil {
+		c.data.Close()
+		c.data = nil
+	}
+	file, err := os.Open(filepath.Join(c.path, freezerDataName(c.report.Name, num, !c.report.Compressed, c.report.Checksummed)))
+	if err != nil {
+		return fmt.Errorf("missing data file %d", num)
+	}
+	stat, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	c.data, c.dataSize = file, stat.Size()
+	return nil
+}
+
+// next reads and validates the next item of the table, returning its contents.
+func (c *freezerTableChecker) next() ([]byte, error) {
+	var entry indexEntry
+	if err := c.readEntry(&entry); err != nil {
+		return nil, fmt.Errorf("failed to read index: %v", err)
+	}
+
+	// Items are either appended to the current data file or start a new one
+	start := c.prev.offset
+	switch {
+	case c.data == nil:
+		if entry.filenum != c.prev.filenum && entry.filenum != c.prev.filenum+1 {
+			return nil, fmt.Errorf("index points to data file %d, expected %d", entry.filenum, c.prev.filenum)
+		}
+		if err := c.openData(entry.filenum); err != nil {
+			return nil, err
+		}
+	case entry.filenum == c.prev.filenum:
+		if entry.offset < c.prev.offset {
+			return nil, fmt.Errorf("index offset %d below previous %d", entry.offset, c.prev.offset)
+		}
+	case entry.filenum == c.prev.filenum+1:
+		if c.dataSize != int64(c.prev.offset) {
+			return nil, fmt.Errorf("data file %d is %d bytes, index expects %d", c.prev.filenum, c.dataSize, c.prev.offset)
+		}
+		if err := c.openData(entry.filenum); err != nil {
+			return nil, err
+		}
+		start = 0
+	default:
+		return nil, fmt.Errorf("index jumps from data file %d to %d", c.prev.filenum, entry.filenum)
+	}
+	if int64(entry.offset) > c.dataSize {
+		return nil, fmt.Errorf("index offset %d beyond data file %d size %d", entry.offset, entry.filenum, c.dataSize)
+	}
+	c.prev = entry
+
+	// Read the item back and make sure it's decodable
+	blob := make([]byte, entry.offset-start)
+	if _, err := c.data.ReadAt(blob, int64(start)); err != nil {
+		return nil, err
+	}
+	if c.report.Checksummed {
+		var err error
+		if blob, err = verifyChecksum(blob); err != nil {
+			return nil, err
+		}
+	}
+	if c.report.Compressed {
+		return snappy.Decode(nil, blob)
+	}
+	return blob, nil
+}
+
+// fail marks the table damaged from the given item onwards.
+func (c *freezerTableChecker) fail(number uint64, err error) {
+	c.report.Intact = number
+	c.report.Err = fmt.Errorf("item %d: %v", number, err)
+}
+
+// failed returns whether the table was found damaged.
+func (c *freezerTableChecker) failed() bool {
+	return c.report.Intact < c.report.Items || c.index == nil
+}
+
+// finish checks that no data was left dangling after the last item of an intact
+// table, which is harmless and truncated when the freezer is opened.
+func (c *freezerTableChecker) finish() {
+	if c.failed() || c.report.Err != nil {
+		return
+	}
+	if c.data == nil {
+		// Empty table, its head file might not even exist yet
+		if err := c.openData(c.prev.filenum); err != nil {
+			return
+		}
+	}
+	if c.dataSize > int64(c.prev.offset) {
+		c.report.Err = fmt.Errorf("data file %d has %d dangling bytes", c.prev.filenum, c.dataSize-int64(c.prev.offset))
+	}
+}
+
+// close releases the files opened by the checker.
+func (c *freezerTableChecker) close() {
+	if c.data != nil {
+		c.data.Close()
+	}
+	if c.indexF != nil {
+		c.indexF.Close()
+	}
+}
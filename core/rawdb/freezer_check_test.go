@@ -0,0 +1,156 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// newTestFreezer creates a freezer in a temporary directory holding the given
+// number of items in every table.
+func newTestFreezer(t *testing.T, checksum bool, items int) string {
+	dir, err := ioutil.TempDir("", "freezer-check-")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	f, err := newFreezer(dir, "", checksum)
+	if err != nil {
+		t.Fatalf("failed to create freezer: %v", err)
+	}
+	defer f.Close()
+
+	for i := 0; i < items; i++ {
+		if err := f.AppendAncient(uint64(i), getChunk(32, i), getChunk(100, i), getChunk(200, i), getChunk(50, i), getChunk(1, i)); err != nil {
+			t.Fatalf("failed to append item %d: %v", i, err)
+		}
+	}
+	return dir
+}
+
+// readIndexEntry reads an entry of the index of a freezer table.
+func readIndexEntry(t *testing.T, dir string, name string, checksum bool, n uint64) indexEntry {
+	blob, err := ioutil.ReadFile(filepath.Join(dir, freezerIndexName(name, freezerNoSnappy[name], checksum)))
+	if err != nil {
+		t.Fatalf("failed to read index: %v", err)
+	}
+	var entry indexEntry
+	entry.unmarshalBinary(blob[n*indexEntrySize:])
+	return entry
+}
+
+// Tests that an intact freezer passes the check, with every item handed to the
+// verifier.
+func TestCheckFreezer(t *testing.T) {
+	for _, checksum := range []bool{false, true} {
+		dir := newTestFreezer(t, checksum, 100)
+		defer os.RemoveAll(dir)
+
+		var verified uint64
+		report, err := CheckFreezer(dir, func(number uint64, items map[string][]byte) error {
+			if number != verified {
+				t.Errorf("checksum %v: verifying item %d, want %d", checksum, number, verified)
+			}
+			if !bytes.Equal(items[freezerBodiesTable], getChunk(200, int(number))) {
+				t.Errorf("checksum %v: item %d body mismatch", checksum, number)
+			}
+			verified++
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("checksum %v: failed to check freezer: %v", checksum, err)
+		}
+		if !report.Healthy() {
+			t.Errorf("checksum %v: intact freezer reported damaged", checksum)
+		}
+		if report.Items != 100 || report.Intact != 100 || verified != 100 {
+			t.Errorf("checksum %v: items mismatch: have %d/%d/%d, want 100", checksum, report.Items, report.Intact, verified)
+		}
+		for _, table := range report.Tables {
+			if table.Checksummed != checksum {
+				t.Errorf("checksum %v: table %s checksummed %v", checksum, table.Name, table.Checksummed)
+			}
+		}
+	}
+}
+
+// Tests that a corrupted item is detected in checksummed tables.
+func TestCheckFreezerCorruptItem(t *testing.T) {
+	dir := newTestFreezer(t, true, 100)
+	defer os.RemoveAll(dir)
+
+	// Flip the first byte of item 40 of the bodies
+	entry := readIndexEntry(t, dir, freezerBodiesTable, true, 40)
+	file, err := os.OpenFile(filepath.Join(dir, freezerDataName(freezerBodiesTable, entry.filenum, false, true)), os.O_RDWR, 0644)
+	if err != nil {
+		t.Fatalf("failed to open data file: %v", err)
+	}
+	buf := make([]byte, 1)
+	file.ReadAt(buf, int64(entry.offset))
+	file.WriteAt([]byte{buf[0] ^ 0xff}, int64(entry.offset))
+	file.Close()
+
+	report, err := CheckFreezer(dir, nil)
+	if err != nil {
+		t.Fatalf("failed to check freezer: %v", err)
+	}
+	if report.Healthy() || report.Intact != 40 {
+		t.Fatalf("corruption not detected: healthy %v, intact %d", report.Healthy(), report.Intact)
+	}
+	for _, table := range report.Tables {
+		if table.Name == freezerBodiesTable && table.Err == nil {
+			t.Errorf("corrupted table reported intact")
+		}
+		if table.Name != freezerBodiesTable && table.Err != nil {
+			t.Errorf("table %s reported damaged: %v", table.Name, table.Err)
+		}
+	}
+}
+
+// Tests that data missing from a table is detected, and that verification
+// failures are reported.
+func TestCheckFreezerTruncatedData(t *testing.T) {
+	dir := newTestFreezer(t, false, 100)
+	defer os.RemoveAll(dir)
+
+	// Cut the receipts from item 90 onwards
+	entry := readIndexEntry(t, dir, freezerReceiptTable, false, 90)
+	if err := os.Truncate(filepath.Join(dir, freezerDataName(freezerReceiptTable, entry.filenum, false, false)), int64(entry.offset)); err != nil {
+		t.Fatalf("failed to truncate data file: %v", err)
+	}
+	report, err := CheckFreezer(dir, func(number uint64, items map[string][]byte) error {
+		if number == 20 {
+			return errors.New("invalid")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to check freezer: %v", err)
+	}
+	if report.VerifyErr == nil || report.Intact != 20 {
+		t.Fatalf("verification failure not reported: err %v, intact %d", report.VerifyErr, report.Intact)
+	}
+	for _, table := range report.Tables {
+		if table.Name == freezerReceiptTable && (table.Err == nil || table.Intact != 90) {
+			t.Errorf("truncated table not detected: err %v, intact %d", table.Err, table.Intact)
+		}
+	}
+}
@@ -1,10 +1,13 @@
 package rawdb
 
 import (
+	"errors"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/params/vars"
@@ -20,6 +23,14 @@ type FreezerRemoteClient struct {
 	threshold uint64             // Number of recent blocks not to freeze (params.FullImmutabilityThreshold apart from tests)
 	trigger   chan chan struct{} // Manual blocking freeze trigger, test determinism
 	closeOnce sync.Once
+
+	// Set if the client only reads from the remote freezer, which is then
+	// shared with (and written to by) another node.
+	readonly bool
+
+	// Set (atomically) once the server turns out not to implement the batch
+	// methods, in which case they are emulated with the per-item ones.
+	legacy uint32
 }
 
 const (
@@ -31,10 +42,53 @@ const (
 	FreezerMethodAppendAncient    = "freezer_appendAncient"
 	FreezerMethodTruncateAncients = "freezer_truncateAncients"
 	FreezerMethodSync             = "freezer_sync"
+
+	// Batch methods, optional for servers.
+	FreezerMethodAncientRange    = "freezer_ancientRange"
+	FreezerMethodAppendAncients  = "freezer_appendAncients"
+	FreezerMethodAncientChecksum = "freezer_ancientChecksum"
 )
 
-// newFreezerRemoteClient constructs a rpc client to connect to a remote freezer
-func newFreezerRemoteClient(endpoint string) (*FreezerRemoteClient, error) {
+// freezerRemoteAppendBatch is the maximum number of blocks sent to the remote
+// freezer in a single freezer_appendAncients call.
+const freezerRemoteAppendBatch = 256
+
+// FreezerRemoteChecksumLimit is the maximum number of items a remote freezer
+// checksums in a single freezer_ancientChecksum call.
+const FreezerRemoteChecksumLimit = 65536
+
+// rpcMethodNotFoundCode is the JSON-RPC error code of calls to unknown methods.
+const rpcMethodNotFoundCode = -32601
+
+// errReadOnlyFreezer is returned if the user attempts to append to a remote
+// freezer opened in read-only mode.
+var errReadOnlyFreezer = errors.New("remote freezer is read-only")
+
+// FreezerRemoteItem holds all the ancient data of a block, as passed in batches
+// to freezer_appendAncients.
+type FreezerRemoteItem struct {
+	Number   uint64 `json:"number"`
+	Hash     []byte `json:"hash"`
+	Header   []byte `json:"header"`
+	Body     []byte `json:"body"`
+	Receipts []byte `json:"receipts"`
+	Td       []byte `json:"td"`
+}
+
+// FreezerRemoteChecksum computes the checksum of a range of ancient items, as
+// returned by freezer_ancientChecksum: the keccak256 hash of the concatenated
+// keccak256 hashes of the items.
+func FreezerRemoteChecksum(blobs [][]byte) common.Hash {
+	hashes := make([][]byte, len(blobs))
+	for i, blob := range blobs {
+		hashes[i] = crypto.Keccak256(blob)
+	}
+	return crypto.Keccak256Hash(hashes...)
+}
+
+// newFreezerRemoteClient constructs a rpc client to connect to a remote freezer.
+// A read-only client never modifies the remote freezer.
+func newFreezerRemoteClient(endpoint string, readonly bool) (*FreezerRemoteClient, error) {
 	client, err := rpc.Dial(endpoint)
 	if err != nil {
 		return nil, err
@@ -44,11 +98,13 @@ func newFreezerRemoteClient(endpoint string) (*FreezerRemoteClient, error) {
 		threshold: vars.FullImmutabilityThreshold,
 		quit:      make(chan struct{}),
 		trigger:   make(chan chan struct{}),
+		readonly:  readonly,
 	}, nil
 }
 
 // Close terminates the chain freezer, unmapping all the data files.
 func (api *FreezerRemoteClient) Close() error {
+	api.closeOnce.Do(func() { close(api.quit) })
 	return api.client.Call(nil, FreezerMethodClose)
 }
 
@@ -92,19 +148,135 @@ func (api *FreezerRemoteClient) AncientSize(kind string) (uint64, error) {
 //
 // Note that the frozen marker is updated outside of the service calls.
 func (api *FreezerRemoteClient) AppendAncient(number uint64, hash, header, body, receipts, td []byte) (err error) {
+	if api.readonly {
+		return errReadOnlyFreezer
+	}
 	return api.client.Call(nil, FreezerMethodAppendAncient, number, hash, header, body, receipts, td)
 }
 
 // TruncateAncients discards any recent data above the provided threshold number.
+//
+// Read-only clients leave the remote freezer untouched: it is owned by another
+// node, whose ancient chain is usually ahead of the local one.
 func (api *FreezerRemoteClient) TruncateAncients(items uint64) error {
+	if api.readonly {
+		log.Debug("Skipping truncation of read-only remote freezer", "items", items)
+		return nil
+	}
 	return api.client.Call(nil, FreezerMethodTruncateAncients, items)
 }
 
 // Sync flushes all data tables to disk.
 func (api *FreezerRemoteClient) Sync() error {
+	if api.readonly {
+		return nil
+	}
 	return api.client.Call(nil, FreezerMethodSync)
 }
 
+// isMethodNotFound reports whether err is the error of a call to a method the
+// server doesn't implement.
+func isMethodNotFound(err error) bool {
+	rpcErr, ok := err.(rpc.Error)
+	return ok && rpcErr.ErrorCode() == rpcMethodNotFoundCode
+}
+
+// useLegacy reports whether the batch methods should be emulated, marking the
+// server as not supporting them if err says so.
+func (api *FreezerRemoteClient) useLegacy(err error) bool {
+	if err == nil || !isMethodNotFound(err) {
+		return false
+	}
+	if atomic.CompareAndSwapUint32(&api.legacy, 0, 1) {
+		log.Warn("Remote freezer doesn't support batch methods, falling back to single items", "err", err)
+	}
+	return true
+}
+
+// AncientRange retrieves up to count consecutive ancient binary blobs of a kind,
+// starting at number start. The result is cut short at the end of the store,
+// and once the total size exceeds maxBytes (if non-zero), but at least one item
+// is returned.
+func (api *FreezerRemoteClient) AncientRange(kind string, start, count, maxBytes uint64) ([][]byte, error) {
+	if atomic.LoadUint32(&api.legacy) == 0 {
+		var res [][]byte
+		err := api.client.Call(&res, FreezerMethodAncientRange, kind, start, count, maxBytes)
+		if !api.useLegacy(err) {
+			return res, err
+		}
+	}
+	items, err := api.Ancients()
+	if err != nil {
+		return nil, err
+	}
+	if start >= items {
+		return nil, errOutOfBounds
+	}
+	if items-start < count {
+		count = items - start
+	}
+	var (
+		res  [][]byte
+		size uint64
+	)
+	for i := uint64(0); i < count; i++ {
+		blob, err := api.Ancient(kind, start+i)
+		if err != nil {
+			return nil, err
+		}
+		if maxBytes != 0 && i > 0 && size+uint64(len(blob)) > maxBytes {
+			break
+		}
+		res = append(res, blob)
+		size += uint64(len(blob))
+	}
+	return res, nil
+}
+
+// AppendAncients injects the ancient data of consecutive blocks at the end of
+// the append-only immutable table files.
+func (api *FreezerRemoteClient) AppendAncients(items []*FreezerRemoteItem) error {
+	if len(items) == 0 {
+		return nil
+	}
+	if api.readonly {
+		return errReadOnlyFreezer
+	}
+	if atomic.LoadUint32(&api.legacy) == 0 {
+		err := api.client.Call(nil, FreezerMethodAppendAncients, items)
+		if !api.useLegacy(err) {
+			return err
+		}
+	}
+	for _, item := range items {
+		if err := api.AppendAncient(item.Number, item.Hash, item.Header, item.Body, item.Receipts, item.Td); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AncientChecksum returns the checksum (see FreezerRemoteChecksum) of count
+// consecutive ancient binary blobs of a kind, starting at number start.
+func (api *FreezerRemoteClient) AncientChecksum(kind string, start, count uint64) (common.Hash, error) {
+	if atomic.LoadUint32(&api.legacy) == 0 {
+		var res common.Hash
+		err := api.client.Call(&res, FreezerMethodAncientChecksum, kind, start, count)
+		if !api.useLegacy(err) {
+			return res, err
+		}
+	}
+	var blobs [][]byte
+	for uint64(len(blobs)) < count {
+		batch, err := api.AncientRange(kind, start+uint64(len(blobs)), count-uint64(len(blobs)), 0)
+		if err != nil {
+			return common.Hash{}, err
+		}
+		blobs = append(blobs, batch...)
+	}
+	return FreezerRemoteChecksum(blobs), nil
+}
+
 // freezeRemote is a background thread that periodically checks the blockchain for any
 // import progress and moves ancient data from the fast database into the freezer.
 //
@@ -116,8 +288,9 @@ func (api *FreezerRemoteClient) Sync() error {
 // store. Code is near-duplicated to permit the default FS ancient store logic
 // to exist unmodified and untouched by the remote freezer client, which demands
 // a slightly different signature, and uses the freezer.Ancients() method instead
-// of direct access to the atomic freezer.frozen field.
-func freezeRemote(db ethdb.KeyValueStore, f ethdb.AncientStore, threshold uint64, quitChan chan struct{}, triggerChanChan chan chan struct{}) {
+// of direct access to the atomic freezer.frozen field. Blocks are also sent to the
+// remote freezer in batches, to avoid a round trip per block.
+func freezeRemote(db ethdb.KeyValueStore, f *FreezerRemoteClient, threshold uint64, quitChan chan struct{}, triggerChanChan chan chan struct{}) {
 	nfdb := &nofreezedb{KeyValueStore: db}
 
 	var (
@@ -191,41 +364,67 @@ func freezeRemote(db ethdb.KeyValueStore, f ethdb.AncientStore, threshold uint64
 			start    = time.Now()
 			first    = numFrozen
 			ancients = make([]common.Hash, 0, limit-numFrozen)
+			items    = make([]*FreezerRemoteItem, 0, freezerRemoteAppendBatch)
 		)
-		for numFrozen <= limit {
+		// flush sends the pending items to the remote freezer in a single call.
+		flush := func() bool {
+			if len(items) == 0 {
+				return true
+			}
+			defer func() { items = items[:0] }()
+			if err := f.AppendAncients(items); err != nil {
+				log.Error("Failed to append ancient blocks", "number", items[0].Number, "count", len(items), "err", err)
+				return false
+			}
+			for _, item := range items {
+				ancients = append(ancients, common.BytesToHash(item.Hash))
+			}
+			numFrozen += uint64(len(items))
+			return true
+		}
+		for next := numFrozen; next <= limit; next++ {
 			// Retrieves all the components of the canonical block
-			hash := ReadCanonicalHash(nfdb, numFrozen)
+			hash := ReadCanonicalHash(nfdb, next)
 			if hash == (common.Hash{}) {
-				log.Error("Canonical hash missing, can't freeze", "number", numFrozen)
+				log.Error("Canonical hash missing, can't freeze", "number", next)
 				break
 			}
-			header := ReadHeaderRLP(nfdb, hash, numFrozen)
+			header := ReadHeaderRLP(nfdb, hash, next)
 			if len(header) == 0 {
-				log.Error("Block header missing, can't freeze", "number", numFrozen, "hash", hash)
+				log.Error("Block header missing, can't freeze", "number", next, "hash", hash)
 				break
 			}
-			body := ReadBodyRLP(nfdb, hash, numFrozen)
+			body := ReadBodyRLP(nfdb, hash, next)
 			if len(body) == 0 {
-				log.Error("Block body missing, can't freeze", "number", numFrozen, "hash", hash)
+				log.Error("Block body missing, can't freeze", "number", next, "hash", hash)
 				break
 			}
-			receipts := ReadReceiptsRLP(nfdb, hash, numFrozen)
+			receipts := ReadReceiptsRLP(nfdb, hash, next)
 			if len(receipts) == 0 {
-				log.Error("Block receipts missing, can't freeze", "number", numFrozen, "hash", hash)
+				log.Error("Block receipts missing, can't freeze", "number", next, "hash", hash)
 				break
 			}
-			td := ReadTdRLP(nfdb, hash, numFrozen)
+			td := ReadTdRLP(nfdb, hash, next)
 			if len(td) == 0 {
-				log.Error("Total difficulty missing, can't freeze", "number", numFrozen, "hash", hash)
+				log.Error("Total difficulty missing, can't freeze", "number", next, "hash", hash)
 				break
 			}
-			log.Trace("Deep froze ancient block", "number", numFrozen, "hash", hash)
-			// Inject all the components into the relevant data tables
-			if err := f.AppendAncient(numFrozen, hash[:], header, body, receipts, td); err != nil {
+			log.Trace("Deep froze ancient block", "number", next, "hash", hash)
+			// Queue all the components for injection into the relevant data tables
+			items = append(items, &FreezerRemoteItem{
+				Number:   next,
+				Hash:     hash[:],
+				Header:   header,
+				Body:     body,
+				Receipts: receipts,
+				Td:       td,
+			})
+			if len(items) == freezerRemoteAppendBatch && !flush() {
 				break
 			}
-			ancients = append(ancients, hash)
 		}
+		flush()
+
 		// Batch of blocks have been frozen, flush them before wiping from leveldb
 		if err := f.Sync(); err != nil {
 			log.Crit("Failed to flush frozen tables", "err", err)
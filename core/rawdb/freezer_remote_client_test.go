@@ -2,7 +2,13 @@ package rawdb
 
 import (
 	"bytes"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/ethereum/go-ethereum/cmd/ancient-store-mem/lib"
 	"github.com/ethereum/go-ethereum/rpc"
@@ -96,3 +102,168 @@ func TestClient1(t *testing.T) {
 		t.Fatalf("got: %d, want: 670", n)
 	}
 }
+
+// legacyFreezerServer exposes only the freezer methods predating the batch ones.
+type legacyFreezerServer struct {
+	legacyFreezer
+}
+
+type legacyFreezer interface {
+	HasAncient(kind string, number uint64) (bool, error)
+	Ancient(kind string, number uint64) ([]byte, error)
+	Ancients() (uint64, error)
+	AncientSize(kind string) (uint64, error)
+	AppendAncient(number uint64, hash, header, body, receipt, td []byte) error
+	TruncateAncients(n uint64) error
+	Sync() error
+	Close() error
+}
+
+func TestClientBatch(t *testing.T) {
+	t.Run("batch", func(t *testing.T) {
+		testClientBatch(t, lib.NewMemFreezerRemoteServerAPI(), false)
+	})
+	t.Run("legacy", func(t *testing.T) {
+		testClientBatch(t, &legacyFreezerServer{lib.NewMemFreezerRemoteServerAPI()}, true)
+	})
+}
+
+func testClientBatch(t *testing.T, service interface{}, legacy bool) {
+	server := rpc.NewServer()
+	if err := server.RegisterName("freezer", service); err != nil {
+		t.Fatal(err)
+	}
+	frClient := &FreezerRemoteClient{
+		client: rpc.DialInProc(server),
+		quit:   make(chan struct{}),
+	}
+	blob := func(n uint64) []byte { return []byte{byte(n), byte(n >> 8), 0xff} }
+
+	var items []*FreezerRemoteItem
+	for n := uint64(0); n < 100; n++ {
+		items = append(items, &FreezerRemoteItem{
+			Number: n, Hash: blob(n), Header: blob(n), Body: blob(n), Receipts: blob(n), Td: blob(n),
+		})
+	}
+	if err := frClient.AppendAncients(items[:60]); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	if err := frClient.AppendAncients(items[70:]); err == nil {
+		t.Fatal("out of order batch append succeeded")
+	}
+	if err := frClient.AppendAncients(items[60:]); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	if got := atomic.LoadUint32(&frClient.legacy) == 1; got != legacy {
+		t.Fatalf("legacy mode mismatch: have %v, want %v", got, legacy)
+	}
+	if n, err := frClient.Ancients(); err != nil || n != 100 {
+		t.Fatalf("ancients: have %d (%v), want 100", n, err)
+	}
+
+	// Ranges are capped by the number of items and by the size limit.
+	for _, tt := range []struct {
+		start, count, maxBytes uint64
+		want                   int
+	}{
+		{0, 10, 0, 10},
+		{95, 10, 0, 5},
+		{10, 50, 30, 10},
+		{10, 50, 31, 10},
+		{10, 50, 1, 1},
+	} {
+		blobs, err := frClient.AncientRange(FreezerRemoteBodiesTable, tt.start, tt.count, tt.maxBytes)
+		if err != nil {
+			t.Fatalf("range %d+%d (max %d): %v", tt.start, tt.count, tt.maxBytes, err)
+		}
+		if len(blobs) != tt.want {
+			t.Fatalf("range %d+%d (max %d): have %d items, want %d", tt.start, tt.count, tt.maxBytes, len(blobs), tt.want)
+		}
+		for i, b := range blobs {
+			if want := blob(tt.start + uint64(i)); !bytes.Equal(b, want) {
+				t.Fatalf("range %d+%d item %d: have %x, want %x", tt.start, tt.count, i, b, want)
+			}
+		}
+	}
+	if _, err := frClient.AncientRange(FreezerRemoteBodiesTable, 100, 1, 0); err == nil {
+		t.Fatal("out of bounds range succeeded")
+	}
+
+	// Checksums match the locally computed ones.
+	var blobs [][]byte
+	for n := uint64(20); n < 50; n++ {
+		blobs = append(blobs, blob(n))
+	}
+	sum, err := frClient.AncientChecksum(FreezerRemoteReceiptTable, 20, 30)
+	if err != nil {
+		t.Fatalf("checksum: %v", err)
+	}
+	if want := FreezerRemoteChecksum(blobs); sum != want {
+		t.Fatalf("checksum mismatch: have %x, want %x", sum, want)
+	}
+	if _, err := frClient.AncientChecksum(FreezerRemoteReceiptTable, 90, 20); err == nil {
+		t.Fatal("out of bounds checksum succeeded")
+	}
+	if _, err := frClient.AncientChecksum(FreezerRemoteReceiptTable, 90, math.MaxUint64-10); err == nil {
+		t.Fatal("overflowing checksum range succeeded")
+	}
+}
+
+func TestClientReadOnly(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	endpoint := filepath.Join(dir, "freezer.ipc")
+	listener, server, err := rpc.StartIPCEndpoint(endpoint, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	if err := server.RegisterName("freezer", lib.NewMemFreezerRemoteServerAPI()); err != nil {
+		t.Fatal(err)
+	}
+	go server.ServeListener(listener)
+
+	// Fill the remote freezer as the node maintaining it would.
+	writer := &FreezerRemoteClient{client: rpc.DialInProc(server), quit: make(chan struct{})}
+	var items []*FreezerRemoteItem
+	for n := uint64(0); n < 10; n++ {
+		blob := []byte{byte(n)}
+		items = append(items, &FreezerRemoteItem{Number: n, Hash: blob, Header: blob, Body: blob, Receipts: blob, Td: blob})
+	}
+	if err := writer.AppendAncients(items); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+
+	db, err := NewDatabaseWithFreezerRemote(NewMemoryDatabase(), endpoint, true)
+	if err != nil {
+		t.Fatalf("failed to open read-only freezer: %v", err)
+	}
+	defer db.Close()
+
+	if blob, err := db.Ancient(FreezerRemoteBodiesTable, 9); err != nil || !bytes.Equal(blob, []byte{9}) {
+		t.Fatalf("ancient: have %x (%v), want 09", blob, err)
+	}
+	if err := db.AppendAncient(10, []byte{10}, []byte{10}, []byte{10}, []byte{10}, []byte{10}); err != errReadOnlyFreezer {
+		t.Fatalf("append error mismatch: have %v, want %v", err, errReadOnlyFreezer)
+	}
+	if err := db.TruncateAncients(5); err != nil {
+		t.Fatalf("truncate: %v", err)
+	}
+	if err := db.Sync(); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	if n, err := writer.Ancients(); err != nil || n != 10 {
+		t.Fatalf("ancients: have %d (%v), want 10", n, err)
+	}
+	// No freezer may be running to pick up manual triggers.
+	client := db.(*freezerdb).AncientStore.(*FreezerRemoteClient)
+	select {
+	case client.trigger <- make(chan struct{}):
+		t.Fatal("read-only freezer is freezing")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
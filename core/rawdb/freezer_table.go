@@ -20,7 +20,9 @@ import (
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"hash/crc32"
 	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"sync"
@@ -29,6 +31,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/golang/snappy"
 )
 
@@ -43,8 +46,87 @@ var (
 
 	// errNotSupported is returned if the database doesn't support the required operation.
 	errNotSupported = errors.New("this operation is not supported")
+
+	// errChecksumMismatch is returned if an item retrieved from a checksummed
+	// freezer table doesn't match the checksum stored alongside it.
+	errChecksumMismatch = errors.New("checksum mismatch")
 )
 
+// freezerTableVersion is the version of the freezer table metadata format.
+const freezerTableVersion = 1
+
+// checksumSize is the number of bytes the checksum of an item takes up in the
+// data file of a checksummed table.
+const checksumSize = 4
+
+// checksumTable is the CRC32 polynomial used to checksum freezer items.
+var checksumTable = crc32.MakeTable(crc32.Castagnoli)
+
+// freezerTableMeta is the metadata of a freezer table, stored next to its index
+// file. Tables created before metadata was introduced have none, which means
+// their items are not checksummed.
+//
+// The index and data files of checksummed tables are named differently from the
+// ones of plain tables (see freezerIndexName), so that freezers unaware of the
+// metadata don't find them instead of misreading the checksums as item data.
+type freezerTableMeta struct {
+	Version  uint16 // Version of the metadata format
+	Checksum bool   // Whether a CRC32 checksum is appended to every stored item
+}
+
+// readFreezerTableMeta reads the metadata of a freezer table, returning nil if
+// the table has none.
+func readFreezerTableMeta(filename string) (*freezerTableMeta, error) {
+	blob, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var meta freezerTableMeta
+	if err := rlp.DecodeBytes(blob, &meta); err != nil {
+		return nil, fmt.Errorf("invalid freezer table metadata %s: %v", filename, err)
+	}
+	if meta.Version > freezerTableVersion {
+		return nil, fmt.Errorf("unsupported freezer table metadata version %d", meta.Version)
+	}
+	return &meta, nil
+}
+
+// writeFreezerTableMeta atomically writes the metadata of a freezer table.
+func writeFreezerTableMeta(filename string, meta *freezerTableMeta) error {
+	blob, err := rlp.EncodeToBytes(meta)
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filename+".tmp", blob, 0644); err != nil {
+		return err
+	}
+	return os.Rename(filename+".tmp", filename)
+}
+
+// appendChecksum returns a copy of the stored form of an item with its checksum
+// appended.
+func appendChecksum(blob []byte) []byte {
+	stored := make([]byte, len(blob)+checksumSize)
+	copy(stored, blob)
+	binary.BigEndian.PutUint32(stored[len(blob):], crc32.Checksum(blob, checksumTable))
+	return stored
+}
+
+// verifyChecksum checks the checksum of an item read from a checksummed table,
+// returning the stored form of the item without it.
+func verifyChecksum(stored []byte) ([]byte, error) {
+	if len(stored) < checksumSize {
+		return nil, errChecksumMismatch
+	}
+	blob := stored[:len(stored)-checksumSize]
+	if binary.BigEndian.Uint32(stored[len(blob):]) != crc32.Checksum(blob, checksumTable) {
+		return nil, errChecksumMismatch
+	}
+	return blob, nil
+}
+
 // indexEntry contains the number/id of the file that the data resides in, aswell as the
 // offset within the file to the end of the data
 // In serialized form, the filenum is stored as uint16.
@@ -80,6 +162,7 @@ type freezerTable struct {
 	items uint64 // Number of items stored in the table (including items removed from tail)
 
 	noCompression bool   // if true, disables snappy compression. Note: does not work retroactively
+	checksum      bool   // if true, a checksum is stored with every item. Note: only set for new tables
 	maxFileSize   uint32 // Max file size for data-files
 	name          string
 	path          string
@@ -104,8 +187,8 @@ type freezerTable struct {
 }
 
 // newTable opens a freezer table with default settings - 2G files
-func newTable(path string, name string, readMeter metrics.Meter, writeMeter metrics.Meter, sizeGauge metrics.Gauge, disableSnappy bool) (*freezerTable, error) {
-	return newCustomTable(path, name, readMeter, writeMeter, sizeGauge, 2*1000*1000*1000, disableSnappy)
+func newTable(path string, name string, readMeter metrics.Meter, writeMeter metrics.Meter, sizeGauge metrics.Gauge, disableSnappy bool, checksum bool) (*freezerTable, error) {
+	return openTable(path, name, readMeter, writeMeter, sizeGauge, 2*1000*1000*1000, disableSnappy, checksum)
 }
 
 // openFreezerFileForAppend opens a freezer table file and seeks to the end
@@ -150,23 +233,71 @@ func truncateFreezerFile(file *os.File, size int64) error {
 // non existent. Both files are truncated to the shortest common length to ensure
 // they don't go out of sync.
 func newCustomTable(path string, name string, readMeter metrics.Meter, writeMeter metrics.Meter, sizeGauge metrics.Gauge, maxFilesize uint32, noCompression bool) (*freezerTable, error) {
-	// Ensure the containing directory exists and open the indexEntry file
+	return openTable(path, name, readMeter, writeMeter, sizeGauge, maxFilesize, noCompression, false)
+}
+
+// freezerIndexName returns the file name of the index of a freezer table. The
+// suffix of checksummed tables carries an extra 's' (e.g. ".rsidx").
+func freezerIndexName(name string, noCompression bool, checksum bool) string {
+	return fmt.Sprintf("%s.%sidx", name, freezerFileKind(noCompression, checksum))
+}
+
+// freezerDataName returns the file name of a data file of a freezer table.
+func freezerDataName(name string, num uint32, noCompression bool, checksum bool) string {
+	return fmt.Sprintf("%s.%04d.%sdat", name, num, freezerFileKind(noCompression, checksum))
+}
+
+// freezerFileKind returns the prefix of the suffixes of the files of a freezer
+// table, telling raw from compressed and checksummed from plain tables.
+func freezerFileKind(noCompression bool, checksum bool) string {
+	kind := "c" // Compressed
+	if noCompression {
+		kind = "r" // Raw
+	}
+	if checksum {
+		kind += "s"
+	}
+	return kind
+}
+
+// freezerMetaName returns the file name of the metadata of a freezer table.
+func freezerMetaName(name string) string {
+	return fmt.Sprintf("%s.meta", name)
+}
+
+// openTable opens a freezer table like newCustomTable does. If the table doesn't
+// exist yet and checksum is set, it's created with a checksum stored alongside
+// every item. Existing tables keep the format they were created with.
+func openTable(path string, name string, readMeter metrics.Meter, writeMeter metrics.Meter, sizeGauge metrics.Gauge, maxFilesize uint32, noCompression bool, checksum bool) (*freezerTable, error) {
+	// Ensure the containing directory exists
 	if err := os.MkdirAll(path, 0755); err != nil {
 		return nil, err
 	}
-	var idxName string
-	if noCompression {
-		// Raw idx
-		idxName = fmt.Sprintf("%s.ridx", name)
-	} else {
-		// Compressed idx
-		idxName = fmt.Sprintf("%s.cidx", name)
+	// Load the table metadata, persisting it first if the table is new
+	metaPath := filepath.Join(path, freezerMetaName(name))
+	meta, err := readFreezerTableMeta(metaPath)
+	if err != nil {
+		return nil, err
 	}
-	offsets, err := openFreezerFileForAppend(filepath.Join(path, idxName))
+	if meta == nil && checksum {
+		_, err := os.Stat(filepath.Join(path, freezerIndexName(name, noCompression, false)))
+		switch {
+		case os.IsNotExist(err):
+			meta = &freezerTableMeta{Version: freezerTableVersion, Checksum: true}
+			if err := writeFreezerTableMeta(metaPath, meta); err != nil {
+				return nil, err
+			}
+		case err != nil:
+			return nil, err
+		}
+	}
+	checksum = meta != nil && meta.Checksum
+
+	// Open the indexEntry file, create the table and repair any past inconsistency
+	offsets, err := openFreezerFileForAppend(filepath.Join(path, freezerIndexName(name, noCompression, checksum)))
 	if err != nil {
 		return nil, err
 	}
-	// Create the table and repair any past inconsistency
 	tab := &freezerTable{
 		index:         offsets,
 		files:         make(map[uint32]*os.File),
@@ -177,6 +308,7 @@ func newCustomTable(path string, name string, readMeter metrics.Meter, writeMete
 		path:          path,
 		logger:        log.New("database", path, "table", name),
 		noCompression: noCompression,
+		checksum:      checksum,
 		maxFileSize:   maxFilesize,
 	}
 	if err := tab.repair(); err != nil {
@@ -416,13 +548,7 @@ func (t *freezerTable) Close() error {
 func (t *freezerTable) openFile(num uint32, opener func(string) (*os.File, error)) (f *os.File, err error) {
 	var exist bool
 	if f, exist = t.files[num]; !exist {
-		var name string
-		if t.noCompression {
-			name = fmt.Sprintf("%s.%04d.rdat", t.name, num)
-		} else {
-			name = fmt.Sprintf("%s.%04d.cdat", t.name, num)
-		}
-		f, err = opener(filepath.Join(t.path, name))
+		f, err = opener(filepath.Join(t.path, freezerDataName(t.name, num, t.noCompression, t.checksum)))
 		if err != nil {
 			return nil, err
 		}
@@ -476,6 +602,9 @@ func (t *freezerTable) Append(item uint64, blob []byte) error {
 	if !t.noCompression {
 		blob = snappy.Encode(nil, blob)
 	}
+	if t.checksum {
+		blob = appendChecksum(blob)
+	}
 	bLen := uint32(len(blob))
 	if t.headBytes+bLen < bLen ||
 		t.headBytes+bLen > t.maxFileSize {
@@ -591,6 +720,11 @@ func (t *freezerTable) Retrieve(item uint64) ([]byte, error) {
 	t.lock.RUnlock()
 	t.readMeter.Mark(int64(len(blob) + 2*indexEntrySize))
 
+	if t.checksum {
+		if blob, err = verifyChecksum(blob); err != nil {
+			return nil, fmt.Errorf("item %d: %v", item, err)
+		}
+	}
 	if t.noCompression {
 		return blob, nil
 	}
@@ -630,6 +630,95 @@ func TestOffset(t *testing.T) {
 	checkPresent(1000000)
 }
 
+// TestFreezerChecksum tests that checksummed tables detect corrupted items, that
+// they're stored apart from plain tables, and that the checksum option only
+// applies to newly created tables.
+func TestFreezerChecksum(t *testing.T) {
+	t.Parallel()
+	rm, wm, sg := metrics.NewMeter(), metrics.NewMeter(), metrics.NewGauge()
+	fname := fmt.Sprintf("checksumtest-%d", rand.Uint64())
+
+	// Create a checksummed table and fill it with 15 bytes, 255 times
+	{
+		f, err := openTable(os.TempDir(), fname, rm, wm, sg, 50, false, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for x := 0; x < 0xff; x++ {
+			f.Append(uint64(x), getChunk(15, x))
+		}
+		f.Close()
+	}
+	// Ensure freezers unaware of checksums can't find its files
+	for _, name := range []string{freezerIndexName(fname, false, false), freezerDataName(fname, 0, false, false)} {
+		if _, err := os.Stat(filepath.Join(os.TempDir(), name)); !os.IsNotExist(err) {
+			t.Fatalf("checksummed table stored in plain table file %s", name)
+		}
+	}
+	// Reopen it without requesting checksums, they should still be verified
+	{
+		f, err := openTable(os.TempDir(), fname, rm, wm, sg, 50, false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !f.checksum {
+			t.Fatalf("checksum option lost on reopen")
+		}
+		for y := 0; y < 0xff; y++ {
+			got, err := f.Retrieve(uint64(y))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if exp := getChunk(15, y); !bytes.Equal(got, exp) {
+				t.Fatalf("test %d, got \n%x != \n%x", y, got, exp)
+			}
+		}
+		f.Close()
+	}
+	// Flip a byte of the first item and ensure it's detected
+	{
+		dataFile, err := os.OpenFile(filepath.Join(os.TempDir(), freezerDataName(fname, 0, false, true)), os.O_RDWR, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dataFile.WriteAt([]byte{0xff}, 0)
+		dataFile.Close()
+
+		f, err := openTable(os.TempDir(), fname, rm, wm, sg, 50, false, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Retrieve(0); err == nil {
+			t.Fatalf("expected checksum error")
+		}
+		if _, err := f.Retrieve(1); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		f.Close()
+	}
+	// Open a pre-existing table without checksums, requesting them
+	{
+		fname := fmt.Sprintf("checksumtest-%d", rand.Uint64())
+		f, err := newCustomTable(os.TempDir(), fname, rm, wm, sg, 50, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Append(0, getChunk(15, 0))
+		f.Close()
+
+		if f, err = openTable(os.TempDir(), fname, rm, wm, sg, 50, false, true); err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		if f.checksum {
+			t.Fatalf("checksum option applied to existing table")
+		}
+		if got, err := f.Retrieve(0); err != nil || !bytes.Equal(got, getChunk(15, 0)) {
+			t.Fatalf("failed to retrieve legacy item: %x, %v", got, err)
+		}
+	}
+}
+
 // TODO (?)
 // - test that if we remove several head-files, aswell as data last data-file,
 //   the index is truncated accordingly
@@ -71,12 +71,17 @@ var (
 	SnapshotAccountPrefix = []byte("a") // SnapshotAccountPrefix + account hash -> account trie value
 	SnapshotStoragePrefix = []byte("o") // SnapshotStoragePrefix + account hash + storage hash -> storage trie value
 	codePrefix            = []byte("c") // codePrefix + code hash -> account code
+	addressTxPrefix       = []byte("x") // addressTxPrefix + address + num (uint64 big endian) + tx index (uint32 big endian) -> tx hash
+	addressTxBlockPrefix  = []byte("X") // addressTxBlockPrefix + num (uint64 big endian) -> address index entries of the block
 
 	preimagePrefix = []byte("secure-key-")      // preimagePrefix + hash -> preimage
 	ConfigPrefix   = []byte("ethereum-config-") // config prefix for the db
 
+	artificialFinalityDecisionPrefix = []byte("ecbp1100-") // artificialFinalityDecisionPrefix + proposed num (uint64 big endian) + proposed hash -> artificial finality decision
+
 	// Chain index prefixes (use `i` + single byte to avoid mixing data types).
 	BloomBitsIndexPrefix = []byte("iB") // BloomBitsIndexPrefix is the data table of a chain indexer to track its progress
+	AddressIndexPrefix   = []byte("iX") // AddressIndexPrefix is the data table of the address chain indexer to track its progress
 
 	preimageCounter    = metrics.NewRegisteredCounter("db/preimage/total", nil)
 	preimageHitCounter = metrics.NewRegisteredCounter("db/preimage/hits", nil)
@@ -220,6 +225,21 @@ func bloomBitsKey(bit uint, section uint64, hash common.Hash) []byte {
 	return key
 }
 
+// addressTxKey = addressTxPrefix + address + num (uint64 big endian) + tx index (uint32 big endian)
+func addressTxKey(address common.Address, number uint64, index uint64) []byte {
+	key := append(append(addressTxPrefix, address.Bytes()...), make([]byte, 12)...)
+
+	binary.BigEndian.PutUint64(key[len(addressTxPrefix)+common.AddressLength:], number)
+	binary.BigEndian.PutUint32(key[len(addressTxPrefix)+common.AddressLength+8:], uint32(index))
+
+	return key
+}
+
+// addressTxBlockKey = addressTxBlockPrefix + num (uint64 big endian)
+func addressTxBlockKey(number uint64) []byte {
+	return append(addressTxBlockPrefix, encodeBlockNumber(number)...)
+}
+
 // preimageKey = preimagePrefix + hash
 func preimageKey(hash common.Hash) []byte {
 	return append(preimagePrefix, hash.Bytes()...)
@@ -239,6 +259,11 @@ func IsCodeKey(key []byte) (bool, []byte) {
 	return false, nil
 }
 
+// artificialFinalityDecisionKey = artificialFinalityDecisionPrefix + proposed num (uint64 big endian) + proposed hash
+func artificialFinalityDecisionKey(number uint64, hash common.Hash) []byte {
+	return append(append(artificialFinalityDecisionPrefix, encodeBlockNumber(number)...), hash.Bytes()...)
+}
+
 // ConfigKey = ConfigPrefix + hash
 func ConfigKey(hash common.Hash) []byte {
 	return append(ConfigPrefix, hash.Bytes()...)
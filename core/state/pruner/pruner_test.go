@@ -0,0 +1,225 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package pruner
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/state/snapshot"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/params/types/genesisT"
+	"github.com/ethereum/go-ethereum/params/vars"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+var (
+	testKey, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	testAddress = crypto.PubkeyToAddress(testKey.PublicKey)
+
+	// testContract stores the current block number into its first slot
+	testContract = common.Address{0xc0, 0xde}
+	testCode     = []byte{byte(vm.NUMBER), byte(vm.PUSH1), 0x00, byte(vm.SSTORE)}
+)
+
+// newPrunableChain creates an archive chain with a state snapshot, changing the
+// state of every block, and returns its database and blocks once stopped.
+func newPrunableChain(t *testing.T, blocks int) (ethdb.Database, []*types.Block) {
+	var (
+		db     = rawdb.NewMemoryDatabase()
+		engine = ethash.NewFaker()
+		signer = types.HomesteadSigner{}
+		gspec  = &genesisT.Genesis{
+			Config: params.TestChainConfig,
+			Alloc: genesisT.GenesisAlloc{
+				testAddress:  {Balance: big.NewInt(1000000000000000)},
+				testContract: {Balance: big.NewInt(0), Code: testCode},
+			},
+		}
+		genesis = core.MustCommitGenesis(db, gspec)
+	)
+	chain, _ := core.GenerateChain(gspec.Config, genesis, engine, db, blocks, func(i int, block *core.BlockGen) {
+		transfer := types.NewTransaction(block.TxNonce(testAddress), common.Address{byte(i), 0x01}, big.NewInt(1), vars.TxGas, nil, nil)
+		transfer, _ = types.SignTx(transfer, signer, testKey)
+		block.AddTx(transfer)
+
+		call := types.NewTransaction(block.TxNonce(testAddress), testContract, big.NewInt(0), 100000, nil, nil)
+		call, _ = types.SignTx(call, signer, testKey)
+		block.AddTx(call)
+	})
+	cacheConfig := &core.CacheConfig{
+		TrieCleanLimit:    256,
+		TrieDirtyLimit:    256,
+		TrieDirtyDisabled: true,
+		SnapshotLimit:     256,
+		SnapshotWait:      true,
+	}
+	blockchain, err := core.NewBlockChain(db, cacheConfig, gspec.Config, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	if _, err := blockchain.InsertChain(chain); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	blockchain.Stop()
+
+	return db, append([]*types.Block{genesis}, chain...)
+}
+
+// checkPruned ensures the pruning kept the genesis and target states intact,
+// dropped the stale ones and left a snapshot rooted at the target.
+func checkPruned(t *testing.T, db ethdb.Database, datadir string, blocks []*types.Block, target int) {
+	t.Helper()
+
+	checkState(t, db, blocks[0].Root())
+	checkState(t, db, blocks[target].Root())
+
+	for _, number := range []int{1, target / 2, target - 1, target + 1, len(blocks) - 1} {
+		if blob := rawdb.ReadTrieNode(db, blocks[number].Root()); len(blob) != 0 {
+			t.Errorf("state root of block %d not pruned", number)
+		}
+	}
+	if path, _, _ := findBloomFilter(datadir); path != "" {
+		t.Errorf("state bloom %s left behind", path)
+	}
+	if _, err := snapshot.Load(db, trie.NewDatabase(db), 16, blocks[target].Root()); err != nil {
+		t.Errorf("failed to load snapshot at pruning target: %v", err)
+	}
+}
+
+// checkState ensures the whole state at the given root is present.
+func checkState(t *testing.T, db ethdb.Database, root common.Hash) {
+	t.Helper()
+
+	accTrie, err := trie.NewSecure(root, trie.NewDatabase(db))
+	if err != nil {
+		t.Fatalf("state %x missing: %v", root, err)
+	}
+	accIt := accTrie.NodeIterator(nil)
+	for accIt.Next(true) {
+		if !accIt.Leaf() {
+			continue
+		}
+		var acc state.Account
+		if err := rlp.DecodeBytes(accIt.LeafBlob(), &acc); err != nil {
+			t.Fatalf("invalid account: %v", err)
+		}
+		if acc.Root != emptyRoot {
+			storageTrie, err := trie.NewSecure(acc.Root, trie.NewDatabase(db))
+			if err != nil {
+				t.Fatalf("storage trie %x missing: %v", acc.Root, err)
+			}
+			storageIt := storageTrie.NodeIterator(nil)
+			for storageIt.Next(true) {
+			}
+			if err := storageIt.Error(); err != nil {
+				t.Fatalf("storage trie %x incomplete: %v", acc.Root, err)
+			}
+		}
+		if !bytes.Equal(acc.CodeHash, emptyCode) && len(rawdb.ReadCode(db, common.BytesToHash(acc.CodeHash))) == 0 {
+			t.Fatalf("code %x missing", acc.CodeHash)
+		}
+	}
+	if err := accIt.Error(); err != nil {
+		t.Fatalf("state %x incomplete: %v", root, err)
+	}
+}
+
+// Tests that pruning retains the genesis state and the state of HEAD-127 only.
+func TestPrune(t *testing.T) {
+	db, blocks := newPrunableChain(t, 200)
+
+	datadir, err := ioutil.TempDir("", "pruner-")
+	if err != nil {
+		t.Fatalf("failed to create temporary datadir: %v", err)
+	}
+	defer os.RemoveAll(datadir)
+
+	p, err := NewPruner(db, datadir, "", 0)
+	if err != nil {
+		t.Fatalf("failed to create pruner: %v", err)
+	}
+	// Avoid allocating a production sized filter
+	if p.stateBloom, err = newStateBloomWithSize(4); err != nil {
+		t.Fatalf("failed to create state bloom: %v", err)
+	}
+	if err := p.Prune(common.Hash{}); err != nil {
+		t.Fatalf("failed to prune state: %v", err)
+	}
+	checkPruned(t, db, datadir, blocks, len(blocks)-128)
+}
+
+// Tests that a pruning interrupted after committing the state bloom is resumed.
+func TestRecoverPruning(t *testing.T) {
+	db, blocks := newPrunableChain(t, 200)
+
+	datadir, err := ioutil.TempDir("", "pruner-")
+	if err != nil {
+		t.Fatalf("failed to create temporary datadir: %v", err)
+	}
+	defer os.RemoveAll(datadir)
+
+	// Nothing to do without a committed state bloom
+	if err := RecoverPruning(datadir, db, ""); err != nil {
+		t.Fatalf("failed to recover without state bloom: %v", err)
+	}
+	// Run the pruning until the state bloom is committed
+	target := len(blocks) - 128
+	root := blocks[target].Root()
+
+	snaptree, err := snapshot.Load(db, trie.NewDatabase(db), 16, blocks[len(blocks)-1].Root())
+	if err != nil {
+		t.Fatalf("failed to load snapshot: %v", err)
+	}
+	bloom, err := newStateBloomWithSize(4)
+	if err != nil {
+		t.Fatalf("failed to create state bloom: %v", err)
+	}
+	if err := snapshot.GenerateTrie(snaptree, root, db, bloom); err != nil {
+		t.Fatalf("failed to generate state bloom: %v", err)
+	}
+	if err := extractGenesis(db, bloom); err != nil {
+		t.Fatalf("failed to add genesis to state bloom: %v", err)
+	}
+	filename := bloomFilterName(datadir, root)
+	if err := bloom.Commit(filename, filename+stateBloomFileTempSuffix); err != nil {
+		t.Fatalf("failed to commit state bloom: %v", err)
+	}
+	if path, found, _ := findBloomFilter(datadir); path != filename || found != root {
+		t.Fatalf("state bloom mismatch: have %s (%x), want %s (%x)", path, found, filename, root)
+	}
+	// Resume the pruning, twice to ensure it's a noop once done
+	for i := 0; i < 2; i++ {
+		if err := RecoverPruning(datadir, db, ""); err != nil {
+			t.Fatalf("failed to recover pruning: %v", err)
+		}
+	}
+	checkPruned(t, db, datadir, blocks, target)
+}
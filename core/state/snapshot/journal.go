@@ -61,8 +61,90 @@ type journalStorage struct {
 	Vals [][]byte
 }
 
+// JournalLayer summarises a diff layer persisted in the snapshot journal.
+type JournalLayer struct {
+	Root      common.Hash // Root hash of the state the layer belongs to
+	Destructs int         // Number of accounts deleted by the layer
+	Accounts  int         // Number of accounts changed by the layer
+	Slots     int         // Number of storage slots changed by the layer
+}
+
+// JournalInfo summarises a persisted snapshot: the generation progress of the
+// disk layer and the diff layers journalled on top of it.
+type JournalInfo struct {
+	DiskRoot common.Hash        // Root hash of the disk layer
+	Wiping   bool               // Whether the disk layer was being wiped
+	Done     bool               // Whether the disk layer is fully generated
+	Marker   []byte             // Generation progress marker, if not done
+	Accounts uint64             // Number of accounts generated so far
+	Slots    uint64             // Number of storage slots generated so far
+	Storage  common.StorageSize // Size of the data generated so far
+	Layers   []JournalLayer     // Diff layers on top of the disk layer, bottom-most first
+	Size     common.StorageSize // Size of the journal
+}
+
+// Head returns the root hash of the topmost layer of the snapshot.
+func (info *JournalInfo) Head() common.Hash {
+	if len(info.Layers) > 0 {
+		return info.Layers[len(info.Layers)-1].Root
+	}
+	return info.DiskRoot
+}
+
+// ReadJournalInfo summarises the snapshot persisted in a key-value store,
+// without loading any of it into memory.
+func ReadJournalInfo(db ethdb.KeyValueReader) (*JournalInfo, error) {
+	info := &JournalInfo{DiskRoot: rawdb.ReadSnapshotRoot(db)}
+	if info.DiskRoot == (common.Hash{}) {
+		return nil, errors.New("missing or corrupted snapshot")
+	}
+	journal := rawdb.ReadSnapshotJournal(db)
+	if len(journal) == 0 {
+		return nil, errors.New("missing or corrupted snapshot journal")
+	}
+	info.Size = common.StorageSize(len(journal))
+
+	r := rlp.NewStream(bytes.NewReader(journal), 0)
+
+	var generator journalGenerator
+	if err := r.Decode(&generator); err != nil {
+		return nil, fmt.Errorf("failed to load snapshot progress marker: %v", err)
+	}
+	info.Wiping, info.Done, info.Marker = generator.Wiping, generator.Done, generator.Marker
+	info.Accounts, info.Slots, info.Storage = generator.Accounts, generator.Slots, common.StorageSize(generator.Storage)
+
+	for {
+		var root common.Hash
+		if err := r.Decode(&root); err != nil {
+			if err == io.EOF {
+				return info, nil
+			}
+			return nil, fmt.Errorf("load diff root: %v", err)
+		}
+		var destructs []journalDestruct
+		if err := r.Decode(&destructs); err != nil {
+			return nil, fmt.Errorf("load diff destructs: %v", err)
+		}
+		var accounts []journalAccount
+		if err := r.Decode(&accounts); err != nil {
+			return nil, fmt.Errorf("load diff accounts: %v", err)
+		}
+		var storage []journalStorage
+		if err := r.Decode(&storage); err != nil {
+			return nil, fmt.Errorf("load diff storage: %v", err)
+		}
+		layer := JournalLayer{Root: root, Destructs: len(destructs), Accounts: len(accounts)}
+		for _, entry := range storage {
+			layer.Slots += len(entry.Keys)
+		}
+		info.Layers = append(info.Layers, layer)
+	}
+}
+
 // loadSnapshot loads a pre-existing state snapshot backed by a key-value store.
-func loadSnapshot(diskdb ethdb.KeyValueStore, triedb *trie.Database, cache int, root common.Hash) (snapshot, error) {
+// If resume is set, an unfinished snapshot generation is resumed, otherwise an
+// unfinished snapshot is reported as an error.
+func loadSnapshot(diskdb ethdb.KeyValueStore, triedb *trie.Database, cache int, root common.Hash, resume bool) (snapshot, error) {
 	// Retrieve the block number and hash of the snapshot, failing if no snapshot
 	// is present in the database (or crashed mid-update).
 	baseRoot := rawdb.ReadSnapshotRoot(diskdb)
@@ -99,6 +181,9 @@ func loadSnapshot(diskdb ethdb.KeyValueStore, triedb *trie.Database, cache int,
 		return nil, fmt.Errorf("head doesn't match snapshot: have %#x, want %#x", head, root)
 	}
 	// Everything loaded correctly, resume any suspended operations
+	if !generator.Done && !resume {
+		return nil, errors.New("snapshot is not fully generated")
+	}
 	if !generator.Done {
 		// If the generator was still wiping, restart one from scratch (fine for
 		// now as it's rare and the wiper deletes the stuff it touches anyway, so
@@ -21,11 +21,14 @@ import (
 	"math/big"
 	"math/rand"
 	"testing"
+	"time"
 
 	"github.com/VictoriaMetrics/fastcache"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
 	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
 )
 
 // randomHash generates a random blob of data and returns it as a hash.
@@ -369,3 +372,107 @@ func TestPostCapBasicDataAccess(t *testing.T) {
 		t.Error("expected error capping the disk layer, got none")
 	}
 }
+
+// Tests that the summary of a journalled snapshot matches the layers it was
+// created from.
+func TestReadJournalInfo(t *testing.T) {
+	// Create an empty base layer and a snapshot tree out of it
+	db := rawdb.NewMemoryDatabase()
+	rawdb.WriteSnapshotRoot(db, common.HexToHash("0x01"))
+
+	base := &diskLayer{
+		diskdb: db,
+		root:   common.HexToHash("0x01"),
+		cache:  fastcache.New(1024 * 500),
+	}
+	snaps := &Tree{
+		diskdb: db,
+		layers: map[common.Hash]snapshot{
+			base.root: base,
+		},
+	}
+	// Without a journal, there's nothing to summarise
+	if _, err := ReadJournalInfo(db); err == nil {
+		t.Fatalf("summarised missing journal")
+	}
+	// Stack two diff layers on top and journal them
+	accounts := randomAccountSet("0xa1", "0xa2")
+	storage := randomStorageSet([]string{"0xa1", "0xa2"}, [][]string{{"0xb1", "0xb2"}, {"0xb3"}}, nil)
+	if err := snaps.Update(common.HexToHash("0x02"), common.HexToHash("0x01"), nil, accounts, storage); err != nil {
+		t.Fatalf("failed to create a diff layer: %v", err)
+	}
+	destructs := map[common.Hash]struct{}{common.HexToHash("0xa2"): {}}
+	if err := snaps.Update(common.HexToHash("0x03"), common.HexToHash("0x02"), destructs, randomAccountSet("0xa3"), nil); err != nil {
+		t.Fatalf("failed to create a diff layer: %v", err)
+	}
+	if _, err := snaps.Journal(common.HexToHash("0x03")); err != nil {
+		t.Fatalf("failed to journal snapshot: %v", err)
+	}
+	info, err := ReadJournalInfo(db)
+	if err != nil {
+		t.Fatalf("failed to summarise journal: %v", err)
+	}
+	if info.DiskRoot != base.root || !info.Done || info.Wiping {
+		t.Errorf("disk layer mismatch: have root %x, done %v, wiping %v", info.DiskRoot, info.Done, info.Wiping)
+	}
+	if head := info.Head(); head != common.HexToHash("0x03") {
+		t.Errorf("head mismatch: have %x, want %x", head, common.HexToHash("0x03"))
+	}
+	want := []JournalLayer{
+		{Root: common.HexToHash("0x02"), Accounts: 2, Slots: 3},
+		{Root: common.HexToHash("0x03"), Destructs: 1, Accounts: 1},
+	}
+	if len(info.Layers) != len(want) {
+		t.Fatalf("layer count mismatch: have %d, want %d", len(info.Layers), len(want))
+	}
+	for i, layer := range info.Layers {
+		if layer != want[i] {
+			t.Errorf("layer %d mismatch: have %+v, want %+v", i, layer, want[i])
+		}
+	}
+	if info.Size != common.StorageSize(len(rawdb.ReadSnapshotJournal(db))) {
+		t.Errorf("journal size mismatch: have %v", info.Size)
+	}
+}
+
+// Tests that loading an unfinished snapshot fails without resuming generation,
+// leaving the database untouched, while a finished one loads fine.
+func TestLoadUnfinished(t *testing.T) {
+	// Create a small account trie and a snapshot of it which has yet to be generated
+	var (
+		diskdb = memorydb.New()
+		triedb = trie.NewDatabase(diskdb)
+	)
+	tr, _ := trie.NewSecure(common.Hash{}, triedb)
+	for i := 1; i <= 3; i++ {
+		val, _ := rlp.EncodeToBytes(&Account{Balance: big.NewInt(int64(i)), Root: emptyRoot.Bytes(), CodeHash: emptyCode.Bytes()})
+		tr.Update([]byte(fmt.Sprintf("acc-%d", i)), val)
+	}
+	root, _ := tr.Commit(nil)
+	triedb.Commit(root, false, nil)
+
+	writeJournal := func(done bool) {
+		journal, _ := rlp.EncodeToBytes(journalGenerator{Done: done, Marker: []byte{}})
+		rawdb.WriteSnapshotRoot(diskdb, root)
+		rawdb.WriteSnapshotJournal(diskdb, journal)
+	}
+	writeJournal(false)
+	items := diskdb.Len()
+
+	if _, err := Load(diskdb, triedb, 16, root); err == nil {
+		t.Fatalf("loaded unfinished snapshot")
+	}
+	time.Sleep(100 * time.Millisecond) // Give a resumed generator time to write
+	if have := diskdb.Len(); have != items {
+		t.Fatalf("database modified by load: have %d items, want %d", have, items)
+	}
+	// Mark the snapshot done and ensure it loads
+	writeJournal(true)
+	snaps, err := Load(diskdb, triedb, 16, root)
+	if err != nil {
+		t.Fatalf("failed to load snapshot: %v", err)
+	}
+	if snaps.Snapshot(root) == nil {
+		t.Fatalf("disk layer missing")
+	}
+}
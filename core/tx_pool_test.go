@@ -34,6 +34,8 @@ import (
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/params/types/coregeth"
+	"github.com/ethereum/go-ethereum/params/types/ctypes"
 	"github.com/ethereum/go-ethereum/params/vars"
 	"github.com/ethereum/go-ethereum/trie"
 )
@@ -270,6 +272,125 @@ func TestInvalidTransactions(t *testing.T) {
 	}
 }
 
+// Tests that access list transactions are only accepted once both the EIP-2718
+// envelope and EIP-2930 are enabled for the pending block, the same transitions
+// types.MakeSigner keys on.
+func TestTransactionTypeNotSupported(t *testing.T) {
+	t.Parallel()
+
+	key, _ := crypto.GenerateKey()
+	tx, _ := types.SignNewTx(key, types.NewEIP2930Signer(params.TestChainConfig.ChainID), &types.AccessListTx{
+		ChainID:  params.TestChainConfig.ChainID,
+		Nonce:    0,
+		To:       &common.Address{},
+		Value:    big.NewInt(100),
+		Gas:      100000,
+		GasPrice: big.NewInt(1),
+	})
+	from := crypto.PubkeyToAddress(key.PublicKey)
+
+	newPool := func(config ctypes.ChainConfigurator) *TxPool {
+		statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+		blockchain := &testBlockChain{statedb, 10000000, new(event.Feed)}
+		pool := NewTxPool(testTxPoolConfig, config, blockchain)
+		pool.currentState.AddBalance(from, big.NewInt(1000000))
+		return pool
+	}
+	berlin := func(block int64) ctypes.ChainConfigurator {
+		config := *params.TestChainConfig
+		config.BerlinBlock = big.NewInt(block)
+		return &config
+	}
+	// Before the transition the pool must reject the transaction outright
+	pool := newPool(berlin(100))
+	defer pool.Stop()
+
+	if err := pool.AddRemote(tx); err != ErrTxTypeNotSupported {
+		t.Error("expected", ErrTxTypeNotSupported, "got", err)
+	}
+	// Neither the typed transaction envelope nor the access list type alone is
+	// enough, blocks only accept access list transactions once both are enabled
+	for _, forks := range [][2]int64{{0, 100}, {100, 0}} {
+		partialPool := newPool(&coregeth.CoreGethChainConfig{
+			ChainID:       params.TestChainConfig.ChainID,
+			EIP155Block:   big.NewInt(0),
+			EIP2718FBlock: big.NewInt(forks[0]),
+			EIP2930FBlock: big.NewInt(forks[1]),
+		})
+		defer partialPool.Stop()
+
+		if err := partialPool.AddRemote(tx); err != ErrTxTypeNotSupported {
+			t.Error("forks", forks, "expected", ErrTxTypeNotSupported, "got", err)
+		}
+	}
+	// With the transition enabled the same transaction must be accepted
+	berlinPool := newPool(berlin(0))
+	defer berlinPool.Stop()
+
+	if err := berlinPool.AddRemote(tx); err != nil {
+		t.Error("expected", nil, "got", err)
+	}
+}
+
+// Tests that dynamic fee transactions are only accepted once EIP-1559 is
+// scheduled, and that their fee fields are sanity checked.
+func TestTransactionDynamicFee(t *testing.T) {
+	t.Parallel()
+
+	key, _ := crypto.GenerateKey()
+	from := crypto.PubkeyToAddress(key.PublicKey)
+
+	newPool := func(london int64) *TxPool {
+		config := *params.TestChainConfig
+		config.BerlinBlock = big.NewInt(0)
+		config.LondonBlock = big.NewInt(london)
+
+		statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+		blockchain := &testBlockChain{statedb, 10000000, new(event.Feed)}
+		pool := NewTxPool(testTxPoolConfig, &config, blockchain)
+		pool.currentState.AddBalance(from, big.NewInt(1000000000))
+		return pool
+	}
+	dynamicFeeTx := func(pool *TxPool, nonce uint64, tip, feeCap int64) *types.Transaction {
+		return types.MustSignNewTx(key, pool.signer, &types.DynamicFeeTx{
+			ChainID:   params.TestChainConfig.ChainID,
+			Nonce:     nonce,
+			To:        &common.Address{},
+			Value:     big.NewInt(100),
+			Gas:       100000,
+			GasTipCap: big.NewInt(tip),
+			GasFeeCap: big.NewInt(feeCap),
+		})
+	}
+	// Before the transition the pool must reject the transaction outright
+	pool := newPool(100)
+	defer pool.Stop()
+
+	if err := pool.AddRemote(dynamicFeeTx(pool, 0, 1, 2)); err != ErrTxTypeNotSupported {
+		t.Error("expected", ErrTxTypeNotSupported, "got", err)
+	}
+	// With the transition enabled the fee fields are validated
+	londonPool := newPool(1)
+	defer londonPool.Stop()
+
+	if err := londonPool.AddRemote(dynamicFeeTx(londonPool, 0, 3, 2)); err != ErrTipAboveFeeCap {
+		t.Error("expected", ErrTipAboveFeeCap, "got", err)
+	}
+	if err := londonPool.AddRemote(dynamicFeeTx(londonPool, 0, 0, 2)); err != ErrUnderpriced {
+		t.Error("expected", ErrUnderpriced, "got", err)
+	}
+	if err := londonPool.AddRemote(dynamicFeeTx(londonPool, 0, 1, 2)); err != nil {
+		t.Error("expected", nil, "got", err)
+	}
+	// Replacements must bump both the fee cap and the tip
+	if err := londonPool.AddRemote(dynamicFeeTx(londonPool, 0, 1, 20)); err != ErrReplaceUnderpriced {
+		t.Error("expected", ErrReplaceUnderpriced, "got", err)
+	}
+	if err := londonPool.AddRemote(dynamicFeeTx(londonPool, 0, 2, 20)); err != nil {
+		t.Error("expected", nil, "got", err)
+	}
+}
+
 func TestTransactionQueue(t *testing.T) {
 	t.Parallel()
 
@@ -0,0 +1,71 @@
+// Copyright 2020 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// ArtificialFinalityDecision records the evaluation of a proposed chain
+// reorganization by the ECBP1100 (MESS) artificial finality mechanism.
+//
+// The total difficulty ratio of the proposed over the current chain segment
+// (both measured from the common ancestor) is ProposedSubchainTD/CurrentSubchainTD.
+// The ratio required for the reorganization to be accepted is
+// AntiGravityNumerator/AntiGravityDenominator.
+type ArtificialFinalityDecision struct {
+	CommonAncestorNumber uint64
+	CommonAncestorHash   common.Hash
+	CurrentNumber        uint64
+	CurrentHash          common.Hash
+	ProposedNumber       uint64
+	ProposedHash         common.Hash
+
+	// TimeDelta is the time in seconds between the common ancestor and the current head.
+	TimeDelta uint64
+
+	CurrentSubchainTD      *big.Int
+	ProposedSubchainTD     *big.Int
+	AntiGravityNumerator   *big.Int
+	AntiGravityDenominator *big.Int
+
+	Accepted bool
+
+	// EvaluatedAt is the local unix time at which the decision was made.
+	EvaluatedAt uint64
+}
+
+// TDRatio returns the total difficulty ratio of the proposed over the current chain segment.
+func (d *ArtificialFinalityDecision) TDRatio() float64 {
+	return ratio(d.ProposedSubchainTD, d.CurrentSubchainTD)
+}
+
+// RequiredRatio returns the total difficulty ratio required by the antigravity curve
+// for the proposed segment to be accepted.
+func (d *ArtificialFinalityDecision) RequiredRatio() float64 {
+	return ratio(d.AntiGravityNumerator, d.AntiGravityDenominator)
+}
+
+func ratio(x, y *big.Int) float64 {
+	if x == nil || y == nil || y.Sign() == 0 {
+		return 0
+	}
+	r, _ := new(big.Float).Quo(new(big.Float).SetInt(x), new(big.Float).SetInt(y)).Float64()
+	return r
+}
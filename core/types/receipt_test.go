@@ -108,6 +108,58 @@ func TestLegacyReceiptDecoding(t *testing.T) {
 	}
 }
 
+// Tests that typed receipts are encoded within an EIP-2718 envelope and that
+// they can be decoded back, both from the consensus RLP and from storage.
+func TestTypedReceiptEncoding(t *testing.T) {
+	receipt := &Receipt{
+		Type:              AccessListTxType,
+		Status:            ReceiptStatusSuccessful,
+		CumulativeGasUsed: 1,
+		Logs: []*Log{
+			{
+				Address: common.BytesToAddress([]byte{0x11}),
+				Topics:  []common.Hash{common.HexToHash("dead"), common.HexToHash("beef")},
+				Data:    []byte{0x01, 0x00, 0xff},
+			},
+		},
+	}
+	receipt.Bloom = CreateBloom(Receipts{receipt})
+
+	enc, err := rlp.EncodeToBytes(receipt)
+	if err != nil {
+		t.Fatalf("Error encoding receipt: %v", err)
+	}
+	// The consensus encoding is the envelope wrapped into an RLP string
+	var envelope []byte
+	if err := rlp.DecodeBytes(enc, &envelope); err != nil {
+		t.Fatalf("Error decoding receipt envelope: %v", err)
+	}
+	if envelope[0] != AccessListTxType {
+		t.Fatalf("Receipt envelope type mismatch, want %d, have %d", AccessListTxType, envelope[0])
+	}
+	if have := Receipts([]*Receipt{receipt}).GetRlp(0); !bytes.Equal(have, envelope) {
+		t.Fatalf("Receipt trie encoding mismatch, want %x, have %x", envelope, have)
+	}
+	var dec Receipt
+	if err := rlp.DecodeBytes(enc, &dec); err != nil {
+		t.Fatalf("Error decoding receipt: %v", err)
+	}
+	if dec.Type != receipt.Type {
+		t.Fatalf("Receipt type mismatch, want %d, have %d", receipt.Type, dec.Type)
+	}
+	if dec.Status != receipt.Status || dec.CumulativeGasUsed != receipt.CumulativeGasUsed || dec.Bloom != receipt.Bloom {
+		t.Fatalf("Receipt consensus fields mismatch, want %v, have %v", receipt, dec)
+	}
+	if len(dec.Logs) != len(receipt.Logs) {
+		t.Fatalf("Receipt log number mismatch, want %v, have %v", len(receipt.Logs), len(dec.Logs))
+	}
+	// Unknown envelope types must be rejected
+	bad, _ := rlp.EncodeToBytes(append([]byte{0x7f}, envelope[1:]...))
+	if err := rlp.DecodeBytes(bad, &dec); err != ErrTxTypeNotSupported {
+		t.Fatalf("Receipt decoding error mismatch, want %v, have %v", ErrTxTypeNotSupported, err)
+	}
+}
+
 func encodeAsStoredReceiptRLP(want *Receipt) ([]byte, error) {
 	stored := &storedReceiptRLP{
 		PostStateOrStatus: want.statusEncoding(),
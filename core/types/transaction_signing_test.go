@@ -22,6 +22,7 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params/types/coregeth"
 	"github.com/ethereum/go-ethereum/rlp"
 )
 
@@ -136,3 +137,34 @@ func TestChainId(t *testing.T) {
 		t.Error("expected no error")
 	}
 }
+
+// Tests that typed transaction signers are only made once the EIP-2718 envelope
+// is enabled alongside the transaction type.
+func TestMakeSignerEIP2718(t *testing.T) {
+	chainID := big.NewInt(1)
+	config := func(eip2718, eip2930, eip1559 int64) *coregeth.CoreGethChainConfig {
+		return &coregeth.CoreGethChainConfig{
+			ChainID:       chainID,
+			EIP155Block:   big.NewInt(0),
+			EIP2718FBlock: big.NewInt(eip2718),
+			EIP2930FBlock: big.NewInt(eip2930),
+			EIP1559FBlock: big.NewInt(eip1559),
+		}
+	}
+	tests := []struct {
+		config *coregeth.CoreGethChainConfig
+		number int64
+		want   Signer
+	}{
+		{config(10, 0, 0), 5, NewEIP155Signer(chainID)},
+		{config(10, 0, 0), 10, NewLondonSigner(chainID)},
+		{config(0, 10, 20), 5, NewEIP155Signer(chainID)},
+		{config(0, 10, 20), 10, NewEIP2930Signer(chainID)},
+		{config(0, 10, 20), 20, NewLondonSigner(chainID)},
+	}
+	for i, tt := range tests {
+		if have := MakeSigner(tt.config, big.NewInt(tt.number)); !have.Equal(tt.want) {
+			t.Errorf("test %d: signer mismatch: have %T, want %T", i, have, tt.want)
+		}
+	}
+}
@@ -21,6 +21,7 @@ import (
 	"crypto/ecdsa"
 	"encoding/json"
 	"math/big"
+	"math/rand"
 	"testing"
 	"time"
 
@@ -73,6 +74,224 @@ func TestTransactionEncode(t *testing.T) {
 	}
 }
 
+func TestEIP2930Signer(t *testing.T) {
+	var (
+		key, _  = defaultTestKey()
+		keyAddr = crypto.PubkeyToAddress(key.PublicKey)
+		signer1 = NewEIP2930Signer(big.NewInt(1))
+		signer2 = NewEIP2930Signer(big.NewInt(2))
+		tx0     = NewTx(&AccessListTx{Nonce: 1})
+		tx1     = NewTx(&AccessListTx{ChainID: big.NewInt(1), Nonce: 1})
+		tx2, _  = SignNewTx(key, signer2, &AccessListTx{ChainID: big.NewInt(2), Nonce: 1})
+	)
+
+	tests := []struct {
+		tx             *Transaction
+		signer         Signer
+		wantSignerHash common.Hash
+		wantSenderErr  error
+		wantSignErr    error
+	}{
+		{
+			tx:             tx0,
+			signer:         signer1,
+			wantSignerHash: common.HexToHash("846ad7672f2a3a40c1f959cd4a8ad21786d620077084d84c8d7c077714caa139"),
+			wantSenderErr:  ErrInvalidChainId,
+		},
+		{
+			tx:             tx1,
+			signer:         signer1,
+			wantSenderErr:  ErrInvalidSig,
+			wantSignerHash: common.HexToHash("846ad7672f2a3a40c1f959cd4a8ad21786d620077084d84c8d7c077714caa139"),
+		},
+		{
+			// This checks what happens when trying to sign an unsigned tx for the wrong chain.
+			tx:             tx1,
+			signer:         signer2,
+			wantSenderErr:  ErrInvalidChainId,
+			wantSignerHash: common.HexToHash("367967247499343401261d718ed5aa4c9486583e4d89251afce47f4a33c33362"),
+			wantSignErr:    ErrInvalidChainId,
+		},
+		{
+			// This checks what happens when trying to re-sign a signed tx for the wrong chain.
+			tx:             tx2,
+			signer:         signer1,
+			wantSenderErr:  ErrInvalidChainId,
+			wantSignerHash: common.HexToHash("846ad7672f2a3a40c1f959cd4a8ad21786d620077084d84c8d7c077714caa139"),
+			wantSignErr:    ErrInvalidChainId,
+		},
+	}
+
+	for i, test := range tests {
+		sigHash := test.signer.Hash(test.tx)
+		if sigHash != test.wantSignerHash {
+			t.Errorf("test %d: wrong sig hash: got %x, want %x", i, sigHash, test.wantSignerHash)
+		}
+		sender, err := Sender(test.signer, test.tx)
+		if err != test.wantSenderErr {
+			t.Errorf("test %d: wrong Sender error %q", i, err)
+		}
+		if err == nil && sender != keyAddr {
+			t.Errorf("test %d: wrong sender address %x", i, sender)
+		}
+		signedTx, err := SignTx(test.tx, test.signer, key)
+		if err != test.wantSignErr {
+			t.Fatalf("test %d: wrong SignTx error %q", i, err)
+		}
+		if signedTx != nil {
+			if from, err := Sender(test.signer, signedTx); err != nil || from != keyAddr {
+				t.Errorf("test %d: wrong sender after signing: %x (err %v)", i, from, err)
+			}
+		}
+	}
+}
+
+func TestTransactionTypedEncoding(t *testing.T) {
+	key, _ := defaultTestKey()
+	to := common.HexToAddress("b94f5374fce5edbc8e2a8697c15331677e6ebf0b")
+	tx, err := SignNewTx(key, NewEIP2930Signer(big.NewInt(1)), &AccessListTx{
+		ChainID:  big.NewInt(1),
+		Nonce:    3,
+		To:       &to,
+		Value:    big.NewInt(10),
+		Gas:      25000,
+		GasPrice: big.NewInt(1),
+		Data:     common.FromHex("5544"),
+		AccessList: AccessList{
+			{Address: to, StorageKeys: []common.Hash{{1}}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("could not sign transaction: %v", err)
+	}
+	// The binary encoding must start with the envelope type byte.
+	bin, err := tx.MarshalBinary()
+	if err != nil {
+		t.Fatalf("binary encode error: %v", err)
+	}
+	if bin[0] != AccessListTxType {
+		t.Fatalf("wrong envelope type: have %d, want %d", bin[0], AccessListTxType)
+	}
+	// The RLP encoding wraps the binary encoding in a byte string.
+	enc, err := rlp.EncodeToBytes(tx)
+	if err != nil {
+		t.Fatalf("rlp encode error: %v", err)
+	}
+	wrapped, _ := rlp.EncodeToBytes(bin)
+	if !bytes.Equal(enc, wrapped) {
+		t.Fatalf("rlp encoding mismatch: have %x, want %x", enc, wrapped)
+	}
+	if uint64(len(bin)) != uint64(tx.Size()) {
+		t.Errorf("size mismatch: have %v, want %d", tx.Size(), len(bin))
+	}
+	// Both encodings must decode to the same transaction.
+	var fromBin Transaction
+	if err := fromBin.UnmarshalBinary(bin); err != nil {
+		t.Fatalf("binary decode error: %v", err)
+	}
+	fromRLP, err := decodeTx(enc)
+	if err != nil {
+		t.Fatalf("rlp decode error: %v", err)
+	}
+	for _, have := range []*Transaction{&fromBin, fromRLP} {
+		if have.Hash() != tx.Hash() {
+			t.Errorf("hash mismatch: have %x, want %x", have.Hash(), tx.Hash())
+		}
+		if have.Type() != AccessListTxType {
+			t.Errorf("type mismatch: have %d, want %d", have.Type(), AccessListTxType)
+		}
+		if len(have.AccessList()) != 1 || have.AccessList().StorageKeys() != 1 {
+			t.Errorf("access list mismatch: have %v", have.AccessList())
+		}
+		from, err := Sender(NewEIP2930Signer(big.NewInt(1)), have)
+		if err != nil {
+			t.Fatalf("could not recover sender: %v", err)
+		}
+		if want := crypto.PubkeyToAddress(key.PublicKey); from != want {
+			t.Errorf("sender mismatch: have %x, want %x", from, want)
+		}
+	}
+	// Pre-Berlin signers must reject typed transactions.
+	if _, err := Sender(NewEIP155Signer(big.NewInt(1)), tx); err != ErrTxTypeNotSupported {
+		t.Errorf("wrong EIP155 sender error: have %v, want %v", err, ErrTxTypeNotSupported)
+	}
+	// Unknown envelope types must be rejected.
+	var unknown Transaction
+	if err := unknown.UnmarshalBinary(append([]byte{0x7f}, bin[1:]...)); err != ErrTxTypeNotSupported {
+		t.Errorf("wrong unknown type error: have %v, want %v", err, ErrTxTypeNotSupported)
+	}
+}
+
+func TestDynamicFeeTransaction(t *testing.T) {
+	key, _ := defaultTestKey()
+	to := common.HexToAddress("b94f5374fce5edbc8e2a8697c15331677e6ebf0b")
+	signer := NewLondonSigner(big.NewInt(1))
+	tx, err := SignNewTx(key, signer, &DynamicFeeTx{
+		ChainID:   big.NewInt(1),
+		Nonce:     3,
+		To:        &to,
+		Value:     big.NewInt(10),
+		Gas:       25000,
+		GasTipCap: big.NewInt(2),
+		GasFeeCap: big.NewInt(10),
+		Data:      common.FromHex("5544"),
+	})
+	if err != nil {
+		t.Fatalf("could not sign transaction: %v", err)
+	}
+	bin, err := tx.MarshalBinary()
+	if err != nil {
+		t.Fatalf("binary encode error: %v", err)
+	}
+	if bin[0] != DynamicFeeTxType {
+		t.Fatalf("wrong envelope type: have %d, want %d", bin[0], DynamicFeeTxType)
+	}
+	var dec Transaction
+	if err := dec.UnmarshalBinary(bin); err != nil {
+		t.Fatalf("binary decode error: %v", err)
+	}
+	if dec.Hash() != tx.Hash() {
+		t.Errorf("hash mismatch: have %x, want %x", dec.Hash(), tx.Hash())
+	}
+	if dec.GasTipCap().Cmp(big.NewInt(2)) != 0 || dec.GasFeeCap().Cmp(big.NewInt(10)) != 0 {
+		t.Errorf("fee mismatch: have tip %v cap %v", dec.GasTipCap(), dec.GasFeeCap())
+	}
+	from, err := Sender(signer, &dec)
+	if err != nil {
+		t.Fatalf("could not recover sender: %v", err)
+	}
+	if want := crypto.PubkeyToAddress(key.PublicKey); from != want {
+		t.Errorf("sender mismatch: have %x, want %x", from, want)
+	}
+	// Pre-London signers must reject dynamic fee transactions.
+	if _, err := Sender(NewEIP2930Signer(big.NewInt(1)), tx); err != ErrTxTypeNotSupported {
+		t.Errorf("wrong EIP2930 sender error: have %v, want %v", err, ErrTxTypeNotSupported)
+	}
+	// The cost is bounded by the fee cap, the tip by the base fee headroom.
+	if want := big.NewInt(25000*10 + 10); tx.Cost().Cmp(want) != 0 {
+		t.Errorf("cost mismatch: have %v, want %v", tx.Cost(), want)
+	}
+	tests := []struct {
+		baseFee *big.Int
+		tip     int64
+		err     error
+	}{
+		{nil, 2, nil},
+		{big.NewInt(5), 2, nil},
+		{big.NewInt(9), 1, nil},
+		{big.NewInt(11), -1, ErrGasFeeCapTooLow},
+	}
+	for i, tt := range tests {
+		tip, err := tx.EffectiveGasTip(tt.baseFee)
+		if err != tt.err {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+		if tip.Int64() != tt.tip {
+			t.Errorf("test %d: tip mismatch: have %v, want %d", i, tip, tt.tip)
+		}
+	}
+}
+
 func decodeTx(data []byte) (*Transaction, error) {
 	var tx Transaction
 	t, err := &tx, rlp.Decode(bytes.NewReader(data), &tx)
@@ -140,7 +359,7 @@ func TestTransactionPriceNonceSort(t *testing.T) {
 		}
 	}
 	// Sort the transactions and cross check the nonce ordering
-	txset := NewTransactionsByPriceAndNonce(signer, groups)
+	txset := NewTransactionsByPriceAndNonce(signer, groups, nil)
 
 	txs := Transactions{}
 	for tx := txset.Peek(); tx != nil; tx = txset.Peek() {
@@ -171,6 +390,91 @@ func TestTransactionPriceNonceSort(t *testing.T) {
 	}
 }
 
+// Tests that when a base fee is given, transactions are sorted by their
+// effective miner tip, and that transactions whose fee cap doesn't cover the
+// base fee are dropped along with the rest of their account's transactions.
+func TestTransactionPriceNonceSort1559(t *testing.T) {
+	keys := make([]*ecdsa.PrivateKey, 25)
+	for i := 0; i < len(keys); i++ {
+		keys[i], _ = crypto.GenerateKey()
+	}
+	signer := NewLondonSigner(common.Big1)
+	baseFee := big.NewInt(10)
+
+	// Generate a batch of transactions mixing legacy and dynamic fee ones
+	groups := map[common.Address]Transactions{}
+	expectedCount := 0
+	for start, key := range keys {
+		addr := crypto.PubkeyToAddress(key.PublicKey)
+		count := 25
+		for i := 0; i < 25; i++ {
+			var tx *Transaction
+			gasFeeCap := big.NewInt(int64(rand.Intn(50)))
+			if start%2 == 0 {
+				tx = NewTx(&LegacyTx{
+					Nonce:    uint64(start + i),
+					To:       &common.Address{},
+					Value:    big.NewInt(100),
+					Gas:      100,
+					GasPrice: gasFeeCap,
+				})
+			} else {
+				tx = NewTx(&DynamicFeeTx{
+					Nonce:     uint64(start + i),
+					To:        &common.Address{},
+					Value:     big.NewInt(100),
+					Gas:       100,
+					GasFeeCap: gasFeeCap,
+					GasTipCap: big.NewInt(int64(rand.Intn(int(gasFeeCap.Int64() + 1)))),
+				})
+			}
+			if count == 25 && gasFeeCap.Cmp(baseFee) < 0 {
+				count = i
+			}
+			tx, err := SignTx(tx, signer, key)
+			if err != nil {
+				t.Fatalf("failed to sign tx: %s", err)
+			}
+			groups[addr] = append(groups[addr], tx)
+		}
+		expectedCount += count
+	}
+	txset := NewTransactionsByPriceAndNonce(signer, groups, baseFee)
+
+	txs := Transactions{}
+	for tx := txset.Peek(); tx != nil; tx = txset.Peek() {
+		txs = append(txs, tx)
+		txset.Shift()
+	}
+	if len(txs) != expectedCount {
+		t.Errorf("expected %d transactions, found %d", expectedCount, len(txs))
+	}
+	for i, txi := range txs {
+		fromi, _ := Sender(signer, txi)
+
+		// Make sure the nonce order is valid
+		for j, txj := range txs[i+1:] {
+			fromj, _ := Sender(signer, txj)
+			if fromi == fromj && txi.Nonce() > txj.Nonce() {
+				t.Errorf("invalid nonce ordering: tx #%d (A=%x N=%v) < tx #%d (A=%x N=%v)", i, fromi[:4], txi.Nonce(), i+j, fromj[:4], txj.Nonce())
+			}
+		}
+		// If the next tx has different from account, the tip must be lower than the current one
+		if i+1 < len(txs) {
+			next := txs[i+1]
+			fromNext, _ := Sender(signer, next)
+			tip, err := txi.EffectiveGasTip(baseFee)
+			nextTip, nextErr := next.EffectiveGasTip(baseFee)
+			if err != nil || nextErr != nil {
+				t.Errorf("error calculating effective tip")
+			}
+			if fromi != fromNext && tip.Cmp(nextTip) < 0 {
+				t.Errorf("invalid effective tip ordering: tx #%d (A=%x P=%v) < tx #%d (A=%x P=%v)", i, fromi[:4], tip, i+1, fromNext[:4], nextTip)
+			}
+		}
+	}
+}
+
 // Tests that if multiple transactions have the same price, the ones seen earlier
 // are prioritized to avoid network spam attacks aiming for a specific ordering.
 func TestTransactionTimeSort(t *testing.T) {
@@ -192,7 +496,7 @@ func TestTransactionTimeSort(t *testing.T) {
 		groups[addr] = append(groups[addr], tx)
 	}
 	// Sort the transactions and cross check the nonce ordering
-	txset := NewTransactionsByPriceAndNonce(signer, groups)
+	txset := NewTransactionsByPriceAndNonce(signer, groups, nil)
 
 	txs := Transactions{}
 	for tx := txset.Peek(); tx != nil; tx = txset.Peek() {
@@ -225,16 +529,42 @@ func TestTransactionJSON(t *testing.T) {
 	if err != nil {
 		t.Fatalf("could not generate key: %v", err)
 	}
-	signer := NewEIP155Signer(common.Big1)
+	signer := NewLondonSigner(common.Big1)
 
 	transactions := make([]*Transaction, 0, 50)
 	for i := uint64(0); i < 25; i++ {
 		var tx *Transaction
-		switch i % 2 {
+		switch i % 4 {
 		case 0:
 			tx = NewTransaction(i, common.Address{1}, common.Big0, 1, common.Big2, []byte("abcdef"))
 		case 1:
 			tx = NewContractCreation(i, common.Big0, 1, common.Big2, []byte("abcdef"))
+		case 2:
+			addr := common.HexToAddress("0x0000000000000000000000000000000000000001")
+			tx = NewTx(&AccessListTx{
+				ChainID:  big.NewInt(1),
+				Nonce:    i,
+				To:       &addr,
+				Value:    common.Big0,
+				Gas:      123457,
+				GasPrice: common.Big2,
+				Data:     []byte("abcdef"),
+				AccessList: AccessList{
+					{Address: addr, StorageKeys: []common.Hash{{0}}},
+				},
+			})
+		case 3:
+			addr := common.HexToAddress("0x0000000000000000000000000000000000000001")
+			tx = NewTx(&DynamicFeeTx{
+				ChainID:   big.NewInt(1),
+				Nonce:     i,
+				To:        &addr,
+				Value:     common.Big0,
+				Gas:       123457,
+				GasTipCap: common.Big1,
+				GasFeeCap: common.Big2,
+				Data:      []byte("abcdef"),
+			})
 		}
 		transactions = append(transactions, tx)
 
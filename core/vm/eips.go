@@ -25,6 +25,7 @@ import (
 )
 
 var activators = map[int]func(*JumpTable){
+	2929: enable2929,
 	2200: enable2200,
 	1884: enable1884,
 	1344: enable1344,
@@ -111,6 +112,50 @@ func enable2200(jt *JumpTable) {
 	jt[SSTORE].dynamicGas = gasSStoreEIP2200
 }
 
+// enable2929 enables "EIP-2929: Gas cost increases for state access opcodes"
+// https://eips.ethereum.org/EIPS/eip-2929
+func enable2929(jt *JumpTable) {
+	jt[SSTORE].dynamicGas = gasSStoreEIP2929
+
+	jt[SLOAD].constantGas = 0
+	jt[SLOAD].dynamicGas = gasSLoadEIP2929
+
+	jt[EXTCODECOPY].constantGas = vars.WarmStorageReadCostEIP2929
+	jt[EXTCODECOPY].dynamicGas = gasExtCodeCopyEIP2929
+
+	jt[EXTCODESIZE].constantGas = vars.WarmStorageReadCostEIP2929
+	jt[EXTCODESIZE].dynamicGas = gasEip2929AccountCheck
+
+	jt[BALANCE].constantGas = vars.WarmStorageReadCostEIP2929
+	jt[BALANCE].dynamicGas = gasEip2929AccountCheck
+
+	jt[CALL].constantGas = vars.WarmStorageReadCostEIP2929
+	jt[CALL].dynamicGas = gasCallEIP2929
+
+	jt[CALLCODE].constantGas = vars.WarmStorageReadCostEIP2929
+	jt[CALLCODE].dynamicGas = gasCallCodeEIP2929
+
+	// The following opcodes are only defined if their respective features
+	// are enabled on the chain, so guard against missing entries.
+	if jt[EXTCODEHASH] != nil {
+		jt[EXTCODEHASH].constantGas = vars.WarmStorageReadCostEIP2929
+		jt[EXTCODEHASH].dynamicGas = gasEip2929AccountCheck
+	}
+	if jt[STATICCALL] != nil {
+		jt[STATICCALL].constantGas = vars.WarmStorageReadCostEIP2929
+		jt[STATICCALL].dynamicGas = gasStaticCallEIP2929
+	}
+	if jt[DELEGATECALL] != nil {
+		jt[DELEGATECALL].constantGas = vars.WarmStorageReadCostEIP2929
+		jt[DELEGATECALL].dynamicGas = gasDelegateCallEIP2929
+	}
+
+	// This was previously part of the dynamic cost, but we're using it as a constantGas
+	// factor here
+	jt[SELFDESTRUCT].constantGas = vars.SelfdestructGasEIP150
+	jt[SELFDESTRUCT].dynamicGas = gasSelfdestructEIP2929
+}
+
 // enable2315 applies EIP-2315 (Simple Subroutines)
 // - Adds opcodes that jump to and return from subroutines
 func enable2315(jt *JumpTable) {
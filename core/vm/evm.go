@@ -90,6 +90,7 @@ type Context struct {
 	BlockNumber *big.Int       // Provides information for NUMBER
 	Time        *big.Int       // Provides information for TIME
 	Difficulty  *big.Int       // Provides information for DIFFICULTY
+	BaseFee     *big.Int       // EIP-1559 base fee of the block, nil before the fork
 }
 
 // EVM is the Ethereum Virtual Machine base object and provides
@@ -133,6 +134,11 @@ type EVM struct {
 // NewEVM returns a new EVM. The returned EVM is not thread safe and should
 // only ever be used *once*.
 func NewEVM(ctx Context, statedb StateDB, chainConfig ctypes.ChainConfigurator, vmConfig Config) *EVM {
+	// Zero-priced calls (eth_call, gas estimation) may run without paying the
+	// base fee, in which case it is treated as zero for the whole execution.
+	if vmConfig.NoBaseFee && ctx.BaseFee != nil && (ctx.GasPrice == nil || ctx.GasPrice.BitLen() == 0) {
+		ctx.BaseFee = new(big.Int)
+	}
 	evm := &EVM{
 		Context:     ctx,
 		StateDB:     statedb,
@@ -403,6 +409,11 @@ func (evm *EVM) create(caller ContractRef, codeAndHash *codeAndHash, gas uint64,
 	}
 	nonce := evm.StateDB.GetNonce(caller.Address())
 	evm.StateDB.SetNonce(caller.Address(), nonce+1)
+	// We add this to the access list _before_ taking a snapshot. Even if the creation fails,
+	// the access-list change should not be rolled back
+	if evm.ChainConfig().IsEnabled(evm.chainConfig.GetEIP2929Transition, evm.BlockNumber) {
+		evm.StateDB.AddAddressToAccessList(address)
+	}
 
 	// Ensure there's no existing contract already at the designated address
 	contractHash := evm.StateDB.GetCodeHash(address)
@@ -486,3 +497,6 @@ func (evm *EVM) Create2(caller ContractRef, code []byte, gas uint64, endowment *
 
 // ChainConfig returns the environment's chain configuration
 func (evm *EVM) ChainConfig() ctypes.ChainConfigurator { return evm.chainConfig }
+
+// VMConfig returns the configuration options the EVM was created with.
+func (evm *EVM) VMConfig() Config { return evm.vmConfig }
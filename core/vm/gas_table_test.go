@@ -106,3 +106,41 @@ func TestEIP2200(t *testing.T) {
 		}
 	}
 }
+
+var eip2929Tests = []struct {
+	input string
+	used  uint64
+}{
+	{"0x6000545060005450", 2210},    // cold SLOAD, warm SLOAD
+	{"0x60ff315060ff3150", 2710},    // cold BALANCE, warm BALANCE
+	{"0x6001600055", 22106},         // cold SSTORE 0 -> 1
+	{"0x600054506001600055", 22111}, // cold SLOAD, warm SSTORE 0 -> 1
+	{"0x60ff3b5060ff3b50", 2710},    // cold EXTCODESIZE, warm EXTCODESIZE
+}
+
+func TestEIP2929(t *testing.T) {
+	for i, tt := range eip2929Tests {
+		address := common.BytesToAddress([]byte("contract"))
+
+		statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+		statedb.CreateAccount(address)
+		statedb.SetCode(address, hexutil.MustDecode(tt.input))
+		statedb.Finalise(true) // Push the state into the "original" slot
+		statedb.PrepareAccessList(common.Address{}, &address, nil, nil)
+
+		vmctx := Context{
+			CanTransfer: func(StateDB, common.Address, *big.Int) bool { return true },
+			Transfer:    func(StateDB, common.Address, common.Address, *big.Int) {},
+		}
+
+		vmenv := NewEVM(vmctx, statedb, params.AllEthashProtocolChanges, Config{ExtraEips: []int{2929}})
+
+		_, gas, err := vmenv.Call(AccountRef(common.Address{}), address, nil, math.MaxUint64, new(big.Int))
+		if err != nil {
+			t.Errorf("test %d: unexpected failure: %v", i, err)
+		}
+		if used := math.MaxUint64 - gas; used != tt.used {
+			t.Errorf("test %d: gas used mismatch: have %v, want %v", i, used, tt.used)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params/types/ctypes"
+)
+
+const (
+	// addressIndexSectionSize is the number of blocks in a single section of the
+	// address index. Blocks past the last section are scanned on demand, so it's
+	// kept small compared to the bloombits.
+	addressIndexSectionSize = 256
+
+	// addressIndexConfirms is the number of confirmations needed before a section
+	// of the address index is processed.
+	addressIndexConfirms = 16
+
+	// addressIndexThrottling is the time to wait between processing two
+	// consecutive sections of the address index.
+	addressIndexThrottling = 10 * time.Millisecond
+)
+
+// AddressIndexer implements a core.ChainIndexer, recording for every address the
+// transactions it sent, received or created a contract by.
+type AddressIndexer struct {
+	db      ethdb.Database
+	config  ctypes.ChainConfigurator
+	batch   ethdb.Batch // Batch accumulating the writes of the current section
+	section uint64      // Section is the section number being processed currently
+}
+
+// NewAddressIndexer returns a chain indexer that builds the address transaction
+// index of the canonical chain.
+func NewAddressIndexer(db ethdb.Database, config ctypes.ChainConfigurator) *core.ChainIndexer {
+	backend := &AddressIndexer{
+		db:     db,
+		config: config,
+	}
+	table := rawdb.NewTable(db, string(rawdb.AddressIndexPrefix))
+
+	return core.NewChainIndexer(db, table, backend, addressIndexSectionSize, addressIndexConfirms, addressIndexThrottling, "addresses")
+}
+
+// Reset implements core.ChainIndexerBackend, starting a new address index
+// section. Any entries left behind by an earlier, since reorged, version of the
+// section are deleted.
+func (a *AddressIndexer) Reset(ctx context.Context, section uint64, lastSectionHead common.Hash) error {
+	a.batch, a.section = a.db.NewBatch(), section
+
+	for number := section * addressIndexSectionSize; number < (section+1)*addressIndexSectionSize; number++ {
+		if entries := rawdb.ReadAddressTxBlockEntries(a.db, number); entries != nil {
+			rawdb.DeleteAddressTxIndex(a.batch, number, entries)
+		}
+	}
+	return nil
+}
+
+// Process implements core.ChainIndexerBackend, adding the transactions of a new
+// block into the index.
+func (a *AddressIndexer) Process(ctx context.Context, header *types.Header) error {
+	number := header.Number.Uint64()
+
+	body := rawdb.ReadBody(a.db, header.Hash(), number)
+	if body == nil {
+		return fmt.Errorf("block #%d [%x…] body not found", number, header.Hash().Bytes()[:4])
+	}
+	entries, err := addressTxEntries(types.MakeSigner(a.config, header.Number), number, body.Transactions)
+	if err != nil {
+		return err
+	}
+	if len(entries) > 0 {
+		rawdb.WriteAddressTxIndex(a.batch, number, entries)
+	}
+	if a.batch.ValueSize() > ethdb.IdealBatchSize {
+		if err := a.batch.Write(); err != nil {
+			return err
+		}
+		a.batch.Reset()
+	}
+	return nil
+}
+
+// Commit implements core.ChainIndexerBackend, writing out the remainder of the
+// section into the database.
+func (a *AddressIndexer) Commit() error {
+	return a.batch.Write()
+}
+
+// Prune returns an empty error since we don't support pruning here.
+func (a *AddressIndexer) Prune(threshold uint64) error {
+	return nil
+}
+
+// addressTxEntries returns the address index entries of a list of transactions
+// included in a block: one for the sender, the recipient or the created contract
+// of each transaction.
+func addressTxEntries(signer types.Signer, number uint64, txs types.Transactions) ([]rawdb.AddressTxEntry, error) {
+	var entries []rawdb.AddressTxEntry
+	for i, tx := range txs {
+		from, err := types.Sender(signer, tx)
+		if err != nil {
+			return nil, fmt.Errorf("transaction %#x of block #%d: %v", tx.Hash(), number, err)
+		}
+		to := crypto.CreateAddress(from, tx.Nonce())
+		if tx.To() != nil {
+			to = *tx.To()
+		}
+		entries = append(entries, rawdb.AddressTxEntry{Address: from, BlockNumber: number, Index: uint64(i), Hash: tx.Hash()})
+		if to != from {
+			entries = append(entries, rawdb.AddressTxEntry{Address: to, BlockNumber: number, Index: uint64(i), Hash: tx.Hash()})
+		}
+	}
+	return entries, nil
+}
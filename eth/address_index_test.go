@@ -0,0 +1,205 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"math"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/params/types/genesisT"
+	"github.com/ethereum/go-ethereum/params/vars"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Tests that the address index records senders, recipients and created
+// contracts, that the RPC method pages through them consistently across the
+// indexed and unindexed parts of the chain, and that the index is rebuilt after
+// the chain is rewound and replaced.
+func TestAddressIndex(t *testing.T) {
+	var (
+		db      = rawdb.NewMemoryDatabase()
+		engine  = ethash.NewFaker()
+		signer  = types.HomesteadSigner{}
+		targets = []common.Address{{0x01}, {0x02}, {0x03}}
+		gspec   = &genesisT.Genesis{
+			Config: params.TestChainConfig,
+			Alloc:  genesisT.GenesisAlloc{testBank: {Balance: big.NewInt(1000000000)}},
+		}
+		genesis = core.MustCommitGenesis(db, gspec)
+	)
+	// generate creates a chain sending a transfer per block to a rotating set
+	// of recipients, deploying a contract in block 10
+	generate := func(parent *types.Block, n int, value int64) []*types.Block {
+		blocks, _ := core.GenerateChain(gspec.Config, parent, engine, db, n, func(i int, block *core.BlockGen) {
+			var tx *types.Transaction
+			if block.Number().Uint64() == 10 {
+				tx = types.NewContractCreation(block.TxNonce(testBank), nil, 100000, nil, []byte{0x00})
+			} else {
+				tx = types.NewTransaction(block.TxNonce(testBank), targets[block.Number().Uint64()%uint64(len(targets))], big.NewInt(value), vars.TxGas, nil, nil)
+			}
+			tx, _ = types.SignTx(tx, signer, testBankKey)
+			block.AddTx(tx)
+		})
+		return blocks
+	}
+	chain, _ := core.NewBlockChain(db, nil, gspec.Config, engine, vm.Config{}, nil, nil)
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(generate(genesis, 300, 1)); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	indexer := NewAddressIndexer(db, gspec.Config)
+	indexer.Start(chain)
+	defer indexer.Close()
+
+	api := NewPublicAddressIndexAPI(&Ethereum{blockchain: chain, chainDb: db, addressIndexer: indexer})
+
+	// waitIndexed waits until the first section is indexed for the current chain
+	waitIndexed := func() {
+		t.Helper()
+
+		for start := time.Now(); time.Since(start) < 5*time.Second; time.Sleep(10 * time.Millisecond) {
+			if sections, _, head := indexer.Sections(); sections == 1 && head == chain.GetCanonicalHash(addressIndexSectionSize-1) {
+				return
+			}
+		}
+		t.Fatalf("address index section not processed")
+	}
+	// check compares the results of the RPC method against a plain chain scan
+	check := func(address common.Address, from, to uint64, after, count uint64) {
+		t.Helper()
+
+		var want []common.Hash
+		for number := from; number <= to; number++ {
+			for _, tx := range chain.GetBlockByNumber(number).Transactions() {
+				sender, _ := types.Sender(signer, tx)
+				recipient := crypto.CreateAddress(sender, tx.Nonce())
+				if tx.To() != nil {
+					recipient = *tx.To()
+				}
+				if sender == address || recipient == address {
+					want = append(want, tx.Hash())
+				}
+			}
+		}
+		if after < uint64(len(want)) {
+			want = want[after:]
+		} else {
+			want = nil
+		}
+		if count < uint64(len(want)) {
+			want = want[:count]
+		}
+		fromBlock, toBlock := rpc.BlockNumber(from), rpc.BlockNumber(to)
+		txs, err := api.GetTransactionsByAddress(context.Background(), address, &AddressTransactionsArgs{
+			FromBlock: &fromBlock,
+			ToBlock:   &toBlock,
+			After:     &after,
+			Count:     &count,
+		})
+		if err != nil {
+			t.Fatalf("%x [%d-%d]: failed to retrieve transactions: %v", address, from, to, err)
+		}
+		if len(txs) != len(want) {
+			t.Fatalf("%x [%d-%d] after %d count %d: transaction count mismatch: have %d, want %d", address, from, to, after, count, len(txs), len(want))
+		}
+		for i, tx := range txs {
+			if tx.Hash != want[i] {
+				t.Errorf("%x [%d-%d] after %d count %d: transaction %d mismatch: have %x, want %x", address, from, to, after, count, i, tx.Hash, want[i])
+			}
+		}
+	}
+	verify := func() {
+		t.Helper()
+
+		head := chain.CurrentBlock().NumberU64()
+		creation := crypto.CreateAddress(testBank, 9)
+
+		check(targets[0], 0, head, 0, 1000)
+		check(targets[1], 0, head, 0, 1000)
+		check(targets[2], 0, head, 0, 1000)
+		check(creation, 0, head, 0, 1000)
+		check(testBank, 0, head, 0, 1000)
+
+		// Page through ranges spanning the indexed and scanned parts
+		check(targets[0], 200, head, 0, 10)
+		check(targets[0], 200, head, 10, 10)
+		check(targets[0], 200, head, 20, 10)
+		check(targets[1], 250, 260, 0, 1000)
+		check(testBank, 100, head, 150, 25)
+		check(testBank, 0, head, 1000, 10)
+	}
+	waitIndexed()
+	verify()
+
+	// Rewind the chain below the indexed section and replace it with a different
+	// one, the index should be rebuilt
+	if err := chain.SetHead(100); err != nil {
+		t.Fatalf("failed to rewind chain: %v", err)
+	}
+	if _, err := chain.InsertChain(generate(chain.CurrentBlock(), 300-int(chain.CurrentBlock().NumberU64()), 2)); err != nil {
+		t.Fatalf("failed to insert fork: %v", err)
+	}
+	waitIndexed()
+	verify()
+}
+
+// Tests that requests skipping too many transactions or needing too many blocks
+// scanned beyond the address index are rejected.
+func TestAddressIndexLimits(t *testing.T) {
+	var (
+		db     = rawdb.NewMemoryDatabase()
+		engine = ethash.NewFaker()
+		gspec  = &genesisT.Genesis{Config: params.TestChainConfig}
+	)
+	genesis := core.MustCommitGenesis(db, gspec)
+	blocks, _ := core.GenerateChain(gspec.Config, genesis, engine, db, maxAddressUnindexedBlocks+10, nil)
+
+	chain, _ := core.NewBlockChain(db, nil, gspec.Config, engine, vm.Config{}, nil, nil)
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	// Leave the index unbuilt, as if it was just enabled
+	api := NewPublicAddressIndexAPI(&Ethereum{blockchain: chain, chainDb: db, addressIndexer: NewAddressIndexer(db, gspec.Config)})
+
+	if _, err := api.GetTransactionsByAddress(context.Background(), testBank, nil); err == nil {
+		t.Errorf("scanning the whole unindexed chain succeeded")
+	}
+	from := rpc.BlockNumber(len(blocks) - 100)
+	if _, err := api.GetTransactionsByAddress(context.Background(), testBank, &AddressTransactionsArgs{FromBlock: &from}); err != nil {
+		t.Errorf("failed to scan recent blocks: %v", err)
+	}
+	for _, after := range []uint64{maxAddressTransactionsSkip + 1, math.MaxInt64, math.MaxUint64} {
+		after := after
+		if _, err := api.GetTransactionsByAddress(context.Background(), testBank, &AddressTransactionsArgs{FromBlock: &from, After: &after}); err == nil {
+			t.Errorf("skipping %d transactions succeeded", after)
+		}
+	}
+}
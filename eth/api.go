@@ -273,6 +273,107 @@ func (api *PrivateAdminAPI) Ecbp1100(blockNr rpc.BlockNumber) (bool, error) {
 			api.eth.blockchain.CurrentBlock().Number()), err
 }
 
+// maxArtificialFinalityDecisions is the maximum number of decisions returned by a single
+// Ecbp1100Decisions query.
+const maxArtificialFinalityDecisions = 1024
+
+// artificialFinalityDecisionChanSize is the size of the channel listening to
+// artificial finality decisions, which are sent during block import.
+const artificialFinalityDecisionChanSize = 10
+
+// ArtificialFinalityDecisionResult is the JSON representation of an artificial finality (ECBP1100)
+// decision about a proposed chain reorganization.
+type ArtificialFinalityDecisionResult struct {
+	CommonAncestorNumber hexutil.Uint64 `json:"commonAncestorNumber"`
+	CommonAncestorHash   common.Hash    `json:"commonAncestorHash"`
+	CurrentNumber        hexutil.Uint64 `json:"currentNumber"`
+	CurrentHash          common.Hash    `json:"currentHash"`
+	ProposedNumber       hexutil.Uint64 `json:"proposedNumber"`
+	ProposedHash         common.Hash    `json:"proposedHash"`
+	TimeDelta            hexutil.Uint64 `json:"timeDelta"`
+	CurrentSubchainTD    *hexutil.Big   `json:"currentSubchainTD"`
+	ProposedSubchainTD   *hexutil.Big   `json:"proposedSubchainTD"`
+	TDRatio              float64        `json:"tdRatio"`
+	RequiredRatio        float64        `json:"requiredRatio"`
+	Accepted             bool           `json:"accepted"`
+	EvaluatedAt          hexutil.Uint64 `json:"evaluatedAt"`
+}
+
+func newArtificialFinalityDecisionResult(d *types.ArtificialFinalityDecision) *ArtificialFinalityDecisionResult {
+	return &ArtificialFinalityDecisionResult{
+		CommonAncestorNumber: hexutil.Uint64(d.CommonAncestorNumber),
+		CommonAncestorHash:   d.CommonAncestorHash,
+		CurrentNumber:        hexutil.Uint64(d.CurrentNumber),
+		CurrentHash:          d.CurrentHash,
+		ProposedNumber:       hexutil.Uint64(d.ProposedNumber),
+		ProposedHash:         d.ProposedHash,
+		TimeDelta:            hexutil.Uint64(d.TimeDelta),
+		CurrentSubchainTD:    (*hexutil.Big)(d.CurrentSubchainTD),
+		ProposedSubchainTD:   (*hexutil.Big)(d.ProposedSubchainTD),
+		TDRatio:              d.TDRatio(),
+		RequiredRatio:        d.RequiredRatio(),
+		Accepted:             d.Accepted,
+		EvaluatedAt:          hexutil.Uint64(d.EvaluatedAt),
+	}
+}
+
+// Ecbp1100Decisions returns the recorded artificial finality (ECBP1100) decisions
+// about proposed blocks numbered in the inclusive range [from, to].
+// If to is omitted, the current head is used.
+func (api *PrivateAdminAPI) Ecbp1100Decisions(from rpc.BlockNumber, to *rpc.BlockNumber) ([]*ArtificialFinalityDecisionResult, error) {
+	head := api.eth.blockchain.CurrentBlock().NumberU64()
+	resolve := func(n rpc.BlockNumber) uint64 {
+		if n < 0 {
+			return head
+		}
+		return uint64(n)
+	}
+	start, end := resolve(from), head
+	if to != nil {
+		end = resolve(*to)
+	}
+	if start > end {
+		return nil, fmt.Errorf("invalid range: from %d > to %d", start, end)
+	}
+	decisions := rawdb.ReadArtificialFinalityDecisions(api.eth.ChainDb(), start, end, maxArtificialFinalityDecisions)
+	results := make([]*ArtificialFinalityDecisionResult, len(decisions))
+	for i, d := range decisions {
+		results[i] = newArtificialFinalityDecisionResult(d)
+	}
+	return results, nil
+}
+
+// NewEcbp1100Decisions creates a subscription that is notified of each artificial
+// finality (ECBP1100) decision as it is made.
+func (api *PrivateAdminAPI) NewEcbp1100Decisions(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return nil, rpc.ErrNotificationsUnsupported
+	}
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		events := make(chan core.ArtificialFinalityDecisionEvent, artificialFinalityDecisionChanSize)
+		sub := api.eth.blockchain.SubscribeArtificialFinalityDecisionEvent(events)
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case ev := <-events:
+				notifier.Notify(rpcSub.ID, newArtificialFinalityDecisionResult(ev.Decision))
+			case <-sub.Err():
+				return
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
+
 // PublicDebugAPI is the collection of Ethereum full node APIs exposed
 // over the public debugging endpoint.
 type PublicDebugAPI struct {
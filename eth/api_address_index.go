@@ -0,0 +1,160 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	// maxAddressTransactions is the maximum number of transactions returned by a
+	// single eth_getTransactionsByAddress call.
+	maxAddressTransactions = 1000
+
+	// maxAddressTransactionsSkip is the maximum number of matching transactions
+	// an eth_getTransactionsByAddress call may skip. Paging further requires
+	// moving the start block instead.
+	maxAddressTransactionsSkip = 100000
+
+	// maxAddressUnindexedBlocks is the maximum number of blocks not covered by
+	// the address index yet that a single call scans directly.
+	maxAddressUnindexedBlocks = 4 * addressIndexSectionSize
+)
+
+// AddressTransactionsArgs represents the arguments for the
+// eth_getTransactionsByAddress method.
+type AddressTransactionsArgs struct {
+	FromBlock *rpc.BlockNumber `json:"fromBlock,omitempty"` // Search from this starting block, defaults to genesis
+	ToBlock   *rpc.BlockNumber `json:"toBlock,omitempty"`   // Search until this end block, defaults to latest
+	After     *uint64          `json:"after,omitempty"`     // The number of matching transactions to skip
+	Count     *uint64          `json:"count,omitempty"`     // The maximum number of transactions to return
+}
+
+// PublicAddressIndexAPI provides access to the address transaction index of a
+// full node, if it's enabled.
+type PublicAddressIndexAPI struct {
+	eth *Ethereum
+}
+
+// NewPublicAddressIndexAPI creates a new API definition for the address index.
+func NewPublicAddressIndexAPI(eth *Ethereum) *PublicAddressIndexAPI {
+	return &PublicAddressIndexAPI{eth: eth}
+}
+
+// GetTransactionsByAddress returns, in chain order, the transactions sent by,
+// sent to or creating a contract at the given address. The after and count
+// arguments allow the results to be paginated.
+func (api *PublicAddressIndexAPI) GetTransactionsByAddress(ctx context.Context, address common.Address, args *AddressTransactionsArgs) ([]*ethapi.RPCTransaction, error) {
+	if args == nil {
+		args = new(AddressTransactionsArgs)
+	}
+	head := api.eth.blockchain.CurrentBlock().NumberU64()
+
+	from, to := uint64(0), head
+	if args.FromBlock != nil && *args.FromBlock >= 0 {
+		from = uint64(*args.FromBlock)
+	}
+	if args.ToBlock != nil && *args.ToBlock >= 0 && uint64(*args.ToBlock) < head {
+		to = uint64(*args.ToBlock)
+	}
+	if from > to {
+		return nil, fmt.Errorf("end block (#%d) needs to come after start block (#%d)", to, from)
+	}
+	var after, count uint64 = 0, maxAddressTransactions
+	if args.After != nil {
+		after = *args.After
+	}
+	if after > maxAddressTransactionsSkip {
+		return nil, fmt.Errorf("cannot skip more than %d transactions, move the start block instead", maxAddressTransactionsSkip)
+	}
+	if args.Count != nil && *args.Count < count {
+		count = *args.Count
+	}
+	results := []*ethapi.RPCTransaction{}
+	if count == 0 {
+		return results, nil
+	}
+	// Gather the references from the indexed sections first, scanning any blocks
+	// beyond them directly
+	var (
+		limit    = int(after + count)
+		sections uint64
+	)
+	sections, _, _ = api.eth.addressIndexer.Sections()
+	indexed := sections * addressIndexSectionSize
+
+	var entries []rawdb.AddressTxEntry
+	if from < indexed {
+		end := to
+		if end >= indexed {
+			end = indexed - 1
+		}
+		entries = rawdb.ReadAddressTxEntries(api.eth.chainDb, address, from, end, limit)
+	}
+	if from < indexed {
+		from = indexed
+	}
+	if len(entries) < limit && from <= to && to-from >= maxAddressUnindexedBlocks {
+		return nil, fmt.Errorf("address index only covers %d blocks yet, cannot scan the %d blocks beyond it", indexed, to-from+1)
+	}
+	for number := from; number <= to && len(entries) < limit; number++ {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+		}
+		block := api.eth.blockchain.GetBlockByNumber(number)
+		if block == nil {
+			return nil, fmt.Errorf("block #%d not found", number)
+		}
+		signer := types.MakeSigner(api.eth.blockchain.Config(), block.Number())
+		blockEntries, err := addressTxEntries(signer, number, block.Transactions())
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range blockEntries {
+			if entry.Address == address && len(entries) < limit {
+				entries = append(entries, entry)
+			}
+		}
+	}
+	if uint64(len(entries)) <= after {
+		return results, nil
+	}
+	// Resolve the requested page of references into transactions
+	var block *types.Block
+	for _, entry := range entries[after:] {
+		if block == nil || block.NumberU64() != entry.BlockNumber {
+			if block = api.eth.blockchain.GetBlockByNumber(entry.BlockNumber); block == nil {
+				return nil, fmt.Errorf("block #%d not found", entry.BlockNumber)
+			}
+		}
+		tx := ethapi.NewRPCTransactionFromBlockIndex(block, entry.Index)
+		if tx == nil || tx.Hash != entry.Hash {
+			return nil, fmt.Errorf("transaction %#x of block #%d not found, index is being rebuilt", entry.Hash, entry.BlockNumber)
+		}
+		results = append(results, tx)
+	}
+	return results, nil
+}
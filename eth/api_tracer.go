@@ -146,12 +146,36 @@ func (api *PrivateDebugAPI) traceChain(ctx context.Context, start, end *types.Bl
 	}
 	sub := notifier.CreateSubscription()
 
+	tasks, err := traceChain(ctx, api.eth, start, end, config, notifier.Closed())
+	if err != nil {
+		return nil, err
+	}
+	// Keep reading the trace results and stream the to the user
+	go func() {
+		for task := range tasks {
+			if len(task.results) > 0 || task.block.NumberU64() == end.NumberU64() {
+				notifier.Notify(sub.ID, &blockTraceResult{
+					Block:  hexutil.Uint64(task.block.NumberU64()),
+					Hash:   task.block.Hash(),
+					Traces: task.results,
+				})
+			}
+		}
+	}()
+	return sub, nil
+}
+
+// traceChain executes all the transactions contained within the blocks after start
+// up to and including end, tracing them concurrently. The traced blocks are sent
+// in chain order on the returned channel, which is closed once tracing finishes,
+// fails or the closed channel is signalled.
+func traceChain(ctx context.Context, eth *Ethereum, start, end *types.Block, config *TraceConfig, closed <-chan interface{}) (<-chan *blockTraceTask, error) {
 	// Ensure we have a valid starting state before doing any work
 	origin := start.NumberU64()
-	database := state.NewDatabaseWithCache(api.eth.ChainDb(), 16, "") // Chain tracing will probably start at genesis
+	database := state.NewDatabaseWithCache(eth.ChainDb(), 16, "") // Chain tracing will probably start at genesis
 
 	if number := start.NumberU64(); number > 0 {
-		start = api.eth.blockchain.GetBlock(start.ParentHash(), start.NumberU64()-1)
+		start = eth.blockchain.GetBlock(start.ParentHash(), start.NumberU64()-1)
 		if start == nil {
 			return nil, fmt.Errorf("parent block #%d not found", number-1)
 		}
@@ -165,7 +189,7 @@ func (api *PrivateDebugAPI) traceChain(ctx context.Context, start, end *types.Bl
 		}
 		// Find the most recent block that has the state available
 		for i := uint64(0); i < reexec; i++ {
-			start = api.eth.blockchain.GetBlock(start.ParentHash(), start.NumberU64()-1)
+			start = eth.blockchain.GetBlock(start.ParentHash(), start.NumberU64()-1)
 			if start == nil {
 				break
 			}
@@ -202,27 +226,33 @@ func (api *PrivateDebugAPI) traceChain(ctx context.Context, start, end *types.Bl
 
 			// Fetch and execute the next block trace tasks
 			for task := range tasks {
-				signer := types.MakeSigner(api.eth.blockchain.Config(), task.block.Number())
+				signer := types.MakeSigner(eth.blockchain.Config(), task.block.Number())
 
 				// Trace all the transactions contained within
 				for i, tx := range task.block.Transactions() {
-					msg, _ := tx.AsMessage(signer)
-					vmctx := core.NewEVMContext(msg, task.block.Header(), api.eth.blockchain, nil)
-
-					res, err := traceTx(ctx, api.eth, msg, vmctx, task.statedb, nil, config)
+					msg, _ := tx.AsMessage(signer, task.block.BaseFee())
+					vmctx := core.NewEVMContext(msg, task.block.Header(), eth.blockchain, nil)
+
+					taskExtraContext := map[string]interface{}{
+						"blockNumber":         task.block.NumberU64(),
+						"blockHash":           task.block.Hash().Hex(),
+						"transactionHash":     tx.Hash().Hex(),
+						"transactionPosition": uint64(i),
+					}
+					res, err := traceTx(ctx, eth, msg, vmctx, task.statedb, taskExtraContext, config)
 					if err != nil {
 						task.results[i] = &txTraceResult{Error: err.Error()}
 						log.Warn("Tracing failed", "hash", tx.Hash(), "block", task.block.NumberU64(), "err", err)
 						break
 					}
 					// Only delete empty objects if EIP158/161 (a.k.a Spurious Dragon) is in effect
-					task.statedb.Finalise(api.eth.blockchain.Config().IsEnabled(api.eth.blockchain.Config().GetEIP161dTransition, task.block.Number()))
+					task.statedb.Finalise(eth.blockchain.Config().IsEnabled(eth.blockchain.Config().GetEIP161dTransition, task.block.Number()))
 					task.results[i] = &txTraceResult{Result: res}
 				}
 				// Stream the result back to the user or abort on teardown
 				select {
 				case results <- task:
-				case <-notifier.Closed():
+				case <-closed:
 					return
 				}
 			}
@@ -258,7 +288,7 @@ func (api *PrivateDebugAPI) traceChain(ctx context.Context, start, end *types.Bl
 		for number = start.NumberU64() + 1; number <= end.NumberU64(); number++ {
 			// Stop tracing if interruption was requested
 			select {
-			case <-notifier.Closed():
+			case <-closed:
 				return
 			default:
 			}
@@ -273,7 +303,7 @@ func (api *PrivateDebugAPI) traceChain(ctx context.Context, start, end *types.Bl
 				logged = time.Now()
 			}
 			// Retrieve the next block to trace
-			block := api.eth.blockchain.GetBlockByNumber(number)
+			block := eth.blockchain.GetBlockByNumber(number)
 			if block == nil {
 				failed = fmt.Errorf("block #%d not found", number)
 				break
@@ -284,19 +314,19 @@ func (api *PrivateDebugAPI) traceChain(ctx context.Context, start, end *types.Bl
 
 				select {
 				case tasks <- &blockTraceTask{statedb: statedb.Copy(), block: block, rootref: proot, results: make([]*txTraceResult, len(txs))}:
-				case <-notifier.Closed():
+				case <-closed:
 					return
 				}
 				traced += uint64(len(txs))
 			}
 			// Generate the next state snapshot fast without tracing
-			_, _, _, err := api.eth.blockchain.Processor().Process(block, statedb, vm.Config{})
+			_, _, _, err := eth.blockchain.Processor().Process(block, statedb, vm.Config{})
 			if err != nil {
 				failed = err
 				break
 			}
 			// Finalize the state so any modifications are written to the trie
-			root, err := statedb.Commit(api.eth.blockchain.Config().IsEnabled(api.eth.blockchain.Config().GetEIP161dTransition, block.Number()))
+			root, err := statedb.Commit(eth.blockchain.Config().IsEnabled(eth.blockchain.Config().GetEIP161dTransition, block.Number()))
 			if err != nil {
 				failed = err
 				break
@@ -320,35 +350,36 @@ func (api *PrivateDebugAPI) traceChain(ctx context.Context, start, end *types.Bl
 		}
 	}()
 
-	// Keep reading the trace results and stream the to the user
+	// Keep reading the trace results and deliver them in chain order
+	ordered := make(chan *blockTraceTask)
+
 	go func() {
+		defer close(ordered)
+
 		var (
-			done = make(map[uint64]*blockTraceResult)
+			done = make(map[uint64]*blockTraceTask)
 			next = origin + 1
 		)
 		for res := range results {
 			// Queue up next received result
-			result := &blockTraceResult{
-				Block:  hexutil.Uint64(res.block.NumberU64()),
-				Hash:   res.block.Hash(),
-				Traces: res.results,
-			}
-			done[uint64(result.Block)] = result
+			done[res.block.NumberU64()] = res
 
 			// Dereference any paret tries held in memory by this task
 			database.TrieDB().Dereference(res.rootref)
 
-			// Stream completed traces to the user, aborting on the first error
-			for result, ok := done[next]; ok; result, ok = done[next] {
-				if len(result.Traces) > 0 || next == end.NumberU64() {
-					notifier.Notify(sub.ID, result)
+			// Deliver completed traces in order, aborting on teardown
+			for task, ok := done[next]; ok; task, ok = done[next] {
+				select {
+				case ordered <- task:
+				case <-closed:
+					return
 				}
 				delete(done, next)
 				next++
 			}
 		}
 	}()
-	return sub, nil
+	return ordered, nil
 }
 
 // TraceBlockByNumber returns the structured logs created during the execution of
@@ -492,7 +523,7 @@ func traceBlock(ctx context.Context, eth *Ethereum, block *types.Block, config *
 
 			// Fetch and execute the next transaction trace tasks
 			for task := range jobs {
-				msg, _ := txs[task.index].AsMessage(signer)
+				msg, _ := txs[task.index].AsMessage(signer, block.BaseFee())
 				vmctx := core.NewEVMContext(msg, block.Header(), eth.blockchain, nil)
 
 				res, err := traceTx(ctx, eth, msg, vmctx, task.statedb, task.taskExtraContext, config)
@@ -518,7 +549,7 @@ func traceBlock(ctx context.Context, eth *Ethereum, block *types.Block, config *
 		jobs <- &txTraceTask{statedb: statedb.Copy(), index: i, taskExtraContext: taskExtraContext}
 
 		// Generate the next state snapshot fast without tracing
-		msg, _ := tx.AsMessage(signer)
+		msg, _ := tx.AsMessage(signer, block.BaseFee())
 		vmctx := core.NewEVMContext(msg, block.Header(), eth.blockchain, nil)
 
 		vmenv := vm.NewEVM(vmctx, statedb, eth.blockchain.Config(), vm.Config{})
@@ -591,7 +622,7 @@ func (api *PrivateDebugAPI) standardTraceBlockToFile(ctx context.Context, block
 	for i, tx := range block.Transactions() {
 		// Prepare the trasaction for un-traced execution
 		var (
-			msg, _ = tx.AsMessage(signer)
+			msg, _ = tx.AsMessage(signer, block.BaseFee())
 			vmctx  = core.NewEVMContext(msg, block.Header(), api.eth.blockchain, nil)
 
 			vmConf vm.Config
@@ -770,69 +801,96 @@ func (api *PrivateDebugAPI) TraceTransaction(ctx context.Context, hash common.Ha
 // if the given transaction was added on top of the provided block and returns them as a JSON object.
 // You can provide -2 as a block number to trace on top of the pending block.
 func (api *PrivateDebugAPI) TraceCall(ctx context.Context, args ethapi.CallArgs, blockNrOrHash rpc.BlockNumberOrHash, config *TraceConfig) (interface{}, error) {
-	// First try to retrieve the state
-	statedb, header, err := api.eth.APIBackend.StateAndHeaderByNumberOrHash(ctx, blockNrOrHash)
+	statedb, header, err := stateAndHeaderForCall(ctx, api.eth, blockNrOrHash, config)
 	if err != nil {
-		// Try to retrieve the specified block
-		var block *types.Block
-		if hash, ok := blockNrOrHash.Hash(); ok {
-			block = api.eth.blockchain.GetBlockByHash(hash)
-		} else if number, ok := blockNrOrHash.Number(); ok {
-			block = api.eth.blockchain.GetBlockByNumber(uint64(number))
-		}
-		if block == nil {
-			return nil, fmt.Errorf("block %v not found: %v", blockNrOrHash, err)
-		}
-		// try to recompute the state
-		reexec := defaultTraceReexec
-		if config != nil && config.Reexec != nil {
-			reexec = *config.Reexec
-		}
-		_, _, statedb, err = api.computeTxEnv(block, 0, reexec)
-		if err != nil {
-			return nil, err
-		}
+		return nil, err
 	}
-
 	// Execute the trace
-	msg := args.ToMessage(api.eth.APIBackend.RPCGasCap())
+	msg, err := args.ToMessage(api.eth.APIBackend.RPCGasCap(), header.BaseFee)
+	if err != nil {
+		return nil, err
+	}
 	vmctx := core.NewEVMContext(msg, header, api.eth.blockchain, nil)
 	return api.traceTx(ctx, msg, vmctx, statedb, config)
 }
 
+// stateAndHeaderForCall retrieves the state and header of the given block to run
+// calls on top of. If the state is no longer available, it is regenerated.
+func stateAndHeaderForCall(ctx context.Context, eth *Ethereum, blockNrOrHash rpc.BlockNumberOrHash, config *TraceConfig) (*state.StateDB, *types.Header, error) {
+	// First try to retrieve the state
+	statedb, header, err := eth.APIBackend.StateAndHeaderByNumberOrHash(ctx, blockNrOrHash)
+	if err == nil {
+		return statedb, header, nil
+	}
+	// Try to retrieve the specified block
+	var block *types.Block
+	if hash, ok := blockNrOrHash.Hash(); ok {
+		block = eth.blockchain.GetBlockByHash(hash)
+	} else if number, ok := blockNrOrHash.Number(); ok {
+		block = eth.blockchain.GetBlockByNumber(uint64(number))
+	}
+	if block == nil {
+		return nil, nil, fmt.Errorf("block %v not found: %v", blockNrOrHash, err)
+	}
+	// Regenerate the state after the block, calls are run on top of it
+	reexec := defaultTraceReexec
+	if config != nil && config.Reexec != nil {
+		reexec = *config.Reexec
+	}
+	statedb, err = computeStateDB(eth, block, reexec)
+	if err != nil {
+		return nil, nil, err
+	}
+	return statedb, block.Header(), nil
+}
+
+// newTxTracer constructs the named native or JavaScript tracer and arranges for
+// it to be stopped when the configured timeout elapses or the request is
+// cancelled. The returned cancel function must be called once tracing is done.
+func newTxTracer(ctx context.Context, name string, config *TraceConfig, extraContext map[string]interface{}) (tracers.TxTracer, context.CancelFunc, error) {
+	// Define a meaningful timeout of a single transaction trace
+	timeout := defaultTraceTimeout
+	if config != nil && config.Timeout != nil {
+		var err error
+		if timeout, err = time.ParseDuration(*config.Timeout); err != nil {
+			return nil, nil, err
+		}
+	}
+	// Constuct the tracer to execute with
+	tracer, err := tracers.NewTxTracer(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Handle timeouts and RPC cancellations
+	deadlineCtx, cancel := context.WithTimeout(ctx, timeout)
+	go func() {
+		<-deadlineCtx.Done()
+		tracer.Stop(errors.New("execution timeout"))
+	}()
+
+	if extraContext != nil {
+		tracer.CaptureExtraContext(extraContext)
+	}
+	return tracer, cancel, nil
+}
+
 // traceTx configures a new tracer according to the provided configuration, and
 // executes the given message in the provided environment. The return value will
 // be tracer dependent.
 func traceTx(ctx context.Context, eth *Ethereum, message core.Message, vmctx vm.Context, statedb *state.StateDB, extraContext map[string]interface{}, config *TraceConfig) (interface{}, error) {
-	// Assemble the structured logger or the JavaScript tracer
+	// Assemble the structured logger or the native or JavaScript tracer
 	var (
 		tracer vm.Tracer
 		err    error
 	)
 	switch {
 	case config != nil && config.Tracer != nil:
-		// Define a meaningful timeout of a single transaction trace
-		timeout := defaultTraceTimeout
-		if config.Timeout != nil {
-			if timeout, err = time.ParseDuration(*config.Timeout); err != nil {
-				return nil, err
-			}
-		}
-		// Constuct the JavaScript tracer to execute with
-		if tracer, err = tracers.New(*config.Tracer); err != nil {
+		txTracer, cancel, err := newTxTracer(ctx, *config.Tracer, config, extraContext)
+		if err != nil {
 			return nil, err
 		}
-		// Handle timeouts and RPC cancellations
-		deadlineCtx, cancel := context.WithTimeout(ctx, timeout)
-		go func() {
-			<-deadlineCtx.Done()
-			tracer.(*tracers.Tracer).Stop(errors.New("execution timeout"))
-		}()
 		defer cancel()
-
-		if extraContext != nil {
-			tracer.(*tracers.Tracer).CaptureExtraContext(extraContext)
-		}
+		tracer = txTracer
 
 	case config == nil:
 		tracer = vm.NewStructLogger(nil)
@@ -841,7 +899,7 @@ func traceTx(ctx context.Context, eth *Ethereum, message core.Message, vmctx vm.
 		tracer = vm.NewStructLogger(config.LogConfig)
 	}
 	// Run the transaction with tracing enabled.
-	vmenv := vm.NewEVM(vmctx, statedb, eth.blockchain.Config(), vm.Config{Debug: true, Tracer: tracer})
+	vmenv := vm.NewEVM(vmctx, statedb, eth.blockchain.Config(), vm.Config{Debug: true, Tracer: tracer, NoBaseFee: true})
 
 	result, err := core.ApplyMessage(vmenv, message, new(core.GasPool).AddGas(message.Gas()))
 	if err != nil {
@@ -862,7 +920,7 @@ func traceTx(ctx context.Context, eth *Ethereum, message core.Message, vmctx vm.
 			StructLogs:  ethapi.FormatLogs(tracer.StructLogs()),
 		}, nil
 
-	case *tracers.Tracer:
+	case tracers.TxTracer:
 		return tracer.GetResult()
 
 	default:
@@ -898,7 +956,7 @@ func computeTxEnv(eth *Ethereum, block *types.Block, txIndex int, reexec uint64)
 
 	for idx, tx := range block.Transactions() {
 		// Assemble the transaction call message and return if the requested offset
-		msg, _ := tx.AsMessage(signer)
+		msg, _ := tx.AsMessage(signer, block.BaseFee())
 		context := core.NewEVMContext(msg, block.Header(), eth.blockchain, nil)
 		if idx == txIndex {
 			return msg, context, statedb, nil
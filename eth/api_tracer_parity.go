@@ -25,7 +25,6 @@ import (
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/rpc"
 )
 
@@ -108,11 +107,29 @@ func traceBlockUncleRewards(ctx context.Context, eth *Ethereum, block *types.Blo
 	return results, nil
 }
 
-// Block returns the structured logs created during the execution of
-// EVM and returns them as a JSON object.
-// The correct name will be TraceBlockByNumber, though we want to be compatible with Parity trace module.
-func (api *PrivateTraceAPI) Block(ctx context.Context, number rpc.BlockNumber, config *TraceConfig) ([]interface{}, error) {
-	// Fetch the block that we want to trace
+const (
+	// maxTraceFilterBlocks is the maximum number of blocks traced by a single
+	// trace_filter call.
+	maxTraceFilterBlocks = 1000
+
+	// maxTraceFilterTraces is the maximum number of traces returned by a single
+	// trace_filter call.
+	maxTraceFilterTraces = 10000
+)
+
+// TraceFilterArgs represents the arguments for the trace_filter method.
+type TraceFilterArgs struct {
+	FromBlock   *rpc.BlockNumber `json:"fromBlock,omitempty"`   // Trace from this starting block
+	ToBlock     *rpc.BlockNumber `json:"toBlock,omitempty"`     // Trace until this end block
+	FromAddress []common.Address `json:"fromAddress,omitempty"` // Sent from these addresses
+	ToAddress   []common.Address `json:"toAddress,omitempty"`   // Sent to these addresses
+	After       *uint64          `json:"after,omitempty"`       // The offset trace number
+	Count       *uint64          `json:"count,omitempty"`       // Integer number of traces to display in a batch
+}
+
+// blockByNumber resolves a block number, including the pending and latest
+// aliases, to the corresponding block.
+func (api *PrivateTraceAPI) blockByNumber(number rpc.BlockNumber) (*types.Block, error) {
 	var block *types.Block
 
 	switch number {
@@ -123,18 +140,27 @@ func (api *PrivateTraceAPI) Block(ctx context.Context, number rpc.BlockNumber, c
 	default:
 		block = api.eth.blockchain.GetBlockByNumber(uint64(number))
 	}
-	// Trace the block if it was found
 	if block == nil {
 		return nil, fmt.Errorf("block #%d not found", number)
 	}
+	return block, nil
+}
 
+// traceBlockParity traces all the transactions of the given block with the
+// Parity call tracer, and appends the block and uncle reward traces.
+func (api *PrivateTraceAPI) traceBlockParity(ctx context.Context, block *types.Block, config *TraceConfig) ([]interface{}, error) {
 	config = setConfigTracerToParity(config)
 
-	traceResults, err := traceBlockByNumber(ctx, api.eth, number, config)
+	traceResults, err := traceBlock(ctx, api.eth, block, config)
 	if err != nil {
 		return nil, err
 	}
+	return api.blockTracesParity(ctx, block, traceResults, config)
+}
 
+// blockTracesParity flattens the Parity call traces of the transactions of the
+// given block, and appends the block and uncle reward traces.
+func (api *PrivateTraceAPI) blockTracesParity(ctx context.Context, block *types.Block, traceResults []*txTraceResult, config *TraceConfig) ([]interface{}, error) {
 	traceReward, err := traceBlockReward(ctx, api.eth, block, config)
 	if err != nil {
 		return nil, err
@@ -147,7 +173,10 @@ func (api *PrivateTraceAPI) Block(ctx context.Context, number rpc.BlockNumber, c
 
 	results := []interface{}{}
 
-	for _, result := range traceResults {
+	for i, result := range traceResults {
+		if result.Error != "" {
+			return nil, fmt.Errorf("tracing transaction %d of block #%d failed: %s", i, block.NumberU64(), result.Error)
+		}
 		var tmp []interface{}
 		if err := json.Unmarshal(result.Result.(json.RawMessage), &tmp); err != nil {
 			return nil, err
@@ -164,6 +193,18 @@ func (api *PrivateTraceAPI) Block(ctx context.Context, number rpc.BlockNumber, c
 	return results, nil
 }
 
+// Block returns the structured logs created during the execution of
+// EVM and returns them as a JSON object.
+// The correct name will be TraceBlockByNumber, though we want to be compatible with Parity trace module.
+func (api *PrivateTraceAPI) Block(ctx context.Context, number rpc.BlockNumber, config *TraceConfig) ([]interface{}, error) {
+	// Fetch the block that we want to trace
+	block, err := api.blockByNumber(number)
+	if err != nil {
+		return nil, err
+	}
+	return api.traceBlockParity(ctx, block, config)
+}
+
 // Transaction returns the structured logs created during the execution of EVM
 // and returns them as a JSON object.
 func (api *PrivateTraceAPI) Transaction(ctx context.Context, hash common.Hash, config *TraceConfig) (interface{}, error) {
@@ -171,7 +212,167 @@ func (api *PrivateTraceAPI) Transaction(ctx context.Context, hash common.Hash, c
 	return traceTransaction(ctx, api.eth, hash, config)
 }
 
-func (api *PrivateTraceAPI) Filter(ctx context.Context, args ethapi.CallArgs, config *TraceConfig) ([]*txTraceResult, error) {
-	// config = setConfigTracerToParity(config)
+// Filter returns the Parity formatted traces of the blocks within the requested
+// range, matching the given sender and recipient addresses.
+// The after and count arguments allow the results to be paginated. At most
+// maxTraceFilterBlocks blocks are traced and maxTraceFilterTraces traces
+// returned by a single call.
+func (api *PrivateTraceAPI) Filter(ctx context.Context, args TraceFilterArgs, config *TraceConfig) ([]interface{}, error) {
+	// Resolve the block range, defaulting to the latest block as Parity does
+	fromNumber, toNumber := rpc.LatestBlockNumber, rpc.LatestBlockNumber
+	if args.FromBlock != nil {
+		fromNumber = *args.FromBlock
+	}
+	if args.ToBlock != nil {
+		toNumber = *args.ToBlock
+	}
+	from, err := api.blockByNumber(fromNumber)
+	if err != nil {
+		return nil, err
+	}
+	to, err := api.blockByNumber(toNumber)
+	if err != nil {
+		return nil, err
+	}
+	if from.NumberU64() > to.NumberU64() {
+		return nil, fmt.Errorf("end block (#%d) needs to come after start block (#%d)", to.NumberU64(), from.NumberU64())
+	}
+	if to.NumberU64()-from.NumberU64() >= maxTraceFilterBlocks {
+		return nil, fmt.Errorf("cannot trace more than %d blocks, narrow the block range", maxTraceFilterBlocks)
+	}
+	var after, count uint64 = 0, maxTraceFilterTraces
+	if args.After != nil {
+		after = *args.After
+	}
+	if args.Count != nil && *args.Count < count {
+		count = *args.Count
+	}
+	results := []interface{}{}
+	if count == 0 {
+		return results, nil
+	}
+	// Stream the range through the chain tracer, which excludes its first block,
+	// so start from the parent of the requested one. The genesis block has no
+	// traces, so it can be skipped instead.
+	start := from
+	if from.NumberU64() > 0 {
+		if start = api.eth.blockchain.GetBlock(from.ParentHash(), from.NumberU64()-1); start == nil {
+			return nil, fmt.Errorf("parent block #%d not found", from.NumberU64()-1)
+		}
+	}
+	if start.NumberU64() == to.NumberU64() {
+		return results, nil
+	}
+	config = setConfigTracerToParity(config)
+
+	closed := make(chan interface{})
+	defer close(closed)
+
+	tasks, err := traceChain(ctx, api.eth, start, to, config, closed)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		skipped uint64
+		next    = start.NumberU64() + 1
+	)
+	for ; next <= to.NumberU64(); next++ {
+		var (
+			task *blockTraceTask
+			ok   bool
+		)
+		select {
+		case task, ok = <-tasks:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if !ok {
+			return nil, fmt.Errorf("tracing block #%d failed", next)
+		}
+		traces, err := api.blockTracesParity(ctx, task.block, task.results, config)
+		if err != nil {
+			return nil, err
+		}
+		for _, trace := range traces {
+			if !traceFilterMatches(trace, args.FromAddress, args.ToAddress) {
+				continue
+			}
+			if skipped < after {
+				skipped++
+				continue
+			}
+			results = append(results, trace)
+
+			if uint64(len(results)) >= count {
+				return results, nil
+			}
+		}
+	}
+	return results, nil
+}
+
+// traceFilterMatches reports whether the given Parity formatted trace matches the
+// sender and recipient addresses of a trace filter. An empty address list
+// matches any address.
+func traceFilterMatches(trace interface{}, fromAddresses, toAddresses []common.Address) bool {
+	from, to := traceAddresses(trace)
+	return addressesMatch(fromAddresses, from) && addressesMatch(toAddresses, to)
+}
+
+// addressesMatch reports whether any of the candidates is contained in the
+// filter list, or whether the filter list is empty.
+func addressesMatch(filter []common.Address, candidates []common.Address) bool {
+	if len(filter) == 0 {
+		return true
+	}
+	for _, candidate := range candidates {
+		for _, address := range filter {
+			if candidate == address {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// traceAddresses extracts the sender and recipient addresses of a Parity formatted
+// trace, following the Parity semantics for each trace type:
+//   - call: action.from and action.to
+//   - create: action.from and result.address
+//   - suicide: action.address and action.refundAddress
+//   - reward: no sender and action.author
+func traceAddresses(trace interface{}) (from []common.Address, to []common.Address) {
+	switch trace := trace.(type) {
+	case *ParityTrace:
+		if trace.Action.Author != nil {
+			to = append(to, *trace.Action.Author)
+		}
+		return from, to
+
+	case map[string]interface{}:
+		action, _ := trace["action"].(map[string]interface{})
+		result, _ := trace["result"].(map[string]interface{})
+
+		lookup := func(fields map[string]interface{}, key string) []common.Address {
+			if fields == nil {
+				return nil
+			}
+			if hex, ok := fields[key].(string); ok && common.IsHexAddress(hex) {
+				return []common.Address{common.HexToAddress(hex)}
+			}
+			return nil
+		}
+		typ, _ := trace["type"].(string)
+		switch typ {
+		case "create":
+			return lookup(action, "from"), lookup(result, "address")
+		case "suicide":
+			return lookup(action, "address"), lookup(action, "refundAddress")
+		case "reward":
+			return nil, lookup(action, "author")
+		default:
+			return lookup(action, "from"), lookup(action, "to")
+		}
+	}
 	return nil, nil
 }
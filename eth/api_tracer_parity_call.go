@@ -0,0 +1,127 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// TraceCallRequest is a single call of a trace_callMany request. It is encoded
+// as a [callArgs, traceTypes] pair, as in Parity.
+type TraceCallRequest struct {
+	Args       ethapi.CallArgs
+	TraceTypes []string
+}
+
+// UnmarshalJSON decodes a [callArgs, traceTypes] pair.
+func (r *TraceCallRequest) UnmarshalJSON(input []byte) error {
+	var pair []json.RawMessage
+	if err := json.Unmarshal(input, &pair); err != nil {
+		return err
+	}
+	if len(pair) != 2 {
+		return fmt.Errorf("invalid call request, expected [call, traceTypes] pair, got %d items", len(pair))
+	}
+	if err := json.Unmarshal(pair[0], &r.Args); err != nil {
+		return err
+	}
+	return json.Unmarshal(pair[1], &r.TraceTypes)
+}
+
+// Call executes the given call on top of the requested block, without making
+// any changes to the chain, and returns the requested trace types.
+func (api *PrivateTraceAPI) Call(ctx context.Context, args ethapi.CallArgs, traceTypes []string, blockNrOrHash *rpc.BlockNumberOrHash, config *TraceConfig) (*TraceReplayResult, error) {
+	results, err := api.CallMany(ctx, []TraceCallRequest{{Args: args, TraceTypes: traceTypes}}, blockNrOrHash, config)
+	if err != nil {
+		return nil, err
+	}
+	return results[0], nil
+}
+
+// CallMany executes a batch of calls on top of the requested block, each of them
+// seeing the state changes made by the previous ones, and returns the requested
+// trace types of every call.
+func (api *PrivateTraceAPI) CallMany(ctx context.Context, calls []TraceCallRequest, blockNrOrHash *rpc.BlockNumberOrHash, config *TraceConfig) ([]*TraceReplayResult, error) {
+	if len(calls) == 0 {
+		return nil, errors.New("no calls to trace")
+	}
+	// Parse all the trace types before doing any work
+	replays := make([]*traceReplayConfig, len(calls))
+	for i, call := range calls {
+		replay, err := newTraceReplayConfig(call.TraceTypes)
+		if err != nil {
+			return nil, err
+		}
+		replays[i] = replay
+	}
+	block := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+	if blockNrOrHash != nil {
+		block = *blockNrOrHash
+	}
+	statedb, header, err := stateAndHeaderForCall(ctx, api.eth, block, config)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]*TraceReplayResult, len(calls))
+	for i, call := range calls {
+		msg, err := call.Args.ToMessage(api.eth.APIBackend.RPCGasCap(), header.BaseFee)
+		if err != nil {
+			return nil, err
+		}
+		vmctx := core.NewEVMContext(msg, header, api.eth.blockchain, nil)
+
+		if results[i], err = traceTxReplay(ctx, api.eth, msg, vmctx, statedb, nil, replays[i], config); err != nil {
+			return nil, fmt.Errorf("call %d failed: %v", i, err)
+		}
+	}
+	return results, nil
+}
+
+// RawTransaction executes the given signed transaction on top of the latest
+// block, without broadcasting it, and returns the requested trace types.
+func (api *PrivateTraceAPI) RawTransaction(ctx context.Context, encodedTx hexutil.Bytes, traceTypes []string, config *TraceConfig) (*TraceReplayResult, error) {
+	replay, err := newTraceReplayConfig(traceTypes)
+	if err != nil {
+		return nil, err
+	}
+	tx := new(types.Transaction)
+	if err := rlp.DecodeBytes(encodedTx, tx); err != nil {
+		return nil, err
+	}
+	statedb, header, err := stateAndHeaderForCall(ctx, api.eth, rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber), config)
+	if err != nil {
+		return nil, err
+	}
+	signer := types.MakeSigner(api.eth.blockchain.Config(), header.Number)
+	msg, err := tx.AsMessage(signer, header.BaseFee)
+	if err != nil {
+		return nil, err
+	}
+	vmctx := core.NewEVMContext(msg, header, api.eth.blockchain, nil)
+
+	return traceTxReplay(ctx, api.eth, msg, vmctx, statedb, nil, replay, config)
+}
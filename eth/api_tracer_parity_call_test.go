@@ -0,0 +1,199 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/params/types/genesisT"
+	"github.com/ethereum/go-ethereum/params/vars"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+var traceTestRecipient = common.HexToAddress("0x1000")
+
+// newTraceTestEthereum creates an archive chain of n blocks, each with any content
+// added by gen followed by a transfer of 1000 wei from the test bank to
+// traceTestRecipient, and returns an Ethereum service backed by it for exercising
+// the trace APIs.
+func newTraceTestEthereum(t *testing.T, n int, gen func(int, *core.BlockGen)) *Ethereum {
+	t.Helper()
+
+	var (
+		db     = rawdb.NewMemoryDatabase()
+		engine = ethash.NewFaker()
+		signer = types.HomesteadSigner{}
+		gspec  = &genesisT.Genesis{
+			Config: params.TestChainConfig,
+			Alloc:  genesisT.GenesisAlloc{testBank: {Balance: big.NewInt(vars.Ether)}},
+		}
+	)
+	genesis := core.MustCommitGenesis(db, gspec)
+	blocks, _ := core.GenerateChain(gspec.Config, genesis, engine, db, n, func(i int, block *core.BlockGen) {
+		if gen != nil {
+			gen(i, block)
+		}
+		tx, _ := types.SignTx(types.NewTransaction(block.TxNonce(testBank), traceTestRecipient, big.NewInt(1000), vars.TxGas, nil, nil), signer, testBankKey)
+		block.AddTx(tx)
+	})
+	chain, err := core.NewBlockChain(db, &core.CacheConfig{TrieDirtyDisabled: true}, gspec.Config, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	t.Cleanup(chain.Stop)
+
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	eth := &Ethereum{blockchain: chain, chainDb: db, engine: engine, config: &Config{RPCGasCap: 25000000}}
+	eth.APIBackend = &EthAPIBackend{eth: eth}
+	return eth
+}
+
+// encodeStateDiff returns the JSON encoding of the state diff of the given
+// account, or an empty string if the account is not part of the diff.
+func encodeStateDiff(t *testing.T, result *TraceReplayResult, addr common.Address) string {
+	t.Helper()
+
+	diff, ok := result.StateDiff[addr]
+	if !ok {
+		return ""
+	}
+	blob, err := json.Marshal(diff)
+	if err != nil {
+		t.Fatalf("failed to encode state diff: %v", err)
+	}
+	return string(blob)
+}
+
+// Tests that trace_call runs on top of the state after the requested block, both
+// when the state is available and when it needs to be regenerated.
+func TestTraceCall(t *testing.T) {
+	var (
+		eth   = newTraceTestEthereum(t, 3, nil)
+		api   = NewPrivateTraceAPI(eth)
+		block = rpc.BlockNumberOrHashWithNumber(2)
+		args  = ethapi.CallArgs{From: &testBank, To: &traceTestRecipient, Value: (*hexutil.Big)(big.NewInt(1))}
+		want  = `{"balance":{"*":{"from":"0xde0b6b3a763f830","to":"0xde0b6b3a763f82f"}},"code":"=","nonce":{"*":{"from":"0x2","to":"0x3"}},"storage":{}}`
+	)
+	result, err := api.Call(context.Background(), args, []string{"stateDiff"}, &block, nil)
+	if err != nil {
+		t.Fatalf("failed to trace call: %v", err)
+	}
+	if diff := encodeStateDiff(t, result, testBank); diff != want {
+		t.Errorf("sender state diff mismatch:\nhave %s\nwant %s", diff, want)
+	}
+	// Drop the state of the block and ensure the regenerated one matches
+	rawdb.DeleteTrieNode(eth.chainDb, eth.blockchain.GetBlockByNumber(2).Root())
+	if _, err := eth.blockchain.StateAt(eth.blockchain.GetBlockByNumber(2).Root()); err == nil {
+		t.Fatalf("state of block 2 still available")
+	}
+	result, err = api.Call(context.Background(), args, []string{"stateDiff"}, &block, nil)
+	if err != nil {
+		t.Fatalf("failed to trace call on regenerated state: %v", err)
+	}
+	if diff := encodeStateDiff(t, result, testBank); diff != want {
+		t.Errorf("sender state diff mismatch on regenerated state:\nhave %s\nwant %s", diff, want)
+	}
+}
+
+// Tests that every call of a trace_callMany batch sees the state changes made
+// by the previous ones.
+func TestTraceCallMany(t *testing.T) {
+	var (
+		api   = NewPrivateTraceAPI(newTraceTestEthereum(t, 3, nil))
+		from  = common.HexToAddress("0x2000")
+		to    = common.HexToAddress("0x3000")
+		calls = []TraceCallRequest{
+			{
+				Args:       ethapi.CallArgs{From: &testBank, To: &from, Value: (*hexutil.Big)(big.NewInt(1000))},
+				TraceTypes: []string{"stateDiff"},
+			},
+			{
+				Args:       ethapi.CallArgs{From: &from, To: &to, Value: (*hexutil.Big)(big.NewInt(400))},
+				TraceTypes: []string{"trace", "stateDiff"},
+			},
+		}
+	)
+	results, err := api.CallMany(context.Background(), calls, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to trace calls: %v", err)
+	}
+	if len(results) != len(calls) {
+		t.Fatalf("result count mismatch: have %d, want %d", len(results), len(calls))
+	}
+	if results[0].Trace != nil {
+		t.Errorf("call 0: unrequested trace returned")
+	}
+	if len(results[1].Trace) != 1 {
+		t.Errorf("call 1: trace count mismatch: have %d, want 1", len(results[1].Trace))
+	}
+	want := `{"balance":{"+":"0x3e8"},"code":{"+":"0x"},"nonce":{"+":"0x0"},"storage":{}}`
+	if diff := encodeStateDiff(t, results[0], from); diff != want {
+		t.Errorf("call 0: recipient state diff mismatch:\nhave %s\nwant %s", diff, want)
+	}
+	want = `{"balance":{"*":{"from":"0x3e8","to":"0x258"}},"code":"=","nonce":{"*":{"from":"0x0","to":"0x1"}},"storage":{}}`
+	if diff := encodeStateDiff(t, results[1], from); diff != want {
+		t.Errorf("call 1: sender state diff mismatch:\nhave %s\nwant %s", diff, want)
+	}
+	if _, err := api.CallMany(context.Background(), nil, nil, nil); err == nil {
+		t.Errorf("empty call batch accepted")
+	}
+}
+
+// Tests that trace_rawTransaction executes signed transactions on top of the
+// latest state.
+func TestTraceRawTransaction(t *testing.T) {
+	api := NewPrivateTraceAPI(newTraceTestEthereum(t, 3, nil))
+
+	encode := func(nonce uint64) hexutil.Bytes {
+		tx, _ := types.SignTx(types.NewTransaction(nonce, traceTestRecipient, big.NewInt(1000), vars.TxGas, big.NewInt(1), nil), types.HomesteadSigner{}, testBankKey)
+		blob, err := rlp.EncodeToBytes(tx)
+		if err != nil {
+			t.Fatalf("failed to encode transaction: %v", err)
+		}
+		return blob
+	}
+	result, err := api.RawTransaction(context.Background(), encode(3), []string{"trace", "stateDiff"}, nil)
+	if err != nil {
+		t.Fatalf("failed to trace transaction: %v", err)
+	}
+	if len(result.Trace) != 1 {
+		t.Errorf("trace count mismatch: have %d, want 1", len(result.Trace))
+	}
+	want := `{"balance":{"*":{"from":"0xbb8","to":"0xfa0"}},"code":"=","nonce":"=","storage":{}}`
+	if diff := encodeStateDiff(t, result, traceTestRecipient); diff != want {
+		t.Errorf("recipient state diff mismatch:\nhave %s\nwant %s", diff, want)
+	}
+	// Transactions already included in the chain must be rejected
+	if _, err := api.RawTransaction(context.Background(), encode(0), []string{"trace"}, nil); err == nil {
+		t.Errorf("stale transaction accepted")
+	}
+}
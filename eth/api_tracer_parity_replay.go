@@ -0,0 +1,370 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	// TraceTypeTrace requests the Parity formatted call traces of a transaction.
+	TraceTypeTrace = "trace"
+
+	// TraceTypeStateDiff requests the state changes made by a transaction.
+	TraceTypeStateDiff = "stateDiff"
+
+	// TraceTypeVMTrace requests the full virtual machine execution trace.
+	TraceTypeVMTrace = "vmTrace"
+)
+
+// TraceReplayResult is the Parity formatted result of replaying a transaction.
+// Outputs which were not requested are left nil.
+type TraceReplayResult struct {
+	Output          hexutil.Bytes                        `json:"output"`
+	StateDiff       map[common.Address]*StateDiffAccount `json:"stateDiff"`
+	Trace           []interface{}                        `json:"trace"`
+	VMTrace         *VMTrace                             `json:"vmTrace"`
+	TransactionHash *common.Hash                         `json:"transactionHash,omitempty"`
+}
+
+// StateDiffAccount is the Parity formatted difference of a single account. Each
+// field is either "=" when unchanged, {"+": value} when the account was created,
+// {"-": value} when it was removed, or {"*": {"from": old, "to": new}}.
+type StateDiffAccount struct {
+	Balance interface{}                 `json:"balance"`
+	Code    interface{}                 `json:"code"`
+	Nonce   interface{}                 `json:"nonce"`
+	Storage map[common.Hash]interface{} `json:"storage"`
+}
+
+// StateDiffChange is a modified value within a StateDiffAccount.
+type StateDiffChange struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// traceReplayConfig is the set of outputs requested from a replay.
+type traceReplayConfig struct {
+	trace     bool
+	stateDiff bool
+	vmTrace   bool
+}
+
+// newTraceReplayConfig parses the requested trace types.
+func newTraceReplayConfig(traceTypes []string) (*traceReplayConfig, error) {
+	replay := new(traceReplayConfig)
+	for _, traceType := range traceTypes {
+		switch traceType {
+		case TraceTypeTrace:
+			replay.trace = true
+		case TraceTypeStateDiff:
+			replay.stateDiff = true
+		case TraceTypeVMTrace:
+			replay.vmTrace = true
+		default:
+			return nil, fmt.Errorf("invalid trace type %q", traceType)
+		}
+	}
+	return replay, nil
+}
+
+// ReplayBlockTransactions replays all the transactions of a block, returning the
+// requested trace types for each of them.
+func (api *PrivateTraceAPI) ReplayBlockTransactions(ctx context.Context, number rpc.BlockNumber, traceTypes []string, config *TraceConfig) ([]*TraceReplayResult, error) {
+	replay, err := newTraceReplayConfig(traceTypes)
+	if err != nil {
+		return nil, err
+	}
+	block, err := api.blockByNumber(number)
+	if err != nil {
+		return nil, err
+	}
+	if block.NumberU64() == 0 {
+		return nil, fmt.Errorf("genesis is not traceable")
+	}
+	parent := api.eth.blockchain.GetBlock(block.ParentHash(), block.NumberU64()-1)
+	if parent == nil {
+		return nil, fmt.Errorf("parent %#x not found", block.ParentHash())
+	}
+	reexec := defaultTraceReexec
+	if config != nil && config.Reexec != nil {
+		reexec = *config.Reexec
+	}
+	statedb, err := computeStateDB(api.eth, parent, reexec)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		signer  = types.MakeSigner(api.eth.blockchain.Config(), block.Number())
+		results = make([]*TraceReplayResult, len(block.Transactions()))
+	)
+	for i, tx := range block.Transactions() {
+		msg, err := tx.AsMessage(signer, block.BaseFee())
+		if err != nil {
+			return nil, fmt.Errorf("transaction %#x failed: %v", tx.Hash(), err)
+		}
+		vmctx := core.NewEVMContext(msg, block.Header(), api.eth.blockchain, nil)
+		statedb.Prepare(tx.Hash(), block.Hash(), i)
+
+		extraContext := map[string]interface{}{
+			"blockNumber":         block.NumberU64(),
+			"blockHash":           block.Hash().Hex(),
+			"transactionHash":     tx.Hash().Hex(),
+			"transactionPosition": uint64(i),
+		}
+		result, err := traceTxReplay(ctx, api.eth, msg, vmctx, statedb, extraContext, replay, config)
+		if err != nil {
+			return nil, fmt.Errorf("transaction %#x failed: %v", tx.Hash(), err)
+		}
+		hash := tx.Hash()
+		result.TransactionHash = &hash
+		results[i] = result
+	}
+	return results, nil
+}
+
+// ReplayTransaction replays a transaction, returning the requested trace types.
+func (api *PrivateTraceAPI) ReplayTransaction(ctx context.Context, hash common.Hash, traceTypes []string, config *TraceConfig) (*TraceReplayResult, error) {
+	replay, err := newTraceReplayConfig(traceTypes)
+	if err != nil {
+		return nil, err
+	}
+	// Retrieve the transaction and assemble its EVM context
+	tx, blockHash, _, index := rawdb.ReadTransaction(api.eth.ChainDb(), hash)
+	if tx == nil {
+		return nil, fmt.Errorf("transaction %#x not found", hash)
+	}
+	reexec := defaultTraceReexec
+	if config != nil && config.Reexec != nil {
+		reexec = *config.Reexec
+	}
+	block := api.eth.blockchain.GetBlockByHash(blockHash)
+	if block == nil {
+		return nil, fmt.Errorf("block %#x not found", blockHash)
+	}
+	msg, vmctx, statedb, err := computeTxEnv(api.eth, block, int(index), reexec)
+	if err != nil {
+		return nil, err
+	}
+	extraContext := map[string]interface{}{
+		"blockNumber":         block.NumberU64(),
+		"blockHash":           blockHash.Hex(),
+		"transactionHash":     tx.Hash().Hex(),
+		"transactionPosition": index,
+	}
+	statedb.Prepare(tx.Hash(), blockHash, int(index))
+	return traceTxReplay(ctx, api.eth, msg, vmctx, statedb, extraContext, replay, config)
+}
+
+// traceTxReplay executes the given message in the provided environment, collecting
+// the requested Parity trace types. The state is finalised afterwards, so that
+// subsequent transactions can be replayed on top of it.
+func traceTxReplay(ctx context.Context, eth *Ethereum, message core.Message, vmctx vm.Context, statedb *state.StateDB, extraContext map[string]interface{}, replay *traceReplayConfig, config *TraceConfig) (*TraceReplayResult, error) {
+	var (
+		combined  multiTracer
+		vmTrace   *vmTracer
+		callTrace tracers.TxTracer
+		prestate  *state.StateDB
+	)
+	if replay.trace {
+		tracer, cancel, err := newTxTracer(ctx, "callTracerParity", config, extraContext)
+		if err != nil {
+			return nil, err
+		}
+		defer cancel()
+		callTrace = tracer
+		combined = append(combined, tracer)
+	}
+	if replay.vmTrace {
+		vmTrace = newVMTracer()
+		combined = append(combined, vmTrace)
+	}
+	if replay.stateDiff {
+		prestate = statedb.Copy()
+	}
+	// Run the transaction with tracing enabled.
+	vmConfig := vm.Config{NoBaseFee: true}
+	if len(combined) > 0 {
+		vmConfig = vm.Config{Debug: true, Tracer: combined, NoBaseFee: true}
+	}
+	vmenv := vm.NewEVM(vmctx, statedb, eth.blockchain.Config(), vmConfig)
+
+	result, err := core.ApplyMessage(vmenv, message, new(core.GasPool).AddGas(message.Gas()))
+	if err != nil {
+		return nil, fmt.Errorf("tracing failed: %v", err)
+	}
+	replayResult := &TraceReplayResult{Output: common.CopyBytes(result.ReturnData)}
+
+	// Only delete empty objects if EIP158/161 (a.k.a Spurious Dragon) is in effect
+	dirties := statedb.JournalDirties()
+	statedb.Finalise(vmenv.ChainConfig().IsEnabled(vmenv.ChainConfig().GetEIP161dTransition, vmctx.BlockNumber))
+
+	if callTrace != nil {
+		res, err := callTrace.GetResult()
+		if err != nil {
+			return nil, err
+		}
+		replayResult.Trace = []interface{}{}
+		if err := json.Unmarshal(res, &replayResult.Trace); err != nil {
+			return nil, err
+		}
+	}
+	if vmTrace != nil {
+		replayResult.VMTrace = vmTrace.VMTrace()
+	}
+	if prestate != nil {
+		replayResult.StateDiff = computeStateDiff(prestate, statedb, dirties)
+	}
+	return replayResult, nil
+}
+
+// computeStateDiff returns the Parity formatted differences between two states,
+// limited to the given accounts and storage slots. Accounts without any changes
+// are omitted.
+func computeStateDiff(pre, post *state.StateDB, dirties map[common.Address]map[common.Hash]struct{}) map[common.Address]*StateDiffAccount {
+	diffs := make(map[common.Address]*StateDiffAccount)
+	for addr, slots := range dirties {
+		var (
+			existed = pre.Exist(addr)
+			exists  = post.Exist(addr)
+		)
+		switch {
+		case !existed && !exists:
+			continue
+
+		case !existed:
+			diff := &StateDiffAccount{
+				Balance: map[string]interface{}{"+": (*hexutil.Big)(post.GetBalance(addr))},
+				Code:    map[string]interface{}{"+": hexutil.Bytes(post.GetCode(addr))},
+				Nonce:   map[string]interface{}{"+": hexutil.Uint64(post.GetNonce(addr))},
+				Storage: make(map[common.Hash]interface{}),
+			}
+			for slot := range slots {
+				if value := post.GetState(addr, slot); value != (common.Hash{}) {
+					diff.Storage[slot] = map[string]interface{}{"+": value}
+				}
+			}
+			diffs[addr] = diff
+
+		case !exists:
+			diff := &StateDiffAccount{
+				Balance: map[string]interface{}{"-": (*hexutil.Big)(pre.GetBalance(addr))},
+				Code:    map[string]interface{}{"-": hexutil.Bytes(pre.GetCode(addr))},
+				Nonce:   map[string]interface{}{"-": hexutil.Uint64(pre.GetNonce(addr))},
+				Storage: make(map[common.Hash]interface{}),
+			}
+			for slot := range slots {
+				if value := pre.GetState(addr, slot); value != (common.Hash{}) {
+					diff.Storage[slot] = map[string]interface{}{"-": value}
+				}
+			}
+			diffs[addr] = diff
+
+		default:
+			var (
+				changed bool
+				diff    = &StateDiffAccount{Storage: make(map[common.Hash]interface{})}
+			)
+			diff.Balance = stateDiffField(&changed, pre.GetBalance(addr).Cmp(post.GetBalance(addr)) == 0,
+				(*hexutil.Big)(pre.GetBalance(addr)), (*hexutil.Big)(post.GetBalance(addr)))
+			diff.Nonce = stateDiffField(&changed, pre.GetNonce(addr) == post.GetNonce(addr),
+				hexutil.Uint64(pre.GetNonce(addr)), hexutil.Uint64(post.GetNonce(addr)))
+			diff.Code = stateDiffField(&changed, bytes.Equal(pre.GetCode(addr), post.GetCode(addr)),
+				hexutil.Bytes(pre.GetCode(addr)), hexutil.Bytes(post.GetCode(addr)))
+
+			for slot := range slots {
+				from, to := pre.GetState(addr, slot), post.GetState(addr, slot)
+				if from != to {
+					diff.Storage[slot] = stateDiffField(&changed, false, from, to)
+				}
+			}
+			if changed {
+				diffs[addr] = diff
+			}
+		}
+	}
+	return diffs
+}
+
+// stateDiffField returns the Parity formatted difference of a single account
+// field, flagging changed if the values differ.
+func stateDiffField(changed *bool, equal bool, from, to interface{}) interface{} {
+	if equal {
+		return "="
+	}
+	*changed = true
+	return map[string]*StateDiffChange{"*": {From: from, To: to}}
+}
+
+// multiTracer is a vm.Tracer forwarding all the events to a set of tracers.
+type multiTracer []vm.Tracer
+
+// CaptureStart implements the vm.Tracer interface to initialize the tracing operation.
+func (t multiTracer) CaptureStart(from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) error {
+	for _, tracer := range t {
+		if err := tracer.CaptureStart(from, to, create, input, gas, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CaptureState implements the vm.Tracer interface to trace a single step of VM execution.
+func (t multiTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, rStack *vm.ReturnStack, rData []byte, contract *vm.Contract, depth int, err error) error {
+	for _, tracer := range t {
+		if err := tracer.CaptureState(env, pc, op, gas, cost, memory, stack, rStack, rData, contract, depth, err); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CaptureFault implements the vm.Tracer interface to trace an execution fault
+// while running an opcode.
+func (t multiTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, rStack *vm.ReturnStack, contract *vm.Contract, depth int, err error) error {
+	for _, tracer := range t {
+		if err := tracer.CaptureFault(env, pc, op, gas, cost, memory, stack, rStack, contract, depth, err); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CaptureEnd is called after the call finishes to finalize the tracing.
+func (t multiTracer) CaptureEnd(output []byte, gasUsed uint64, tm time.Duration, err error) error {
+	for _, tracer := range t {
+		if err := tracer.CaptureEnd(output, gasUsed, tm, err); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/core/vm/runtime"
+)
+
+// Tests that the vmTrace collector records the operations of nested call frames
+// along with their stack, memory and storage side effects.
+func TestVMTracer(t *testing.T) {
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+
+	// Callee storing 0x2a into slot 0
+	callee := common.HexToAddress("0x00000000000000000000000000000000000000bb")
+	statedb.SetCode(callee, common.FromHex("602a60005500"))
+
+	// Caller storing 0x2a into memory and calling the callee
+	code := common.FromHex("602a600052" + "6000600060006000600073" + callee.Hex()[2:] + "61fffff100")
+
+	tracer := newVMTracer()
+	_, _, err := runtime.Execute(code, nil, &runtime.Config{
+		State:     statedb,
+		GasLimit:  1000000,
+		EVMConfig: vm.Config{Debug: true, Tracer: tracer},
+	})
+	if err != nil {
+		t.Fatalf("failed to execute code: %v", err)
+	}
+	trace := tracer.VMTrace()
+	if !bytes.Equal(trace.Code, code) {
+		t.Fatalf("code mismatch: have %x, want %x", trace.Code, code)
+	}
+	if len(trace.Ops) != 12 {
+		t.Fatalf("operation count mismatch: have %d, want 12", len(trace.Ops))
+	}
+	for i, op := range trace.Ops {
+		if op.Ex == nil {
+			t.Fatalf("operation %d: missing execution results", i)
+		}
+		// Calls return part of their cost, everything else consumes it fully
+		if i > 0 && op.Sub == nil && trace.Ops[i-1].Ex.Used-op.Cost != op.Ex.Used {
+			t.Errorf("operation %d: gas mismatch: have %d, want %d", i, op.Ex.Used, trace.Ops[i-1].Ex.Used-op.Cost)
+		}
+	}
+	// MSTORE should report the written memory
+	mstore := trace.Ops[2]
+	if mstore.Ex.Mem == nil || mstore.Ex.Mem.Off != 0 || !bytes.Equal(mstore.Ex.Mem.Data, common.LeftPadBytes([]byte{0x2a}, 32)) {
+		t.Errorf("MSTORE memory mismatch: have %+v", mstore.Ex.Mem)
+	}
+	// DUP/PUSH style operations should report the pushed items
+	if push := trace.Ops[0].Ex.Push; len(push) != 1 || (*big.Int)(push[0]).Uint64() != 0x2a {
+		t.Errorf("PUSH1 stack mismatch: have %v", push)
+	}
+	// CALL should report success and the callee trace
+	call := trace.Ops[10]
+	if push := call.Ex.Push; len(push) != 1 || (*big.Int)(push[0]).Uint64() != 1 {
+		t.Errorf("CALL stack mismatch: have %v", push)
+	}
+	if call.Sub == nil {
+		t.Fatalf("CALL missing callee trace")
+	}
+	if !bytes.Equal(call.Sub.Code, common.FromHex("602a60005500")) {
+		t.Errorf("callee code mismatch: have %x", call.Sub.Code)
+	}
+	if len(call.Sub.Ops) != 4 {
+		t.Fatalf("callee operation count mismatch: have %d, want 4", len(call.Sub.Ops))
+	}
+	store := call.Sub.Ops[2].Ex.Store
+	if store == nil || (*big.Int)(store.Key).Sign() != 0 || (*big.Int)(store.Val).Uint64() != 0x2a {
+		t.Errorf("SSTORE storage mismatch: have %+v", store)
+	}
+	// The output must be JSON encodable in the Parity format
+	if _, err := json.Marshal(trace); err != nil {
+		t.Fatalf("failed to encode trace: %v", err)
+	}
+}
+
+// Tests that state diffs report created, removed and modified accounts in the
+// Parity format, omitting untouched or unchanged ones.
+func TestComputeStateDiff(t *testing.T) {
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+
+	var (
+		modified  = common.HexToAddress("0x01")
+		removed   = common.HexToAddress("0x02")
+		created   = common.HexToAddress("0x03")
+		unchanged = common.HexToAddress("0x04")
+		slot      = common.HexToHash("0x01")
+	)
+	statedb.SetBalance(modified, big.NewInt(10))
+	statedb.SetState(modified, slot, common.HexToHash("0xaa"))
+	statedb.SetBalance(removed, big.NewInt(5))
+	statedb.SetNonce(unchanged, 1)
+	statedb.Finalise(true)
+
+	prestate := statedb.Copy()
+
+	statedb.SetBalance(modified, big.NewInt(20))
+	statedb.SetState(modified, slot, common.HexToHash("0xbb"))
+	statedb.Suicide(removed)
+	statedb.SetNonce(created, 1)
+	statedb.SetNonce(unchanged, 2)
+	statedb.SetNonce(unchanged, 1)
+
+	dirties := statedb.JournalDirties()
+	statedb.Finalise(true)
+
+	diffs := computeStateDiff(prestate, statedb, dirties)
+	if len(diffs) != 3 {
+		t.Fatalf("account diff count mismatch: have %d, want 3", len(diffs))
+	}
+	blob, err := json.Marshal(diffs)
+	if err != nil {
+		t.Fatalf("failed to encode state diff: %v", err)
+	}
+	want := `{` +
+		`"0x0000000000000000000000000000000000000001":{"balance":{"*":{"from":"0xa","to":"0x14"}},"code":"=","nonce":"=","storage":{"0x0000000000000000000000000000000000000000000000000000000000000001":{"*":{"from":"0x00000000000000000000000000000000000000000000000000000000000000aa","to":"0x00000000000000000000000000000000000000000000000000000000000000bb"}}}},` +
+		`"0x0000000000000000000000000000000000000002":{"balance":{"-":"0x5"},"code":{"-":"0x"},"nonce":{"-":"0x0"},"storage":{}},` +
+		`"0x0000000000000000000000000000000000000003":{"balance":{"+":"0x0"},"code":{"+":"0x"},"nonce":{"+":"0x1"},"storage":{}}` +
+		`}`
+	if string(blob) != want {
+		t.Fatalf("state diff mismatch:\nhave %s\nwant %s", blob, want)
+	}
+}
@@ -1,7 +1,18 @@
 package eth
 
 import (
+	"context"
+	"encoding/json"
+	"math/big"
 	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/params/types/ctypes"
+	"github.com/ethereum/go-ethereum/rpc"
 )
 
 // BenchmarkTraceResultsAppend1 compares performance against BenchmarkTraceResultsAppend2,
@@ -39,3 +50,211 @@ func BenchmarkTraceResultsAppend2(b *testing.B) {
 		results = append(results, traceResults...) // nolint:ineffassign
 	}
 }
+
+func TestTraceFilterMatches(t *testing.T) {
+	var (
+		alice = common.HexToAddress("0x000000000000000000000000000000000000aaaa")
+		bob   = common.HexToAddress("0x000000000000000000000000000000000000bbbb")
+		carol = common.HexToAddress("0x000000000000000000000000000000000000cccc")
+	)
+	decode := func(blob string) interface{} {
+		var trace interface{}
+		if err := json.Unmarshal([]byte(blob), &trace); err != nil {
+			t.Fatalf("failed to decode trace: %v", err)
+		}
+		return trace
+	}
+	var (
+		call = decode(`{"type":"call","action":{"from":"0x000000000000000000000000000000000000aaaa","to":"0x000000000000000000000000000000000000bbbb","callType":"call"},"result":{"gasUsed":"0x0","output":"0x"}}`)
+
+		create = decode(`{"type":"create","action":{"from":"0x000000000000000000000000000000000000aaaa","init":"0x"},"result":{"gasUsed":"0x0","address":"0x000000000000000000000000000000000000cccc","code":"0x"}}`)
+
+		failedCreate = decode(`{"type":"create","action":{"from":"0x000000000000000000000000000000000000aaaa","init":"0x"},"error":"Out of gas"}`)
+
+		suicide = decode(`{"type":"suicide","action":{"address":"0x000000000000000000000000000000000000bbbb","refundAddress":"0x000000000000000000000000000000000000cccc","balance":"0x0"},"result":null}`)
+
+		reward = &ParityTrace{Type: "reward", Action: TraceRewardAction{Author: &carol, RewardType: "block"}}
+	)
+	tests := []struct {
+		trace interface{}
+		from  []common.Address
+		to    []common.Address
+		want  bool
+	}{
+		{call, nil, nil, true},
+		{call, []common.Address{alice}, nil, true},
+		{call, []common.Address{bob}, nil, false},
+		{call, nil, []common.Address{bob}, true},
+		{call, []common.Address{alice}, []common.Address{carol}, false},
+		{call, []common.Address{carol, alice}, []common.Address{carol, bob}, true},
+		{create, nil, []common.Address{carol}, true},
+		{create, []common.Address{alice}, []common.Address{bob}, false},
+		{failedCreate, []common.Address{alice}, nil, true},
+		{failedCreate, nil, []common.Address{carol}, false},
+		{suicide, []common.Address{bob}, []common.Address{carol}, true},
+		{suicide, []common.Address{alice}, nil, false},
+		{reward, nil, []common.Address{carol}, true},
+		{reward, []common.Address{carol}, nil, false},
+		{reward, nil, nil, true},
+	}
+	for i, tt := range tests {
+		if have := traceFilterMatches(tt.trace, tt.from, tt.to); have != tt.want {
+			t.Errorf("test %d: match mismatch: have %v, want %v", i, have, tt.want)
+		}
+	}
+}
+
+func TestTraceCallRequestUnmarshal(t *testing.T) {
+	var requests []TraceCallRequest
+	input := `[[{"from":"0x000000000000000000000000000000000000aaaa","to":"0x000000000000000000000000000000000000bbbb","value":"0x1"},["trace","stateDiff"]],[{"to":"0x000000000000000000000000000000000000cccc"},[]]]`
+	if err := json.Unmarshal([]byte(input), &requests); err != nil {
+		t.Fatalf("failed to decode requests: %v", err)
+	}
+	if len(requests) != 2 {
+		t.Fatalf("request count mismatch: have %d, want 2", len(requests))
+	}
+	if from := requests[0].Args.From; from == nil || *from != common.HexToAddress("0xaaaa") {
+		t.Errorf("sender mismatch: have %v", from)
+	}
+	if len(requests[0].TraceTypes) != 2 || requests[0].TraceTypes[1] != "stateDiff" {
+		t.Errorf("trace types mismatch: have %v", requests[0].TraceTypes)
+	}
+	if to := requests[1].Args.To; to == nil || *to != common.HexToAddress("0xcccc") {
+		t.Errorf("recipient mismatch: have %v", to)
+	}
+	if err := json.Unmarshal([]byte(`[[{}]]`), &requests); err == nil {
+		t.Errorf("expected error for incomplete request")
+	}
+}
+
+// Tests that trace_filter traces the requested block range, matching addresses
+// in both the trace actions and results, and paginates the matches.
+func TestTraceFilter(t *testing.T) {
+	var (
+		miner    = common.HexToAddress("0x4000")
+		contract = crypto.CreateAddress(testBank, 1)
+	)
+	// Mine 4 blocks with a transfer each, deploying a contract in block 2
+	eth := newTraceTestEthereum(t, 4, func(i int, block *core.BlockGen) {
+		block.SetCoinbase(miner)
+		if i == 1 {
+			tx, _ := types.SignTx(types.NewContractCreation(block.TxNonce(testBank), nil, 100000, nil, []byte{0x00}), types.HomesteadSigner{}, testBankKey)
+			block.AddTx(tx)
+		}
+	})
+	api := NewPrivateTraceAPI(eth)
+
+	// summary is the type and block of a single trace
+	type summary struct {
+		Type        string `json:"type"`
+		BlockNumber uint64 `json:"blockNumber"`
+	}
+	filter := func(from, to []common.Address, after, count *uint64) []summary {
+		t.Helper()
+
+		first, last := rpc.BlockNumber(1), rpc.BlockNumber(4)
+		traces, err := api.Filter(context.Background(), TraceFilterArgs{FromBlock: &first, ToBlock: &last, FromAddress: from, ToAddress: to, After: after, Count: count}, nil)
+		if err != nil {
+			t.Fatalf("failed to filter traces: %v", err)
+		}
+		blob, err := json.Marshal(traces)
+		if err != nil {
+			t.Fatalf("failed to encode traces: %v", err)
+		}
+		var summaries []summary
+		if err := json.Unmarshal(blob, &summaries); err != nil {
+			t.Fatalf("failed to decode traces: %v", err)
+		}
+		return summaries
+	}
+	uint64p := func(n uint64) *uint64 { return &n }
+
+	tests := []struct {
+		from, to     []common.Address
+		after, count *uint64
+		want         []summary
+	}{
+		// Every transaction and block reward
+		{
+			want: []summary{
+				{"call", 1}, {"reward", 1},
+				{"create", 2}, {"call", 2}, {"reward", 2},
+				{"call", 3}, {"reward", 3},
+				{"call", 4}, {"reward", 4},
+			},
+		},
+		// Senders matched on the action
+		{
+			from: []common.Address{testBank},
+			want: []summary{{"call", 1}, {"create", 2}, {"call", 2}, {"call", 3}, {"call", 4}},
+		},
+		// Recipients matched on the action
+		{
+			to:   []common.Address{traceTestRecipient},
+			want: []summary{{"call", 1}, {"call", 2}, {"call", 3}, {"call", 4}},
+		},
+		// Created contracts matched on the result
+		{
+			to:   []common.Address{contract},
+			want: []summary{{"create", 2}},
+		},
+		// Block rewards matched on the author
+		{
+			to:   []common.Address{miner},
+			want: []summary{{"reward", 1}, {"reward", 2}, {"reward", 3}, {"reward", 4}},
+		},
+		{
+			from: []common.Address{miner},
+			want: []summary{},
+		},
+		// Paginated matches
+		{
+			to:    []common.Address{traceTestRecipient},
+			after: uint64p(1), count: uint64p(2),
+			want: []summary{{"call", 2}, {"call", 3}},
+		},
+		{
+			to:    []common.Address{traceTestRecipient},
+			after: uint64p(3), count: uint64p(2),
+			want: []summary{{"call", 4}},
+		},
+		{
+			after: uint64p(100),
+			want:  []summary{},
+		},
+		{
+			count: uint64p(0),
+			want:  []summary{},
+		},
+	}
+	for i, tt := range tests {
+		have := filter(tt.from, tt.to, tt.after, tt.count)
+		if len(have) != len(tt.want) {
+			t.Errorf("test %d: trace count mismatch: have %v, want %v", i, have, tt.want)
+			continue
+		}
+		for j := range have {
+			if have[j] != tt.want[j] {
+				t.Errorf("test %d: trace %d mismatch: have %v, want %v", i, j, have[j], tt.want[j])
+			}
+		}
+	}
+	// Ensure the block rewards are reported in full
+	first := rpc.BlockNumber(1)
+	traces, err := api.Filter(context.Background(), TraceFilterArgs{FromBlock: &first, ToBlock: &first, ToAddress: []common.Address{miner}}, nil)
+	if err != nil {
+		t.Fatalf("failed to filter rewards: %v", err)
+	}
+	reward, ok := traces[0].(*ParityTrace)
+	if len(traces) != 1 || !ok {
+		t.Fatalf("reward trace mismatch: have %v", traces)
+	}
+	if reward.Action.RewardType != "block" || reward.Action.Value.ToInt().Cmp(ctypes.EthashBlockReward(params.TestChainConfig, big.NewInt(1))) != 0 || reward.BlockHash != eth.blockchain.GetBlockByNumber(1).Hash() {
+		t.Errorf("reward trace mismatch: have %+v", reward.Action)
+	}
+	// Ensure inverted ranges are rejected
+	from, to := rpc.BlockNumber(3), rpc.BlockNumber(2)
+	if _, err := api.Filter(context.Background(), TraceFilterArgs{FromBlock: &from, ToBlock: &to}, nil); err == nil {
+		t.Errorf("inverted block range accepted")
+	}
+}
@@ -0,0 +1,276 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"errors"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/vm"
+)
+
+// VMTrace is the Parity formatted virtual machine execution trace of a single
+// call frame. See: https://openethereum.github.io/wiki/JSONRPC-trace-module
+type VMTrace struct {
+	Code hexutil.Bytes `json:"code"` // Code executed by the call frame
+	Ops  []*VMTraceOp  `json:"ops"`  // Operations executed by the call frame
+}
+
+// VMTraceOp is a single operation executed within a VMTrace.
+type VMTraceOp struct {
+	Cost uint64     `json:"cost"` // Gas cost of the operation
+	Ex   *VMTraceEx `json:"ex"`   // Execution results, nil if the operation failed
+	Pc   uint64     `json:"pc"`   // Program counter of the operation
+	Sub  *VMTrace   `json:"sub"`  // Trace of the call frame entered by the operation, if any
+}
+
+// VMTraceEx holds the results of an executed operation.
+type VMTraceEx struct {
+	Mem   *VMTraceMem    `json:"mem"`   // Memory written by the operation
+	Push  []*hexutil.Big `json:"push"`  // Stack items pushed by the operation
+	Store *VMTraceStore  `json:"store"` // Storage slot written by the operation
+	Used  uint64         `json:"used"`  // Gas remaining after the operation
+}
+
+// VMTraceMem is a memory region written by an operation.
+type VMTraceMem struct {
+	Data hexutil.Bytes `json:"data"`
+	Off  uint64        `json:"off"`
+}
+
+// VMTraceStore is a storage slot written by an operation.
+type VMTraceStore struct {
+	Key *hexutil.Big `json:"key"`
+	Val *hexutil.Big `json:"val"`
+}
+
+// vmTraceFrame is a call frame being traced by the vmTracer.
+type vmTraceFrame struct {
+	trace *VMTrace
+
+	pending *VMTraceOp    // Last operation of the frame, awaiting its results
+	gas     uint64        // Gas available before the pending operation
+	pushes  int           // Number of stack items pushed by the pending operation
+	memOff  uint64        // Offset of the memory written by the pending operation
+	memSize uint64        // Size of the memory written by the pending operation
+	store   *VMTraceStore // Storage slot written by the pending operation
+}
+
+// vmTracer is a vm.Tracer collecting the Parity formatted VMTrace of a
+// transaction execution.
+//
+// The results of an operation only become available once the next operation in
+// the same call frame is about to be executed, so each frame keeps its last
+// operation pending until then.
+type vmTracer struct {
+	root   *VMTrace
+	frames []*vmTraceFrame
+}
+
+// newVMTracer creates a new Parity VMTrace collector.
+func newVMTracer() *vmTracer {
+	return &vmTracer{}
+}
+
+// CaptureStart implements the vm.Tracer interface to initialize the tracing operation.
+func (t *vmTracer) CaptureStart(from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) error {
+	t.root = &VMTrace{Ops: []*VMTraceOp{}}
+	t.frames = []*vmTraceFrame{{trace: t.root}}
+	return nil
+}
+
+// CaptureState implements the vm.Tracer interface to trace a single step of VM execution.
+func (t *vmTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, rStack *vm.ReturnStack, rData []byte, contract *vm.Contract, depth int, err error) error {
+	if len(t.frames) == 0 {
+		return nil
+	}
+	switch {
+	case depth > len(t.frames):
+		// Entered a new call frame, attach it to the operation which created it
+		trace := &VMTrace{Code: common.CopyBytes(contract.Code), Ops: []*VMTraceOp{}}
+		if parent := t.frames[len(t.frames)-1].pending; parent != nil {
+			parent.Sub = trace
+		}
+		t.frames = append(t.frames, &vmTraceFrame{trace: trace})
+
+	case depth < len(t.frames):
+		// Returned from inner call frames, finalise their last operations
+		for len(t.frames) > depth {
+			t.frames[len(t.frames)-1].finalise()
+			t.frames = t.frames[:len(t.frames)-1]
+		}
+		fallthrough
+
+	default:
+		// Still within the same call frame, the previous operation is now done
+		frame := t.frames[len(t.frames)-1]
+		frame.complete(gas, memory, stack)
+	}
+	frame := t.frames[len(t.frames)-1]
+	if frame.trace.Code == nil {
+		frame.trace.Code = common.CopyBytes(contract.Code)
+	}
+	operation := &VMTraceOp{Pc: pc, Cost: cost}
+	frame.trace.Ops = append(frame.trace.Ops, operation)
+
+	// Operations failing before execution have no results
+	if err != nil {
+		frame.pending = nil
+		return nil
+	}
+	frame.pending, frame.gas = operation, gas
+	frame.pushes = vmTracePushes(op)
+	frame.memOff, frame.memSize = vmTraceMemoryWritten(op, stack)
+	frame.store = nil
+	if op == vm.SSTORE && len(stack.Data()) >= 2 {
+		frame.store = &VMTraceStore{
+			Key: (*hexutil.Big)(stack.Back(0).ToBig()),
+			Val: (*hexutil.Big)(stack.Back(1).ToBig()),
+		}
+	}
+	return nil
+}
+
+// CaptureFault implements the vm.Tracer interface to trace an execution fault
+// while running an opcode.
+func (t *vmTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, rStack *vm.ReturnStack, contract *vm.Contract, depth int, err error) error {
+	if depth == 0 || depth > len(t.frames) {
+		return nil
+	}
+	// Reverts are regular operations, only the frame is unwound
+	if errors.Is(err, vm.ErrExecutionReverted) {
+		return nil
+	}
+	// Failed operations have no results
+	t.frames[depth-1].pending = nil
+	return nil
+}
+
+// CaptureEnd is called after the call finishes to finalize the tracing.
+func (t *vmTracer) CaptureEnd(output []byte, gasUsed uint64, tm time.Duration, err error) error {
+	for len(t.frames) > 0 {
+		t.frames[len(t.frames)-1].finalise()
+		t.frames = t.frames[:len(t.frames)-1]
+	}
+	return nil
+}
+
+// VMTrace returns the collected VMTrace, or nil if nothing was executed.
+func (t *vmTracer) VMTrace() *VMTrace {
+	return t.root
+}
+
+// complete fills in the results of the pending operation of a frame, given the
+// state of the frame right before its next operation executes.
+func (f *vmTraceFrame) complete(gas uint64, memory *vm.Memory, stack *vm.Stack) {
+	if f.pending == nil {
+		return
+	}
+	ex := &VMTraceEx{Used: gas, Push: []*hexutil.Big{}, Store: f.store}
+	if data := stack.Data(); f.pushes > 0 && len(data) >= f.pushes {
+		for _, item := range data[len(data)-f.pushes:] {
+			ex.Push = append(ex.Push, (*hexutil.Big)(item.ToBig()))
+		}
+	}
+	if f.memSize > 0 && f.memOff+f.memSize <= uint64(memory.Len()) {
+		ex.Mem = &VMTraceMem{
+			Data: memory.GetCopy(int64(f.memOff), int64(f.memSize)),
+			Off:  f.memOff,
+		}
+	}
+	f.pending.Ex, f.pending = ex, nil
+}
+
+// finalise fills in the results of the last operation of a frame which will
+// not execute any further operations.
+func (f *vmTraceFrame) finalise() {
+	if f.pending == nil {
+		return
+	}
+	used := uint64(0)
+	if f.gas >= f.pending.Cost {
+		used = f.gas - f.pending.Cost
+	}
+	f.pending.Ex, f.pending = &VMTraceEx{Used: used, Push: []*hexutil.Big{}, Store: f.store}, nil
+}
+
+// vmTracePushes returns the number of stack items reported as pushed by an
+// operation, which for DUP and SWAP operations includes all the items they
+// touch.
+func vmTracePushes(op vm.OpCode) int {
+	switch {
+	case op >= vm.PUSH1 && op <= vm.PUSH32:
+		return 1
+	case op >= vm.DUP1 && op <= vm.DUP16:
+		return int(op-vm.DUP1) + 2
+	case op >= vm.SWAP1 && op <= vm.SWAP16:
+		return int(op-vm.SWAP1) + 2
+	case op >= vm.LOG0 && op <= vm.LOG4:
+		return 0
+	}
+	switch op {
+	case vm.STOP, vm.POP, vm.MSTORE, vm.MSTORE8, vm.SSTORE, vm.JUMP, vm.JUMPI, vm.JUMPDEST,
+		vm.CALLDATACOPY, vm.CODECOPY, vm.EXTCODECOPY, vm.RETURNDATACOPY,
+		vm.BEGINSUB, vm.JUMPSUB, vm.RETURNSUB,
+		vm.RETURN, vm.REVERT, vm.SELFDESTRUCT:
+		return 0
+	}
+	return 1
+}
+
+// vmTraceMemoryWritten returns the memory region written by an operation, given
+// the stack before it executes.
+func vmTraceMemoryWritten(op vm.OpCode, stack *vm.Stack) (uint64, uint64) {
+	var offset, size int
+	switch op {
+	case vm.MSTORE, vm.MLOAD:
+		offset, size = 0, -32
+	case vm.MSTORE8:
+		offset, size = 0, -1
+	case vm.CALLDATACOPY, vm.CODECOPY, vm.RETURNDATACOPY:
+		offset, size = 0, 2
+	case vm.EXTCODECOPY:
+		offset, size = 1, 3
+	case vm.CALL, vm.CALLCODE:
+		offset, size = 5, 6
+	case vm.DELEGATECALL, vm.STATICCALL:
+		offset, size = 4, 5
+	default:
+		return 0, 0
+	}
+	// Negative sizes denote fixed sized writes
+	if len(stack.Data()) <= offset || (size > 0 && len(stack.Data()) <= size) {
+		return 0, 0
+	}
+	off, overflow := stack.Back(offset).Uint64WithOverflow()
+	if overflow {
+		return 0, 0
+	}
+	length := uint64(-size)
+	if size > 0 {
+		if length, overflow = stack.Back(size).Uint64WithOverflow(); overflow {
+			return 0, 0
+		}
+	}
+	if length == 0 || off+length < off {
+		return 0, 0
+	}
+	return off, length
+}
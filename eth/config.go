@@ -123,6 +123,7 @@ type Config struct {
 	NoPrefetch bool // Whether to disable prefetching and only load state on demand
 
 	TxLookupLimit uint64 `toml:",omitempty"` // The maximum number of blocks from head whose tx indices are reserved.
+	AddressIndex  bool   `toml:",omitempty"` // Whether to maintain an index of the transactions touching every address
 
 	// Whitelist of required block number -> hash values to accept
 	Whitelist map[uint64]common.Hash `toml:"-"`
@@ -140,11 +141,12 @@ type Config struct {
 	UltraLightOnlyAnnounce bool     `toml:",omitempty"` // Whether to only announce headers, or also serve them
 
 	// Database options
-	SkipBcVersionCheck    bool `toml:"-"`
-	DatabaseHandles       int  `toml:"-"`
-	DatabaseCache         int
-	DatabaseFreezer       string
-	DatabaseFreezerRemote string
+	SkipBcVersionCheck            bool `toml:"-"`
+	DatabaseHandles               int  `toml:"-"`
+	DatabaseCache                 int
+	DatabaseFreezer               string
+	DatabaseFreezerRemote         string
+	DatabaseFreezerRemoteReadOnly bool
 
 	TrieCleanCache          int
 	TrieCleanCacheJournal   string        `toml:",omitempty"` // Disk journal directory for trie cache to survive node restarts
@@ -102,6 +102,7 @@ type EventSystem struct {
 	pendingLogsCh chan []*types.Log          // Channel to receive new log event
 	rmLogsCh      chan core.RemovedLogsEvent // Channel to receive removed log event
 	chainCh       chan core.ChainEvent       // Channel to receive new chain event
+	quit          chan struct{}              // Channel to terminate the event loop
 }
 
 // NewEventSystem creates a new manager that listens for event on the given mux,
@@ -121,6 +122,7 @@ func NewEventSystem(backend Backend, lightMode bool) *EventSystem {
 		rmLogsCh:      make(chan core.RemovedLogsEvent, rmLogsChanSize),
 		pendingLogsCh: make(chan []*types.Log, logsChanSize),
 		chainCh:       make(chan core.ChainEvent, chainEvChanSize),
+		quit:          make(chan struct{}),
 	}
 
 	// Subscribe events
@@ -139,6 +141,12 @@ func NewEventSystem(backend Backend, lightMode bool) *EventSystem {
 	return m
 }
 
+// Stop terminates the event loop, unsubscribing from the backend events. Every
+// subscription created by the system must be unsubscribed before.
+func (es *EventSystem) Stop() {
+	close(es.quit)
+}
+
 // Subscription is created when the client registers itself for a particular event.
 type Subscription struct {
 	ID        rpc.ID
@@ -489,6 +497,8 @@ func (es *EventSystem) eventLoop() {
 			close(f.err)
 
 		// System stopped
+		case <-es.quit:
+			return
 		case <-es.txsSub.Err():
 			return
 		case <-es.logsSub.Err():
@@ -0,0 +1,91 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package gasprice
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/params/vars"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+func TestFeeHistory(t *testing.T) {
+	var cases = []struct {
+		count    int
+		last     rpc.BlockNumber
+		percent  []float64
+		expFirst uint64
+		expCount int
+		expErr   error
+	}{
+		{0, 30, nil, 0, 0, nil},
+		{1, 30, nil, 30, 1, nil},
+		{4, 30, nil, 27, 4, nil},
+		{4, rpc.LatestBlockNumber, []float64{0, 50}, 29, 4, nil},
+		{40, 10, nil, 0, 11, nil},
+		{1, 33, nil, 0, 0, errRequestBeyondHead},
+		{2, 30, []float64{50, 10}, 0, 0, errInvalidPercentile},
+		{2, 30, []float64{101}, 0, 0, errInvalidPercentile},
+	}
+	backend := newTestBackend(t)
+	oracle := NewOracle(backend, Config{Blocks: 20, Percentile: 60})
+
+	for i, c := range cases {
+		first, reward, baseFee, ratio, err := oracle.FeeHistory(context.Background(), c.count, c.last, c.percent)
+		if !errors.Is(err, c.expErr) {
+			t.Fatalf("Test case %d: error mismatch, want %v, got %v", i, c.expErr, err)
+		}
+		if err != nil {
+			continue
+		}
+		if c.expCount > 0 && first.Uint64() != c.expFirst {
+			t.Fatalf("Test case %d: first block mismatch, want %d, got %d", i, c.expFirst, first)
+		}
+		if len(ratio) != c.expCount {
+			t.Fatalf("Test case %d: gasUsedRatio array length mismatch, want %d, got %d", i, c.expCount, len(ratio))
+		}
+		expBaseFee := c.expCount
+		if expBaseFee != 0 {
+			expBaseFee++
+		}
+		if len(baseFee) != expBaseFee {
+			t.Fatalf("Test case %d: baseFee array length mismatch, want %d, got %d", i, expBaseFee, len(baseFee))
+		}
+		if len(c.percent) == 0 {
+			if reward != nil {
+				t.Fatalf("Test case %d: unexpected reward array", i)
+			}
+			continue
+		}
+		if len(reward) != c.expCount {
+			t.Fatalf("Test case %d: reward array length mismatch, want %d, got %d", i, c.expCount, len(reward))
+		}
+		// Every test block carries a single transaction priced at (number)G,
+		// which pre-London is also its effective tip.
+		for j, rewards := range reward {
+			want := big.NewInt(int64(c.expFirst+uint64(j)) * vars.GWei)
+			for _, r := range rewards {
+				if r.Cmp(want) != 0 {
+					t.Fatalf("Test case %d: reward mismatch at block %d, want %v, got %v", i, c.expFirst+uint64(j), want, r)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,1635 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snap
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+var (
+	// emptyRoot is the known root hash of an empty trie.
+	emptyRoot = common.HexToHash("56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421")
+
+	// emptyCode is the known hash of the empty EVM bytecode.
+	emptyCode = crypto.Keccak256Hash(nil)
+)
+
+const (
+	// maxRequestSize is the maximum number of bytes to request from a remote peer.
+	maxRequestSize = 512 * 1024
+
+	// maxStorageSetFetchCount is the maximum number of contracts to request the
+	// storage of in a single query. If this number is too low, we're not filling
+	// responses fully and waste round trip times. If it's too high, we're capping
+	// responses and waste bandwidth.
+	maxStorageSetFetchCount = maxRequestSize / 1024
+
+	// maxCodeRequestCount is the maximum number of bytecode blobs to request in a
+	// single query. If this number is too low, we're not filling responses fully
+	// and waste round trip times. If it's too high, we're capping responses and
+	// waste bandwidth.
+	//
+	// Deployed bytecodes are currently capped at 24KB, so the minimum request
+	// size should be maxRequestSize / 24K. Assuming that most contracts do not
+	// come close to that, requesting 4x should be a good approximation.
+	maxCodeRequestCount = maxRequestSize / (24 * 1024) * 4
+
+	// maxTrieRequestCount is the maximum number of trie node blobs to request in
+	// a single query. If this number is too low, we're not filling responses fully
+	// and waste round trip times. If it's too high, we're capping responses and
+	// waste bandwidth.
+	maxTrieRequestCount = 512
+
+	// requestTimeout is the maximum time a peer is allowed to spend on serving
+	// a single network request.
+	requestTimeout = 10 * time.Second
+
+	// accountConcurrency is the number of chunks to split the account trie into
+	// to allow concurrent retrievals.
+	accountConcurrency = 16
+)
+
+// ErrCancelled is returned from snap syncing if the operation was prematurely
+// terminated.
+var ErrCancelled = errors.New("sync cancelled")
+
+// SyncPeer abstracts out the methods required for a peer to be synced against
+// with the goal of allowing the construction of mock peers without the full
+// blown networking.
+type SyncPeer interface {
+	// ID retrieves the peer's unique identifier.
+	ID() string
+
+	// RequestAccountRange fetches a batch of accounts rooted in a specific account
+	// trie, starting with the origin.
+	RequestAccountRange(id uint64, root, origin, limit common.Hash, bytes uint64) error
+
+	// RequestStorageRanges fetches a batch of storage slots belonging to one or
+	// more accounts. If slots from only one account is requested, an origin marker
+	// may also be used to retrieve from there.
+	RequestStorageRanges(id uint64, root common.Hash, accounts []common.Hash, origin, limit []byte, bytes uint64) error
+
+	// RequestByteCodes fetches a batch of bytecodes by hash.
+	RequestByteCodes(id uint64, hashes []common.Hash, bytes uint64) error
+
+	// RequestTrieNodes fetches a batch of account or storage trie nodes rooted in
+	// a specific state trie.
+	RequestTrieNodes(id uint64, root common.Hash, paths []TrieNodePathSet, bytes uint64) error
+
+	// Log retrieves the peer's own contextual logger.
+	Log() log.Logger
+}
+
+// accountRequest tracks a pending account range request to ensure responses are
+// to actual requests and to validate any security constraints.
+type accountRequest struct {
+	peer string // Peer to which this request is assigned
+	id   uint64 // Request ID of this request
+
+	deliver chan *accountResponse // Channel to deliver the response on
+	quit    chan struct{}         // Channel closed when the owning sync cycle terminates
+	timeout *time.Timer           // Timer to track delivery timeout
+
+	root   common.Hash  // State root the range is requested from
+	origin common.Hash  // First account requested to allow continuation checks
+	limit  common.Hash  // Last account requested to allow non-overlapping chunking
+	task   *accountTask // Task which this request is filling
+}
+
+// fail signals the sync loop that the request is void and needs rescheduling.
+func (req *accountRequest) fail() {
+	select {
+	case req.deliver <- &accountResponse{req: req, failed: true}:
+	case <-req.quit:
+	}
+}
+
+// accountResponse is an already verified remote response to an account range
+// request, or a notification that the request failed.
+type accountResponse struct {
+	req    *accountRequest // Original request this is a response for
+	failed bool            // Whether the request failed and needs rescheduling
+
+	hashes   []common.Hash    // Account hashes in the returned range
+	accounts []*state.Account // Expanded accounts in the returned range
+	blobs    [][]byte         // Consensus encoded accounts to feed into the trie
+	cont     bool             // Whether the account range has a continuation
+}
+
+// storageRequest tracks a pending storage ranges request to ensure responses are
+// to actual requests and to validate any security constraints.
+type storageRequest struct {
+	peer string // Peer to which this request is assigned
+	id   uint64 // Request ID of this request
+
+	deliver chan *storageResponse // Channel to deliver the response on
+	quit    chan struct{}         // Channel closed when the owning sync cycle terminates
+	timeout *time.Timer           // Timer to track delivery timeout
+
+	root     common.Hash   // State root the ranges are requested from
+	accounts []common.Hash // Account hashes to validate responses
+	roots    []common.Hash // Storage roots to validate responses
+	origin   common.Hash   // First storage slot requested to allow continuation checks
+	task     *accountTask  // Task which this request is filling
+}
+
+// fail signals the sync loop that the request is void and needs rescheduling.
+func (req *storageRequest) fail() {
+	select {
+	case req.deliver <- &storageResponse{req: req, failed: true}:
+	case <-req.quit:
+	}
+}
+
+// storageResponse is an already verified remote response to a storage ranges
+// request, or a notification that the request failed.
+type storageResponse struct {
+	req    *storageRequest // Original request this is a response for
+	failed bool            // Whether the request failed and needs rescheduling
+
+	hashes [][]common.Hash // Storage slot hashes in the returned ranges
+	slots  [][][]byte      // Storage slot values in the returned ranges
+	cont   bool            // Whether the last storage range has a continuation
+}
+
+// bytecodeRequest tracks a pending bytecode request to ensure responses are to
+// actual requests and to validate any security constraints.
+type bytecodeRequest struct {
+	peer string // Peer to which this request is assigned
+	id   uint64 // Request ID of this request
+
+	deliver chan *bytecodeResponse // Channel to deliver the response on
+	quit    chan struct{}          // Channel closed when the owning sync cycle terminates
+	timeout *time.Timer            // Timer to track delivery timeout
+
+	hashes []common.Hash // Bytecode hashes to validate responses
+	task   *accountTask  // Task which this request is filling (nil if healing)
+}
+
+// fail signals the sync loop that the request is void and needs rescheduling.
+func (req *bytecodeRequest) fail() {
+	select {
+	case req.deliver <- &bytecodeResponse{req: req, failed: true}:
+	case <-req.quit:
+	}
+}
+
+// bytecodeResponse is an already verified remote response to a bytecode request,
+// or a notification that the request failed.
+type bytecodeResponse struct {
+	req    *bytecodeRequest // Original request this is a response for
+	failed bool             // Whether the request failed and needs rescheduling
+
+	codes map[common.Hash][]byte // Actual bytecodes delivered, keyed by hash
+}
+
+// trienodeRequest tracks a pending state trie node request to ensure responses
+// are to actual requests and to validate any security constraints.
+type trienodeRequest struct {
+	peer string // Peer to which this request is assigned
+	id   uint64 // Request ID of this request
+
+	deliver chan *trienodeResponse // Channel to deliver the response on
+	quit    chan struct{}          // Channel closed when the owning sync cycle terminates
+	timeout *time.Timer            // Timer to track delivery timeout
+
+	hashes []common.Hash   // Trie node hashes to validate responses
+	paths  []trie.SyncPath // Trie node paths requested for rescheduling
+}
+
+// fail signals the sync loop that the request is void and needs rescheduling.
+func (req *trienodeRequest) fail() {
+	select {
+	case req.deliver <- &trienodeResponse{req: req, failed: true}:
+	case <-req.quit:
+	}
+}
+
+// trienodeResponse is an already verified remote response to a trie node
+// request, or a notification that the request failed.
+type trienodeResponse struct {
+	req    *trienodeRequest // Original request this is a response for
+	failed bool             // Whether the request failed and needs rescheduling
+
+	nodes map[common.Hash][]byte // Actual trie nodes delivered, keyed by hash
+}
+
+// accountTask represents the sync task for a chunk of the account snapshot.
+type accountTask struct {
+	first common.Hash // First account of this interval, used for progress reporting
+	next  common.Hash // Next account to sync in this interval
+	last  common.Hash // Last account to sync in this interval
+
+	req *accountRequest  // Pending request to fill this task
+	res *accountResponse // Validated response filling this task, waiting on its dependencies
+
+	codeTasks  map[common.Hash]bool         // Code hashes that need retrieval, flagged if in flight
+	stateTasks map[common.Hash]*storageTask // Storage tries that need retrieval, keyed by account hash
+
+	genTrie *trie.StackTrie // Trie generator laying down the nodes of the range
+	done    bool            // Flag whether the task has been completed
+}
+
+// reset drops all the transient data of the task that refers to a specific state
+// root, keeping only the progress made in the account range.
+func (task *accountTask) reset() {
+	task.req, task.res = nil, nil
+	task.codeTasks = make(map[common.Hash]bool)
+	task.stateTasks = make(map[common.Hash]*storageTask)
+}
+
+// storageTask represents the sync task for the storage trie of a single account.
+type storageTask struct {
+	root     common.Hash     // Storage root hash the slots need to add up to
+	next     common.Hash     // Next slot to sync for partially retrieved storage tries
+	genTrie  *trie.StackTrie // Trie generator, set once a partial range has been retrieved
+	inflight bool            // Flag whether a retrieval request is pending
+}
+
+// healTask represents the sync task for healing the snap-synced chunk boundaries
+// and any state that changed while the ranges were being downloaded.
+type healTask struct {
+	scheduler *trie.Sync // State trie sync scheduler defining the tasks
+
+	trieTasks map[common.Hash]*healNode // Set of trie node tasks currently queued for retrieval
+	codeTasks map[common.Hash]bool      // Set of byte code tasks currently queued, flagged if in flight
+}
+
+// healNode is a single trie node scheduled for retrieval during healing.
+type healNode struct {
+	path     trie.SyncPath // Path of the node to request it by
+	inflight bool          // Flag whether a retrieval request is pending
+}
+
+// syncBloomWriter is a database writer that also injects every written key into
+// the sync bloom, so the state healer can skip the trie nodes laid down by the
+// range sync without hitting the database.
+type syncBloomWriter struct {
+	ethdb.KeyValueWriter
+	bloom *trie.SyncBloom
+}
+
+// Put inserts the given value into the underlying writer and the key into the
+// bloom filter.
+func (w *syncBloomWriter) Put(key []byte, value []byte) error {
+	if w.bloom != nil {
+		w.bloom.Add(key)
+	}
+	return w.KeyValueWriter.Put(key, value)
+}
+
+// Syncer is an Ethereum account and storage trie syncer based on snapshots and
+// the snap protocol. Its purpose is to download all the accounts and storage
+// slots from remote peers and reassemble chunks of the state trie, on top of
+// which a state sync can be run to fix any gaps / overlaps.
+//
+// Every network request has a variety of failure events:
+//   - The peer disconnects after task assignment, failing to send the request
+//   - The peer disconnects after sending the request, before delivering on it
+//   - The peer remains connected, but does not deliver a response in time
+//   - The peer delivers a stale response after a previous timeout
+//   - The peer delivers a refusal to serve the requested state
+type Syncer struct {
+	db    ethdb.KeyValueStore // Database to store the trie nodes into (and dedup)
+	bloom *trie.SyncBloom     // Bloom filter to deduplicate nodes for state fixup
+
+	root   common.Hash    // Current state trie root being synced
+	tasks  []*accountTask // Current account task set being synced
+	healer *healTask      // Current state healing task being executed
+
+	batch      ethdb.Batch          // Database batch accumulating the synced state
+	trieWriter ethdb.KeyValueWriter // Batch wrapper feeding written trie nodes into the bloom
+
+	peers     map[string]SyncPeer // Currently active peers to download from
+	idlers    map[string]struct{} // Peers that aren't serving requests
+	stateless map[string]struct{} // Peers that refused to serve the current state root
+
+	accountReqs  map[uint64]*accountRequest  // Account requests currently running
+	storageReqs  map[uint64]*storageRequest  // Storage requests currently running
+	bytecodeReqs map[uint64]*bytecodeRequest // Bytecode requests currently running
+	trienodeReqs map[uint64]*trienodeRequest // Trie node requests currently running
+
+	update chan struct{} // Notification channel for possible sync progression
+
+	accountSynced uint64             // Number of accounts downloaded
+	accountBytes  common.StorageSize // Number of account trie bytes persisted to disk
+	storageSynced uint64             // Number of storage slots downloaded
+	storageBytes  common.StorageSize // Number of storage trie bytes persisted to disk
+	codeSynced    uint64             // Number of bytecodes downloaded
+	codeBytes     common.StorageSize // Number of bytecode bytes downloaded
+
+	trienodeHealSynced uint64             // Number of state trie nodes downloaded
+	trienodeHealBytes  common.StorageSize // Number of state trie bytes persisted to disk
+	bytecodeHealSynced uint64             // Number of bytecodes downloaded
+	bytecodeHealBytes  common.StorageSize // Number of bytecodes persisted to disk
+
+	startTime time.Time // Time instance when snapshot sync started
+	logTime   time.Time // Time instance when status was last reported
+
+	lock sync.RWMutex // Protects fields that can change outside of sync (peers, reqs, root)
+}
+
+// NewSyncer creates a new snapshot syncer to download the Ethereum state over the
+// snap protocol.
+func NewSyncer(db ethdb.KeyValueStore, bloom *trie.SyncBloom) *Syncer {
+	batch := db.NewBatch()
+	return &Syncer{
+		db:           db,
+		bloom:        bloom,
+		batch:        batch,
+		trieWriter:   &syncBloomWriter{KeyValueWriter: batch, bloom: bloom},
+		peers:        make(map[string]SyncPeer),
+		idlers:       make(map[string]struct{}),
+		stateless:    make(map[string]struct{}),
+		accountReqs:  make(map[uint64]*accountRequest),
+		storageReqs:  make(map[uint64]*storageRequest),
+		bytecodeReqs: make(map[uint64]*bytecodeRequest),
+		trienodeReqs: make(map[uint64]*trienodeRequest),
+		update:       make(chan struct{}, 1),
+	}
+}
+
+// Register injects a new data source into the syncer's peerset.
+func (s *Syncer) Register(peer SyncPeer) error {
+	// Make sure the peer is not registered yet
+	id := peer.ID()
+
+	s.lock.Lock()
+	if _, ok := s.peers[id]; ok {
+		log.Error("Snap peer already registered", "id", id)
+
+		s.lock.Unlock()
+		return errors.New("already registered")
+	}
+	s.peers[id] = peer
+	s.idlers[id] = struct{}{}
+	s.lock.Unlock()
+
+	// Notify any active syncs that a new peer can be assigned data
+	s.notify()
+	return nil
+}
+
+// Unregister removes a data source from the syncer's peerset, rescheduling all
+// the requests it was serving.
+func (s *Syncer) Unregister(id string) error {
+	// Remove all traces of the peer from the registry
+	s.lock.Lock()
+	if _, ok := s.peers[id]; !ok {
+		log.Error("Snap peer not registered", "id", id)
+
+		s.lock.Unlock()
+		return errors.New("not registered")
+	}
+	delete(s.peers, id)
+	delete(s.idlers, id)
+	delete(s.stateless, id)
+
+	// Gather all the requests the peer was serving to reschedule them
+	var fails []func()
+	for reqid, req := range s.accountReqs {
+		if req.peer == id {
+			req.timeout.Stop()
+			delete(s.accountReqs, reqid)
+			fails = append(fails, req.fail)
+		}
+	}
+	for reqid, req := range s.storageReqs {
+		if req.peer == id {
+			req.timeout.Stop()
+			delete(s.storageReqs, reqid)
+			fails = append(fails, req.fail)
+		}
+	}
+	for reqid, req := range s.bytecodeReqs {
+		if req.peer == id {
+			req.timeout.Stop()
+			delete(s.bytecodeReqs, reqid)
+			fails = append(fails, req.fail)
+		}
+	}
+	for reqid, req := range s.trienodeReqs {
+		if req.peer == id {
+			req.timeout.Stop()
+			delete(s.trienodeReqs, reqid)
+			fails = append(fails, req.fail)
+		}
+	}
+	s.lock.Unlock()
+
+	for _, fail := range fails {
+		fail()
+	}
+	// Notify any active syncs that pending requests need to be reverted
+	s.notify()
+	return nil
+}
+
+// Sync starts (or resumes a previous) sync cycle to iterate over an state trie
+// with the given root and reconstruct the nodes based on the snapshot leaves.
+// Previously downloaded segments will not be redownloaded or fixed, rather any
+// errors will be healed after the leaves are fully accumulated.
+func (s *Syncer) Sync(root common.Hash, cancel chan struct{}) error {
+	// If the state root is empty, there's nothing to retrieve
+	if root == emptyRoot {
+		return nil
+	}
+	// Move the trie root from any previous value, revert stateless markers for
+	// any peers and initialize the syncer if it was not yet run
+	s.lock.Lock()
+	if s.tasks == nil {
+		s.tasks = s.newAccountTasks()
+	}
+	if s.root != root {
+		s.root = root
+		s.healer = nil
+		s.stateless = make(map[string]struct{})
+		for _, task := range s.tasks {
+			task.reset()
+		}
+	}
+	if s.startTime == (time.Time{}) {
+		s.startTime = time.Now()
+	}
+	s.lock.Unlock()
+
+	var (
+		accountResps  = make(chan *accountResponse)
+		storageResps  = make(chan *storageResponse)
+		bytecodeResps = make(chan *bytecodeResponse)
+		trienodeResps = make(chan *trienodeResponse)
+		quit          = make(chan struct{})
+	)
+	defer func() {
+		// Abandon all pending requests and flush whatever was synced so far
+		close(quit)
+
+		s.lock.Lock()
+		s.abandonRequests()
+		s.commit(true)
+		s.lock.Unlock()
+	}()
+	log.Debug("Starting snapshot sync cycle", "root", root)
+
+	for {
+		s.lock.Lock()
+
+		// If the range sync completed, switch over to healing the trie
+		if s.healer == nil && s.rangeSynced() {
+			// Flush everything to disk first, the healer reads from there
+			s.commit(true)
+			s.healer = &healTask{
+				scheduler: state.NewStateSync(root, s.db, s.bloom),
+				trieTasks: make(map[common.Hash]*healNode),
+				codeTasks: make(map[common.Hash]bool),
+			}
+			log.Debug("Snapshot range sync done, healing state", "root", root)
+		}
+		if s.healer != nil {
+			// Healing in progress, check for completion or assign new tasks
+			s.fillHealTasks()
+			if s.healer.scheduler.Pending() == 0 && len(s.trienodeReqs) == 0 && len(s.bytecodeReqs) == 0 {
+				s.reportSyncProgress(true)
+				s.lock.Unlock()
+
+				log.Debug("Snapshot sync completed", "root", root)
+				return nil
+			}
+			s.assignTrienodeHealTasks(trienodeResps, quit)
+			s.assignBytecodeHealTasks(bytecodeResps, quit)
+		} else {
+			// Range sync in progress, assign new tasks to any idle peers
+			s.assignAccountTasks(accountResps, quit)
+			s.assignBytecodeTasks(bytecodeResps, quit)
+			s.assignStorageTasks(storageResps, quit)
+		}
+		s.reportSyncProgress(false)
+		s.lock.Unlock()
+
+		// Wait for something to happen
+		select {
+		case <-s.update:
+			// Something happened (new peer, delivery, timeout), recheck tasks
+		case <-cancel:
+			return ErrCancelled
+
+		case res := <-accountResps:
+			s.processAccountResponse(res)
+		case res := <-storageResps:
+			s.processStorageResponse(res)
+		case res := <-bytecodeResps:
+			s.processBytecodeResponse(res)
+		case res := <-trienodeResps:
+			s.processTrienodeHealResponse(res)
+		}
+	}
+}
+
+// newAccountTasks splits the account hash space into equal chunks which can be
+// retrieved concurrently.
+func (s *Syncer) newAccountTasks() []*accountTask {
+	var (
+		tasks []*accountTask
+		next  common.Hash
+		step  = new(big.Int).Exp(common.Big2, common.Big256, nil)
+	)
+	step.Div(step, big.NewInt(accountConcurrency))
+	step.Sub(step, common.Big1)
+
+	for i := 0; i < accountConcurrency; i++ {
+		last := common.BigToHash(new(big.Int).Add(next.Big(), step))
+		if i == accountConcurrency-1 {
+			// Make sure we don't overflow if the step is not a proper divisor
+			last = common.HexToHash("0xffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff")
+		}
+		task := &accountTask{
+			first:   next,
+			next:    next,
+			last:    last,
+			genTrie: trie.NewStackTrie(s.trieWriter),
+		}
+		task.reset()
+		tasks = append(tasks, task)
+
+		next = common.BigToHash(new(big.Int).Add(last.Big(), common.Big1))
+	}
+	return tasks
+}
+
+// rangeSynced returns whether all account ranges have been fully retrieved.
+func (s *Syncer) rangeSynced() bool {
+	for _, task := range s.tasks {
+		if !task.done {
+			return false
+		}
+	}
+	return true
+}
+
+// abandonRequests drops all the pending requests of a terminating sync cycle and
+// resets the in-flight markers of the tasks, so they get rescheduled next time.
+// The method assumes the lock is held.
+func (s *Syncer) abandonRequests() {
+	for id, req := range s.accountReqs {
+		req.timeout.Stop()
+		delete(s.accountReqs, id)
+		s.idle(req.peer)
+	}
+	for id, req := range s.storageReqs {
+		req.timeout.Stop()
+		delete(s.storageReqs, id)
+		s.idle(req.peer)
+	}
+	for id, req := range s.bytecodeReqs {
+		req.timeout.Stop()
+		delete(s.bytecodeReqs, id)
+		s.idle(req.peer)
+	}
+	for id, req := range s.trienodeReqs {
+		req.timeout.Stop()
+		delete(s.trienodeReqs, id)
+		s.idle(req.peer)
+	}
+	// Requests might have been in the middle of delivery, reset all the task
+	// markers instead of only the ones tracked above
+	for _, task := range s.tasks {
+		task.req = nil
+		for hash := range task.codeTasks {
+			task.codeTasks[hash] = false
+		}
+		for _, st := range task.stateTasks {
+			st.inflight = false
+		}
+	}
+	if s.healer != nil {
+		for _, node := range s.healer.trieTasks {
+			node.inflight = false
+		}
+		for hash := range s.healer.codeTasks {
+			s.healer.codeTasks[hash] = false
+		}
+	}
+}
+
+// notify signals the sync loop that something might have changed.
+func (s *Syncer) notify() {
+	select {
+	case s.update <- struct{}{}:
+	default:
+	}
+}
+
+// idle marks a peer as available for new requests, if it's still connected.
+// The method assumes the lock is held.
+func (s *Syncer) idle(id string) {
+	if _, ok := s.peers[id]; ok {
+		s.idlers[id] = struct{}{}
+	}
+}
+
+// idlePeer retrieves a peer that is not serving any requests and is not known
+// to have refused the current state root. The method assumes the lock is held.
+func (s *Syncer) idlePeer() SyncPeer {
+	for id := range s.idlers {
+		if _, ok := s.stateless[id]; ok {
+			continue
+		}
+		return s.peers[id]
+	}
+	return nil
+}
+
+// newRequestID generates a random request identifier not yet in use by any of
+// the pending requests. The method assumes the lock is held.
+func (s *Syncer) newRequestID() uint64 {
+	for {
+		id := uint64(rand.Int63())
+		if _, ok := s.accountReqs[id]; ok {
+			continue
+		}
+		if _, ok := s.storageReqs[id]; ok {
+			continue
+		}
+		if _, ok := s.bytecodeReqs[id]; ok {
+			continue
+		}
+		if _, ok := s.trienodeReqs[id]; ok {
+			continue
+		}
+		return id
+	}
+}
+
+// commit flushes the accumulated state data to disk if the batch grew large
+// enough, or unconditionally if forced. The method assumes the lock is held.
+func (s *Syncer) commit(force bool) {
+	if !force && s.batch.ValueSize() < ethdb.IdealBatchSize {
+		return
+	}
+	if err := s.batch.Write(); err != nil {
+		log.Crit("Failed to persist snap sync data", "err", err)
+	}
+	s.batch.Reset()
+}
+
+// fillHealTasks pulls new trie node and bytecode tasks from the healing scheduler
+// if the locally queued ones are running low. The method assumes the lock is held.
+func (s *Syncer) fillHealTasks() {
+	var queued int
+	for _, node := range s.healer.trieTasks {
+		if !node.inflight {
+			queued++
+		}
+	}
+	for _, inflight := range s.healer.codeTasks {
+		if !inflight {
+			queued++
+		}
+	}
+	want := (len(s.idlers) + 1) * maxTrieRequestCount
+	if queued >= want {
+		return
+	}
+	nodes, paths, codes := s.healer.scheduler.Missing(want - queued)
+	for i, hash := range nodes {
+		s.healer.trieTasks[hash] = &healNode{path: paths[i]}
+	}
+	for _, hash := range codes {
+		s.healer.codeTasks[hash] = false
+	}
+}
+
+// assignAccountTasks attempts to match idle peers to pending account range
+// retrievals. The method assumes the lock is held.
+func (s *Syncer) assignAccountTasks(deliver chan *accountResponse, quit chan struct{}) {
+	for _, task := range s.tasks {
+		// Skip any tasks already filling or waiting on dependencies
+		if task.done || task.req != nil || task.res != nil {
+			continue
+		}
+		peer := s.idlePeer()
+		if peer == nil {
+			return
+		}
+		// Matched a pending task to an idle peer, allocate a unique request id
+		req := &accountRequest{
+			peer:    peer.ID(),
+			id:      s.newRequestID(),
+			deliver: deliver,
+			quit:    quit,
+			root:    s.root,
+			origin:  task.next,
+			limit:   task.last,
+			task:    task,
+		}
+		req.timeout = time.AfterFunc(requestTimeout, func() {
+			peer.Log().Debug("Account range request timed out", "reqid", req.id)
+			s.revertAccountRequest(req)
+		})
+		s.accountReqs[req.id] = req
+		delete(s.idlers, req.peer)
+		task.req = req
+
+		go func() {
+			if err := peer.RequestAccountRange(req.id, req.root, req.origin, req.limit, maxRequestSize); err != nil {
+				peer.Log().Debug("Failed to request account range", "err", err)
+				s.revertAccountRequest(req)
+			}
+		}()
+	}
+}
+
+// assignStorageTasks attempts to match idle peers to pending storage range
+// retrievals. Partially retrieved large storage tries are continued one by one,
+// the small ones are batched together. The method assumes the lock is held.
+func (s *Syncer) assignStorageTasks(deliver chan *storageResponse, quit chan struct{}) {
+	for _, task := range s.tasks {
+		for len(task.stateTasks) > 0 {
+			peer := s.idlePeer()
+			if peer == nil {
+				return
+			}
+			// Prioritize the continuation of partially retrieved storage tries
+			var (
+				accounts []common.Hash
+				roots    []common.Hash
+				origin   common.Hash
+			)
+			for account, st := range task.stateTasks {
+				if st.inflight || st.genTrie == nil {
+					continue
+				}
+				accounts, roots, origin = []common.Hash{account}, []common.Hash{st.root}, st.next
+				break
+			}
+			if len(accounts) == 0 {
+				for account, st := range task.stateTasks {
+					if st.inflight || st.genTrie != nil {
+						continue
+					}
+					accounts = append(accounts, account)
+					roots = append(roots, st.root)
+
+					if len(accounts) >= maxStorageSetFetchCount {
+						break
+					}
+				}
+			}
+			if len(accounts) == 0 {
+				break
+			}
+			for _, account := range accounts {
+				task.stateTasks[account].inflight = true
+			}
+			// Matched pending storage tries to an idle peer, allocate a unique request id
+			req := &storageRequest{
+				peer:     peer.ID(),
+				id:       s.newRequestID(),
+				deliver:  deliver,
+				quit:     quit,
+				root:     s.root,
+				accounts: accounts,
+				roots:    roots,
+				origin:   origin,
+				task:     task,
+			}
+			req.timeout = time.AfterFunc(requestTimeout, func() {
+				peer.Log().Debug("Storage request timed out", "reqid", req.id)
+				s.revertStorageRequest(req)
+			})
+			s.storageReqs[req.id] = req
+			delete(s.idlers, req.peer)
+
+			go func() {
+				var start []byte
+				if req.origin != (common.Hash{}) {
+					start = req.origin[:]
+				}
+				if err := peer.RequestStorageRanges(req.id, req.root, req.accounts, start, nil, maxRequestSize); err != nil {
+					peer.Log().Debug("Failed to request storage", "err", err)
+					s.revertStorageRequest(req)
+				}
+			}()
+		}
+	}
+}
+
+// assignBytecodeTasks attempts to match idle peers to pending code retrievals
+// of the account ranges. The method assumes the lock is held.
+func (s *Syncer) assignBytecodeTasks(deliver chan *bytecodeResponse, quit chan struct{}) {
+	for _, task := range s.tasks {
+		for len(task.codeTasks) > 0 {
+			peer := s.idlePeer()
+			if peer == nil {
+				return
+			}
+			var hashes []common.Hash
+			for hash, inflight := range task.codeTasks {
+				if inflight {
+					continue
+				}
+				hashes = append(hashes, hash)
+				if len(hashes) >= maxCodeRequestCount {
+					break
+				}
+			}
+			if len(hashes) == 0 {
+				break
+			}
+			for _, hash := range hashes {
+				task.codeTasks[hash] = true
+			}
+			s.sendBytecodeRequest(peer, hashes, task, deliver, quit)
+		}
+	}
+}
+
+// assignTrienodeHealTasks attempts to match idle peers to trie node requests to
+// heal any trie errors caused by the snap sync's chunked retrieval model. The
+// method assumes the lock is held.
+func (s *Syncer) assignTrienodeHealTasks(deliver chan *trienodeResponse, quit chan struct{}) {
+	for len(s.healer.trieTasks) > 0 {
+		peer := s.idlePeer()
+		if peer == nil {
+			return
+		}
+		var (
+			hashes []common.Hash
+			paths  []trie.SyncPath
+		)
+		for hash, node := range s.healer.trieTasks {
+			if node.inflight {
+				continue
+			}
+			hashes = append(hashes, hash)
+			paths = append(paths, node.path)
+
+			if len(hashes) >= maxTrieRequestCount {
+				break
+			}
+		}
+		if len(hashes) == 0 {
+			return
+		}
+		for _, hash := range hashes {
+			s.healer.trieTasks[hash].inflight = true
+		}
+		// Matched pending trie nodes to an idle peer, allocate a unique request id
+		req := &trienodeRequest{
+			peer:    peer.ID(),
+			id:      s.newRequestID(),
+			deliver: deliver,
+			quit:    quit,
+			hashes:  hashes,
+			paths:   paths,
+		}
+		req.timeout = time.AfterFunc(requestTimeout, func() {
+			peer.Log().Debug("Trienode heal request timed out", "reqid", req.id)
+			s.revertTrienodeRequest(req)
+		})
+		s.trienodeReqs[req.id] = req
+		delete(s.idlers, req.peer)
+
+		pathsets := make([]TrieNodePathSet, len(paths))
+		for i, path := range paths {
+			pathsets[i] = TrieNodePathSet(path)
+		}
+		go func(root common.Hash) {
+			if err := peer.RequestTrieNodes(req.id, root, pathsets, maxRequestSize); err != nil {
+				peer.Log().Debug("Failed to request trienode healers", "err", err)
+				s.revertTrienodeRequest(req)
+			}
+		}(s.root)
+	}
+}
+
+// assignBytecodeHealTasks attempts to match idle peers to bytecode requests to
+// heal any trie errors caused by the snap sync's chunked retrieval model. The
+// method assumes the lock is held.
+func (s *Syncer) assignBytecodeHealTasks(deliver chan *bytecodeResponse, quit chan struct{}) {
+	for len(s.healer.codeTasks) > 0 {
+		peer := s.idlePeer()
+		if peer == nil {
+			return
+		}
+		var hashes []common.Hash
+		for hash, inflight := range s.healer.codeTasks {
+			if inflight {
+				continue
+			}
+			hashes = append(hashes, hash)
+			if len(hashes) >= maxCodeRequestCount {
+				break
+			}
+		}
+		if len(hashes) == 0 {
+			return
+		}
+		for _, hash := range hashes {
+			s.healer.codeTasks[hash] = true
+		}
+		s.sendBytecodeRequest(peer, hashes, nil, deliver, quit)
+	}
+}
+
+// sendBytecodeRequest tracks and sends a bytecode request to an idle peer, either
+// on behalf of an account range task or the healer (nil task). The method assumes
+// the lock is held.
+func (s *Syncer) sendBytecodeRequest(peer SyncPeer, hashes []common.Hash, task *accountTask, deliver chan *bytecodeResponse, quit chan struct{}) {
+	req := &bytecodeRequest{
+		peer:    peer.ID(),
+		id:      s.newRequestID(),
+		deliver: deliver,
+		quit:    quit,
+		hashes:  hashes,
+		task:    task,
+	}
+	req.timeout = time.AfterFunc(requestTimeout, func() {
+		peer.Log().Debug("Bytecode request timed out", "reqid", req.id)
+		s.revertBytecodeRequest(req)
+	})
+	s.bytecodeReqs[req.id] = req
+	delete(s.idlers, req.peer)
+
+	go func() {
+		if err := peer.RequestByteCodes(req.id, req.hashes, maxRequestSize); err != nil {
+			peer.Log().Debug("Failed to request bytecodes", "err", err)
+			s.revertBytecodeRequest(req)
+		}
+	}()
+}
+
+// revertAccountRequest cleans up an account range request and returns all failed
+// retrieval tasks to the scheduler for reassignment.
+func (s *Syncer) revertAccountRequest(req *accountRequest) {
+	s.lock.Lock()
+	if _, ok := s.accountReqs[req.id]; !ok {
+		s.lock.Unlock()
+		return
+	}
+	req.timeout.Stop()
+	delete(s.accountReqs, req.id)
+	s.idle(req.peer)
+	s.lock.Unlock()
+
+	req.fail()
+}
+
+// revertStorageRequest cleans up a storage range request and returns all failed
+// retrieval tasks to the scheduler for reassignment.
+func (s *Syncer) revertStorageRequest(req *storageRequest) {
+	s.lock.Lock()
+	if _, ok := s.storageReqs[req.id]; !ok {
+		s.lock.Unlock()
+		return
+	}
+	req.timeout.Stop()
+	delete(s.storageReqs, req.id)
+	s.idle(req.peer)
+	s.lock.Unlock()
+
+	req.fail()
+}
+
+// revertBytecodeRequest cleans up a bytecode request and returns all failed
+// retrieval tasks to the scheduler for reassignment.
+func (s *Syncer) revertBytecodeRequest(req *bytecodeRequest) {
+	s.lock.Lock()
+	if _, ok := s.bytecodeReqs[req.id]; !ok {
+		s.lock.Unlock()
+		return
+	}
+	req.timeout.Stop()
+	delete(s.bytecodeReqs, req.id)
+	s.idle(req.peer)
+	s.lock.Unlock()
+
+	req.fail()
+}
+
+// revertTrienodeRequest cleans up a trie node request and returns all failed
+// retrieval tasks to the scheduler for reassignment.
+func (s *Syncer) revertTrienodeRequest(req *trienodeRequest) {
+	s.lock.Lock()
+	if _, ok := s.trienodeReqs[req.id]; !ok {
+		s.lock.Unlock()
+		return
+	}
+	req.timeout.Stop()
+	delete(s.trienodeReqs, req.id)
+	s.idle(req.peer)
+	s.lock.Unlock()
+
+	req.fail()
+}
+
+// processAccountResponse integrates an already validated account range response
+// into the account tasks.
+func (s *Syncer) processAccountResponse(res *accountResponse) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	task := res.req.task
+	task.req = nil
+	if res.failed {
+		return
+	}
+	// Ensure that the response doesn't overflow into the subsequent task
+	for i, hash := range res.hashes {
+		if cmp := bytes.Compare(hash[:], task.last[:]); cmp >= 0 {
+			if cmp == 0 {
+				i++
+			}
+			res.hashes, res.accounts, res.blobs = res.hashes[:i], res.accounts[:i], res.blobs[:i]
+			res.cont = false
+			break
+		}
+	}
+	// Schedule the retrieval of any bytecodes and storage tries not yet present
+	for i, account := range res.accounts {
+		if !bytes.Equal(account.CodeHash, emptyCode[:]) {
+			hash := common.BytesToHash(account.CodeHash)
+			if _, ok := task.codeTasks[hash]; !ok && len(rawdb.ReadCode(s.db, hash)) == 0 {
+				task.codeTasks[hash] = false
+			}
+		}
+		if account.Root != emptyRoot {
+			if len(rawdb.ReadTrieNode(s.db, account.Root)) == 0 {
+				task.stateTasks[res.hashes[i]] = &storageTask{root: account.Root}
+			}
+		}
+	}
+	task.res = res
+	s.forwardAccountTask(task)
+}
+
+// processStorageResponse integrates an already validated storage ranges response
+// into the account tasks.
+func (s *Syncer) processStorageResponse(res *storageResponse) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	task := res.req.task
+	for _, account := range res.req.accounts {
+		if st := task.stateTasks[account]; st != nil {
+			st.inflight = false
+		}
+	}
+	if res.failed {
+		return
+	}
+	for i, account := range res.req.accounts[:len(res.hashes)] {
+		st := task.stateTasks[account]
+		if st == nil {
+			continue
+		}
+		if st.genTrie == nil {
+			st.genTrie = trie.NewStackTrie(s.trieWriter)
+		}
+		for j, hash := range res.hashes[i] {
+			st.genTrie.Update(hash[:], res.slots[i][j])
+			s.storageBytes += common.StorageSize(common.HashLength + len(res.slots[i][j]))
+		}
+		s.storageSynced += uint64(len(res.hashes[i]))
+
+		// If the storage trie is incomplete, track the continuation point
+		if i == len(res.hashes)-1 && res.cont {
+			st.next = incHash(res.hashes[i][len(res.hashes[i])-1])
+			continue
+		}
+		if root, _ := st.genTrie.Commit(); root != st.root {
+			log.Error("Storage trie generation mismatch", "account", account, "have", root, "want", st.root)
+		}
+		delete(task.stateTasks, account)
+	}
+	s.forwardAccountTask(task)
+}
+
+// processBytecodeResponse integrates an already validated bytecode response into
+// the account tasks, or the healer if it was requested on its behalf.
+func (s *Syncer) processBytecodeResponse(res *bytecodeResponse) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	// Bytecodes requested by the healer are fed into the trie scheduler
+	if res.req.task == nil {
+		if s.healer == nil {
+			return
+		}
+		for _, hash := range res.req.hashes {
+			if _, ok := s.healer.codeTasks[hash]; ok {
+				s.healer.codeTasks[hash] = false
+			}
+		}
+		if res.failed {
+			return
+		}
+		for hash, code := range res.codes {
+			if err := s.healer.scheduler.Process(trie.SyncResult{Hash: hash, Data: code}); err != nil && err != trie.ErrAlreadyProcessed && err != trie.ErrNotRequested {
+				log.Error("Invalid bytecode processed", "hash", hash, "err", err)
+			}
+			delete(s.healer.codeTasks, hash)
+
+			s.bytecodeHealSynced++
+			s.bytecodeHealBytes += common.StorageSize(len(code))
+		}
+		if err := s.healer.scheduler.Commit(s.batch); err != nil {
+			log.Error("Failed to commit healing data", "err", err)
+		}
+		s.commit(false)
+		return
+	}
+	// Bytecodes requested by the range sync are written out directly
+	task := res.req.task
+	for _, hash := range res.req.hashes {
+		if _, ok := task.codeTasks[hash]; ok {
+			task.codeTasks[hash] = false
+		}
+	}
+	if res.failed {
+		return
+	}
+	for hash, code := range res.codes {
+		rawdb.WriteCode(s.batch, hash, code)
+		if s.bloom != nil {
+			s.bloom.Add(hash[:])
+		}
+		delete(task.codeTasks, hash)
+
+		s.codeSynced++
+		s.codeBytes += common.StorageSize(len(code))
+	}
+	s.forwardAccountTask(task)
+}
+
+// processTrienodeHealResponse integrates an already validated trienode response
+// into the healer tasks.
+func (s *Syncer) processTrienodeHealResponse(res *trienodeResponse) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.healer == nil {
+		return
+	}
+	for _, hash := range res.req.hashes {
+		if node := s.healer.trieTasks[hash]; node != nil {
+			node.inflight = false
+		}
+	}
+	if res.failed {
+		return
+	}
+	for hash, node := range res.nodes {
+		if err := s.healer.scheduler.Process(trie.SyncResult{Hash: hash, Data: node}); err != nil && err != trie.ErrAlreadyProcessed && err != trie.ErrNotRequested {
+			log.Error("Invalid trienode processed", "hash", hash, "err", err)
+		}
+		delete(s.healer.trieTasks, hash)
+
+		s.trienodeHealSynced++
+		s.trienodeHealBytes += common.StorageSize(len(node))
+	}
+	if err := s.healer.scheduler.Commit(s.batch); err != nil {
+		log.Error("Failed to commit healing data", "err", err)
+	}
+	s.commit(false)
+}
+
+// forwardAccountTask takes a filled account task and persists anything available
+// into the database, after which it forwards the next account marker so that the
+// task's next chunk may be filled. The accounts are only persisted once all their
+// storage tries and bytecodes have been retrieved, so that any account trie node
+// on disk is guaranteed to have a complete subtrie beneath it. The method assumes
+// the lock is held.
+func (s *Syncer) forwardAccountTask(task *accountTask) {
+	res := task.res
+	if res == nil || len(task.codeTasks) > 0 || len(task.stateTasks) > 0 {
+		return
+	}
+	task.res = nil
+
+	for i, hash := range res.hashes {
+		task.genTrie.Update(hash[:], res.blobs[i])
+		s.accountBytes += common.StorageSize(common.HashLength + len(res.blobs[i]))
+	}
+	s.accountSynced += uint64(len(res.hashes))
+
+	if res.cont && len(res.hashes) > 0 {
+		task.next = incHash(res.hashes[len(res.hashes)-1])
+	} else {
+		task.done = true
+		if _, err := task.genTrie.Commit(); err != nil {
+			log.Error("Failed to commit account range", "err", err)
+		}
+	}
+	s.commit(false)
+}
+
+// OnAccounts is a callback method to invoke when a range of accounts are
+// received from a remote peer.
+func (s *Syncer) OnAccounts(peer SyncPeer, id uint64, hashes []common.Hash, accounts [][]byte, proof [][]byte) error {
+	size := common.StorageSize(len(hashes) * common.HashLength)
+	for _, account := range accounts {
+		size += common.StorageSize(len(account))
+	}
+	for _, node := range proof {
+		size += common.StorageSize(len(node))
+	}
+	logger := peer.Log().New("reqid", id)
+	logger.Trace("Delivering range of accounts", "hashes", len(hashes), "accounts", len(accounts), "proofs", len(proof), "bytes", size)
+
+	// Whether or not the response is valid, we can mark the peer as idle and
+	// notify the scheduler to assign a new task. If the response is invalid,
+	// we'll drop the peer in a bit.
+	s.lock.Lock()
+	req, ok := s.accountReqs[id]
+	if !ok || req.peer != peer.ID() {
+		// Request stale, perhaps the peer timed out but came through in the end
+		logger.Warn("Unexpected account range packet")
+		s.lock.Unlock()
+		return nil
+	}
+	req.timeout.Stop()
+	delete(s.accountReqs, id)
+	s.idle(req.peer)
+
+	// Response is valid, but check if peer is signalling that it does not have
+	// the requested data. For account range queries that means the state being
+	// retrieved was either already pruned remotely, or the peer is not yet
+	// synced to our head.
+	if len(hashes) == 0 && len(proof) == 0 {
+		logger.Debug("Peer rejected account range request", "root", req.root)
+		s.stateless[req.peer] = struct{}{}
+		s.lock.Unlock()
+
+		req.fail()
+		return nil
+	}
+	s.lock.Unlock()
+
+	// Reconstruct a partial trie from the response and verify it
+	keys := make([][]byte, len(hashes))
+	for i, key := range hashes {
+		keys[i] = common.CopyBytes(key[:])
+	}
+	var end []byte
+	if len(keys) > 0 {
+		end = keys[len(keys)-1]
+	}
+	err, cont := trie.VerifyRangeProof(req.root, req.origin[:], end, keys, accounts, proofDatabase(proof))
+	if err != nil {
+		logger.Warn("Account range failed proof", "err", err)
+		req.fail()
+		return err
+	}
+	accs := make([]*state.Account, len(accounts))
+	for i, account := range accounts {
+		acc := new(state.Account)
+		if err := rlp.DecodeBytes(account, acc); err != nil {
+			req.fail()
+			return fmt.Errorf("invalid account %x: %v", account, err)
+		}
+		accs[i] = acc
+	}
+	response := &accountResponse{
+		req:      req,
+		hashes:   hashes,
+		accounts: accs,
+		blobs:    accounts,
+		cont:     cont,
+	}
+	select {
+	case req.deliver <- response:
+	case <-req.quit:
+	}
+	return nil
+}
+
+// OnStorage is a callback method to invoke when ranges of storage slots
+// are received from a remote peer.
+func (s *Syncer) OnStorage(peer SyncPeer, id uint64, hashes [][]common.Hash, slots [][][]byte, proof [][]byte) error {
+	// Gather some trace stats to aid in debugging issues
+	var (
+		hashCount int
+		slotCount int
+		size      common.StorageSize
+	)
+	for _, hashset := range hashes {
+		size += common.StorageSize(common.HashLength * len(hashset))
+		hashCount += len(hashset)
+	}
+	for _, slotset := range slots {
+		for _, slot := range slotset {
+			size += common.StorageSize(len(slot))
+		}
+		slotCount += len(slotset)
+	}
+	for _, node := range proof {
+		size += common.StorageSize(len(node))
+	}
+	logger := peer.Log().New("reqid", id)
+	logger.Trace("Delivering ranges of storage slots", "accounts", len(hashes), "hashes", hashCount, "slots", slotCount, "proofs", len(proof), "size", size)
+
+	// Whether or not the response is valid, we can mark the peer as idle and
+	// notify the scheduler to assign a new task. If the response is invalid,
+	// we'll drop the peer in a bit.
+	s.lock.Lock()
+	req, ok := s.storageReqs[id]
+	if !ok || req.peer != peer.ID() {
+		// Request stale, perhaps the peer timed out but came through in the end
+		logger.Warn("Unexpected storage ranges packet")
+		s.lock.Unlock()
+		return nil
+	}
+	req.timeout.Stop()
+	delete(s.storageReqs, id)
+	s.idle(req.peer)
+
+	// Reject the response if the hash sets and slot sets don't match, or if the
+	// peer sent more data than requested.
+	if len(hashes) != len(slots) {
+		s.lock.Unlock()
+		req.fail()
+		logger.Warn("Hash and slot set size mismatch", "hashset", len(hashes), "slotset", len(slots))
+		return errBadRequest
+	}
+	if len(hashes) > len(req.accounts) {
+		s.lock.Unlock()
+		req.fail()
+		logger.Warn("Hash set larger than requested", "hashset", len(hashes), "requested", len(req.accounts))
+		return errBadRequest
+	}
+	// Response is valid, but check if peer is signalling that it does not have
+	// the requested data. For storage range queries that means the state being
+	// retrieved was either already pruned remotely, or the peer is not yet
+	// synced to our head.
+	if len(hashes) == 0 {
+		logger.Debug("Peer rejected storage request")
+		s.stateless[req.peer] = struct{}{}
+		s.lock.Unlock()
+
+		req.fail()
+		return nil
+	}
+	s.lock.Unlock()
+
+	// Reconstruct the partial tries from the response and verify them
+	var cont bool
+	for i := 0; i < len(hashes); i++ {
+		keys := make([][]byte, len(hashes[i]))
+		for j, key := range hashes[i] {
+			keys[j] = common.CopyBytes(key[:])
+		}
+		// If the storage range is complete, verify it without any proofs,
+		// otherwise the last range needs to be proven by the edge nodes
+		if i < len(hashes)-1 || len(proof) == 0 {
+			if err, _ := trie.VerifyRangeProof(req.roots[i], nil, nil, keys, slots[i], nil); err != nil {
+				logger.Warn("Storage slots failed proof", "err", err)
+				req.fail()
+				return err
+			}
+			continue
+		}
+		var end []byte
+		if len(keys) > 0 {
+			end = keys[len(keys)-1]
+		}
+		var err error
+		if err, cont = trie.VerifyRangeProof(req.roots[i], req.origin[:], end, keys, slots[i], proofDatabase(proof)); err != nil {
+			logger.Warn("Storage range failed proof", "err", err)
+			req.fail()
+			return err
+		}
+	}
+	response := &storageResponse{
+		req:    req,
+		hashes: hashes,
+		slots:  slots,
+		cont:   cont,
+	}
+	select {
+	case req.deliver <- response:
+	case <-req.quit:
+	}
+	return nil
+}
+
+// OnByteCodes is a callback method to invoke when a batch of contract
+// bytes codes are received from a remote peer.
+func (s *Syncer) OnByteCodes(peer SyncPeer, id uint64, bytecodes [][]byte) error {
+	var size common.StorageSize
+	for _, code := range bytecodes {
+		size += common.StorageSize(len(code))
+	}
+	logger := peer.Log().New("reqid", id)
+	logger.Trace("Delivering set of bytecodes", "bytecodes", len(bytecodes), "bytes", size)
+
+	// Whether or not the response is valid, we can mark the peer as idle and
+	// notify the scheduler to assign a new task. If the response is invalid,
+	// we'll drop the peer in a bit.
+	s.lock.Lock()
+	req, ok := s.bytecodeReqs[id]
+	if !ok || req.peer != peer.ID() {
+		// Request stale, perhaps the peer timed out but came through in the end
+		logger.Warn("Unexpected bytecode packet")
+		s.lock.Unlock()
+		return nil
+	}
+	req.timeout.Stop()
+	delete(s.bytecodeReqs, id)
+	s.idle(req.peer)
+
+	// Response is valid, but check if peer is signalling that it does not have
+	// the requested data. For bytecode range queries that means the peer is not
+	// yet synced.
+	if len(bytecodes) == 0 {
+		logger.Debug("Peer rejected bytecode request")
+		s.stateless[req.peer] = struct{}{}
+		s.lock.Unlock()
+
+		req.fail()
+		return nil
+	}
+	s.lock.Unlock()
+
+	// Cross reference the requested bytecodes with the response to find gaps
+	// that the serving node is missing
+	requested := make(map[common.Hash]struct{}, len(req.hashes))
+	for _, hash := range req.hashes {
+		requested[hash] = struct{}{}
+	}
+	codes := make(map[common.Hash][]byte, len(bytecodes))
+	for _, code := range bytecodes {
+		hash := crypto.Keccak256Hash(code)
+		if _, ok := requested[hash]; !ok {
+			// We've either ran out of hashes, or got unrequested data
+			logger.Warn("Unexpected bytecodes", "count", len(bytecodes))
+			req.fail()
+			return errors.New("unexpected bytecode")
+		}
+		codes[hash] = code
+	}
+	response := &bytecodeResponse{
+		req:   req,
+		codes: codes,
+	}
+	select {
+	case req.deliver <- response:
+	case <-req.quit:
+	}
+	return nil
+}
+
+// OnTrieNodes is a callback method to invoke when a batch of trie nodes
+// are received from a remote peer.
+func (s *Syncer) OnTrieNodes(peer SyncPeer, id uint64, trienodes [][]byte) error {
+	var size common.StorageSize
+	for _, node := range trienodes {
+		size += common.StorageSize(len(node))
+	}
+	logger := peer.Log().New("reqid", id)
+	logger.Trace("Delivering set of healing trienodes", "trienodes", len(trienodes), "bytes", size)
+
+	// Whether or not the response is valid, we can mark the peer as idle and
+	// notify the scheduler to assign a new task. If the response is invalid,
+	// we'll drop the peer in a bit.
+	s.lock.Lock()
+	req, ok := s.trienodeReqs[id]
+	if !ok || req.peer != peer.ID() {
+		// Request stale, perhaps the peer timed out but came through in the end
+		logger.Warn("Unexpected trienode heal packet")
+		s.lock.Unlock()
+		return nil
+	}
+	req.timeout.Stop()
+	delete(s.trienodeReqs, id)
+	s.idle(req.peer)
+
+	// Response is valid, but check if peer is signalling that it does not have
+	// the requested data. For trie node queries that means the state being
+	// retrieved was either already pruned remotely, or the peer is not yet
+	// synced to our head.
+	if len(trienodes) == 0 {
+		logger.Debug("Peer rejected trienode heal request")
+		s.stateless[req.peer] = struct{}{}
+		s.lock.Unlock()
+
+		req.fail()
+		return nil
+	}
+	s.lock.Unlock()
+
+	// Cross reference the requested trienodes with the response to find gaps
+	// that the serving node is missing
+	requested := make(map[common.Hash]struct{}, len(req.hashes))
+	for _, hash := range req.hashes {
+		requested[hash] = struct{}{}
+	}
+	nodes := make(map[common.Hash][]byte, len(trienodes))
+	for _, node := range trienodes {
+		hash := crypto.Keccak256Hash(node)
+		if _, ok := requested[hash]; !ok {
+			// We've either ran out of hashes, or got unrequested data
+			logger.Warn("Unexpected healing trienodes", "count", len(trienodes))
+			req.fail()
+			return errors.New("unexpected healing trienode")
+		}
+		nodes[hash] = node
+	}
+	response := &trienodeResponse{
+		req:   req,
+		nodes: nodes,
+	}
+	select {
+	case req.deliver <- response:
+	case <-req.quit:
+	}
+	return nil
+}
+
+// reportSyncProgress logs the current sync status, either after a fixed interval
+// or unconditionally if forced. The method assumes the lock is held.
+func (s *Syncer) reportSyncProgress(force bool) {
+	// Don't report all the events, just occasionally
+	if !force && time.Since(s.logTime) < 8*time.Second {
+		return
+	}
+	s.logTime = time.Now()
+
+	if s.healer != nil {
+		log.Info("State heal in progress", "nodes", s.trienodeHealSynced, "nodebytes", s.trienodeHealBytes,
+			"codes", s.bytecodeHealSynced, "codebytes", s.bytecodeHealBytes, "pending", s.healer.scheduler.Pending())
+		return
+	}
+	// Estimate the progress by the portion of the account hash space covered
+	covered := new(big.Int)
+	for _, task := range s.tasks {
+		end := task.next
+		if task.done {
+			end = incHash(task.last)
+		}
+		span := new(big.Int).Sub(end.Big(), task.first.Big())
+		if task.done && end == (common.Hash{}) {
+			// The last task wrapped around, add back the full hash space
+			span.Add(span, new(big.Int).Exp(common.Big2, common.Big256, nil))
+		}
+		covered.Add(covered, span)
+	}
+	progress := new(big.Float).Quo(new(big.Float).SetInt(covered), new(big.Float).SetInt(new(big.Int).Exp(common.Big2, common.Big256, nil)))
+	percent, _ := progress.Float64()
+
+	log.Info("State sync in progress", "synced", fmt.Sprintf("%.2f%%", percent*100),
+		"accounts", s.accountSynced, "accountbytes", s.accountBytes, "slots", s.storageSynced,
+		"storagebytes", s.storageBytes, "codes", s.codeSynced, "codebytes", s.codeBytes,
+		"elapsed", common.PrettyDuration(time.Since(s.startTime)))
+}
+
+// proofDatabase collects a list of Merkle proof nodes into a database keyed by
+// their hashes, ready to be used in range proof verifications.
+func proofDatabase(proof [][]byte) ethdb.KeyValueReader {
+	db := memorydb.New()
+	for _, node := range proof {
+		db.Put(crypto.Keccak256(node), node)
+	}
+	return db
+}
+
+// incHash returns the next hash, in lexicographical order (a.k.a plus one).
+func incHash(h common.Hash) common.Hash {
+	for i := len(h) - 1; i >= 0; i-- {
+		h[i]++
+		if h[i] != 0 {
+			break
+		}
+	}
+	return h
+}
@@ -0,0 +1,444 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snap
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// kv is a single trie leaf used to serve ranges from the test peers.
+type kv struct {
+	k, v []byte
+}
+
+// entrySlice is a list of trie leaves sortable by key.
+type entrySlice []*kv
+
+func (p entrySlice) Len() int           { return len(p) }
+func (p entrySlice) Less(i, j int) bool { return bytes.Compare(p[i].k, p[j].k) < 0 }
+func (p entrySlice) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
+
+// testState is a complete state trie with storage and codes to serve from.
+type testState struct {
+	root     common.Hash
+	triedb   *trie.Database
+	accounts entrySlice
+	storages map[common.Hash]entrySlice
+	roots    map[common.Hash]common.Hash
+	codes    map[common.Hash][]byte
+}
+
+// newTestState creates a state with the given number of accounts, every second
+// one of which being a contract with the given number of storage slots. The salt
+// is mixed into the contents to allow creating slightly different states.
+func newTestState(accounts int, slots int, salt uint64) *testState {
+	var (
+		triedb = trie.NewDatabase(memorydb.New())
+		ts     = &testState{
+			triedb:   triedb,
+			storages: make(map[common.Hash]entrySlice),
+			roots:    make(map[common.Hash]common.Hash),
+			codes:    make(map[common.Hash][]byte),
+		}
+	)
+	accTrie, _ := trie.New(common.Hash{}, triedb)
+	for i := uint64(1); i <= uint64(accounts); i++ {
+		key := key32(i)
+		account := state.Account{
+			Nonce:    i,
+			Balance:  big.NewInt(int64(i + salt)),
+			Root:     emptyRoot,
+			CodeHash: emptyCode[:],
+		}
+		if i%2 == 0 {
+			code := []byte(fmt.Sprintf("code-%d-%d", i, salt%2))
+			hash := crypto.Keccak256Hash(code)
+			ts.codes[hash] = code
+			account.CodeHash = hash[:]
+
+			stTrie, _ := trie.New(common.Hash{}, triedb)
+			var entries entrySlice
+			for j := uint64(1); j <= uint64(slots); j++ {
+				val, _ := rlp.EncodeToBytes(j + i + salt)
+				entry := &kv{key32(j), val}
+				stTrie.Update(entry.k, entry.v)
+				entries = append(entries, entry)
+			}
+			root, _ := stTrie.Commit(nil)
+			sort.Sort(entries)
+
+			ts.storages[common.BytesToHash(key)] = entries
+			ts.roots[common.BytesToHash(key)] = root
+			account.Root = root
+		}
+		val, _ := rlp.EncodeToBytes(&account)
+		accTrie.Update(key, val)
+		ts.accounts = append(ts.accounts, &kv{key, val})
+	}
+	ts.root, _ = accTrie.Commit(nil)
+	sort.Sort(ts.accounts)
+	return ts
+}
+
+// key32 derives a pseudo-random 32 byte trie key from a number.
+func key32(i uint64) []byte {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], i)
+	return crypto.Keccak256(buf[:])
+}
+
+// testPeer is a mock snap peer serving requests from a test state.
+type testPeer struct {
+	id     string
+	test   *testing.T
+	remote *Syncer
+	state  *testState
+	logger log.Logger
+
+	bytesLimit uint64 // Artificial response size limit to force pagination
+	stateless  bool   // Whether the peer refuses to serve any state
+}
+
+func newTestPeer(id string, t *testing.T, ts *testState) *testPeer {
+	return &testPeer{
+		id:     id,
+		test:   t,
+		state:  ts,
+		logger: log.New("id", id),
+	}
+}
+
+func (t *testPeer) ID() string      { return t.id }
+func (t *testPeer) Log() log.Logger { return t.logger }
+
+func (t *testPeer) RequestAccountRange(id uint64, root, origin, limit common.Hash, bytes uint64) error {
+	go t.serveAccounts(id, origin, limit, t.limit(bytes))
+	return nil
+}
+
+func (t *testPeer) RequestStorageRanges(id uint64, root common.Hash, accounts []common.Hash, origin, limit []byte, bytes uint64) error {
+	go t.serveStorage(id, accounts, origin, t.limit(bytes))
+	return nil
+}
+
+func (t *testPeer) RequestByteCodes(id uint64, hashes []common.Hash, bytes uint64) error {
+	go func() {
+		var codes [][]byte
+		if !t.stateless {
+			for _, hash := range hashes {
+				if code, ok := t.state.codes[hash]; ok {
+					codes = append(codes, code)
+				}
+			}
+		}
+		if err := t.remote.OnByteCodes(t, id, codes); err != nil {
+			t.test.Errorf("Remote side rejected our delivery: %v", err)
+		}
+	}()
+	return nil
+}
+
+func (t *testPeer) RequestTrieNodes(id uint64, root common.Hash, paths []TrieNodePathSet, bytes uint64) error {
+	go func() {
+		var nodes [][]byte
+		if !t.stateless {
+			accTrie, _ := trie.New(root, t.state.triedb)
+			for _, pathset := range paths {
+				if len(pathset) == 1 {
+					if blob, _, err := accTrie.TryGetNode(pathset[0]); err == nil && blob != nil {
+						nodes = append(nodes, blob)
+					}
+					continue
+				}
+				stTrie, _ := trie.New(t.state.roots[common.BytesToHash(pathset[0])], t.state.triedb)
+				for _, path := range pathset[1:] {
+					if blob, _, err := stTrie.TryGetNode(path); err == nil && blob != nil {
+						nodes = append(nodes, blob)
+					}
+				}
+			}
+		}
+		if err := t.remote.OnTrieNodes(t, id, nodes); err != nil {
+			t.test.Errorf("Remote side rejected our delivery: %v", err)
+		}
+	}()
+	return nil
+}
+
+// limit caps the requested response size to the peer's artificial limit.
+func (t *testPeer) limit(bytes uint64) uint64 {
+	if t.bytesLimit != 0 && t.bytesLimit < bytes {
+		return t.bytesLimit
+	}
+	return bytes
+}
+
+func (t *testPeer) serveAccounts(id uint64, origin, limit common.Hash, cap uint64) {
+	var (
+		hashes   []common.Hash
+		accounts [][]byte
+		proofs   [][]byte
+	)
+	if !t.stateless {
+		var size uint64
+		for _, entry := range t.state.accounts {
+			if bytes.Compare(origin[:], entry.k) > 0 {
+				continue
+			}
+			// Deliver copies as the syncer may take ownership of the blobs,
+			// just like it would of anything decoded from the network
+			hashes = append(hashes, common.BytesToHash(entry.k))
+			accounts = append(accounts, common.CopyBytes(entry.v))
+			size += uint64(32 + len(entry.v))
+			if bytes.Compare(entry.k, limit[:]) >= 0 || size > cap {
+				break
+			}
+		}
+		accTrie, _ := trie.New(t.state.root, t.state.triedb)
+		proofs = proveRange(t.test, accTrie, origin, hashes)
+	}
+	if err := t.remote.OnAccounts(t, id, hashes, accounts, proofs); err != nil {
+		t.test.Errorf("Remote side rejected our delivery: %v", err)
+	}
+}
+
+func (t *testPeer) serveStorage(id uint64, accounts []common.Hash, start []byte, cap uint64) {
+	var (
+		hashes [][]common.Hash
+		slots  [][][]byte
+		proofs [][]byte
+	)
+	if !t.stateless {
+		var size uint64
+		for i, account := range accounts {
+			if size >= cap {
+				break
+			}
+			var origin common.Hash
+			if i == 0 && len(start) > 0 {
+				origin = common.BytesToHash(start)
+			}
+			var (
+				keys  []common.Hash
+				vals  [][]byte
+				abort bool
+			)
+			for _, entry := range t.state.storages[account] {
+				if bytes.Compare(origin[:], entry.k) > 0 {
+					continue
+				}
+				keys = append(keys, common.BytesToHash(entry.k))
+				vals = append(vals, common.CopyBytes(entry.v))
+				size += uint64(32 + len(entry.v))
+				if size >= cap {
+					abort = true
+					break
+				}
+			}
+			hashes = append(hashes, keys)
+			slots = append(slots, vals)
+
+			if origin != (common.Hash{}) || abort {
+				stTrie, _ := trie.New(t.state.roots[account], t.state.triedb)
+				proofs = proveRange(t.test, stTrie, origin, keys)
+				break
+			}
+		}
+	}
+	if err := t.remote.OnStorage(t, id, hashes, slots, proofs); err != nil {
+		t.test.Errorf("Remote side rejected our delivery: %v", err)
+	}
+}
+
+// proveRange creates the edge proofs for a range of keys starting at origin.
+func proveRange(t *testing.T, tr *trie.Trie, origin common.Hash, keys []common.Hash) [][]byte {
+	proof := memorydb.New()
+	if err := tr.Prove(origin[:], 0, proof); err != nil {
+		t.Errorf("Could not prove origin %x: %v", origin, err)
+	}
+	if len(keys) > 0 {
+		if err := tr.Prove(keys[len(keys)-1][:], 0, proof); err != nil {
+			t.Errorf("Could not prove last %x: %v", keys[len(keys)-1], err)
+		}
+	}
+	var proofs [][]byte
+	it := proof.NewIterator(nil, nil)
+	for it.Next() {
+		proofs = append(proofs, common.CopyBytes(it.Value()))
+	}
+	it.Release()
+	return proofs
+}
+
+// runSync runs a sync cycle against the given root, failing the test if it
+// does not finish in time.
+func runSync(t *testing.T, syncer *Syncer, root common.Hash) {
+	cancel := make(chan struct{})
+	done := make(chan error, 1)
+	go func() { done <- syncer.Sync(root, cancel) }()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("sync failed: %v", err)
+		}
+	case <-time.After(30 * time.Second):
+		close(cancel)
+		t.Fatalf("sync timed out")
+	}
+}
+
+// verifyState checks that the entire state trie, including all storage tries
+// and bytecodes, is present in the database.
+func verifyState(t *testing.T, db ethdb.KeyValueStore, root common.Hash) {
+	t.Helper()
+
+	triedb := trie.NewDatabase(db)
+	accTrie, err := trie.New(root, triedb)
+	if err != nil {
+		t.Fatalf("failed to open account trie: %v", err)
+	}
+	var accounts, slots int
+	accIt := trie.NewIterator(accTrie.NodeIterator(nil))
+	for accIt.Next() {
+		var acc state.Account
+		if err := rlp.DecodeBytes(accIt.Value, &acc); err != nil {
+			t.Fatalf("invalid account encountered: %v", err)
+		}
+		accounts++
+		if !bytes.Equal(acc.CodeHash, emptyCode[:]) {
+			if code := rawdb.ReadCode(db, common.BytesToHash(acc.CodeHash)); len(code) == 0 {
+				t.Errorf("missing code for account %x", accIt.Key)
+			}
+		}
+		if acc.Root != emptyRoot {
+			stTrie, err := trie.New(acc.Root, triedb)
+			if err != nil {
+				t.Fatalf("failed to open storage trie: %v", err)
+			}
+			stIt := trie.NewIterator(stTrie.NodeIterator(nil))
+			for stIt.Next() {
+				slots++
+			}
+			if stIt.Err != nil {
+				t.Fatalf("storage iteration failed: %v", stIt.Err)
+			}
+		}
+	}
+	if accIt.Err != nil {
+		t.Fatalf("account iteration failed: %v", accIt.Err)
+	}
+	t.Logf("accounts: %d, slots: %d", accounts, slots)
+}
+
+// Tests that an empty state root is synced without any network activity.
+func TestSyncEmpty(t *testing.T) {
+	syncer := NewSyncer(memorydb.New(), nil)
+	runSync(t, syncer, emptyRoot)
+}
+
+// Tests that a state with accounts, storage and code is fully retrieved from a
+// single peer, including healing the chunk boundaries.
+func TestSyncWithStorage(t *testing.T) {
+	var (
+		ts     = newTestState(100, 10, 0)
+		db     = memorydb.New()
+		syncer = NewSyncer(db, nil)
+	)
+	peer := newTestPeer("source", t, ts)
+	peer.remote = syncer
+	syncer.Register(peer)
+
+	runSync(t, syncer, ts.root)
+	verifyState(t, db, ts.root)
+}
+
+// Tests that account and storage ranges are paginated and continued correctly
+// if the serving peer caps its responses.
+func TestSyncPaginated(t *testing.T) {
+	var (
+		ts     = newTestState(200, 100, 0)
+		db     = memorydb.New()
+		syncer = NewSyncer(db, nil)
+	)
+	peer := newTestPeer("capped", t, ts)
+	peer.remote = syncer
+	peer.bytesLimit = 500
+	syncer.Register(peer)
+
+	runSync(t, syncer, ts.root)
+	verifyState(t, db, ts.root)
+}
+
+// Tests that peers refusing to serve the state are skipped and the data is
+// retrieved from the remaining ones.
+func TestSyncStatelessPeer(t *testing.T) {
+	var (
+		ts     = newTestState(100, 10, 0)
+		db     = memorydb.New()
+		syncer = NewSyncer(db, nil)
+	)
+	good := newTestPeer("good", t, ts)
+	good.remote = syncer
+	bad := newTestPeer("stateless", t, ts)
+	bad.remote = syncer
+	bad.stateless = true
+
+	syncer.Register(bad)
+	syncer.Register(good)
+
+	runSync(t, syncer, ts.root)
+	verifyState(t, db, ts.root)
+}
+
+// Tests that switching the sync to a new root after a completed range sync
+// heals the state differences, including changed storage and code.
+func TestSyncRootChangeHeals(t *testing.T) {
+	var (
+		old    = newTestState(100, 10, 0)
+		fresh  = newTestState(100, 10, 1)
+		db     = memorydb.New()
+		syncer = NewSyncer(db, nil)
+	)
+	peer := newTestPeer("source", t, old)
+	peer.remote = syncer
+	syncer.Register(peer)
+
+	runSync(t, syncer, old.root)
+	verifyState(t, db, old.root)
+
+	// Move the peer and the sync over to the new state
+	peer.state = fresh
+	runSync(t, syncer, fresh.root)
+	verifyState(t, db, fresh.root)
+}
@@ -21,10 +21,13 @@ import (
 	"testing"
 	"time"
 
+	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/eth/downloader"
+	"github.com/ethereum/go-ethereum/node"
 	"github.com/ethereum/go-ethereum/p2p"
 	"github.com/ethereum/go-ethereum/p2p/enode"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/params/types/genesisT"
 )
 
 func TestFastSyncDisabling63(t *testing.T) { testFastSyncDisabling(t, 63) }
@@ -64,6 +67,44 @@ func testFastSyncDisabling(t *testing.T, protocol int) {
 	}
 }
 
+// Tests that snap sync started from the default configuration runs the snap
+// protocol it syncs with, and that it falls back to fast sync if the snapshots
+// are disabled.
+func TestSnapSyncDefaultConfig(t *testing.T) {
+	for _, snapshots := range []bool{true, false} {
+		stack, err := node.New(&node.Config{})
+		if err != nil {
+			t.Fatalf("failed to create node: %v", err)
+		}
+		config := DefaultConfig
+		config.SyncMode = downloader.SnapSync
+		config.Ethash.PowMode = ethash.ModeFake
+		config.Genesis = &genesisT.Genesis{Config: params.TestChainConfig}
+		if !snapshots {
+			config.SnapshotCache = 0
+		}
+		backend, err := New(stack, &config)
+		if err != nil {
+			stack.Close()
+			t.Fatalf("snapshots %v: failed to create backend: %v", snapshots, err)
+		}
+		var snap bool
+		for _, proto := range backend.Protocols() {
+			snap = snap || proto.Name == "snap"
+		}
+		if snap != snapshots {
+			t.Errorf("snapshots %v: snap protocol served: %v", snapshots, snap)
+		}
+		if synced := atomic.LoadUint32(&backend.protocolManager.snapSync) == 1; synced != snapshots {
+			t.Errorf("snapshots %v: snap sync enabled: %v", snapshots, synced)
+		}
+		if atomic.LoadUint32(&backend.protocolManager.fastSync) == 0 {
+			t.Errorf("snapshots %v: fast sync disabled", snapshots)
+		}
+		stack.Close()
+	}
+}
+
 func TestArtificialFinalityFeatureEnablingDisabling(t *testing.T) {
 	// Create a full protocol manager, check that fast sync gets disabled
 	a, _ := newTestProtocolManagerMust(t, downloader.FastSync, 1024, nil, nil)
@@ -0,0 +1,236 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// TxTracer is a vm.Tracer producing a JSON result for a single transaction,
+// implemented either natively in Go or by a JavaScript Tracer.
+type TxTracer interface {
+	vm.Tracer
+
+	// CaptureExtraContext injects additional transaction context (block number
+	// and hash, transaction hash and position) into the tracer.
+	CaptureExtraContext(map[string]interface{}) error
+
+	// GetResult returns the result of the tracing, or any accumulated error.
+	GetResult() (json.RawMessage, error)
+
+	// Stop terminates execution of the tracer at the first opportune moment.
+	Stop(err error)
+}
+
+// native contains the Go implementations of the built in tracers by name. They
+// produce the exact same output as the JavaScript tracers they replace, which
+// remain available with a "Legacy" suffix (e.g. callTracerLegacy).
+var native = map[string]func() TxTracer{
+	"callTracer":       func() TxTracer { return newCallTracer() },
+	"callTracerParity": func() TxTracer { return newCallParityTracer() },
+	"prestateTracer":   func() TxTracer { return newPrestateTracer() },
+}
+
+// legacySuffix is appended to the name of a built in tracer to select its
+// JavaScript implementation instead of the native one.
+const legacySuffix = "Legacy"
+
+// NewTxTracer instantiates a transaction tracer by name. Built in tracers with a
+// native implementation are constructed natively, anything else is interpreted
+// by New as the name of a built in JavaScript tracer or as JavaScript code.
+func NewTxTracer(code string) (TxTracer, error) {
+	if constructor, ok := native[code]; ok {
+		return constructor(), nil
+	}
+	if name := strings.TrimSuffix(code, legacySuffix); name != code {
+		if _, ok := native[name]; ok {
+			if tracer, ok := tracer(name); ok {
+				return New(tracer)
+			}
+		}
+	}
+	return New(code)
+}
+
+// nativeContext is the transaction context collected by a native tracer, which
+// mirrors the ctx object handed to the result function of JavaScript tracers.
+type nativeContext struct {
+	typ     string
+	from    common.Address
+	to      common.Address
+	input   []byte
+	gas     uint64
+	value   *big.Int
+	block   *uint64
+	output  []byte
+	gasUsed uint64
+	time    string
+	err     string
+
+	extra map[string]interface{}
+
+	env     *vm.EVM // Last EVM seen, giving access to the state database
+	opError string  // Error of the last inner call, cleared on every step
+
+	interrupt uint32 // Atomic flag to signal execution interruption
+	reason    error  // Textual reason for the interruption
+	failure   error  // Error stopping the tracing, if any
+}
+
+// CaptureStart implements the vm.Tracer interface to initialize the tracing operation.
+func (ctx *nativeContext) CaptureStart(from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) error {
+	ctx.typ = "CALL"
+	if create {
+		ctx.typ = "CREATE"
+	}
+	ctx.from, ctx.to = from, to
+	ctx.input, ctx.gas, ctx.value = input, gas, value
+	return nil
+}
+
+// CaptureEnd implements the vm.Tracer interface to finalize the tracing operation.
+func (ctx *nativeContext) CaptureEnd(output []byte, gasUsed uint64, t time.Duration, err error) error {
+	ctx.output, ctx.gasUsed, ctx.time = output, gasUsed, t.String()
+	if err != nil {
+		ctx.err = err.Error()
+	}
+	return nil
+}
+
+// CaptureExtraContext implements TxTracer, injecting additional transaction context.
+func (ctx *nativeContext) CaptureExtraContext(inputs map[string]interface{}) error {
+	if ctx.extra == nil {
+		ctx.extra = make(map[string]interface{})
+	}
+	for key, val := range inputs {
+		ctx.extra[key] = val
+	}
+	return nil
+}
+
+// Stop implements TxTracer, terminating the tracing at the next step.
+func (ctx *nativeContext) Stop(err error) {
+	ctx.reason = err
+	atomic.StoreUint32(&ctx.interrupt, 1)
+}
+
+// step updates the context before a step of the VM execution is traced, and
+// reports whether the tracer should process it. Like with JavaScript tracers,
+// the error of the last inner call is consumed from the EVM.
+func (ctx *nativeContext) step(env *vm.EVM) bool {
+	if ctx.failure != nil {
+		return false
+	}
+	if ctx.block == nil {
+		block := env.BlockNumber.Uint64()
+		ctx.block = &block
+	}
+	if atomic.LoadUint32(&ctx.interrupt) > 0 {
+		ctx.failure = ctx.reason
+		return false
+	}
+	ctx.env = env
+
+	ctx.opError = ""
+	if env.CallErrorTemp != nil {
+		ctx.opError = env.CallErrorTemp.Error()
+		env.CallErrorTemp = nil
+	}
+	return true
+}
+
+// isPrecompiled reports whether the address is a precompiled contract, using the
+// same set of precompiles as the JavaScript tracers.
+func isPrecompiled(addr common.Address) bool {
+	_, ok := vm.PrecompiledContractsForConfig(params.AllEthashProtocolChanges, big.NewInt(0))[addr]
+	return ok
+}
+
+// stackPeek returns the nth item from the top of the stack, or zero if the stack
+// is too shallow.
+func stackPeek(stack *vm.Stack, n int) *big.Int {
+	if len(stack.Data()) <= n || n < 0 {
+		return new(big.Int)
+	}
+	return stack.Back(n).ToBig()
+}
+
+// stackPeekAddress returns the nth item from the top of the stack as an address.
+func stackPeekAddress(stack *vm.Stack, n int) common.Address {
+	return common.BigToAddress(stackPeek(stack, n))
+}
+
+// stackPeekInt returns the nth item from the top of the stack as an int64,
+// saturating on overflow.
+func stackPeekInt(stack *vm.Stack, n int) int64 {
+	value := stackPeek(stack, n)
+	if !value.IsInt64() {
+		return math.MaxInt64
+	}
+	return value.Int64()
+}
+
+// memorySlice returns a copy of the memory between the given offsets, or an
+// empty slice if the range is out of bounds.
+func memorySlice(memory *vm.Memory, begin, end int64) []byte {
+	if end < begin || begin < 0 || end > int64(memory.Len()) {
+		return nil
+	}
+	return memory.GetCopy(begin, end-begin)
+}
+
+// addInt adds two offsets, saturating on overflow.
+func addInt(a, b int64) int64 {
+	if a > math.MaxInt64-b {
+		return math.MaxInt64
+	}
+	return a + b
+}
+
+// hexInt formats a number the way JavaScript tracers do, including the sign of
+// negative numbers.
+func hexInt(n int64) string {
+	return "0x" + strconv.FormatInt(n, 16)
+}
+
+// hexBig formats a big number the way JavaScript tracers do.
+func hexBig(n *big.Int) string {
+	return "0x" + n.Text(16)
+}
+
+// encodeResult JSON encodes the result of a native tracer the same way the
+// JavaScript engine does.
+func encodeResult(result interface{}) (json.RawMessage, error) {
+	buf := new(bytes.Buffer)
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(result); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
@@ -0,0 +1,293 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"encoding/json"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/vm"
+)
+
+// callFrame is an internal call being assembled by the call tracers. Empty
+// string fields are left out of the results.
+type callFrame struct {
+	typ   string
+	from  string
+	to    string
+	input string
+	value string
+
+	gas     *int64 // Gas available to the call, while it is executing
+	gasHex  string // Gas available to the call, once it has finished
+	gasUsed string
+	gasIn   int64 // Gas available before the calling operation
+	gasCost int64 // Cost of the calling operation
+	outOff  int64 // Memory offset of the call output
+	outLen  int64 // Memory size of the call output
+
+	output string
+	err    string
+	calls  []*callFrame
+}
+
+// finishGas formats the gas of a finished call, if known.
+func (call *callFrame) finishGas() {
+	if call.gas != nil {
+		call.gasHex = hexInt(*call.gas)
+	}
+}
+
+// callTracerResult is a call reported by the callTracer, in the field order of
+// the JavaScript implementation.
+type callTracerResult struct {
+	Type    string              `json:"type,omitempty"`
+	From    string              `json:"from,omitempty"`
+	To      string              `json:"to,omitempty"`
+	Value   string              `json:"value,omitempty"`
+	Gas     string              `json:"gas,omitempty"`
+	GasUsed string              `json:"gasUsed,omitempty"`
+	Input   string              `json:"input,omitempty"`
+	Output  string              `json:"output,omitempty"`
+	Error   string              `json:"error,omitempty"`
+	Time    string              `json:"time,omitempty"`
+	Calls   []*callTracerResult `json:"calls,omitempty"`
+}
+
+// callTracer is the native implementation of the callTracer, extracting and
+// reporting all the internal calls made by a transaction.
+type callTracer struct {
+	nativeContext
+
+	callstack []*callFrame // Current recursive call stack of the EVM execution
+	descended bool         // Whether we've just descended into an inner call
+}
+
+// newCallTracer creates a native callTracer.
+func newCallTracer() *callTracer {
+	return &callTracer{callstack: []*callFrame{{}}}
+}
+
+// CaptureState implements the vm.Tracer interface to trace a single step of VM execution.
+func (t *callTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, rStack *vm.ReturnStack, rData []byte, contract *vm.Contract, depth int, err error) error {
+	if !t.step(env) {
+		return nil
+	}
+	// Capture any errors immediately
+	if err != nil {
+		t.fault(err)
+		return nil
+	}
+	switch op {
+	case vm.CREATE, vm.CREATE2:
+		// If a new contract is being created, add to the call stack
+		inOff := stackPeekInt(stack, 1)
+		inEnd := addInt(inOff, stackPeekInt(stack, 2))
+
+		callGas := int64(env.CallGasTemp)
+		t.callstack = append(t.callstack, &callFrame{
+			typ:     op.String(),
+			from:    hexutil.Encode(contract.Address().Bytes()),
+			input:   hexutil.Encode(memorySlice(memory, inOff, inEnd)),
+			gas:     &callGas,
+			gasIn:   int64(gas),
+			gasCost: int64(cost),
+			value:   hexBig(stackPeek(stack, 0)),
+		})
+		t.descended = true
+		return nil
+
+	case vm.SELFDESTRUCT:
+		// If a contract is being self destructed, gather that as a subcall too
+		parent := t.callstack[len(t.callstack)-1]
+		parent.calls = append(parent.calls, &callFrame{
+			typ:     op.String(),
+			from:    hexutil.Encode(contract.Address().Bytes()),
+			to:      hexutil.Encode(stackPeekAddress(stack, 0).Bytes()),
+			gasIn:   int64(gas),
+			gasCost: int64(cost),
+			value:   hexBig(env.StateDB.GetBalance(contract.Address())),
+		})
+		return nil
+
+	case vm.CALL, vm.CALLCODE, vm.DELEGATECALL, vm.STATICCALL:
+		// If a new method invocation is being done, add to the call stack, skipping
+		// any pre-compile invocations, those are just fancy opcodes
+		to := stackPeekAddress(stack, 1)
+		if isPrecompiled(to) {
+			return nil
+		}
+		off := 1
+		if op == vm.DELEGATECALL || op == vm.STATICCALL {
+			off = 0
+		}
+		inOff := stackPeekInt(stack, 2+off)
+		inEnd := addInt(inOff, stackPeekInt(stack, 3+off))
+
+		call := &callFrame{
+			typ:     op.String(),
+			from:    hexutil.Encode(contract.Address().Bytes()),
+			to:      hexutil.Encode(to.Bytes()),
+			input:   hexutil.Encode(memorySlice(memory, inOff, inEnd)),
+			gasIn:   int64(gas),
+			gasCost: int64(cost),
+			outOff:  stackPeekInt(stack, 4+off),
+			outLen:  stackPeekInt(stack, 5+off),
+		}
+		if op != vm.DELEGATECALL && op != vm.STATICCALL {
+			call.value = hexBig(stackPeek(stack, 2))
+		}
+		t.callstack = append(t.callstack, call)
+		t.descended = true
+		return nil
+	}
+	// If we've just descended into an inner call, retrieve it's true allowance. We
+	// need to extract if from within the call as there may be funky gas dynamics
+	// with regard to requested and actually given gas (2300 stipend, 63/64 rule).
+	// Calls made to plain accounts don't get any gas assigned.
+	if t.descended {
+		if depth >= len(t.callstack) {
+			callGas := int64(gas)
+			t.callstack[len(t.callstack)-1].gas = &callGas
+		}
+		t.descended = false
+	}
+	// If an existing call is returning, pop off the call stack
+	if op == vm.REVERT {
+		t.callstack[len(t.callstack)-1].err = "execution reverted"
+		return nil
+	}
+	if depth == len(t.callstack)-1 {
+		// Pop off the last call and get the execution results
+		call := t.callstack[len(t.callstack)-1]
+		t.callstack = t.callstack[:len(t.callstack)-1]
+
+		ret := stackPeek(stack, 0)
+		if call.typ == vm.CREATE.String() || call.typ == vm.CREATE2.String() {
+			// If the call was a CREATE, retrieve the contract address and output code
+			call.gasUsed = hexInt(*call.gas - (int64(gas) - (call.gasIn - call.gasCost)))
+
+			if ret.Sign() != 0 {
+				addr := stackPeekAddress(stack, 0)
+				call.to = hexutil.Encode(addr.Bytes())
+				call.output = hexutil.Encode(env.StateDB.GetCode(addr))
+			} else if call.err == "" {
+				call.err = "internal failure"
+			}
+		} else {
+			// If the call was a contract call, retrieve the gas usage and output
+			if call.gas != nil {
+				call.gasUsed = hexInt(call.gasIn - call.gasCost + *call.gas - int64(gas))
+			}
+			if ret.Sign() != 0 {
+				call.output = hexutil.Encode(memorySlice(memory, call.outOff, addInt(call.outOff, call.outLen)))
+			} else if call.err == "" {
+				call.err = "internal failure"
+			}
+		}
+		call.finishGas()
+
+		// Inject the call into the previous one
+		parent := t.callstack[len(t.callstack)-1]
+		parent.calls = append(parent.calls, call)
+	}
+	return nil
+}
+
+// CaptureFault implements the vm.Tracer interface to trace an execution fault
+// while running an opcode.
+func (t *callTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, rStack *vm.ReturnStack, contract *vm.Contract, depth int, err error) error {
+	if t.failure == nil {
+		t.fault(err)
+	}
+	return nil
+}
+
+// fault handles the failure of an operation, flattening the failed call into
+// its parent.
+func (t *callTracer) fault(err error) {
+	// If the topmost call already reverted, don't handle the additional fault again
+	if t.callstack[len(t.callstack)-1].err != "" {
+		return
+	}
+	// Pop off the just failed call, consuming all available gas
+	call := t.callstack[len(t.callstack)-1]
+	t.callstack = t.callstack[:len(t.callstack)-1]
+
+	call.err = err.Error()
+	if call.gas != nil {
+		call.finishGas()
+		call.gasUsed = call.gasHex
+	}
+	// Flatten the failed call into its parent, or leave it in the stack if the
+	// last call failed too
+	if len(t.callstack) > 0 {
+		parent := t.callstack[len(t.callstack)-1]
+		parent.calls = append(parent.calls, call)
+		return
+	}
+	t.callstack = append(t.callstack, call)
+}
+
+// GetResult implements TxTracer, returning the call tree of the transaction.
+func (t *callTracer) GetResult() (json.RawMessage, error) {
+	result := &callFrame{
+		typ:     t.typ,
+		from:    hexutil.Encode(t.from.Bytes()),
+		to:      hexutil.Encode(t.to.Bytes()),
+		value:   hexBig(t.value),
+		gasHex:  hexInt(int64(t.gas)),
+		gasUsed: hexInt(int64(t.gasUsed)),
+		input:   hexutil.Encode(t.input),
+		output:  hexutil.Encode(t.output),
+		calls:   t.callstack[0].calls,
+		err:     t.callstack[0].err,
+	}
+	if result.err == "" {
+		result.err = t.err
+	}
+	if result.err != "" && (result.err != "execution reverted" || result.output == "0x") {
+		result.output = ""
+	}
+	res := t.finalize(result)
+	res.Time = t.time
+
+	blob, err := encodeResult(res)
+	if err != nil {
+		return nil, err
+	}
+	return blob, t.failure
+}
+
+// finalize converts a call into its reported form.
+func (t *callTracer) finalize(call *callFrame) *callTracerResult {
+	result := &callTracerResult{
+		Type:    call.typ,
+		From:    call.from,
+		To:      call.to,
+		Value:   call.value,
+		Gas:     call.gasHex,
+		GasUsed: call.gasUsed,
+		Input:   call.input,
+		Output:  call.output,
+		Error:   call.err,
+	}
+	for _, child := range call.calls {
+		result.Calls = append(result.Calls, t.finalize(child))
+	}
+	return result
+}
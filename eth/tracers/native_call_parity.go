@@ -0,0 +1,462 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/params/vars"
+)
+
+// parityErrorMapping translates EVM errors into their Parity counterparts.
+var parityErrorMapping = map[string]string{
+	"contract creation code storage out of gas": "Out of gas",
+	"out of gas":                      "Out of gas",
+	"gas uint64 overflow":             "Out of gas",
+	"max code size exceeded":          "Out of gas",
+	"invalid jump destination":        "Bad jump destination",
+	"execution reverted":              "Reverted",
+	"return data out of bounds":       "Out of bounds",
+	"stack limit reached 1024 (1023)": "Out of stack",
+	"precompiled failed":              "Built-in failed",
+}
+
+// parityErrorMappingContaining translates EVM errors containing the given
+// messages into their Parity counterparts.
+var parityErrorMappingContaining = []struct {
+	message string
+	parity  string
+}{
+	{"invalid opcode:", "Bad instruction"},
+	{"stack underflow", "Stack underflow"},
+}
+
+// paritySkipTracesForErrors lists the inner call errors for which Parity does
+// not report a trace.
+var paritySkipTracesForErrors = map[string]bool{
+	"insufficient balance for transfer": true,
+}
+
+// parityCreateAction is the action of a Parity create trace.
+type parityCreateAction struct {
+	From           string `json:"from,omitempty"`
+	Value          string `json:"value,omitempty"`
+	Gas            string `json:"gas,omitempty"`
+	Init           string `json:"init,omitempty"`
+	CreationMethod string `json:"creationMethod,omitempty"`
+}
+
+// parityCreateResult is the result of a Parity create trace.
+type parityCreateResult struct {
+	GasUsed string `json:"gasUsed,omitempty"`
+	Code    string `json:"code,omitempty"`
+	Address string `json:"address,omitempty"`
+}
+
+// parityCallAction is the action of a Parity call trace.
+type parityCallAction struct {
+	From     string `json:"from,omitempty"`
+	To       string `json:"to,omitempty"`
+	Value    string `json:"value,omitempty"`
+	Gas      string `json:"gas,omitempty"`
+	Input    string `json:"input,omitempty"`
+	CallType string `json:"callType,omitempty"`
+}
+
+// parityCallResult is the result of a Parity call trace.
+type parityCallResult struct {
+	GasUsed string `json:"gasUsed,omitempty"`
+	Output  string `json:"output,omitempty"`
+}
+
+// paritySuicideAction is the action of a Parity suicide trace.
+type paritySuicideAction struct {
+	Address       string `json:"address,omitempty"`
+	RefundAddress string `json:"refundAddress,omitempty"`
+	Balance       string `json:"balance,omitempty"`
+}
+
+// parityNullResult is the result of traces reported without one.
+var parityNullResult = json.RawMessage("null")
+
+// parityTraceResult is a trace reported by the callTracerParity, in the field
+// order of the JavaScript implementation.
+type parityTraceResult struct {
+	Type                string      `json:"type"`
+	Action              interface{} `json:"action"`
+	Result              interface{} `json:"result,omitempty"`
+	Error               string      `json:"error,omitempty"`
+	TraceAddress        []int       `json:"traceAddress"`
+	Subtraces           int         `json:"subtraces"`
+	TransactionPosition interface{} `json:"transactionPosition,omitempty"`
+	TransactionHash     interface{} `json:"transactionHash,omitempty"`
+	BlockNumber         interface{} `json:"blockNumber,omitempty"`
+	BlockHash           interface{} `json:"blockHash,omitempty"`
+	Time                string      `json:"time,omitempty"`
+}
+
+// callParityTracer is the native implementation of the callTracerParity,
+// reporting all the internal calls made by a transaction as flat Parity traces.
+type callParityTracer struct {
+	nativeContext
+
+	callstack []*callFrame // Current recursive call stack of the EVM execution
+	descended bool         // Whether we've just descended into an inner call
+}
+
+// newCallParityTracer creates a native callTracerParity.
+func newCallParityTracer() *callParityTracer {
+	return &callParityTracer{callstack: []*callFrame{{}}}
+}
+
+// CaptureState implements the vm.Tracer interface to trace a single step of VM execution.
+func (t *callParityTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, rStack *vm.ReturnStack, rData []byte, contract *vm.Contract, depth int, err error) error {
+	if !t.step(env) {
+		return nil
+	}
+	// Capture any errors immediately
+	if err != nil {
+		t.fault(err, gas)
+		return nil
+	}
+	switch op {
+	case vm.CREATE, vm.CREATE2:
+		// If a new contract is being created, add to the call stack
+		inOff := stackPeekInt(stack, 1)
+		inEnd := addInt(inOff, stackPeekInt(stack, 2))
+
+		callGas := int64(env.CallGasTemp)
+		t.callstack = append(t.callstack, &callFrame{
+			typ:     op.String(),
+			from:    hexutil.Encode(contract.Address().Bytes()),
+			input:   hexutil.Encode(memorySlice(memory, inOff, inEnd)),
+			gas:     &callGas,
+			gasIn:   int64(gas),
+			gasCost: int64(cost),
+			value:   hexBig(stackPeek(stack, 0)),
+		})
+		t.descended = true
+		return nil
+
+	case vm.SELFDESTRUCT:
+		// If a contract is being self destructed, gather that as a subcall too
+		parent := t.callstack[len(t.callstack)-1]
+		parent.calls = append(parent.calls, &callFrame{
+			typ:     op.String(),
+			from:    hexutil.Encode(contract.Address().Bytes()),
+			to:      hexutil.Encode(stackPeekAddress(stack, 0).Bytes()),
+			gasIn:   int64(gas),
+			gasCost: int64(cost),
+			value:   hexBig(env.StateDB.GetBalance(contract.Address())),
+		})
+		return nil
+
+	case vm.CALL, vm.CALLCODE, vm.DELEGATECALL, vm.STATICCALL:
+		// If a new method invocation is being done, add to the call stack, skipping
+		// any pre-compile invocations, those are just fancy opcodes
+		to := stackPeekAddress(stack, 1)
+		if isPrecompiled(to) && (op == vm.CALL || op == vm.STATICCALL) {
+			return nil
+		}
+		off := 1
+		if op == vm.DELEGATECALL || op == vm.STATICCALL {
+			off = 0
+		}
+		inOff := stackPeekInt(stack, 2+off)
+		inEnd := addInt(inOff, stackPeekInt(stack, 3+off))
+
+		callGas := int64(env.CallGasTemp)
+		call := &callFrame{
+			typ:     op.String(),
+			from:    hexutil.Encode(contract.Address().Bytes()),
+			to:      hexutil.Encode(to.Bytes()),
+			input:   hexutil.Encode(memorySlice(memory, inOff, inEnd)),
+			gas:     &callGas,
+			gasIn:   int64(gas),
+			gasCost: int64(cost),
+			outOff:  stackPeekInt(stack, 4+off),
+			outLen:  stackPeekInt(stack, 5+off),
+		}
+		switch op {
+		case vm.CALL, vm.CALLCODE:
+			value := stackPeek(stack, 2)
+			call.value = hexBig(value)
+
+			// Add the stipend granted to value transfers
+			if value.Sign() > 0 {
+				callGas += int64(vars.CallStipend)
+			}
+		case vm.STATICCALL:
+			call.value = "0x0"
+		}
+		t.callstack = append(t.callstack, call)
+		t.descended = true
+		return nil
+	}
+	// If we've just descended into an inner call, retrieve it's true allowance if
+	// not yet known.
+	if t.descended {
+		if depth >= len(t.callstack) {
+			if call := t.callstack[len(t.callstack)-1]; call.gas == nil {
+				callGas := int64(gas)
+				call.gas = &callGas
+			}
+		}
+		t.descended = false
+	}
+	switch op {
+	case vm.REVERT:
+		t.callstack[len(t.callstack)-1].err = "execution reverted"
+		return nil
+
+	case vm.RETURN:
+		if depth == len(t.callstack) {
+			outOff := stackPeekInt(stack, 0)
+			outEnd := addInt(outOff, stackPeekInt(stack, 1))
+			t.callstack[len(t.callstack)-1].output = hexutil.Encode(memorySlice(memory, outOff, outEnd))
+		}
+		return nil
+	}
+	if depth == len(t.callstack)-1 {
+		// Pop off the last call and get the execution results
+		call := t.callstack[len(t.callstack)-1]
+		t.callstack = t.callstack[:len(t.callstack)-1]
+
+		ret := stackPeek(stack, 0)
+		if call.typ == vm.CREATE.String() || call.typ == vm.CREATE2.String() {
+			// If the call was a CREATE, retrieve the contract address and output code
+			call.gasUsed = hexInt(*call.gas - (int64(gas) - (call.gasIn - call.gasCost)))
+
+			if ret.Sign() != 0 {
+				addr := stackPeekAddress(stack, 0)
+				call.to = hexutil.Encode(addr.Bytes())
+				call.output = hexutil.Encode(env.StateDB.GetCode(addr))
+			} else if call.err == "" {
+				if t.opError == "" {
+					call.err = "internal failure"
+					return nil
+				}
+				if paritySkipTracesForErrors[t.opError] {
+					return nil
+				}
+				call.err = t.opError
+			}
+		} else {
+			// If the call was a contract call, retrieve the gas usage and output
+			if call.gas != nil {
+				call.gasUsed = hexInt(call.gasIn - call.gasCost + *call.gas - int64(gas))
+			}
+			if ret.Sign() != 0 {
+				if call.output == "" || call.output == "0x" {
+					call.output = hexutil.Encode(rData)
+				}
+			} else if call.err == "" {
+				switch {
+				case t.opError == "":
+					call.err = "internal failure"
+				case paritySkipTracesForErrors[t.opError]:
+					return nil
+				case isPrecompiled(common.HexToAddress(call.to)) && t.opError != vm.ErrOutOfGas.Error():
+					call.err = "precompiled failed"
+				default:
+					call.err = t.opError
+				}
+			}
+		}
+		call.finishGas()
+
+		// Inject the call into the previous one
+		parent := t.callstack[len(t.callstack)-1]
+		parent.calls = append(parent.calls, call)
+	}
+	return nil
+}
+
+// CaptureFault implements the vm.Tracer interface to trace an execution fault
+// while running an opcode.
+func (t *callParityTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, rStack *vm.ReturnStack, contract *vm.Contract, depth int, err error) error {
+	if t.failure == nil {
+		t.fault(err, gas)
+	}
+	return nil
+}
+
+// fault handles the failure of an operation, flattening the failed call into
+// its parent.
+func (t *callParityTracer) fault(err error, gas uint64) {
+	// If the topmost call already reverted, don't handle the additional fault again
+	if t.callstack[len(t.callstack)-1].err != "" {
+		return
+	}
+	// Pop off the just failed call
+	call := t.callstack[len(t.callstack)-1]
+	t.callstack = t.callstack[:len(t.callstack)-1]
+
+	call.err = err.Error()
+	if t.opError != "" {
+		if paritySkipTracesForErrors[t.opError] {
+			return
+		}
+		call.err = t.opError
+	}
+	// Consume all available gas, or retrieve the true allowance from within the
+	// inner call if not yet known
+	if call.gas != nil {
+		call.finishGas()
+		call.gasUsed = call.gasHex
+	} else {
+		call.gasHex = hexInt(int64(gas))
+	}
+	// Flatten the failed call into its parent, or leave it in the stack if the
+	// last call failed too
+	if len(t.callstack) > 0 {
+		parent := t.callstack[len(t.callstack)-1]
+		parent.calls = append(parent.calls, call)
+		return
+	}
+	t.callstack = append(t.callstack, call)
+}
+
+// GetResult implements TxTracer, returning the flattened Parity traces of the
+// transaction.
+func (t *callParityTracer) GetResult() (json.RawMessage, error) {
+	result := &callFrame{
+		typ:     t.typ,
+		from:    hexutil.Encode(t.from.Bytes()),
+		to:      hexutil.Encode(t.to.Bytes()),
+		value:   hexBig(t.value),
+		gasHex:  hexInt(int64(t.gas)),
+		gasUsed: hexInt(int64(t.gasUsed)),
+		input:   hexutil.Encode(t.input),
+		output:  hexutil.Encode(t.output),
+	}
+	// When still descended into a call which never started executing, drop the
+	// empty outer frame to report the calls made from within it.
+	if t.descended && len(t.callstack) > 1 && t.callstack[0].empty() {
+		t.callstack = t.callstack[1:]
+	}
+	result.calls = t.callstack[0].calls
+	if result.err = t.callstack[0].err; result.err == "" {
+		result.err = t.err
+	}
+	if result.err != "" && (result.err != "execution reverted" || result.output == "0x") {
+		result.output = ""
+	}
+	var block interface{}
+	if t.block != nil && *t.block != 0 {
+		block = *t.block
+	}
+	traces := t.finalize(result, nil, block)
+	traces[0].Time = t.time
+
+	blob, err := encodeResult(traces)
+	if err != nil {
+		return nil, err
+	}
+	return blob, t.failure
+}
+
+// empty reports whether no field of the call has been set.
+func (call *callFrame) empty() bool {
+	return call.typ == "" && call.from == "" && call.to == "" && call.input == "" && call.value == "" &&
+		call.gas == nil && call.gasHex == "" && call.gasUsed == "" && call.output == "" && call.err == "" && call.calls == nil
+}
+
+// finalize converts a call and all its inner calls into flat Parity traces.
+func (t *callParityTracer) finalize(call *callFrame, traceAddress []int, block interface{}) []*parityTraceResult {
+	trace := &parityTraceResult{
+		Error:               call.err,
+		TraceAddress:        traceAddress,
+		Subtraces:           len(call.calls),
+		TransactionPosition: t.extra["transactionPosition"],
+		TransactionHash:     t.extra["transactionHash"],
+		BlockNumber:         block,
+		BlockHash:           t.extra["blockHash"],
+	}
+	if trace.TraceAddress == nil {
+		trace.TraceAddress = []int{}
+	}
+	if trace.BlockNumber == nil {
+		trace.BlockNumber = t.extra["blockNumber"]
+	}
+	switch call.typ {
+	case vm.CREATE.String(), vm.CREATE2.String():
+		trace.Type = "create"
+		trace.Action = &parityCreateAction{
+			From:           call.from,
+			Value:          call.value,
+			Gas:            call.gasHex,
+			Init:           call.input,
+			CreationMethod: strings.ToLower(call.typ),
+		}
+		trace.Result = &parityCreateResult{
+			GasUsed: call.gasUsed,
+			Code:    call.output,
+			Address: call.to,
+		}
+	case vm.SELFDESTRUCT.String():
+		trace.Type = "suicide"
+		trace.Action = &paritySuicideAction{
+			Address:       call.from,
+			RefundAddress: call.to,
+			Balance:       call.value,
+		}
+		trace.Result = parityNullResult
+	default:
+		trace.Type = "call"
+		trace.Action = &parityCallAction{
+			From:     call.from,
+			To:       call.to,
+			Value:    call.value,
+			Gas:      call.gasHex,
+			Input:    call.input,
+			CallType: strings.ToLower(call.typ),
+		}
+		trace.Result = &parityCallResult{
+			GasUsed: call.gasUsed,
+			Output:  call.output,
+		}
+	}
+	if trace.Error != "" {
+		if parity, ok := parityErrorMapping[trace.Error]; ok {
+			trace.Error, trace.Result = parity, nil
+		} else {
+			for _, mapping := range parityErrorMappingContaining {
+				if strings.Contains(trace.Error, mapping.message) {
+					trace.Error, trace.Result = mapping.parity, nil
+				}
+			}
+		}
+	}
+	traces := []*parityTraceResult{trace}
+	for i, child := range call.calls {
+		// Delegate and static calls use the value of their parent
+		if (child.typ == vm.DELEGATECALL.String() || child.typ == vm.STATICCALL.String()) && child.value == "" {
+			child.value = call.value
+		}
+		address := make([]int, len(traceAddress)+1)
+		copy(address, traceAddress)
+		address[len(traceAddress)] = i
+
+		traces = append(traces, t.finalize(child, address, nil)...)
+	}
+	return traces
+}
@@ -0,0 +1,196 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// prestateAccount is the state of an account before the transaction executed.
+type prestateAccount struct {
+	Balance *big.Int
+	Nonce   int64
+	Code    []byte
+	Storage []prestateSlot
+}
+
+// prestateSlot is a storage slot of an account before the transaction executed.
+type prestateSlot struct {
+	key   common.Hash
+	value common.Hash
+}
+
+// MarshalJSON encodes the account in the field order of the JavaScript
+// prestateTracer, with storage slots in the order they were accessed.
+func (acc *prestateAccount) MarshalJSON() ([]byte, error) {
+	buf := new(bytes.Buffer)
+	buf.WriteString(`{"balance":"` + hexBig(acc.Balance) + `","nonce":`)
+	nonce, err := json.Marshal(acc.Nonce)
+	if err != nil {
+		return nil, err
+	}
+	buf.Write(nonce)
+	buf.WriteString(`,"code":"` + hexutil.Encode(acc.Code) + `","storage":{`)
+	for i, slot := range acc.Storage {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.WriteString(`"` + slot.key.Hex() + `":"` + slot.value.Hex() + `"`)
+	}
+	buf.WriteString(`}}`)
+	return buf.Bytes(), nil
+}
+
+// prestateTracer is the native implementation of the prestateTracer, reporting
+// all the state accessed by a transaction, as it was before its execution.
+type prestateTracer struct {
+	nativeContext
+
+	accounts []common.Address                    // Accessed accounts, in access order
+	prestate map[common.Address]*prestateAccount // Accessed state, nil until tracing starts
+}
+
+// newPrestateTracer creates a native prestateTracer.
+func newPrestateTracer() *prestateTracer {
+	return &prestateTracer{}
+}
+
+// lookupAccount injects the specified account into the prestate.
+func (t *prestateTracer) lookupAccount(addr common.Address, db vm.StateDB) {
+	if _, ok := t.prestate[addr]; ok {
+		return
+	}
+	t.accounts = append(t.accounts, addr)
+	t.prestate[addr] = &prestateAccount{
+		Balance: db.GetBalance(addr),
+		Nonce:   int64(db.GetNonce(addr)),
+		Code:    db.GetCode(addr),
+	}
+}
+
+// lookupStorage injects the specified storage entry of the given account into
+// the prestate.
+func (t *prestateTracer) lookupStorage(addr common.Address, key common.Hash, db vm.StateDB) {
+	acc, ok := t.prestate[addr]
+	if !ok {
+		t.lookupAccount(addr, db)
+		acc = t.prestate[addr]
+	}
+	for _, slot := range acc.Storage {
+		if slot.key == key {
+			return
+		}
+	}
+	acc.Storage = append(acc.Storage, prestateSlot{key: key, value: db.GetState(addr, key)})
+}
+
+// CaptureState implements the vm.Tracer interface to trace a single step of VM execution.
+func (t *prestateTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, rStack *vm.ReturnStack, rData []byte, contract *vm.Contract, depth int, err error) error {
+	if !t.step(env) {
+		return nil
+	}
+	// Add the current account if we just started tracing. Its balance will
+	// include the value sent along with the message, fixed up in GetResult.
+	if t.prestate == nil {
+		t.prestate = make(map[common.Address]*prestateAccount)
+		t.lookupAccount(contract.Address(), env.StateDB)
+	}
+	// Whenever new state is accessed, add it to the prestate
+	switch op {
+	case vm.EXTCODECOPY, vm.EXTCODESIZE, vm.BALANCE:
+		t.lookupAccount(stackPeekAddress(stack, 0), env.StateDB)
+
+	case vm.CREATE:
+		from := contract.Address()
+		t.lookupAccount(crypto.CreateAddress(from, env.StateDB.GetNonce(from)), env.StateDB)
+
+	case vm.CREATE2:
+		offset := stackPeekInt(stack, 1)
+		end := addInt(offset, stackPeekInt(stack, 2))
+		salt := common.BigToHash(stackPeek(stack, 3))
+		code := memorySlice(memory, offset, end)
+		t.lookupAccount(crypto.CreateAddress2(contract.Address(), salt, crypto.Keccak256(code)), env.StateDB)
+
+	case vm.CALL, vm.CALLCODE, vm.DELEGATECALL, vm.STATICCALL:
+		t.lookupAccount(stackPeekAddress(stack, 1), env.StateDB)
+
+	case vm.SSTORE, vm.SLOAD:
+		t.lookupStorage(contract.Address(), common.BigToHash(stackPeek(stack, 0)), env.StateDB)
+	}
+	return nil
+}
+
+// CaptureFault implements the vm.Tracer interface to trace an execution fault
+// while running an opcode.
+func (t *prestateTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, rStack *vm.ReturnStack, contract *vm.Contract, depth int, err error) error {
+	return nil
+}
+
+// GetResult implements TxTracer, returning the accessed accounts with their
+// state before the transaction executed.
+func (t *prestateTracer) GetResult() (json.RawMessage, error) {
+	if t.env != nil {
+		// Deduct the value from the outer transaction, and move it back to the origin
+		t.lookupAccount(t.from, t.env.StateDB)
+
+		if to, ok := t.prestate[t.to]; ok {
+			to.Balance = new(big.Int).Sub(to.Balance, t.value)
+		}
+		from := t.prestate[t.from]
+		from.Balance = new(big.Int).Add(from.Balance, t.value)
+
+		// Decrement the caller's nonce, and remove empty create targets. Any existing
+		// state would have caused the transaction to be rejected as invalid.
+		from.Nonce--
+		if t.typ == "CREATE" {
+			t.remove(t.to)
+		}
+	}
+	buf := new(bytes.Buffer)
+	buf.WriteByte('{')
+	for i, addr := range t.accounts {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		account, err := json.Marshal(t.prestate[addr])
+		if err != nil {
+			return nil, err
+		}
+		buf.WriteString(`"` + hexutil.Encode(addr.Bytes()) + `":`)
+		buf.Write(account)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), t.failure
+}
+
+// remove drops an account from the prestate.
+func (t *prestateTracer) remove(addr common.Address) {
+	delete(t.prestate, addr)
+	for i, acc := range t.accounts {
+		if acc == addr {
+			t.accounts = append(t.accounts[:i], t.accounts[i+1:]...)
+			return
+		}
+	}
+}
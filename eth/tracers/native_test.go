@@ -0,0 +1,201 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/core/vm/runtime"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/tests"
+)
+
+// timeField matches the execution time reported by the tracers, which differs
+// between runs.
+var timeField = regexp.MustCompile(`,?"time":"[^"]*"`)
+
+// runTracerTest executes the transaction of a call tracer test with the given
+// tracer, returning the tracing result.
+func runTracerTest(t *testing.T, test *callTracerTest, tracer TxTracer) json.RawMessage {
+	tx := new(types.Transaction)
+	if err := rlp.DecodeBytes(common.FromHex(test.Input), tx); err != nil {
+		t.Fatalf("failed to parse testcase input: %v", err)
+	}
+	signer := types.MakeSigner(test.Genesis.Config, new(big.Int).SetUint64(uint64(test.Context.Number)))
+	origin, _ := signer.Sender(tx)
+
+	context := vm.Context{
+		CanTransfer: core.CanTransfer,
+		Transfer:    core.Transfer,
+		Origin:      origin,
+		Coinbase:    test.Context.Miner,
+		BlockNumber: new(big.Int).SetUint64(uint64(test.Context.Number)),
+		Time:        new(big.Int).SetUint64(uint64(test.Context.Time)),
+		Difficulty:  (*big.Int)(test.Context.Difficulty),
+		GasLimit:    uint64(test.Context.GasLimit),
+		GasPrice:    tx.GasPrice(),
+	}
+	_, statedb := tests.MakePreState(rawdb.NewMemoryDatabase(), test.Genesis.Alloc, false)
+
+	tracer.CaptureExtraContext(map[string]interface{}{
+		"blockNumber":         uint64(test.Context.Number),
+		"blockHash":           common.Hash{0x01}.Hex(),
+		"transactionHash":     tx.Hash().Hex(),
+		"transactionPosition": uint64(0),
+	})
+	evm := vm.NewEVM(context, statedb, test.Genesis.Config, vm.Config{Debug: true, Tracer: tracer})
+
+	msg, err := tx.AsMessage(signer, nil)
+	if err != nil {
+		t.Fatalf("failed to prepare transaction for tracing: %v", err)
+	}
+	st := core.NewStateTransition(evm, msg, new(core.GasPool).AddGas(tx.Gas()))
+	if _, err = st.TransitionDb(); err != nil {
+		t.Fatalf("failed to execute transaction: %v", err)
+	}
+	res, err := tracer.GetResult()
+	if err != nil {
+		t.Fatalf("failed to retrieve trace result: %v", err)
+	}
+	return res
+}
+
+// Tests that the native tracers produce the exact same output as the JavaScript
+// tracers they replace.
+func TestNativeTracers(t *testing.T) {
+	files, err := ioutil.ReadDir("testdata")
+	if err != nil {
+		t.Fatalf("failed to retrieve tracer test suite: %v", err)
+	}
+	for _, file := range files {
+		if !strings.HasPrefix(file.Name(), "call_tracer_") {
+			continue
+		}
+		file := file // capture range variable
+		t.Run(camel(strings.TrimSuffix(strings.TrimPrefix(file.Name(), "call_tracer_"), ".json")), func(t *testing.T) {
+			t.Parallel()
+
+			blob, err := ioutil.ReadFile(filepath.Join("testdata", file.Name()))
+			if err != nil {
+				t.Fatalf("failed to read testcase: %v", err)
+			}
+			test := new(callTracerTest)
+			if err := json.Unmarshal(blob, test); err != nil {
+				t.Fatalf("failed to parse testcase: %v", err)
+			}
+			for name := range native {
+				nativeTracer, err := NewTxTracer(name)
+				if err != nil {
+					t.Fatalf("failed to create native %s: %v", name, err)
+				}
+				if _, ok := nativeTracer.(*Tracer); ok {
+					t.Fatalf("%s: native tracer not selected", name)
+				}
+				jsTracer, err := NewTxTracer(name + legacySuffix)
+				if err != nil {
+					t.Fatalf("failed to create legacy %s: %v", name, err)
+				}
+				if _, ok := jsTracer.(*Tracer); !ok {
+					t.Fatalf("%s: legacy tracer not selected", name)
+				}
+				have := timeField.ReplaceAll(runTracerTest(t, test, nativeTracer), nil)
+				want := timeField.ReplaceAll(runTracerTest(t, test, jsTracer), nil)
+				if string(have) != string(want) {
+					t.Errorf("%s: result mismatch:\nhave %s\nwant %s", name, have, want)
+				}
+				if name == "callTracer" {
+					ret := new(callTrace)
+					if err := json.Unmarshal(have, ret); err != nil {
+						t.Fatalf("failed to unmarshal trace result: %v", err)
+					}
+					if !jsonEqual(ret, test.Result) {
+						t.Errorf("trace mismatch: \nhave %+v\nwant %+v", ret, test.Result)
+					}
+				}
+			}
+		})
+	}
+}
+
+// Tests that the native tracers match the JavaScript ones on the edge cases of
+// the call frame tracking.
+func TestNativeTracersEdgeCases(t *testing.T) {
+	callee := common.HexToAddress("0x00000000000000000000000000000000000000cc")
+	tests := []struct {
+		name    string
+		code    string
+		callee  string
+		balance int64
+	}{
+		{"createSelfdestruct", "605a600053600160006001f0ff00", "", 1},
+		{"createInsufficientBalance", "605a600053600160006001f0ff00", "", 0},
+		{"create2", "605a6000536000600160006000f500", "", 0},
+		{"valueTransfer", "6000600060006000600173" + callee.Hex()[2:] + "61fffff100", "", 1},
+		{"precompiles", "60206000600060006000600261fffff160206000600060006000600261fffff200", "", 0},
+		{"revert", "602a60005260206000fd", "", 0},
+		{"invalidOpcode", "fe", "", 0},
+		{"stackUnderflow", "01", "", 0},
+		{"storage", "6001600055600054543100", "", 0},
+		{"innerRevert", "6000600060006000600073" + callee.Hex()[2:] + "61fffff100", "602a60005260206000fd", 0},
+		{"innerOutOfGas", "6000600060006000600073" + callee.Hex()[2:] + "610100f100", "5b600056", 0},
+		{"delegateReturn", "6020600060006000" + "73" + callee.Hex()[2:] + "61fffff400", "602a60005260206000f3", 0},
+	}
+	for _, tt := range tests {
+		for name := range native {
+			run := func(tracer TxTracer) (json.RawMessage, error) {
+				statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+				statedb.SetBalance(common.BytesToAddress([]byte("contract")), big.NewInt(tt.balance))
+				if tt.callee != "" {
+					statedb.SetCode(callee, common.FromHex(tt.callee))
+				}
+				runtime.Execute(common.FromHex(tt.code), nil, &runtime.Config{
+					State:     statedb,
+					GasLimit:  1000000,
+					EVMConfig: vm.Config{Debug: true, Tracer: tracer},
+				})
+				return tracer.GetResult()
+			}
+			nativeTracer, _ := NewTxTracer(name)
+			jsTracer, _ := NewTxTracer(name + legacySuffix)
+
+			have, err := run(nativeTracer)
+			if err != nil {
+				t.Fatalf("%s/%s: native tracing failed: %v", tt.name, name, err)
+			}
+			want, err := run(jsTracer)
+			if err != nil {
+				t.Fatalf("%s/%s: legacy tracing failed: %v", tt.name, name, err)
+			}
+			have, want = timeField.ReplaceAll(have, nil), timeField.ReplaceAll(want, nil)
+			if string(have) != string(want) {
+				t.Errorf("%s/%s: result mismatch:\nhave %s\nwant %s", tt.name, name, have, want)
+			}
+		}
+	}
+}
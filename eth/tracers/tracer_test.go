@@ -21,6 +21,7 @@ import (
 	"encoding/json"
 	"errors"
 	"math/big"
+	"strings"
 	"testing"
 	"time"
 
@@ -142,10 +143,8 @@ func TestOpcodes(t *testing.T) {
 }
 
 func TestHalt(t *testing.T) {
-	t.Skip("duktape doesn't support abortion")
-
 	timeout := errors.New("stahp")
-	tracer, err := New("{step: function() { while(1); }, result: function() { return null; }}")
+	tracer, err := New("{step: function() { while(1); }, fault: function() {}, result: function() { return null; }}")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -160,6 +159,34 @@ func TestHalt(t *testing.T) {
 	}
 }
 
+func TestInvalidBuffer(t *testing.T) {
+	tracer, err := New("{step: function(log, db) { db.getBalance({}); }, fault: function() {}, result: function() { return null; }}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = runTrace(tracer); err == nil || !strings.Contains(err.Error(), "in server-side tracer function 'step'") {
+		t.Errorf("Expected step error, got %v", err)
+	}
+}
+
+func TestBuffers(t *testing.T) {
+	tracer, err := New(`{res: [], step: function(log, db) {
+		var addr = toAddress("0x00000000000000000000000000000000deadbeef");
+		this.res.push(toHex(addr), addr.length, addr[19], toHex(slice([1, 2, 3], 1, 3)), toHex(toWord([0xff])));
+	}, fault: function() {}, result: function() { return this.res.slice(0, 5); }}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ret, err := runTrace(tracer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `["0x00000000000000000000000000000000deadbeef",20,239,"0x0203","0x00000000000000000000000000000000000000000000000000000000000000ff"]`
+	if string(ret) != want {
+		t.Errorf("Expected return value to be %s, got %s", want, ret)
+	}
+}
+
 func TestHaltBetweenSteps(t *testing.T) {
 	tracer, err := New("{step: function() {}, fault: function() {}, result: function() { return null; }}")
 	if err != nil {
@@ -178,3 +205,21 @@ func TestHaltBetweenSteps(t *testing.T) {
 		t.Errorf("Expected timeout error, got %v", err)
 	}
 }
+
+func TestHaltBeforeResult(t *testing.T) {
+	tracer, err := New("{step: function() {}, fault: function() {}, result: function() { return null; }}")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	env := vm.NewEVM(vm.Context{BlockNumber: big.NewInt(1)}, &dummyStatedb{}, params.TestChainConfig, vm.Config{Debug: true, Tracer: tracer})
+	contract := vm.NewContract(&account{}, &account{}, big.NewInt(0), 0)
+
+	tracer.CaptureState(env, 0, 0, 0, 0, nil, nil, nil, nil, contract, 0, nil)
+	timeout := errors.New("stahp")
+	tracer.Stop(timeout)
+
+	if _, err := tracer.GetResult(); err == nil || err.Error() != timeout.Error() {
+		t.Errorf("Expected timeout error, got %v", err)
+	}
+}
@@ -20,6 +20,7 @@ package graphql
 import (
 	"context"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/ethereum/go-ethereum"
@@ -29,12 +30,17 @@ import (
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/eth"
 	"github.com/ethereum/go-ethereum/eth/filters"
 	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/rpc"
 )
 
+// maxAccountsRange is the maximum number of blocks a single accounts query may
+// span.
+const maxAccountsRange = 1000
+
 var (
 	errBlockInvariant = errors.New("block objects must be instantiated with at least one of num or hash")
 )
@@ -119,6 +125,10 @@ func (l *Log) Data(ctx context.Context) hexutil.Bytes {
 	return hexutil.Bytes(l.log.Data)
 }
 
+func (l *Log) Removed(ctx context.Context) bool {
+	return l.log.Removed
+}
+
 // Transaction represents an Ethereum transaction.
 // backend and hash are mandatory; all others will be fetched when required.
 type Transaction struct {
@@ -127,6 +137,7 @@ type Transaction struct {
 	tx      *types.Transaction
 	block   *Block
 	index   uint64
+	replay  *eth.TraceReplayResult
 }
 
 // resolve returns the internal transaction object, fetching it if needed.
@@ -215,7 +226,7 @@ func (t *Transaction) From(ctx context.Context, args BlockNumberArgs) (*Account,
 	}
 	var signer types.Signer = types.HomesteadSigner{}
 	if tx.Protected() {
-		signer = types.NewEIP155Signer(tx.ChainId())
+		signer = types.LatestSignerForChainID(tx.ChainId())
 	}
 	from, _ := types.Sender(signer, tx)
 
@@ -314,6 +325,51 @@ func (t *Transaction) Logs(ctx context.Context) (*[]*Log, error) {
 	return &ret, nil
 }
 
+// resolveReplay returns the traces and state diff of the transaction, replaying
+// it if needed. Nil is returned for transactions not yet mined.
+func (t *Transaction) resolveReplay(ctx context.Context) (*eth.TraceReplayResult, error) {
+	if t.replay != nil {
+		return t.replay, nil
+	}
+	tracer, err := backendTracer(t.backend)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := t.resolve(ctx); err != nil || t.block == nil {
+		return nil, err
+	}
+	replay, err := tracer.replayTransaction(ctx, t.hash, []string{eth.TraceTypeTrace, eth.TraceTypeStateDiff})
+	if err != nil {
+		return nil, err
+	}
+	t.replay = replay
+	return t.replay, nil
+}
+
+func (t *Transaction) Trace(ctx context.Context) (*[]*Trace, error) {
+	replay, err := t.resolveReplay(ctx)
+	if err != nil || replay == nil {
+		return nil, err
+	}
+	traces, err := newTraces(t.backend, replay.Trace)
+	if err != nil {
+		return nil, err
+	}
+	return &traces, nil
+}
+
+func (t *Transaction) StateDiff(ctx context.Context) (*[]*AccountDiff, error) {
+	replay, err := t.resolveReplay(ctx)
+	if err != nil || replay == nil {
+		return nil, err
+	}
+	diffs, err := newAccountDiffs(replay.StateDiff)
+	if err != nil {
+		return nil, err
+	}
+	return &diffs, nil
+}
+
 func (t *Transaction) R(ctx context.Context) (hexutil.Big, error) {
 	tx, err := t.resolve(ctx)
 	if err != nil || tx == nil {
@@ -748,6 +804,22 @@ func (b *Block) Logs(ctx context.Context, args struct{ Filter BlockFilterCriteri
 	return runFilter(ctx, b.backend, filter)
 }
 
+func (b *Block) Traces(ctx context.Context) ([]*Trace, error) {
+	tracer, err := backendTracer(b.backend)
+	if err != nil {
+		return nil, err
+	}
+	block, err := b.resolve(ctx)
+	if err != nil || block == nil {
+		return nil, err
+	}
+	traces, err := tracer.traceBlock(ctx, block)
+	if err != nil {
+		return nil, err
+	}
+	return newTraces(b.backend, traces)
+}
+
 func (b *Block) Account(ctx context.Context, args struct {
 	Address common.Address
 }) (*Account, error) {
@@ -899,6 +971,7 @@ func (p *Pending) EstimateGas(ctx context.Context, args struct {
 // Resolver is the top-level object in the GraphQL hierarchy.
 type Resolver struct {
 	backend ethapi.Backend
+	subs    *subscriptions // Event system backing subscriptions, nil if unavailable
 }
 
 func (r *Resolver) Block(ctx context.Context, args struct {
@@ -964,6 +1037,37 @@ func (r *Resolver) Blocks(ctx context.Context, args struct {
 	return ret, nil
 }
 
+func (r *Resolver) Accounts(ctx context.Context, args struct {
+	Address common.Address
+	From    hexutil.Uint64
+	To      *hexutil.Uint64
+}) ([]*Account, error) {
+	from := rpc.BlockNumber(args.From)
+
+	var to rpc.BlockNumber
+	if args.To != nil {
+		to = rpc.BlockNumber(*args.To)
+	} else {
+		to = rpc.BlockNumber(r.backend.CurrentBlock().Number().Int64())
+	}
+	if to < from {
+		return []*Account{}, nil
+	}
+	// Block numbers past the int64 range would wrap into the special ones
+	if from < 0 || to-from >= maxAccountsRange {
+		return nil, fmt.Errorf("cannot query more than %d blocks, narrow the block range", maxAccountsRange)
+	}
+	ret := make([]*Account, 0, to-from+1)
+	for i := from; i <= to; i++ {
+		ret = append(ret, &Account{
+			backend:       r.backend,
+			address:       args.Address,
+			blockNrOrHash: rpc.BlockNumberOrHashWithNumber(i),
+		})
+	}
+	return ret, nil
+}
+
 func (r *Resolver) Pending(ctx context.Context) *Pending {
 	return &Pending{r.backend}
 }
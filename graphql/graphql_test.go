@@ -19,12 +19,22 @@ package graphql
 import (
 	"fmt"
 	"io/ioutil"
+	"math/big"
 	"net/http"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/eth"
 	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/params/types/genesisT"
+	"github.com/gorilla/websocket"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -90,6 +100,213 @@ func TestGraphQLHTTPOnSamePort_GQLRequest_Unsuccessful(t *testing.T) {
 	assert.Equal(t, "404 page not found\n", string(bodyBytes))
 }
 
+// Tests that account state can be queried over a range of blocks.
+func TestGraphQLAccountsRange(t *testing.T) {
+	stack := createNode(t, true)
+	defer stack.Close()
+	if err := stack.Start(); err != nil {
+		t.Fatalf("could not start node: %v", err)
+	}
+	body := strings.NewReader(`{"query": "{accounts(address: \"0x0000000000000000000000000000000000000000\", from: 0){balance transactionCount}}"}`)
+	gqlReq, err := http.NewRequest(http.MethodPost, fmt.Sprintf("http://%s/graphql", "127.0.0.1:9393"), body)
+	if err != nil {
+		t.Error("could not issue new http request ", err)
+	}
+	gqlReq.Header.Set("Content-Type", "application/json")
+	resp := doHTTPRequest(t, gqlReq)
+	bodyBytes, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("could not read from response body: %v", err)
+	}
+	expected := `{"data":{"accounts":[{"balance":"0x0","transactionCount":"0x0"}]}}`
+	assert.Equal(t, expected, string(bodyBytes))
+
+	// Ranges beyond the limit are rejected
+	body = strings.NewReader(fmt.Sprintf(`{"query": "{accounts(address: \"0x0000000000000000000000000000000000000000\", from: 0, to: %d){balance}}"}`, maxAccountsRange))
+	gqlReq, err = http.NewRequest(http.MethodPost, fmt.Sprintf("http://%s/graphql", "127.0.0.1:9393"), body)
+	if err != nil {
+		t.Error("could not issue new http request ", err)
+	}
+	gqlReq.Header.Set("Content-Type", "application/json")
+	resp = doHTTPRequest(t, gqlReq)
+	bodyBytes, err = ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("could not read from response body: %v", err)
+	}
+	expected = `{"errors":[{"message":"cannot query more than 1000 blocks, narrow the block range","path":["accounts"]}],"data":null}`
+	assert.Equal(t, expected, string(bodyBytes))
+}
+
+// Tests that queries and subscriptions are served over websockets.
+func TestGraphQLWebsocket(t *testing.T) {
+	stack := createNode(t, false)
+	defer stack.Close()
+	ethBackend := createTestBackend(t, stack)
+	if err := New(stack, ethBackend.APIBackend, []string{}, []string{}, false); err != nil {
+		t.Fatalf("could not create graphql service: %v", err)
+	}
+	if err := stack.Start(); err != nil {
+		t.Fatalf("could not start node: %v", err)
+	}
+	dialer := websocket.Dialer{Subprotocols: []string{wsSubprotocol}}
+	conn, _, err := dialer.Dial(fmt.Sprintf("ws://%s/graphql", "127.0.0.1:9393"), nil)
+	if err != nil {
+		t.Fatalf("could not dial websocket: %v", err)
+	}
+	defer conn.Close()
+
+	send := func(msg string) {
+		if err := conn.WriteMessage(websocket.TextMessage, []byte(msg)); err != nil {
+			t.Fatalf("could not write message: %v", err)
+		}
+	}
+	expect := func(want string) {
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		_, msg, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("could not read message: %v", err)
+		}
+		assert.JSONEq(t, want, string(msg))
+	}
+	send(`{"type": "connection_init"}`)
+	expect(`{"type": "connection_ack"}`)
+	expect(`{"type": "ka"}`)
+
+	// Plain queries are answered once and completed
+	send(`{"id": "1", "type": "start", "payload": {"query": "{block{number}}"}}`)
+	expect(`{"id": "1", "type": "data", "payload": {"data": {"block": {"number": "0x0"}}}}`)
+	expect(`{"id": "1", "type": "complete"}`)
+
+	// Subscriptions deliver events until stopped. Operations are started in
+	// order, so the subscription is installed once the next query is answered.
+	send(`{"id": "2", "type": "start", "payload": {"query": "subscription{newBlocks{number}}"}}`)
+	send(`{"id": "4", "type": "start", "payload": {"query": "{block{number}}"}}`)
+	expect(`{"id": "4", "type": "data", "payload": {"data": {"block": {"number": "0x0"}}}}`)
+	expect(`{"id": "4", "type": "complete"}`)
+
+	blocks, _ := core.GenerateChain(params.AllEthashProtocolChanges, ethBackend.BlockChain().Genesis(), ethash.NewFaker(), ethBackend.ChainDb(), 1, nil)
+	if _, err := ethBackend.BlockChain().InsertChain(blocks); err != nil {
+		t.Fatalf("could not insert chain: %v", err)
+	}
+	expect(`{"id": "2", "type": "data", "payload": {"data": {"newBlocks": {"number": "0x1"}}}}`)
+
+	send(`{"id": "2", "type": "stop"}`)
+	expect(`{"id": "2", "type": "complete"}`)
+
+	// Invalid subscriptions are reported back
+	send(`{"id": "3", "type": "start", "payload": {"query": "subscription{newLogs(filter: {fromBlock: 2, toBlock: 1}){index}}"}}`)
+	expect(`{"id": "3", "type": "data", "payload": {"errors": [{"message": "invalid from and to block combination: from \u003e to"}]}}`)
+	expect(`{"id": "3", "type": "complete"}`)
+}
+
+// Tests that websocket upgrades are subject to the virtual host check.
+func TestGraphQLWebsocketVirtualHosts(t *testing.T) {
+	stack := createNode(t, false)
+	defer stack.Close()
+	ethBackend := createTestBackend(t, stack)
+	if err := New(stack, ethBackend.APIBackend, []string{}, []string{"localhost"}, false); err != nil {
+		t.Fatalf("could not create graphql service: %v", err)
+	}
+	if err := stack.Start(); err != nil {
+		t.Fatalf("could not start node: %v", err)
+	}
+	dialer := websocket.Dialer{Subprotocols: []string{wsSubprotocol}}
+	for _, tt := range []struct {
+		host   string
+		status int
+	}{
+		{"localhost", http.StatusSwitchingProtocols},
+		{"evil.com", http.StatusForbidden},
+	} {
+		conn, resp, err := dialer.Dial(fmt.Sprintf("ws://%s/graphql", "127.0.0.1:9393"), http.Header{"Host": {tt.host}})
+		if conn != nil {
+			conn.Close()
+		}
+		if resp == nil {
+			t.Fatalf("host %s: could not dial websocket: %v", tt.host, err)
+		}
+		if resp.StatusCode != tt.status {
+			t.Errorf("host %s: status mismatch: have %d, want %d", tt.host, resp.StatusCode, tt.status)
+		}
+	}
+}
+
+// Tests that the traces and state diffs of transactions and blocks are exposed.
+func TestGraphQLTraces(t *testing.T) {
+	stack := createNode(t, false)
+	defer stack.Close()
+	tx := createGQLServiceWithTransactions(t, stack, true)
+	if err := stack.Start(); err != nil {
+		t.Fatalf("could not start node: %v", err)
+	}
+	for i, tt := range []struct {
+		query string
+		want  string
+	}{
+		{
+			query: fmt.Sprintf(`{transaction(hash: \"%s\"){trace{type action{callType from to value gas input} result{gasUsed output} error traceAddress subtraces}}}`, tx.Hash().Hex()),
+			want:  `{"data":{"transaction":{"trace":[{"type":"call","action":{"callType":"call","from":"0x71562b71999873db5b286df957af199ec94617f7","to":"0x0000000000000000000000000000000000000dad","value":"0x3e8","gas":"0x0","input":"0x"},"result":{"gasUsed":"0x0","output":"0x"},"error":null,"traceAddress":[],"subtraces":0}]}}}`,
+		},
+		{
+			query: fmt.Sprintf(`{transaction(hash: \"%s\"){stateDiff{address balance{from to} nonce{from to} code{from to} storage{slot}}}}`, tx.Hash().Hex()),
+			want:  `{"data":{"transaction":{"stateDiff":[{"address":"0x0000000000000000000000000000000000000dad","balance":{"from":null,"to":"0x3e8"},"nonce":{"from":null,"to":"0x0"},"code":{"from":null,"to":"0x"},"storage":[]},{"address":"0x0100000000000000000000000000000000000000","balance":{"from":null,"to":"0x5208"},"nonce":{"from":null,"to":"0x0"},"code":{"from":null,"to":"0x"},"storage":[]},{"address":"0x71562b71999873db5b286df957af199ec94617f7","balance":{"from":"0x3b9aca00","to":"0x3b9a7410"},"nonce":{"from":"0x0","to":"0x1"},"code":null,"storage":[]}]}}}`,
+		},
+		{
+			query: `{block(number: 1){traces{type action{from to author rewardType} transaction{hash}}}}`,
+			want:  fmt.Sprintf(`{"data":{"block":{"traces":[{"type":"call","action":{"from":"0x71562b71999873db5b286df957af199ec94617f7","to":"0x0000000000000000000000000000000000000dad","author":null,"rewardType":null},"transaction":{"hash":"%s"}},{"type":"reward","action":{"from":null,"to":null,"author":"0x0100000000000000000000000000000000000000","rewardType":"block"},"transaction":null}]}}}`, tx.Hash().Hex()),
+		},
+	} {
+		body := strings.NewReader(fmt.Sprintf(`{"query": "%s"}`, tt.query))
+		gqlReq, err := http.NewRequest(http.MethodPost, fmt.Sprintf("http://%s/graphql", "127.0.0.1:9393"), body)
+		if err != nil {
+			t.Fatalf("test %d: could not issue new http request: %v", i, err)
+		}
+		gqlReq.Header.Set("Content-Type", "application/json")
+		resp := doHTTPRequest(t, gqlReq)
+		bodyBytes, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("test %d: could not read from response body: %v", i, err)
+		}
+		assert.Equal(t, tt.want, string(bodyBytes), "test %d", i)
+	}
+}
+
+// Tests that traces and state diffs are refused unless tracing is enabled.
+func TestGraphQLTracesDisabled(t *testing.T) {
+	stack := createNode(t, false)
+	defer stack.Close()
+	tx := createGQLServiceWithTransactions(t, stack, false)
+	if err := stack.Start(); err != nil {
+		t.Fatalf("could not start node: %v", err)
+	}
+	for i, tt := range []struct {
+		query string
+		want  string
+	}{
+		{
+			query: fmt.Sprintf(`{transaction(hash: \"%s\"){trace{type}}}`, tx.Hash().Hex()),
+			want:  `{"errors":[{"message":"tracing is not enabled on this node","path":["transaction","trace"]}],"data":{"transaction":{"trace":null}}}`,
+		},
+		{
+			query: `{block(number: 1){traces{type}}}`,
+			want:  `{"errors":[{"message":"tracing is not enabled on this node","path":["block","traces"]}],"data":{"block":null}}`,
+		},
+	} {
+		body := strings.NewReader(fmt.Sprintf(`{"query": "%s"}`, tt.query))
+		gqlReq, err := http.NewRequest(http.MethodPost, fmt.Sprintf("http://%s/graphql", "127.0.0.1:9393"), body)
+		if err != nil {
+			t.Fatalf("test %d: could not issue new http request: %v", i, err)
+		}
+		gqlReq.Header.Set("Content-Type", "application/json")
+		resp := doHTTPRequest(t, gqlReq)
+		bodyBytes, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("test %d: could not read from response body: %v", i, err)
+		}
+		assert.Equal(t, tt.want, string(bodyBytes), "test %d", i)
+	}
+}
+
 func createNode(t *testing.T, gqlEnabled bool) *node.Node {
 	stack, err := node.New(&node.Config{
 		HTTPHost: "127.0.0.1",
@@ -117,14 +334,15 @@ func createGQLService(t *testing.T, stack *node.Node, endpoint string) {
 	}
 
 	// create gql service
-	err = New(stack, ethBackend.APIBackend, []string{}, []string{})
+	err = New(stack, ethBackend.APIBackend, []string{}, []string{}, false)
 	if err != nil {
 		t.Fatalf("could not create graphql service: %v", err)
 	}
 }
 
 func doHTTPRequest(t *testing.T, req *http.Request) *http.Response {
-	client := &http.Client{}
+	// Don't reuse connections, they may belong to the node of a previous test
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
 	resp, err := client.Do(req)
 	if err != nil {
 		t.Fatal("could not issue a GET request to the given endpoint", err)
@@ -132,3 +350,63 @@ func doHTTPRequest(t *testing.T, req *http.Request) *http.Response {
 	}
 	return resp
 }
+
+// testKey is the key of the account funded in the genesis of createTestBackend.
+var testKey, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+
+// createTestBackend creates an eth backend on a fake ethash chain, allowing
+// blocks to be inserted.
+func createTestBackend(t *testing.T, stack *node.Node) *eth.Ethereum {
+	var (
+		address = crypto.PubkeyToAddress(testKey.PublicKey)
+		funds   = big.NewInt(1000000000)
+	)
+	ethConf := &eth.Config{
+		Genesis: &genesisT.Genesis{
+			Config:     params.AllEthashProtocolChanges,
+			GasLimit:   11500000,
+			Difficulty: big.NewInt(1048576),
+			Alloc: genesisT.GenesisAlloc{
+				address: {Balance: funds},
+			},
+		},
+		Ethash: ethash.Config{
+			PowMode: ethash.ModeFake,
+		},
+		NetworkId:      1337,
+		TrieCleanCache: 5,
+		TrieDirtyCache: 5,
+		TrieTimeout:    60 * time.Minute,
+		SnapshotCache:  5,
+	}
+	ethBackend, err := eth.New(stack, ethConf)
+	if err != nil {
+		t.Fatalf("could not create eth backend: %v", err)
+	}
+	return ethBackend
+}
+
+// createGQLServiceWithTransactions creates a GraphQL service on top of a chain
+// holding a single block, with a single value transfer in it.
+func createGQLServiceWithTransactions(t *testing.T, stack *node.Node, tracing bool) *types.Transaction {
+	var (
+		ethBackend = createTestBackend(t, stack)
+		dad        = common.HexToAddress("0x0000000000000000000000000000000000000dad")
+	)
+	signer := types.NewEIP155Signer(ethBackend.BlockChain().Config().GetChainID())
+	tx, err := types.SignTx(types.NewTransaction(0, dad, big.NewInt(1000), 21000, big.NewInt(1), nil), signer, testKey)
+	if err != nil {
+		t.Fatalf("could not sign transaction: %v", err)
+	}
+	chain, _ := core.GenerateChain(params.AllEthashProtocolChanges, ethBackend.BlockChain().Genesis(), ethash.NewFaker(), ethBackend.ChainDb(), 1, func(i int, b *core.BlockGen) {
+		b.SetCoinbase(common.Address{1})
+		b.AddTx(tx)
+	})
+	if _, err := ethBackend.BlockChain().InsertChain(chain); err != nil {
+		t.Fatalf("could not insert chain: %v", err)
+	}
+	if err := New(stack, ethBackend.APIBackend, []string{}, []string{}, tracing); err != nil {
+		t.Fatalf("could not create graphql service: %v", err)
+	}
+	return tx
+}
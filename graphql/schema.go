@@ -34,6 +34,7 @@ const schema string = `
     schema {
         query: Query
         mutation: Mutation
+        subscription: Subscription
     }
 
     # Account is an Ethereum account at a particular block.
@@ -67,6 +68,9 @@ const schema string = `
         data: Bytes!
         # Transaction is the transaction that generated this log entry.
         transaction: Transaction!
+        # Removed is true if this log was reverted due to a chain reorganisation.
+        # It is only ever set on logs delivered by the newLogs subscription.
+        removed: Boolean!
     }
 
     # Transaction is an Ethereum transaction.
@@ -118,6 +122,120 @@ const schema string = `
         r: BigInt!
         s: BigInt!
         v: BigInt!
+        # Trace is the flattened tree of calls made by this transaction, in the
+        # format of the Parity call tracer. If the transaction has not yet been
+        # mined, this field will be null. Tracing must be enabled on the node.
+        trace: [Trace!]
+        # StateDiff lists the accounts modified by this transaction, ordered by
+        # address. If the transaction has not yet been mined, this field will be
+        # null. Tracing must be enabled on the node.
+        stateDiff: [AccountDiff!]
+    }
+
+    # Trace is a single call, contract creation, self-destruct or reward, in the
+    # format of the Parity call tracer.
+    type Trace {
+        # Type is one of call, create, suicide or reward.
+        type: String!
+        # Action describes the operation performed.
+        action: TraceAction!
+        # Result is the outcome of a successful call or creation. It will be null
+        # for failed operations, self-destructs and rewards.
+        result: TraceResult
+        # Error is the reason the operation failed, if it did.
+        error: String
+        # TraceAddress is the position of this trace in the call tree, as the
+        # list of child indexes leading to it from the top level call.
+        traceAddress: [Int!]!
+        # Subtraces is the number of calls made directly by this one.
+        subtraces: Int!
+        # Transaction is the transaction this trace belongs to. It will be null
+        # for block and uncle rewards.
+        transaction: Transaction
+    }
+
+    # TraceAction is the operation performed by a trace. Only the fields relevant
+    # to the trace type are set.
+    type TraceAction {
+        # CallType is one of call, callcode, delegatecall or staticcall.
+        callType: String
+        # From is the sender of a call or creation.
+        from: Address
+        # To is the recipient of a call.
+        to: Address
+        # Value is the amount of wei transferred by a call or creation.
+        value: BigInt
+        # Gas is the gas made available to a call or creation.
+        gas: Long
+        # Input is the data sent along with a call.
+        input: Bytes
+        # Init is the initialisation code of a creation.
+        init: Bytes
+        # CreationMethod is either create or create2.
+        creationMethod: String
+        # Address is the contract destroyed by a self-destruct.
+        address: Address
+        # RefundAddress is the beneficiary of a self-destruct.
+        refundAddress: Address
+        # Balance is the amount of wei refunded by a self-destruct.
+        balance: BigInt
+        # Author is the beneficiary of a reward.
+        author: Address
+        # RewardType is either block or uncle.
+        rewardType: String
+    }
+
+    # TraceResult is the outcome of a successful call or creation.
+    type TraceResult {
+        # GasUsed is the amount of gas used by the call or creation.
+        gasUsed: Long
+        # Output is the data returned by a call.
+        output: Bytes
+        # Address is the contract deployed by a creation.
+        address: Address
+        # Code is the code of the contract deployed by a creation.
+        code: Bytes
+    }
+
+    # AccountDiff is the change made to a single account. Fields left unchanged
+    # are null.
+    type AccountDiff {
+        # Address is the address of the modified account.
+        address: Address!
+        balance: BigIntDiff
+        nonce: LongDiff
+        code: BytesDiff
+        # Storage lists the modified storage slots, ordered by slot.
+        storage: [StorageDiff!]!
+    }
+
+    # BigIntDiff is the change of a value. From is null if the account was
+    # created and to is null if it was destroyed.
+    type BigIntDiff {
+        from: BigInt
+        to: BigInt
+    }
+
+    # LongDiff is the change of a value. From is null if the account was
+    # created and to is null if it was destroyed.
+    type LongDiff {
+        from: Long
+        to: Long
+    }
+
+    # BytesDiff is the change of a value. From is null if the account was
+    # created and to is null if it was destroyed.
+    type BytesDiff {
+        from: Bytes
+        to: Bytes
+    }
+
+    # StorageDiff is the change of a storage slot. From is null if the slot was
+    # created and to is null if it was destroyed.
+    type StorageDiff {
+        slot: Bytes32!
+        from: Bytes32
+        to: Bytes32
     }
 
     # BlockFilterCriteria encapsulates log filter criteria for a filter applied
@@ -209,6 +327,10 @@ const schema string = `
         # EstimateGas estimates the amount of gas that will be required for
         # successful execution of a transaction at the current block's state.
         estimateGas(data: CallData!): Long!
+        # Traces is the flattened tree of calls made by all transactions in this
+        # block, followed by the block and uncle rewards, in the format of the
+        # Parity call tracer. Tracing must be enabled on the node.
+        traces: [Trace!]!
     }
 
     # CallData represents the data associated with a local contract call.
@@ -301,6 +423,11 @@ const schema string = `
         # Blocks returns all the blocks between two numbers, inclusive. If
         # to is not supplied, it defaults to the most recent known block.
         blocks(from: Long!, to: Long): [Block!]!
+        # Accounts returns the state of an account at every block between two
+        # numbers, inclusive, in ascending block order. If to is not supplied,
+        # it defaults to the most recent known block. At most 1000 blocks may be
+        # queried at once.
+        accounts(address: Address!, from: Long!, to: Long): [Account!]!
         # Pending returns the current pending state.
         pending: Pending!
         # Transaction returns a transaction specified by its hash.
@@ -322,4 +449,15 @@ const schema string = `
         # SendRawTransaction sends an RLP-encoded transaction to the network.
         sendRawTransaction(data: Bytes!): Bytes32!
     }
+
+    type Subscription {
+        # NewBlocks delivers each block as it is imported into the canonical chain.
+        newBlocks: Block!
+        # NewLogs delivers log entries matching the provided filter as they are
+        # mined. Logs reverted by a reorganisation are delivered again with
+        # removed set to true.
+        newLogs(filter: FilterCriteria!): Log!
+        # PendingTransactions delivers transactions as they enter the transaction pool.
+        pendingTransactions: Transaction!
+    }
 `
@@ -17,32 +17,51 @@
 package graphql
 
 import (
+	"net/http"
+
 	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/node"
+	"github.com/gorilla/websocket"
 	"github.com/graph-gophers/graphql-go"
 	"github.com/graph-gophers/graphql-go/relay"
 )
 
-// New constructs a new GraphQL service instance.
-func New(stack *node.Node, backend ethapi.Backend, cors, vhosts []string) error {
+// New constructs a new GraphQL service instance. The trace and state diff fields
+// are only served if tracing is enabled, as they re-execute historical blocks.
+func New(stack *node.Node, backend ethapi.Backend, cors, vhosts []string, tracing bool) error {
 	if backend == nil {
 		panic("missing backend")
 	}
+	if tracing {
+		if tracer, ok := backend.(traceBackend); ok {
+			backend = &tracingBackend{Backend: backend, tracer: tracer}
+		} else {
+			log.Warn("GraphQL tracing is not supported by this node")
+		}
+	}
 	// check if http server with given endpoint exists and enable graphQL on it
 	return newHandler(stack, backend, cors, vhosts)
 }
 
 // newHandler returns a new `http.Handler` that will answer GraphQL queries.
 // It additionally exports an interactive query browser on the / endpoint.
+// Websocket connections to the GraphQL endpoint are served subscriptions.
 func newHandler(stack *node.Node, backend ethapi.Backend, cors, vhosts []string) error {
-	q := Resolver{backend}
-
+	q := Resolver{backend: backend}
+	if backend != nil {
+		q.subs = newSubscriptions(backend)
+		stack.RegisterLifecycle(q.subs)
+	}
 	s, err := graphql.ParseSchema(schema, &q)
 	if err != nil {
 		return err
 	}
 	h := &relay.Handler{Schema: s}
-	handler := node.NewHTTPHandlerStack(h, cors, vhosts)
+	handler := &dispatcher{
+		http: node.NewHTTPHandlerStack(h, cors, vhosts),
+		ws:   node.NewWSHandlerStack(newWebsocketHandler(s, cors), vhosts),
+	}
 
 	stack.RegisterHandler("GraphQL UI", "/graphql/ui", GraphiQL{})
 	stack.RegisterHandler("GraphQL", "/graphql", handler)
@@ -50,3 +69,20 @@ func newHandler(stack *node.Node, backend ethapi.Backend, cors, vhosts []string)
 
 	return nil
 }
+
+// dispatcher routes websocket upgrades to the subscription transport and all
+// other requests to the plain HTTP handler. Upgrades are split off before the
+// HTTP handler stack, whose response compression can't be hijacked, so they
+// get their own virtual host check.
+type dispatcher struct {
+	http http.Handler
+	ws   http.Handler
+}
+
+func (d *dispatcher) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if websocket.IsWebSocketUpgrade(r) {
+		d.ws.ServeHTTP(w, r)
+		return
+	}
+	d.http.ServeHTTP(w, r)
+}
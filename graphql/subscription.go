@@ -0,0 +1,244 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/eth/filters"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// subscriptionQueueSize is the number of events buffered for a single GraphQL
+// subscription. Subscribers that fall further behind are dropped, rather than
+// stalling the event system shared with every other filter.
+const subscriptionQueueSize = 256
+
+var errNoEventSystem = errors.New("subscriptions are not available")
+
+// subscriptions owns the event system backing GraphQL subscriptions. It runs
+// while the node does, ending all subscriptions still running when stopped.
+type subscriptions struct {
+	backend filters.Backend
+	events  *filters.EventSystem // Event system, nil unless running
+
+	lock sync.Mutex
+	quit chan struct{}  // Closed to end the running subscriptions
+	wg   sync.WaitGroup // Tracks the running subscriptions
+}
+
+// newSubscriptions creates the subscription manager for the given backend.
+func newSubscriptions(backend filters.Backend) *subscriptions {
+	return &subscriptions{backend: backend}
+}
+
+// Start implements node.Lifecycle, starting the event system.
+func (s *subscriptions) Start() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.events = filters.NewEventSystem(s.backend, false)
+	s.quit = make(chan struct{})
+	return nil
+}
+
+// Stop implements node.Lifecycle, ending the running subscriptions and then
+// stopping the event system.
+func (s *subscriptions) Stop() error {
+	s.lock.Lock()
+	events := s.events
+	if events != nil {
+		s.events = nil
+		close(s.quit)
+	}
+	s.lock.Unlock()
+
+	if events != nil {
+		s.wg.Wait()
+		events.Stop()
+	}
+	return nil
+}
+
+// add registers a new subscription, which must call done once it ended. The
+// returned channel is closed when the subscription has to end.
+func (s *subscriptions) add() (*filters.EventSystem, <-chan struct{}, error) {
+	if s == nil {
+		return nil, nil, errNoEventSystem
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.events == nil {
+		return nil, nil, errNoEventSystem
+	}
+	s.wg.Add(1)
+	return s.events, s.quit, nil
+}
+
+// done marks a subscription ended.
+func (s *subscriptions) done() {
+	s.wg.Done()
+}
+
+// NewBlocks streams every block imported into the canonical chain.
+func (r *Resolver) NewBlocks(ctx context.Context) (<-chan *Block, error) {
+	events, quit, err := r.subs.add()
+	if err != nil {
+		return nil, err
+	}
+	headers := make(chan *types.Header)
+	sub := events.SubscribeNewHeads(headers)
+
+	blocks := make(chan *Block, subscriptionQueueSize)
+	go func() {
+		defer r.subs.done()
+		defer close(blocks)
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case header := <-headers:
+				numberOrHash := rpc.BlockNumberOrHashWithHash(header.Hash(), false)
+				block := &Block{
+					backend:      r.backend,
+					numberOrHash: &numberOrHash,
+					hash:         header.Hash(),
+					header:       header,
+				}
+				select {
+				case blocks <- block:
+				default:
+					log.Warn("Dropping slow GraphQL subscription", "type", "newBlocks")
+					return
+				}
+			case <-sub.Err():
+				return
+			case <-ctx.Done():
+				return
+			case <-quit:
+				return
+			}
+		}
+	}()
+	return blocks, nil
+}
+
+// NewLogs streams log entries matching the provided filter as they are mined.
+// Logs reverted by a chain reorganisation are sent again with removed set.
+func (r *Resolver) NewLogs(ctx context.Context, args struct{ Filter FilterCriteria }) (<-chan *Log, error) {
+	crit := ethereum.FilterQuery{}
+	if args.Filter.FromBlock != nil {
+		crit.FromBlock = new(big.Int).SetUint64(uint64(*args.Filter.FromBlock))
+	}
+	if args.Filter.ToBlock != nil {
+		crit.ToBlock = new(big.Int).SetUint64(uint64(*args.Filter.ToBlock))
+	}
+	if args.Filter.Addresses != nil {
+		crit.Addresses = *args.Filter.Addresses
+	}
+	if args.Filter.Topics != nil {
+		crit.Topics = *args.Filter.Topics
+	}
+	events, quit, err := r.subs.add()
+	if err != nil {
+		return nil, err
+	}
+	matched := make(chan []*types.Log)
+	sub, err := events.SubscribeLogs(crit, matched)
+	if err != nil {
+		r.subs.done()
+		return nil, err
+	}
+	logs := make(chan *Log, subscriptionQueueSize)
+	go func() {
+		defer r.subs.done()
+		defer close(logs)
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case batch := <-matched:
+				for _, l := range batch {
+					entry := &Log{
+						backend:     r.backend,
+						transaction: &Transaction{backend: r.backend, hash: l.TxHash},
+						log:         l,
+					}
+					select {
+					case logs <- entry:
+					default:
+						log.Warn("Dropping slow GraphQL subscription", "type", "newLogs")
+						return
+					}
+				}
+			case <-sub.Err():
+				return
+			case <-ctx.Done():
+				return
+			case <-quit:
+				return
+			}
+		}
+	}()
+	return logs, nil
+}
+
+// PendingTransactions streams transactions as they enter the transaction pool.
+func (r *Resolver) PendingTransactions(ctx context.Context) (<-chan *Transaction, error) {
+	events, quit, err := r.subs.add()
+	if err != nil {
+		return nil, err
+	}
+	hashes := make(chan []common.Hash)
+	sub := events.SubscribePendingTxs(hashes)
+
+	txs := make(chan *Transaction, subscriptionQueueSize)
+	go func() {
+		defer r.subs.done()
+		defer close(txs)
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case batch := <-hashes:
+				for _, hash := range batch {
+					select {
+					case txs <- &Transaction{backend: r.backend, hash: hash}:
+					default:
+						log.Warn("Dropping slow GraphQL subscription", "type", "pendingTransactions")
+						return
+					}
+				}
+			case <-sub.Err():
+				return
+			case <-ctx.Done():
+				return
+			case <-quit:
+				return
+			}
+		}
+	}()
+	return txs, nil
+}
@@ -0,0 +1,458 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/eth"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+)
+
+const (
+	// traceTimeout is the time a single transaction may execute when traced
+	// through GraphQL before being forcefully aborted.
+	traceTimeout = "5s"
+
+	// traceReexec is the number of blocks GraphQL tracing is willing to go back
+	// and reexecute to produce missing historical state.
+	traceReexec = uint64(32)
+)
+
+var (
+	errTracingUnsupported = errors.New("tracing is not supported by this node")
+	errTracingDisabled    = errors.New("tracing is not enabled on this node")
+)
+
+// traceBackend is implemented by backends able to re-execute historical blocks,
+// which light clients are not.
+type traceBackend interface {
+	TraceBlock(ctx context.Context, block *types.Block, config *eth.TraceConfig) ([]interface{}, error)
+	ReplayTransaction(ctx context.Context, hash common.Hash, traceTypes []string, config *eth.TraceConfig) (*eth.TraceReplayResult, error)
+}
+
+// tracingBackend wraps the backend of a GraphQL service which was explicitly
+// allowed to trace, as tracing re-executes historical blocks and is expensive.
+type tracingBackend struct {
+	ethapi.Backend
+	tracer traceBackend
+}
+
+// newTraceConfig returns the limits applied to tracing through GraphQL.
+func newTraceConfig() *eth.TraceConfig {
+	var (
+		timeout = traceTimeout
+		reexec  = traceReexec
+	)
+	return &eth.TraceConfig{Timeout: &timeout, Reexec: &reexec}
+}
+
+// traceBlock traces all the transactions of a block, appending the rewards.
+func (b *tracingBackend) traceBlock(ctx context.Context, block *types.Block) ([]interface{}, error) {
+	return b.tracer.TraceBlock(ctx, block, newTraceConfig())
+}
+
+// replayTransaction replays a mined transaction, returning the requested trace
+// types.
+func (b *tracingBackend) replayTransaction(ctx context.Context, hash common.Hash, traceTypes []string) (*eth.TraceReplayResult, error) {
+	return b.tracer.ReplayTransaction(ctx, hash, traceTypes, newTraceConfig())
+}
+
+// backendTracer returns the tracing backend of a GraphQL service, or an error
+// if the node is unable to trace or tracing was not enabled.
+func backendTracer(backend ethapi.Backend) (*tracingBackend, error) {
+	if tracer, ok := backend.(*tracingBackend); ok {
+		return tracer, nil
+	}
+	if _, ok := backend.(traceBackend); ok {
+		return nil, errTracingDisabled
+	}
+	return nil, errTracingUnsupported
+}
+
+// parityTrace is a single Parity formatted trace, as produced by the
+// callTracerParity or a block reward.
+type parityTrace struct {
+	Type         string        `json:"type"`
+	Action       *parityAction `json:"action"`
+	Result       *parityResult `json:"result"`
+	Error        string        `json:"error"`
+	TraceAddress []int         `json:"traceAddress"`
+	Subtraces    int           `json:"subtraces"`
+	TxHash       *common.Hash  `json:"transactionHash"`
+}
+
+// newTraces converts Parity formatted traces into their GraphQL representation.
+func newTraces(backend ethapi.Backend, traces []interface{}) ([]*Trace, error) {
+	blob, err := json.Marshal(traces)
+	if err != nil {
+		return nil, err
+	}
+	var decoded []*parityTrace
+	if err := json.Unmarshal(blob, &decoded); err != nil {
+		return nil, err
+	}
+	ret := make([]*Trace, 0, len(decoded))
+	for _, trace := range decoded {
+		ret = append(ret, &Trace{backend: backend, trace: trace})
+	}
+	return ret, nil
+}
+
+// Trace represents a single internal call, contract creation, self-destruct or
+// reward of a transaction or block.
+type Trace struct {
+	backend ethapi.Backend
+	trace   *parityTrace
+}
+
+func (t *Trace) Type(ctx context.Context) string {
+	return t.trace.Type
+}
+
+func (t *Trace) Action(ctx context.Context) *TraceAction {
+	if t.trace.Action == nil {
+		return &TraceAction{action: new(parityAction)}
+	}
+	return &TraceAction{action: t.trace.Action}
+}
+
+func (t *Trace) Result(ctx context.Context) *TraceResult {
+	if t.trace.Result == nil {
+		return nil
+	}
+	return &TraceResult{result: t.trace.Result}
+}
+
+func (t *Trace) Error(ctx context.Context) *string {
+	if t.trace.Error == "" {
+		return nil
+	}
+	return &t.trace.Error
+}
+
+func (t *Trace) TraceAddress(ctx context.Context) []int32 {
+	ret := make([]int32, len(t.trace.TraceAddress))
+	for i, index := range t.trace.TraceAddress {
+		ret[i] = int32(index)
+	}
+	return ret
+}
+
+func (t *Trace) Subtraces(ctx context.Context) int32 {
+	return int32(t.trace.Subtraces)
+}
+
+func (t *Trace) Transaction(ctx context.Context) *Transaction {
+	if t.trace.TxHash == nil {
+		return nil
+	}
+	return &Transaction{backend: t.backend, hash: *t.trace.TxHash}
+}
+
+// parityAction is the action of a Parity formatted trace. Only the fields
+// relevant to the type of the trace are set.
+type parityAction struct {
+	CallType       *string         `json:"callType"`
+	From           *common.Address `json:"from"`
+	To             *common.Address `json:"to"`
+	Value          *hexutil.Big    `json:"value"`
+	Gas            *hexutil.Uint64 `json:"gas"`
+	Input          *hexutil.Bytes  `json:"input"`
+	Init           *hexutil.Bytes  `json:"init"`
+	CreationMethod *string         `json:"creationMethod"`
+	Address        *common.Address `json:"address"`
+	RefundAddress  *common.Address `json:"refundAddress"`
+	Balance        *hexutil.Big    `json:"balance"`
+	Author         *common.Address `json:"author"`
+	RewardType     *string         `json:"rewardType"`
+}
+
+// parityResult is the result of a successful Parity formatted call or create
+// trace.
+type parityResult struct {
+	GasUsed *hexutil.Uint64 `json:"gasUsed"`
+	Output  *hexutil.Bytes  `json:"output"`
+	Address *common.Address `json:"address"`
+	Code    *hexutil.Bytes  `json:"code"`
+}
+
+// TraceAction is the action performed by a trace.
+type TraceAction struct {
+	action *parityAction
+}
+
+func (a *TraceAction) CallType(ctx context.Context) *string {
+	return a.action.CallType
+}
+
+func (a *TraceAction) From(ctx context.Context) *common.Address {
+	return a.action.From
+}
+
+func (a *TraceAction) To(ctx context.Context) *common.Address {
+	return a.action.To
+}
+
+func (a *TraceAction) Value(ctx context.Context) *hexutil.Big {
+	return a.action.Value
+}
+
+func (a *TraceAction) Gas(ctx context.Context) *hexutil.Uint64 {
+	return a.action.Gas
+}
+
+func (a *TraceAction) Input(ctx context.Context) *hexutil.Bytes {
+	return a.action.Input
+}
+
+func (a *TraceAction) Init(ctx context.Context) *hexutil.Bytes {
+	return a.action.Init
+}
+
+func (a *TraceAction) CreationMethod(ctx context.Context) *string {
+	return a.action.CreationMethod
+}
+
+func (a *TraceAction) Address(ctx context.Context) *common.Address {
+	return a.action.Address
+}
+
+func (a *TraceAction) RefundAddress(ctx context.Context) *common.Address {
+	return a.action.RefundAddress
+}
+
+func (a *TraceAction) Balance(ctx context.Context) *hexutil.Big {
+	return a.action.Balance
+}
+
+func (a *TraceAction) Author(ctx context.Context) *common.Address {
+	return a.action.Author
+}
+
+func (a *TraceAction) RewardType(ctx context.Context) *string {
+	return a.action.RewardType
+}
+
+// TraceResult is the outcome of a successful call or contract creation.
+type TraceResult struct {
+	result *parityResult
+}
+
+func (r *TraceResult) GasUsed(ctx context.Context) *hexutil.Uint64 {
+	return r.result.GasUsed
+}
+
+func (r *TraceResult) Output(ctx context.Context) *hexutil.Bytes {
+	return r.result.Output
+}
+
+func (r *TraceResult) Address(ctx context.Context) *common.Address {
+	return r.result.Address
+}
+
+func (r *TraceResult) Code(ctx context.Context) *hexutil.Bytes {
+	return r.result.Code
+}
+
+// parityDiff is a Parity formatted state diff field: "=" if unchanged,
+// {"+": new} if created, {"-": old} if removed or {"*": {"from", "to"}}.
+type parityDiff struct {
+	from json.RawMessage
+	to   json.RawMessage
+}
+
+// UnmarshalJSON implements json.Unmarshaler, leaving unchanged fields empty.
+func (d *parityDiff) UnmarshalJSON(input []byte) error {
+	if string(input) == `"="` {
+		return nil
+	}
+	var diff struct {
+		Created json.RawMessage `json:"+"`
+		Removed json.RawMessage `json:"-,"`
+		Changed *struct {
+			From json.RawMessage `json:"from"`
+			To   json.RawMessage `json:"to"`
+		} `json:"*"`
+	}
+	if err := json.Unmarshal(input, &diff); err != nil {
+		return err
+	}
+	switch {
+	case diff.Created != nil:
+		d.to = diff.Created
+	case diff.Removed != nil:
+		d.from = diff.Removed
+	case diff.Changed != nil:
+		d.from, d.to = diff.Changed.From, diff.Changed.To
+	}
+	return nil
+}
+
+// changed reports whether the field was modified.
+func (d *parityDiff) changed() bool {
+	return d.from != nil || d.to != nil
+}
+
+// decode unmarshals the old and new values, leaving absent ones untouched.
+func (d *parityDiff) decode(from, to interface{}) error {
+	if d.from != nil {
+		if err := json.Unmarshal(d.from, from); err != nil {
+			return err
+		}
+	}
+	if d.to != nil {
+		if err := json.Unmarshal(d.to, to); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parityAccountDiff is the Parity formatted state diff of a single account.
+type parityAccountDiff struct {
+	Balance parityDiff                 `json:"balance"`
+	Nonce   parityDiff                 `json:"nonce"`
+	Code    parityDiff                 `json:"code"`
+	Storage map[common.Hash]parityDiff `json:"storage"`
+}
+
+// newAccountDiffs converts a Parity formatted state diff into its GraphQL
+// representation, ordered by account address.
+func newAccountDiffs(diffs map[common.Address]*eth.StateDiffAccount) ([]*AccountDiff, error) {
+	blob, err := json.Marshal(diffs)
+	if err != nil {
+		return nil, err
+	}
+	var decoded map[common.Address]*parityAccountDiff
+	if err := json.Unmarshal(blob, &decoded); err != nil {
+		return nil, err
+	}
+	ret := make([]*AccountDiff, 0, len(decoded))
+	for addr, diff := range decoded {
+		account := &AccountDiff{address: addr}
+		if diff.Balance.changed() {
+			account.balance = new(BigIntDiff)
+			if err := diff.Balance.decode(&account.balance.from, &account.balance.to); err != nil {
+				return nil, err
+			}
+		}
+		if diff.Nonce.changed() {
+			account.nonce = new(LongDiff)
+			if err := diff.Nonce.decode(&account.nonce.from, &account.nonce.to); err != nil {
+				return nil, err
+			}
+		}
+		if diff.Code.changed() {
+			account.code = new(BytesDiff)
+			if err := diff.Code.decode(&account.code.from, &account.code.to); err != nil {
+				return nil, err
+			}
+		}
+		for slot, value := range diff.Storage {
+			storage := &StorageDiff{slot: slot}
+			if err := value.decode(&storage.from, &storage.to); err != nil {
+				return nil, err
+			}
+			account.storage = append(account.storage, storage)
+		}
+		sort.Slice(account.storage, func(i, j int) bool {
+			return account.storage[i].slot.Hex() < account.storage[j].slot.Hex()
+		})
+		ret = append(ret, account)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].address.Hex() < ret[j].address.Hex()
+	})
+	return ret, nil
+}
+
+// AccountDiff represents the changes made to an account by a transaction.
+type AccountDiff struct {
+	address common.Address
+	balance *BigIntDiff
+	nonce   *LongDiff
+	code    *BytesDiff
+	storage []*StorageDiff
+}
+
+func (d *AccountDiff) Address(ctx context.Context) common.Address {
+	return d.address
+}
+
+func (d *AccountDiff) Balance(ctx context.Context) *BigIntDiff {
+	return d.balance
+}
+
+func (d *AccountDiff) Nonce(ctx context.Context) *LongDiff {
+	return d.nonce
+}
+
+func (d *AccountDiff) Code(ctx context.Context) *BytesDiff {
+	return d.code
+}
+
+func (d *AccountDiff) Storage(ctx context.Context) []*StorageDiff {
+	if d.storage == nil {
+		return []*StorageDiff{}
+	}
+	return d.storage
+}
+
+// BigIntDiff is the change of a big integer account field.
+type BigIntDiff struct {
+	from *hexutil.Big
+	to   *hexutil.Big
+}
+
+func (d *BigIntDiff) From(ctx context.Context) *hexutil.Big { return d.from }
+func (d *BigIntDiff) To(ctx context.Context) *hexutil.Big   { return d.to }
+
+// LongDiff is the change of an integer account field.
+type LongDiff struct {
+	from *hexutil.Uint64
+	to   *hexutil.Uint64
+}
+
+func (d *LongDiff) From(ctx context.Context) *hexutil.Uint64 { return d.from }
+func (d *LongDiff) To(ctx context.Context) *hexutil.Uint64   { return d.to }
+
+// BytesDiff is the change of a binary account field.
+type BytesDiff struct {
+	from *hexutil.Bytes
+	to   *hexutil.Bytes
+}
+
+func (d *BytesDiff) From(ctx context.Context) *hexutil.Bytes { return d.from }
+func (d *BytesDiff) To(ctx context.Context) *hexutil.Bytes   { return d.to }
+
+// StorageDiff is the change of a single storage slot.
+type StorageDiff struct {
+	slot common.Hash
+	from *common.Hash
+	to   *common.Hash
+}
+
+func (d *StorageDiff) Slot(ctx context.Context) common.Hash  { return d.slot }
+func (d *StorageDiff) From(ctx context.Context) *common.Hash { return d.from }
+func (d *StorageDiff) To(ctx context.Context) *common.Hash   { return d.to }
@@ -0,0 +1,296 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/gorilla/websocket"
+	"github.com/graph-gophers/graphql-go"
+)
+
+const (
+	wsReadLimit      = 1024 * 1024
+	wsWriteTimeout   = 10 * time.Second
+	wsKeepAlive      = 15 * time.Second
+	wsSubprotocol    = "graphql-ws"
+	wsReadBufferSize = 1024
+)
+
+// Message types of the graphql-ws protocol, as used by the Apollo
+// subscriptions-transport-ws client.
+const (
+	wsConnectionInit      = "connection_init"
+	wsConnectionAck       = "connection_ack"
+	wsConnectionError     = "connection_error"
+	wsConnectionKeepAlive = "ka"
+	wsConnectionTerminate = "connection_terminate"
+	wsStart               = "start"
+	wsStop                = "stop"
+	wsData                = "data"
+	wsError               = "error"
+	wsComplete            = "complete"
+)
+
+// wsMessage is the envelope of every graphql-ws message in either direction.
+type wsMessage struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// wsStartPayload is the payload of a start message, a regular GraphQL request.
+type wsStartPayload struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// wsErrorPayload reports a failure that is not a GraphQL response.
+type wsErrorPayload struct {
+	Message string `json:"message"`
+}
+
+// wsHandler serves GraphQL queries, mutations and subscriptions over websocket
+// connections speaking the graphql-ws protocol.
+type wsHandler struct {
+	schema   *graphql.Schema
+	upgrader websocket.Upgrader
+}
+
+// newWebsocketHandler creates a websocket handler for the given schema. The
+// allowed origins are the same as those configured for cross-origin requests;
+// if none are configured, only same-origin connections are accepted.
+func newWebsocketHandler(schema *graphql.Schema, allowedOrigins []string) *wsHandler {
+	origins := make(map[string]bool)
+	for _, origin := range allowedOrigins {
+		origins[strings.ToLower(origin)] = true
+	}
+	h := &wsHandler{
+		schema: schema,
+		upgrader: websocket.Upgrader{
+			ReadBufferSize:  wsReadBufferSize,
+			WriteBufferSize: wsReadBufferSize,
+			Subprotocols:    []string{wsSubprotocol},
+		},
+	}
+	if len(origins) > 0 {
+		h.upgrader.CheckOrigin = func(r *http.Request) bool {
+			// Non-browser clients don't send an origin, there's nothing to verify.
+			if _, ok := r.Header["Origin"]; !ok {
+				return true
+			}
+			origin := strings.ToLower(r.Header.Get("Origin"))
+			if origins["*"] || origins[origin] {
+				return true
+			}
+			log.Warn("Rejected GraphQL websocket connection", "origin", origin)
+			return false
+		}
+	}
+	return h
+}
+
+// ServeHTTP upgrades the connection and serves it until the client leaves.
+func (h *wsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Debug("GraphQL websocket upgrade failed", "err", err)
+		return
+	}
+	ctx, cancel := context.WithCancel(r.Context())
+	c := &wsConn{
+		schema: h.schema,
+		conn:   conn,
+		ctx:    ctx,
+		cancel: cancel,
+		ops:    make(map[string]*wsOperation),
+	}
+	c.serve()
+}
+
+// wsOperation is a running operation started by a client.
+type wsOperation struct {
+	cancel context.CancelFunc
+}
+
+// wsConn tracks the operations running on a single websocket connection.
+type wsConn struct {
+	schema *graphql.Schema
+	conn   *websocket.Conn
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	writeMu sync.Mutex // Serialises writes to the connection
+
+	lock sync.Mutex
+	ops  map[string]*wsOperation
+	wg   sync.WaitGroup
+}
+
+// serve reads client messages until the connection fails or is terminated,
+// then tears down every operation that is still running.
+func (c *wsConn) serve() {
+	defer func() {
+		c.cancel()
+		c.wg.Wait()
+		c.conn.Close()
+	}()
+	c.conn.SetReadLimit(wsReadLimit)
+
+	initialised := false
+	for {
+		var msg wsMessage
+		if err := c.conn.ReadJSON(&msg); err != nil {
+			log.Debug("GraphQL websocket read failed", "err", err)
+			return
+		}
+		switch msg.Type {
+		case wsConnectionInit:
+			c.send(&wsMessage{Type: wsConnectionAck})
+			c.send(&wsMessage{Type: wsConnectionKeepAlive})
+
+			if !initialised {
+				initialised = true
+				c.wg.Add(1)
+				go c.keepAlive()
+			}
+
+		case wsStart:
+			var payload wsStartPayload
+			if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+				c.sendError(msg.ID, wsError, err.Error())
+				continue
+			}
+			c.start(msg.ID, &payload)
+
+		case wsStop:
+			c.stop(msg.ID)
+
+		case wsConnectionTerminate:
+			return
+
+		default:
+			c.sendError(msg.ID, wsConnectionError, "unknown message type "+msg.Type)
+		}
+	}
+}
+
+// start runs an operation, forwarding every response to the client until the
+// operation ends or is stopped.
+func (c *wsConn) start(id string, payload *wsStartPayload) {
+	c.lock.Lock()
+	if _, ok := c.ops[id]; ok {
+		c.lock.Unlock()
+		c.sendError(id, wsError, "operation id already in use")
+		return
+	}
+	ctx, cancel := context.WithCancel(c.ctx)
+	op := &wsOperation{cancel: cancel}
+	c.ops[id] = op
+	c.lock.Unlock()
+
+	responses, err := c.schema.Subscribe(ctx, payload.Query, payload.OperationName, payload.Variables)
+	if err != nil {
+		c.finish(id, op)
+		c.sendError(id, wsError, err.Error())
+		return
+	}
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+
+		// The responses must be drained until closed, the schema blocks on
+		// delivering them even after the context is cancelled.
+		for response := range responses {
+			data, err := json.Marshal(response)
+			if err != nil {
+				log.Warn("Failed to encode GraphQL response", "err", err)
+				continue
+			}
+			c.send(&wsMessage{ID: id, Type: wsData, Payload: data})
+		}
+		c.finish(id, op)
+		c.send(&wsMessage{ID: id, Type: wsComplete})
+	}()
+}
+
+// stop cancels a running operation.
+func (c *wsConn) stop(id string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if op, ok := c.ops[id]; ok {
+		op.cancel()
+		delete(c.ops, id)
+	}
+}
+
+// finish releases a completed operation, unless the id was reused since.
+func (c *wsConn) finish(id string, op *wsOperation) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	op.cancel()
+	if c.ops[id] == op {
+		delete(c.ops, id)
+	}
+}
+
+// keepAlive periodically pings the client, as the protocol expects.
+func (c *wsConn) keepAlive() {
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(wsKeepAlive)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.send(&wsMessage{Type: wsConnectionKeepAlive})
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
+
+// sendError delivers an error message, of either the operation or connection
+// error type, to the client.
+func (c *wsConn) sendError(id string, typ string, message string) {
+	payload, _ := json.Marshal(&wsErrorPayload{Message: message})
+	c.send(&wsMessage{ID: id, Type: typ, Payload: payload})
+}
+
+// send writes a message to the client. Write failures tear down the connection,
+// which is noticed by the read loop.
+func (c *wsConn) send(msg *wsMessage) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	c.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+	if err := c.conn.WriteJSON(msg); err != nil {
+		log.Debug("GraphQL websocket write failed", "err", err)
+		c.cancel()
+		c.conn.Close()
+	}
+}
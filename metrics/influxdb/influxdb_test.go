@@ -0,0 +1,140 @@
+package influxdb
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	uurl "net/url"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+func TestMain(m *testing.M) {
+	metrics.Enabled = true
+	os.Exit(m.Run())
+}
+
+// capture records the last write request received by a fake InfluxDB server.
+type capture struct {
+	path  string
+	query map[string]string
+	auth  string
+	body  string
+}
+
+func newTestServer(t *testing.T, c *capture) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("failed to read request body: %v", err)
+		}
+		c.path = r.URL.Path
+		c.query = make(map[string]string)
+		for k := range r.URL.Query() {
+			c.query[k] = r.URL.Query().Get(k)
+		}
+		c.auth = r.Header.Get("Authorization")
+		c.body = string(body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+}
+
+func newTestRegistry() metrics.Registry {
+	r := metrics.NewRegistry()
+	metrics.NewRegisteredCounter("test/counter", r).Inc(12)
+	metrics.NewRegisteredGauge("test/gauge", r).Update(34)
+	return r
+}
+
+func TestInfluxDBV1(t *testing.T) {
+	var c capture
+	srv := newTestServer(t, &c)
+	defer srv.Close()
+
+	reg := newTestRegistry()
+	tags := map[string]string{"host": "node1"}
+	if err := InfluxDBWithTagsOnce(reg, srv.URL, "geth", "user", "pass", "geth.", tags); err != nil {
+		t.Fatalf("failed to report: %v", err)
+	}
+	if c.path != "/write" {
+		t.Errorf("write path mismatch: have %q, want %q", c.path, "/write")
+	}
+	if c.query["db"] != "geth" {
+		t.Errorf("database mismatch: have %q, want %q", c.query["db"], "geth")
+	}
+	for _, want := range []string{
+		"geth.test/counter.count,host=node1 value=12i",
+		"geth.test/gauge.gauge,host=node1 value=34i",
+	} {
+		if !strings.Contains(c.body, want) {
+			t.Errorf("missing line %q in body:\n%s", want, c.body)
+		}
+	}
+}
+
+func TestInfluxDBV2(t *testing.T) {
+	var c capture
+	srv := newTestServer(t, &c)
+	defer srv.Close()
+
+	reg := newTestRegistry()
+	tags := map[string]string{"host": "node1", "network": "classic"}
+	if err := InfluxDBV2WithTagsOnce(reg, srv.URL, "secret", "bucket", "org", "geth.", tags); err != nil {
+		t.Fatalf("failed to report: %v", err)
+	}
+	if c.path != "/api/v2/write" {
+		t.Errorf("write path mismatch: have %q, want %q", c.path, "/api/v2/write")
+	}
+	if c.query["bucket"] != "bucket" || c.query["org"] != "org" {
+		t.Errorf("bucket/org mismatch: have %q/%q", c.query["bucket"], c.query["org"])
+	}
+	if c.auth != "Token secret" {
+		t.Errorf("authorization mismatch: have %q, want %q", c.auth, "Token secret")
+	}
+	for _, want := range []string{
+		"geth.test/counter.count,host=node1,network=classic value=12i",
+		"geth.test/gauge.gauge,host=node1,network=classic value=34i",
+	} {
+		if !strings.Contains(c.body, want) {
+			t.Errorf("missing line %q in body:\n%s", want, c.body)
+		}
+	}
+}
+
+func TestInfluxDBV2CounterDelta(t *testing.T) {
+	var c capture
+	srv := newTestServer(t, &c)
+	defer srv.Close()
+
+	reg := metrics.NewRegistry()
+	counter := metrics.NewRegisteredCounter("test/counter", reg)
+	counter.Inc(5)
+
+	rep := &v2Reporter{
+		reg:      reg,
+		client:   srv.Client(),
+		cache:    make(map[string]int64),
+		endpoint: *mustParse(t, srv.URL),
+	}
+	if err := rep.send(); err != nil {
+		t.Fatalf("failed to report: %v", err)
+	}
+	counter.Inc(3)
+	if err := rep.send(); err != nil {
+		t.Fatalf("failed to report: %v", err)
+	}
+	if !strings.Contains(c.body, "test/counter.count value=3i") {
+		t.Errorf("expected counter delta of 3, body:\n%s", c.body)
+	}
+}
+
+func mustParse(t *testing.T, rawurl string) *uurl.URL {
+	u, err := uurl.Parse(rawurl)
+	if err != nil {
+		t.Fatalf("failed to parse url %q: %v", rawurl, err)
+	}
+	return u
+}
@@ -0,0 +1,178 @@
+package influxdb
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	uurl "net/url"
+	"path"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/influxdata/influxdb/client"
+)
+
+// v2Reporter posts the metrics of a registry to an InfluxDB 2.x instance using
+// the line protocol and token based authentication.
+type v2Reporter struct {
+	reg      metrics.Registry
+	interval time.Duration
+
+	endpoint     uurl.URL
+	token        string
+	bucket       string
+	organization string
+	namespace    string
+	tags         map[string]string
+
+	client *http.Client
+
+	cache map[string]int64
+}
+
+// InfluxDBV2WithTags starts an InfluxDB 2.x reporter which will post the metrics
+// from the given metrics.Registry at each d interval with the specified tags.
+func InfluxDBV2WithTags(r metrics.Registry, d time.Duration, endpoint string, token string, bucket string, organization string, namespace string, tags map[string]string) {
+	u, err := uurl.Parse(endpoint)
+	if err != nil {
+		log.Warn("Unable to parse InfluxDB", "url", endpoint, "err", err)
+		return
+	}
+	rep := &v2Reporter{
+		reg:          r,
+		interval:     d,
+		endpoint:     *u,
+		token:        token,
+		bucket:       bucket,
+		organization: organization,
+		namespace:    namespace,
+		tags:         tags,
+		client:       &http.Client{Timeout: 10 * time.Second},
+		cache:        make(map[string]int64),
+	}
+	rep.run()
+}
+
+// InfluxDBV2WithTagsOnce runs an InfluxDB 2.x reporter once and posts the given
+// metrics.Registry with the specified tags.
+func InfluxDBV2WithTagsOnce(r metrics.Registry, endpoint string, token string, bucket string, organization string, namespace string, tags map[string]string) error {
+	u, err := uurl.Parse(endpoint)
+	if err != nil {
+		return fmt.Errorf("unable to parse InfluxDB. url: %s, err: %v", endpoint, err)
+	}
+	rep := &v2Reporter{
+		reg:          r,
+		endpoint:     *u,
+		token:        token,
+		bucket:       bucket,
+		organization: organization,
+		namespace:    namespace,
+		tags:         tags,
+		client:       &http.Client{Timeout: 10 * time.Second},
+		cache:        make(map[string]int64),
+	}
+	if err := rep.send(); err != nil {
+		return fmt.Errorf("unable to send to InfluxDB. err: %v", err)
+	}
+	return nil
+}
+
+func (r *v2Reporter) run() {
+	intervalTicker := time.Tick(r.interval)
+	pingTicker := time.Tick(time.Second * 5)
+
+	for {
+		select {
+		case <-intervalTicker:
+			if err := r.send(); err != nil {
+				log.Warn("Unable to send to InfluxDB", "err", err)
+			}
+		case <-pingTicker:
+			if err := r.ping(); err != nil {
+				log.Warn("Got error while sending a ping to InfluxDB", "err", err)
+			}
+		}
+	}
+}
+
+// ping checks the health endpoint of the InfluxDB instance.
+func (r *v2Reporter) ping() error {
+	u := r.endpoint
+	u.Path = path.Join(u.Path, "health")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	res, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected health status: %s", res.Status)
+	}
+	return nil
+}
+
+func (r *v2Reporter) send() error {
+	var buf bytes.Buffer
+
+	r.reg.Each(func(name string, i interface{}) {
+		now := time.Now()
+		measurement, fields := readMeter(r.namespace, name, i)
+		if fields == nil {
+			return
+		}
+		// Counters are reported as the delta since the last report
+		if metric, ok := i.(metrics.Counter); ok {
+			v := metric.Count()
+			fields["value"] = v - r.cache[name]
+			r.cache[name] = v
+		}
+		pt := client.Point{
+			Measurement: measurement,
+			Tags:        r.tags,
+			Fields:      fields,
+			Time:        now,
+		}
+		buf.WriteString(pt.MarshalString())
+		buf.WriteByte('\n')
+	})
+	if buf.Len() == 0 {
+		return nil
+	}
+	u := r.endpoint
+	u.Path = path.Join(u.Path, "api/v2/write")
+
+	params := u.Query()
+	params.Set("org", r.organization)
+	params.Set("bucket", r.bucket)
+	params.Set("precision", "ns")
+	u.RawQuery = params.Encode()
+
+	req, err := http.NewRequest(http.MethodPost, u.String(), &buf)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	req.Header.Set("Authorization", "Token "+r.token)
+
+	res, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode/100 != 2 {
+		body, _ := ioutil.ReadAll(res.Body)
+		return fmt.Errorf("InfluxDB write failed: %s: %s", res.Status, bytes.TrimSpace(body))
+	}
+	return nil
+}
@@ -115,6 +115,20 @@ func (c *collector) writeSummaryPercentile(name, p string, value interface{}) {
 	c.buff.WriteString(fmt.Sprintf(keyQuantileTagValueTpl, name, p, value))
 }
 
+// mutateKey converts a registry metric name into a valid Prometheus metric
+// name. Path separators and any other characters outside of [a-zA-Z0-9_:] are
+// replaced with underscores, and names starting with a digit are prefixed.
 func mutateKey(key string) string {
-	return strings.Replace(key, "/", "_", -1)
+	key = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == ':':
+			return r
+		default:
+			return '_'
+		}
+	}, key)
+	if len(key) > 0 && key[0] >= '0' && key[0] <= '9' {
+		key = "_" + key
+	}
+	return key
 }
@@ -0,0 +1,69 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package prometheus
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+func TestHandler(t *testing.T) {
+	reg := metrics.NewRegistry()
+	metrics.NewRegisteredCounter("test/b/counter", reg).Inc(2)
+	metrics.NewRegisteredGauge("test/a.gauge", reg).Update(1)
+
+	rec := httptest.NewRecorder()
+	Handler(reg).ServeHTTP(rec, httptest.NewRequest("GET", "/debug/metrics/prometheus", nil))
+
+	if ct := rec.Header().Get("Content-Type"); ct != "text/plain" {
+		t.Fatalf("content type mismatch: have %q, want %q", ct, "text/plain")
+	}
+	body, _ := ioutil.ReadAll(rec.Body)
+
+	// Metrics are sorted by their registry name and mutated into valid
+	// Prometheus identifiers.
+	const expectedOutput = `# TYPE test_a_gauge gauge
+test_a_gauge 1
+
+# TYPE test_b_counter gauge
+test_b_counter 2
+
+`
+	if string(body) != expectedOutput {
+		t.Fatalf("unexpected handler output:\nhave:\n%s\nwant:\n%s", body, expectedOutput)
+	}
+}
+
+func TestMutateKey(t *testing.T) {
+	tests := []struct {
+		key, want string
+	}{
+		{"chain/head/block", "chain_head_block"},
+		{"p2p/ingress/eth/65/0x01", "p2p_ingress_eth_65_0x01"},
+		{"eth/db/chaindata/disk-read", "eth_db_chaindata_disk_read"},
+		{"les.server:load", "les_server:load"},
+		{"1st/metric", "_1st_metric"},
+	}
+	for _, tt := range tests {
+		if have := mutateKey(tt.key); have != tt.want {
+			t.Errorf("mutateKey(%q): have %q, want %q", tt.key, have, tt.want)
+		}
+	}
+}
@@ -71,6 +71,15 @@ type Config struct {
 	// in memory.
 	DataDir string
 
+	// DBEngine is the key-value store engine backing the databases of the node,
+	// either "leveldb" or "pebble". If empty, the engine of a pre-existing database
+	// is used, defaulting to leveldb.
+	DBEngine string `toml:",omitempty"`
+
+	// AncientChecksum enables storing a checksum with every item of the freezer
+	// tables created by the node. Existing tables keep their format.
+	AncientChecksum bool `toml:",omitempty"`
+
 	// Configuration of peer-to-peer networking.
 	P2P p2p.Config
 
@@ -177,6 +186,10 @@ type Config struct {
 	// Requests using ip address directly are not affected
 	GraphQLVirtualHosts []string `toml:",omitempty"`
 
+	// GraphQLTracing enables the trace and state diff fields of GraphQL. These
+	// re-execute historical blocks, so they're expensive to serve.
+	GraphQLTracing bool `toml:",omitempty"`
+
 	// Logger is a custom logger to use with the p2p.Server.
 	Logger log.Logger `toml:",omitempty"`
 
@@ -544,7 +544,13 @@ func (n *Node) OpenDatabase(name string, cache, handles int, namespace string) (
 	if n.config.DataDir == "" {
 		db = rawdb.NewMemoryDatabase()
 	} else {
-		db, err = rawdb.NewLevelDBDatabase(n.ResolvePath(name), cache, handles, namespace)
+		db, err = rawdb.Open(rawdb.OpenOptions{
+			Type:      n.config.DBEngine,
+			Directory: n.ResolvePath(name),
+			Namespace: namespace,
+			Cache:     cache,
+			Handles:   handles,
+		})
 	}
 
 	if err == nil {
@@ -557,13 +563,21 @@ func (n *Node) OpenDatabase(name string, cache, handles int, namespace string) (
 // creates one if no previous can be found) from within the node's data directory,
 // also attaching a chain freezer to it that moves ancient chain data from the
 // database to immutable append-only files. If the node is an ephemeral one, a
-// memory database is returned.
-func (n *Node) OpenDatabaseWithFreezerRemote(name string, cache, handles int, freezerURL string) (ethdb.Database, error) {
+// memory database is returned. If readonly is set, the remote freezer is only
+// read from, leaving it to be maintained by another node.
+func (n *Node) OpenDatabaseWithFreezerRemote(name string, cache, handles int, freezerURL string, readonly bool) (ethdb.Database, error) {
 	if n.config.DataDir == "" {
 		return rawdb.NewMemoryDatabase(), nil
 	}
-	root := n.config.ResolvePath(name)
-	return rawdb.NewLevelDBDatabaseWithFreezerRemote(root, cache, handles, freezerURL)
+	return rawdb.Open(rawdb.OpenOptions{
+		Type:             n.config.DBEngine,
+		Directory:        n.config.ResolvePath(name),
+		AncientsRemote:   freezerURL,
+		AncientsReadOnly: readonly,
+		Namespace:        "eth/db/chaindata",
+		Cache:            cache,
+		Handles:          handles,
+	})
 }
 
 // OpenDatabaseWithFreezer opens an existing database with the given name (or
@@ -590,7 +604,15 @@ func (n *Node) OpenDatabaseWithFreezer(name string, cache, handles int, freezer,
 		case !filepath.IsAbs(freezer):
 			freezer = n.ResolvePath(freezer)
 		}
-		db, err = rawdb.NewLevelDBDatabaseWithFreezer(root, cache, handles, freezer, namespace)
+		db, err = rawdb.Open(rawdb.OpenOptions{
+			Type:              n.config.DBEngine,
+			Directory:         root,
+			AncientsDirectory: freezer,
+			AncientsChecksum:  n.config.AncientChecksum,
+			Namespace:         namespace,
+			Cache:             cache,
+			Handles:           handles,
+		})
 	}
 
 	if err == nil {
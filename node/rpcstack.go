@@ -324,6 +324,12 @@ func NewHTTPHandlerStack(srv http.Handler, cors []string, vhosts []string) http.
 	return newGzipHandler(handler)
 }
 
+// NewWSHandlerStack returns a wrapped websocket handler which validates the
+// Host-header of incoming upgrade requests. Origins are left to the upgrader.
+func NewWSHandlerStack(srv http.Handler, vhosts []string) http.Handler {
+	return newVHostHandler(vhosts, srv)
+}
+
 func newCorsHandler(srv http.Handler, allowedOrigins []string) http.Handler {
 	// disable CORS support if user has not specified a custom CORS configuration
 	if len(allowedOrigins) == 0 {
@@ -21,6 +21,7 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/ethereum/go-ethereum/params/types/besu"
 	"github.com/ethereum/go-ethereum/params/types/coregeth"
 	"github.com/ethereum/go-ethereum/params/types/ctypes"
 	"github.com/ethereum/go-ethereum/params/types/goethereum"
@@ -53,6 +54,9 @@ func (c GenericCC) DAOSupport() bool {
 	if mg, ok := c.ChainConfigurator.(*coregeth.CoreGethChainConfig); ok {
 		return mg.GetEthashEIP779Transition() != nil
 	}
+	if bc, ok := c.ChainConfigurator.(*besu.ChainConfig); ok {
+		return bc.GetEthashEIP779Transition() != nil
+	}
 	if pc, ok := c.ChainConfigurator.(*parity.ParityChainSpec); ok {
 		return pc.Engine.Ethash.Params.DaoHardforkTransition != nil &&
 			pc.Engine.Ethash.Params.DaoHardforkBeneficiary != nil &&
@@ -93,6 +97,26 @@ var (
 		"requireBlockHashes", "config.requireBlockHashes",
 	}
 
+	// These are fields which differentiate a Besu config from a goethereum config.
+	besuSchemaSuffice = []string{
+		"classicForkBlock", "config.classicForkBlock",
+		"ecip1015Block", "config.ecip1015Block",
+		"diehardBlock", "config.diehardBlock",
+		"gothamBlock", "config.gothamBlock",
+		"ecip1041Block", "config.ecip1041Block",
+		"atlantisBlock", "config.atlantisBlock",
+		"aghartaBlock", "config.aghartaBlock",
+		"phoenixBlock", "config.phoenixBlock",
+		"thanosBlock", "config.thanosBlock",
+		"clique.blockperiodseconds", "config.clique.blockperiodseconds",
+		"ethash.fixeddifficulty", "config.ethash.fixeddifficulty",
+	}
+	besuSchemaMustNot = []string{
+		"engine",
+		"genesis.seal",
+		"networkId", "config.networkId",
+	}
+
 	goethereumSchemaSuffice = []string{
 		"difficulty",
 		"byzantiumBlock", "config.byzantiumBlock",
@@ -115,6 +139,7 @@ func UnmarshalChainConfigurator(input []byte) (ctypes.ChainConfigurator, error)
 		{&parity.ParityChainSpec{}, paritySchemaKeysSuffice, paritySchemaKeysMustNot},
 		{&coregeth.CoreGethChainConfig{}, multigethSchemaSuffice, multigethSchemaMustNot},
 		{&multigeth.ChainConfig{}, oldmultigethSchemaSuffice, oldmultigethSchemaMustNot},
+		{&besu.ChainConfig{}, besuSchemaSuffice, besuSchemaMustNot},
 		{&goethereum.ChainConfig{}, goethereumSchemaSuffice, goethereumSchemaMustNot},
 	}
 	for _, c := range cases {
@@ -25,13 +25,16 @@ import (
 	"reflect"
 	"testing"
 
+	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/params/confp"
 	"github.com/ethereum/go-ethereum/params/confp/tconvert"
 	"github.com/ethereum/go-ethereum/params/types/aleth"
+	"github.com/ethereum/go-ethereum/params/types/besu"
 	"github.com/ethereum/go-ethereum/params/types/coregeth"
 	"github.com/ethereum/go-ethereum/params/types/ctypes"
 	"github.com/ethereum/go-ethereum/params/types/genesisT"
 	"github.com/ethereum/go-ethereum/params/types/goethereum"
+	"github.com/ethereum/go-ethereum/params/types/nethermind"
 	"github.com/ethereum/go-ethereum/params/types/parity"
 )
 
@@ -48,7 +51,7 @@ func mustOpenF(t *testing.T, fabbrev string, into interface{}) {
 
 func Test_UnmarshalJSON(t *testing.T) {
 	for _, f := range []string{
-		"geth", "parity", "aleth",
+		"geth", "parity", "aleth", "besu", "nethermind",
 	} {
 		switch f {
 		case "geth":
@@ -67,6 +70,23 @@ func Test_UnmarshalJSON(t *testing.T) {
 		case "aleth":
 			a := &aleth.AlethGenesisSpec{}
 			mustOpenF(t, f, a)
+		case "besu":
+			var c struct {
+				Config *besu.ChainConfig `json:"config"`
+			}
+			mustOpenF(t, f, &c)
+			if *c.Config.GetNetworkID() != 314158 {
+				t.Errorf("networkid")
+			}
+			if n := c.Config.GetEIP1283DisableTransition(); n == nil || *n != 40000 {
+				t.Errorf("petersburg")
+			}
+		case "nethermind":
+			n := &nethermind.NethermindChainSpec{}
+			mustOpenF(t, f, n)
+			if *n.GetNetworkID() != 314158 {
+				t.Errorf("networkid")
+			}
 		}
 	}
 }
@@ -97,6 +117,8 @@ func TestIdentical(t *testing.T) {
 		&coregeth.CoreGethChainConfig{},
 		&goethereum.ChainConfig{},
 		&parity.ParityChainSpec{},
+		&besu.ChainConfig{},
+		&nethermind.NethermindChainSpec{},
 		&coregeth.CoreGethChainConfig{}, // Complete combination test set.
 	}
 	for i := range configs {
@@ -124,6 +146,7 @@ func TestIdentical(t *testing.T) {
 func TestConfiguratorImplementationsSatisfied(t *testing.T) {
 	for _, ty := range []interface{}{
 		&parity.ParityChainSpec{},
+		&nethermind.NethermindChainSpec{},
 	} {
 		_ = ty.(ctypes.Configurator)
 	}
@@ -131,6 +154,7 @@ func TestConfiguratorImplementationsSatisfied(t *testing.T) {
 	for _, ty := range []interface{}{
 		&goethereum.ChainConfig{},
 		&coregeth.CoreGethChainConfig{},
+		&besu.ChainConfig{},
 	} {
 		_ = ty.(ctypes.ChainConfigurator)
 	}
@@ -142,6 +166,70 @@ func TestConfiguratorImplementationsSatisfied(t *testing.T) {
 	}
 }
 
+// TestConvertBesuNethermind tests that the default chain configurations survive
+// conversion to the Besu and Nethermind formats, a JSON round trip, and
+// conversion back.
+func TestConvertBesuNethermind(t *testing.T) {
+	defaults := map[string]*genesisT.Genesis{
+		"classic":    params.DefaultClassicGenesisBlock(),
+		"mordor":     params.DefaultMordorGenesisBlock(),
+		"kotti":      params.DefaultKottiGenesisBlock(),
+		"foundation": params.DefaultGenesisBlock(),
+		"ropsten":    params.DefaultRopstenGenesisBlock(),
+		"goerli":     params.DefaultGoerliGenesisBlock(),
+	}
+	formats := map[string]func() ctypes.Configurator{
+		"besu": func() ctypes.Configurator {
+			return &genesisT.Genesis{Config: &besu.ChainConfig{}, Alloc: genesisT.GenesisAlloc{}}
+		},
+		"nethermind": func() ctypes.Configurator {
+			return &nethermind.NethermindChainSpec{}
+		},
+	}
+	for name, gen := range defaults {
+		for format, newConf := range formats {
+			conf := newConf()
+			if err := confp.Convert(gen, conf); err != nil {
+				t.Errorf("%s -> %s: %v", name, format, err)
+				continue
+			}
+			b, err := json.Marshal(conf)
+			if err != nil {
+				t.Errorf("%s -> %s: marshal: %v", name, format, err)
+				continue
+			}
+			decoded := newConf()
+			if err := json.Unmarshal(b, decoded); err != nil {
+				t.Errorf("%s -> %s: unmarshal: %v", name, format, err)
+				continue
+			}
+			// Besu configs overlap with the goethereum schema, so the genesis
+			// decoder may infer either; enforce the intended config type.
+			if g, ok := decoded.(*genesisT.Genesis); ok {
+				var dec struct {
+					Config *besu.ChainConfig `json:"config"`
+				}
+				if err := json.Unmarshal(b, &dec); err != nil {
+					t.Fatal(err)
+				}
+				g.Config = dec.Config
+			}
+			back := &genesisT.Genesis{Config: &coregeth.CoreGethChainConfig{}}
+			if err := confp.Convert(decoded, back); err != nil {
+				t.Errorf("%s -> %s -> coregeth: %v", name, format, err)
+				continue
+			}
+			k := reflect.TypeOf((*ctypes.ChainConfigurator)(nil))
+			for _, diff := range confp.Equal(k, gen, back) {
+				t.Errorf("%s -> %s: not equal: %s %v %v", name, format, diff.Field, diff.A, diff.B)
+			}
+			if !confp.Identical(gen, back, []string{"ChainID"}) {
+				t.Errorf("%s -> %s: chain id not identical", name, format)
+			}
+		}
+	}
+}
+
 func TestCompatible(t *testing.T) {
 	spec := &parity.ParityChainSpec{}
 	fns, names := confp.Transitions(spec)
@@ -70,6 +70,9 @@ func NewAlethGenesisSpec(network string, genesis *genesisT.Genesis) (*aleth.Alet
 	if num := genesis.Config.GetEIP145Transition(); num != nil {
 		spec.Params.IstanbulForkBlock = (*hexutil.Big)(((*hexutil.Uint64)(num)).Big())
 	}
+	if num := genesis.Config.GetEIP2718Transition(); num != nil {
+		spec.Params.BerlinForkBlock = (*hexutil.Big)(((*hexutil.Uint64)(num)).Big())
+	}
 	spec.Params.NetworkID = (hexutil.Uint64)(genesis.Config.GetChainID().Uint64())
 	spec.Params.ChainID = (hexutil.Uint64)(genesis.Config.GetChainID().Uint64())
 	spec.Params.MaximumExtraDataSize = (hexutil.Uint64)(vars.MaximumExtraDataSize)
@@ -0,0 +1,373 @@
+// Copyright 2020 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package besu
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"sort"
+	"strings"
+)
+
+// ChainConfig is the "config" object of a Hyperledger Besu genesis file.
+//
+// Besu configures protocol upgrades by named forks only, so the
+// feature-level transitions of the Configurator interface are derived from
+// (and, when set, encoded back into) the fork blocks below. Setting a
+// transition stores it in a feature schedule; the fork blocks are recomputed
+// from that schedule when the config is marshaled to JSON.
+type ChainConfig struct {
+	NetworkID uint64   `json:"-"`
+	ChainID   *big.Int `json:"chainId"`
+
+	// Ethereum Foundation forks.
+	HomesteadBlock      *big.Int `json:"homesteadBlock,omitempty"`
+	DAOForkBlock        *big.Int `json:"daoForkBlock,omitempty"`
+	EIP150Block         *big.Int `json:"eip150Block,omitempty"`
+	EIP155Block         *big.Int `json:"eip155Block,omitempty"`
+	EIP158Block         *big.Int `json:"eip158Block,omitempty"`
+	ByzantiumBlock      *big.Int `json:"byzantiumBlock,omitempty"`
+	ConstantinopleBlock *big.Int `json:"constantinopleBlock,omitempty"`
+	PetersburgBlock     *big.Int `json:"petersburgBlock,omitempty"`
+	IstanbulBlock       *big.Int `json:"istanbulBlock,omitempty"`
+	MuirGlacierBlock    *big.Int `json:"muirGlacierBlock,omitempty"`
+	BerlinBlock         *big.Int `json:"berlinBlock,omitempty"`
+	LondonBlock         *big.Int `json:"londonBlock,omitempty"`
+
+	// Ethereum Classic forks.
+	// ClassicForkBlock is the block at which Besu requires the non-DAO chain.
+	// It does not enable any protocol features and is passed through as-is.
+	ClassicForkBlock *big.Int `json:"classicForkBlock,omitempty"`
+	ECIP1015Block    *big.Int `json:"ecip1015Block,omitempty"`
+	DieHardBlock     *big.Int `json:"diehardBlock,omitempty"`
+	GothamBlock      *big.Int `json:"gothamBlock,omitempty"`
+	ECIP1041Block    *big.Int `json:"ecip1041Block,omitempty"`
+	AtlantisBlock    *big.Int `json:"atlantisBlock,omitempty"`
+	AghartaBlock     *big.Int `json:"aghartaBlock,omitempty"`
+	PhoenixBlock     *big.Int `json:"phoenixBlock,omitempty"`
+	ThanosBlock      *big.Int `json:"thanosBlock,omitempty"`
+	ECBP1100Block    *big.Int `json:"ecbp1100Block,omitempty"`
+
+	ECIP1017EraRounds *big.Int `json:"ecip1017EraRounds,omitempty"`
+
+	// Various consensus engines
+	Ethash *EthashConfig `json:"ethash,omitempty"`
+	Clique *CliqueConfig `json:"clique,omitempty"`
+
+	// schedule holds the feature transitions set through the Configurator
+	// interface. It is nil until the first transition is set.
+	schedule map[feature]*uint64
+}
+
+// EthashConfig is the consensus engine config for proof-of-work based sealing.
+type EthashConfig struct {
+	FixedDifficulty *big.Int `json:"fixeddifficulty,omitempty"`
+}
+
+// CliqueConfig is the consensus engine config for proof-of-authority based sealing.
+type CliqueConfig struct {
+	BlockPeriodSeconds uint64 `json:"blockperiodseconds"`
+	EpochLength        uint64 `json:"epochlength"`
+}
+
+var errUnencodableSchedule = errors.New("transition schedule cannot be expressed as Besu fork blocks")
+
+// feature names a single protocol change which Besu enables as part of a fork.
+type feature string
+
+const (
+	fEIP2            feature = "EIP2"
+	fEIP7            feature = "EIP7"
+	fEIP150          feature = "EIP150"
+	fEIP155          feature = "EIP155"
+	fEIP160          feature = "EIP160"
+	fEIP161abc       feature = "EIP161abc"
+	fEIP161d         feature = "EIP161d"
+	fEIP170          feature = "EIP170"
+	fEIP140          feature = "EIP140"
+	fEIP198          feature = "EIP198"
+	fEIP211          feature = "EIP211"
+	fEIP212          feature = "EIP212"
+	fEIP213          feature = "EIP213"
+	fEIP214          feature = "EIP214"
+	fEIP658          feature = "EIP658"
+	fEIP145          feature = "EIP145"
+	fEIP1014         feature = "EIP1014"
+	fEIP1052         feature = "EIP1052"
+	fEIP1283         feature = "EIP1283"
+	fEIP1283Disable  feature = "EIP1283Disable"
+	fEIP152          feature = "EIP152"
+	fEIP1108         feature = "EIP1108"
+	fEIP1344         feature = "EIP1344"
+	fEIP1884         feature = "EIP1884"
+	fEIP2028         feature = "EIP2028"
+	fEIP2200         feature = "EIP2200"
+	fEIP2718         feature = "EIP2718"
+	fEIP2929         feature = "EIP2929"
+	fEIP2930         feature = "EIP2930"
+	fEIP1559         feature = "EIP1559"
+	fECBP1100        feature = "ECBP1100"
+	fEthashHomestead feature = "EthashHomestead"
+	fEthashEIP779    feature = "EthashEIP779"
+	fEthashEIP100B   feature = "EthashEIP100B"
+	fEthashEIP649    feature = "EthashEIP649"
+	fEthashEIP1234   feature = "EthashEIP1234"
+	fEthashEIP2384   feature = "EthashEIP2384"
+	fEthashECIP1010P feature = "EthashECIP1010Pause"
+	fEthashECIP1010C feature = "EthashECIP1010Continue"
+	fEthashECIP1017  feature = "EthashECIP1017"
+	fEthashECIP1041  feature = "EthashECIP1041"
+	fEthashECIP1099  feature = "EthashECIP1099"
+)
+
+// ethash reports whether the feature only applies to the Ethash consensus engine.
+func (f feature) ethash() bool {
+	return strings.HasPrefix(string(f), "Ethash")
+}
+
+// fork describes a named Besu fork and the features it enables.
+type fork struct {
+	name     string
+	block    func(c *ChainConfig) **big.Int
+	features []feature
+}
+
+var (
+	byzantiumFeatures = []feature{
+		fEIP140, fEIP198, fEIP211, fEIP212, fEIP213, fEIP214, fEIP658, fEthashEIP100B,
+	}
+	constantinopleFeatures = []feature{fEIP145, fEIP1014, fEIP1052}
+	istanbulFeatures       = []feature{fEIP152, fEIP1108, fEIP1344, fEIP1884, fEIP2028, fEIP2200}
+)
+
+func concat(sets ...[]feature) []feature {
+	var out []feature
+	for _, s := range sets {
+		out = append(out, s...)
+	}
+	return out
+}
+
+// ethForks are the Ethereum Foundation forks known to Besu.
+var ethForks = []fork{
+	{"homestead", func(c *ChainConfig) **big.Int { return &c.HomesteadBlock }, []feature{fEIP2, fEIP7, fEthashHomestead}},
+	{"dao", func(c *ChainConfig) **big.Int { return &c.DAOForkBlock }, []feature{fEthashEIP779}},
+	{"eip150", func(c *ChainConfig) **big.Int { return &c.EIP150Block }, []feature{fEIP150}},
+	{"eip155", func(c *ChainConfig) **big.Int { return &c.EIP155Block }, []feature{fEIP155}},
+	{"eip158", func(c *ChainConfig) **big.Int { return &c.EIP158Block }, []feature{fEIP160, fEIP161abc, fEIP161d, fEIP170}},
+	{"byzantium", func(c *ChainConfig) **big.Int { return &c.ByzantiumBlock }, concat(byzantiumFeatures, []feature{fEthashEIP649})},
+	{"constantinople", func(c *ChainConfig) **big.Int { return &c.ConstantinopleBlock }, concat(constantinopleFeatures, []feature{fEIP1283, fEthashEIP1234})},
+	{"petersburg", func(c *ChainConfig) **big.Int { return &c.PetersburgBlock }, []feature{fEIP1283Disable}},
+	{"istanbul", func(c *ChainConfig) **big.Int { return &c.IstanbulBlock }, istanbulFeatures},
+	{"muirGlacier", func(c *ChainConfig) **big.Int { return &c.MuirGlacierBlock }, []feature{fEthashEIP2384}},
+	{"berlin", func(c *ChainConfig) **big.Int { return &c.BerlinBlock }, []feature{fEIP2718, fEIP2929, fEIP2930}},
+	{"london", func(c *ChainConfig) **big.Int { return &c.LondonBlock }, []feature{fEIP1559}},
+}
+
+// etcForks are the Ethereum Classic forks known to Besu.
+var etcForks = []fork{
+	{"ecip1015", func(c *ChainConfig) **big.Int { return &c.ECIP1015Block }, []feature{fEIP150}},
+	{"diehard", func(c *ChainConfig) **big.Int { return &c.DieHardBlock }, []feature{fEIP155, fEIP160, fEthashECIP1010P}},
+	{"gotham", func(c *ChainConfig) **big.Int { return &c.GothamBlock }, []feature{fEthashECIP1017, fEthashECIP1010C}},
+	{"ecip1041", func(c *ChainConfig) **big.Int { return &c.ECIP1041Block }, []feature{fEthashECIP1041}},
+	{"atlantis", func(c *ChainConfig) **big.Int { return &c.AtlantisBlock }, concat(byzantiumFeatures, []feature{fEIP161abc, fEIP161d, fEIP170})},
+	{"agharta", func(c *ChainConfig) **big.Int { return &c.AghartaBlock }, constantinopleFeatures},
+	{"phoenix", func(c *ChainConfig) **big.Int { return &c.PhoenixBlock }, istanbulFeatures},
+	{"thanos", func(c *ChainConfig) **big.Int { return &c.ThanosBlock }, []feature{fEthashECIP1099}},
+	{"ecbp1100", func(c *ChainConfig) **big.Int { return &c.ECBP1100Block }, []feature{fECBP1100}},
+}
+
+// transitions decodes the configured fork blocks into a feature schedule.
+// A feature shared by several forks activates at the earliest of them.
+func (c *ChainConfig) transitions() map[feature]*uint64 {
+	isEthash := c.Clique == nil
+	m := make(map[feature]*uint64)
+	for _, forks := range [][]fork{ethForks, etcForks} {
+		for _, f := range forks {
+			b := *f.block(c)
+			if b == nil {
+				continue
+			}
+			n := b.Uint64()
+			for _, ft := range f.features {
+				if ft.ethash() && !isEthash {
+					continue
+				}
+				// Gotham only ends the difficulty bomb pause if Die Hard started it.
+				if ft == fEthashECIP1010C && c.DieHardBlock == nil {
+					continue
+				}
+				if cur, ok := m[ft]; ok && *cur <= n {
+					continue
+				}
+				m[ft] = newU64(n)
+			}
+		}
+	}
+	return m
+}
+
+// wantedTransitions returns the set transitions relevant to the configured
+// consensus engine.
+func (c *ChainConfig) wantedTransitions() map[feature]*uint64 {
+	isEthash := c.Clique == nil
+	want := make(map[feature]*uint64)
+	for ft, n := range c.schedule {
+		if n == nil || (ft.ethash() && !isEthash) {
+			continue
+		}
+		want[ft] = n
+	}
+	return want
+}
+
+// forkCount returns the number of configured forks.
+func (c *ChainConfig) forkCount() (n int) {
+	for _, forks := range [][]fork{ethForks, etcForks} {
+		for _, f := range forks {
+			if *f.block(c) != nil {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// encodeSchedule returns a copy of the config with its fork blocks derived from
+// the feature schedule. Forks are assigned greedily, once preferring the
+// Ethereum Foundation forks and once the Ethereum Classic ones, and the valid
+// result using fewer forks is returned.
+func (c *ChainConfig) encodeSchedule() (*ChainConfig, error) {
+	want := c.wantedTransitions()
+
+	var best *ChainConfig
+	for _, order := range [][][]fork{{ethForks, etcForks}, {etcForks, ethForks}} {
+		cand := new(ChainConfig)
+		*cand = *c
+		cand.schedule = nil
+		for _, forks := range order {
+			for _, f := range forks {
+				*f.block(cand) = nil
+			}
+		}
+		for _, forks := range order {
+			for _, f := range forks {
+				cand.assignFork(f, want)
+			}
+		}
+		if !equalTransitions(cand.transitions(), want) {
+			continue
+		}
+		if best == nil || cand.forkCount() < best.forkCount() {
+			best = cand
+		}
+	}
+	if best == nil {
+		return nil, errUnencodableSchedule
+	}
+	return best, nil
+}
+
+// assignFork sets the fork's block to the first of its features' wanted
+// transitions which activates something new without activating anything
+// early or unwanted. The fork is left unset if there is no such block.
+func (c *ChainConfig) assignFork(f fork, want map[feature]*uint64) {
+	var candidates []uint64
+	seen := make(map[uint64]bool)
+	for _, ft := range f.features {
+		if n, ok := want[ft]; ok && !seen[*n] {
+			seen[*n] = true
+			candidates = append(candidates, *n)
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i] < candidates[j] })
+
+	before := c.transitions()
+	for _, n := range candidates {
+		*f.block(c) = new(big.Int).SetUint64(n)
+		got := c.transitions()
+		if consistentTransitions(got, want) && coversMore(before, got, want) {
+			return
+		}
+	}
+	*f.block(c) = nil
+}
+
+// consistentTransitions reports whether no feature in got activates before
+// its wanted block, or at all if it is not wanted.
+func consistentTransitions(got, want map[feature]*uint64) bool {
+	for ft, n := range got {
+		w, ok := want[ft]
+		if !ok || *n < *w {
+			return false
+		}
+	}
+	return true
+}
+
+// coversMore reports whether got activates some wanted feature at its wanted
+// block which before did not.
+func coversMore(before, got, want map[feature]*uint64) bool {
+	for ft, n := range got {
+		if *n != *want[ft] {
+			continue
+		}
+		if b, ok := before[ft]; !ok || *b != *n {
+			return true
+		}
+	}
+	return false
+}
+
+func equalTransitions(a, b map[feature]*uint64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for ft, n := range a {
+		m, ok := b[ft]
+		if !ok || *m != *n {
+			return false
+		}
+	}
+	return true
+}
+
+// MarshalJSON implements the json.Marshaler interface, encoding any set
+// transitions as fork blocks.
+func (c *ChainConfig) MarshalJSON() ([]byte, error) {
+	type besuChainConfig ChainConfig
+	enc := c
+	if c.schedule != nil {
+		var err error
+		if enc, err = c.encodeSchedule(); err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal((*besuChainConfig)(enc))
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface. Any previously set
+// transitions are discarded in favor of the decoded fork blocks.
+func (c *ChainConfig) UnmarshalJSON(input []byte) error {
+	type besuChainConfig ChainConfig
+	if err := json.Unmarshal(input, (*besuChainConfig)(c)); err != nil {
+		return err
+	}
+	c.schedule = nil
+	return nil
+}
@@ -0,0 +1,700 @@
+// Copyright 2020 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package besu
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/params/types/ctypes"
+	"github.com/ethereum/go-ethereum/params/types/internal"
+	"github.com/ethereum/go-ethereum/params/vars"
+)
+
+// File contains the Besu implementation of the Configurator interface.
+
+func newU64(u uint64) *uint64 {
+	return &u
+}
+
+func bigNewU64(i *big.Int) *uint64 {
+	if i == nil {
+		return nil
+	}
+	return newU64(i.Uint64())
+}
+
+func setBig(u *uint64) *big.Int {
+	if u == nil {
+		return nil
+	}
+	return new(big.Int).SetUint64(*u)
+}
+
+// getTransition returns the feature's transition, either from the schedule
+// if any transitions have been set, or as decoded from the fork blocks.
+func (c *ChainConfig) getTransition(f feature) *uint64 {
+	if f.ethash() && c.GetConsensusEngineType() != ctypes.ConsensusEngineT_Ethash {
+		return nil
+	}
+	if c.schedule == nil {
+		return c.transitions()[f]
+	}
+	if n := c.schedule[f]; n != nil {
+		return newU64(*n)
+	}
+	return nil
+}
+
+// setTransition sets the feature's transition in the schedule, initializing
+// the schedule from the fork blocks on first use.
+func (c *ChainConfig) setTransition(f feature, n *uint64) error {
+	if c.schedule == nil {
+		c.schedule = c.transitions()
+	}
+	if n == nil {
+		delete(c.schedule, f)
+		return nil
+	}
+	c.schedule[f] = newU64(*n)
+	return nil
+}
+
+func (c *ChainConfig) GetAccountStartNonce() *uint64 {
+	return internal.GlobalConfigurator().GetAccountStartNonce()
+}
+
+func (c *ChainConfig) SetAccountStartNonce(n *uint64) error {
+	return internal.GlobalConfigurator().SetAccountStartNonce(n)
+}
+
+func (c *ChainConfig) GetMaximumExtraDataSize() *uint64 {
+	return internal.GlobalConfigurator().GetMaximumExtraDataSize()
+}
+
+func (c *ChainConfig) SetMaximumExtraDataSize(n *uint64) error {
+	return internal.GlobalConfigurator().SetMaximumExtraDataSize(n)
+}
+
+func (c *ChainConfig) GetMinGasLimit() *uint64 {
+	return internal.GlobalConfigurator().GetMinGasLimit()
+}
+
+func (c *ChainConfig) SetMinGasLimit(n *uint64) error {
+	return internal.GlobalConfigurator().SetMinGasLimit(n)
+}
+
+func (c *ChainConfig) GetGasLimitBoundDivisor() *uint64 {
+	return internal.GlobalConfigurator().GetGasLimitBoundDivisor()
+}
+
+func (c *ChainConfig) SetGasLimitBoundDivisor(n *uint64) error {
+	return internal.GlobalConfigurator().SetGasLimitBoundDivisor(n)
+}
+
+// GetNetworkID returns the network id, which Besu does not read from the
+// genesis file; it defaults to the chain id.
+func (c *ChainConfig) GetNetworkID() *uint64 {
+	if c.NetworkID != 0 {
+		return &c.NetworkID
+	}
+	if c.ChainID != nil {
+		return newU64(c.ChainID.Uint64())
+	}
+	return newU64(vars.DefaultNetworkID)
+}
+
+func (c *ChainConfig) SetNetworkID(n *uint64) error {
+	if n == nil {
+		return ctypes.ErrUnsupportedConfigFatal
+	}
+	if c.ChainID == nil {
+		c.ChainID = new(big.Int).SetUint64(*n)
+	}
+	c.NetworkID = *n
+	return nil
+}
+
+func (c *ChainConfig) GetChainID() *big.Int {
+	return c.ChainID
+}
+
+func (c *ChainConfig) SetChainID(n *big.Int) error {
+	c.ChainID = n
+	return nil
+}
+
+func (c *ChainConfig) GetMaxCodeSize() *uint64 {
+	return internal.GlobalConfigurator().GetMaxCodeSize()
+}
+
+func (c *ChainConfig) SetMaxCodeSize(n *uint64) error {
+	return internal.GlobalConfigurator().SetMaxCodeSize(n)
+}
+
+func (c *ChainConfig) GetEIP2Transition() *uint64 {
+	return c.getTransition(fEIP2)
+}
+
+func (c *ChainConfig) SetEIP2Transition(n *uint64) error {
+	return c.setTransition(fEIP2, n)
+}
+
+func (c *ChainConfig) GetEIP7Transition() *uint64 {
+	return c.getTransition(fEIP7)
+}
+
+func (c *ChainConfig) SetEIP7Transition(n *uint64) error {
+	return c.setTransition(fEIP7, n)
+}
+
+func (c *ChainConfig) GetEIP150Transition() *uint64 {
+	return c.getTransition(fEIP150)
+}
+
+func (c *ChainConfig) SetEIP150Transition(n *uint64) error {
+	return c.setTransition(fEIP150, n)
+}
+
+func (c *ChainConfig) GetEIP152Transition() *uint64 {
+	return c.getTransition(fEIP152)
+}
+
+func (c *ChainConfig) SetEIP152Transition(n *uint64) error {
+	return c.setTransition(fEIP152, n)
+}
+
+func (c *ChainConfig) GetEIP160Transition() *uint64 {
+	return c.getTransition(fEIP160)
+}
+
+func (c *ChainConfig) SetEIP160Transition(n *uint64) error {
+	return c.setTransition(fEIP160, n)
+}
+
+func (c *ChainConfig) GetEIP161abcTransition() *uint64 {
+	return c.getTransition(fEIP161abc)
+}
+
+func (c *ChainConfig) SetEIP161abcTransition(n *uint64) error {
+	return c.setTransition(fEIP161abc, n)
+}
+
+func (c *ChainConfig) GetEIP161dTransition() *uint64 {
+	return c.getTransition(fEIP161d)
+}
+
+func (c *ChainConfig) SetEIP161dTransition(n *uint64) error {
+	return c.setTransition(fEIP161d, n)
+}
+
+func (c *ChainConfig) GetEIP170Transition() *uint64 {
+	return c.getTransition(fEIP170)
+}
+
+func (c *ChainConfig) SetEIP170Transition(n *uint64) error {
+	return c.setTransition(fEIP170, n)
+}
+
+func (c *ChainConfig) GetEIP155Transition() *uint64 {
+	return c.getTransition(fEIP155)
+}
+
+func (c *ChainConfig) SetEIP155Transition(n *uint64) error {
+	return c.setTransition(fEIP155, n)
+}
+
+func (c *ChainConfig) GetEIP140Transition() *uint64 {
+	return c.getTransition(fEIP140)
+}
+
+func (c *ChainConfig) SetEIP140Transition(n *uint64) error {
+	return c.setTransition(fEIP140, n)
+}
+
+func (c *ChainConfig) GetEIP198Transition() *uint64 {
+	return c.getTransition(fEIP198)
+}
+
+func (c *ChainConfig) SetEIP198Transition(n *uint64) error {
+	return c.setTransition(fEIP198, n)
+}
+
+func (c *ChainConfig) GetEIP211Transition() *uint64 {
+	return c.getTransition(fEIP211)
+}
+
+func (c *ChainConfig) SetEIP211Transition(n *uint64) error {
+	return c.setTransition(fEIP211, n)
+}
+
+func (c *ChainConfig) GetEIP212Transition() *uint64 {
+	return c.getTransition(fEIP212)
+}
+
+func (c *ChainConfig) SetEIP212Transition(n *uint64) error {
+	return c.setTransition(fEIP212, n)
+}
+
+func (c *ChainConfig) GetEIP213Transition() *uint64 {
+	return c.getTransition(fEIP213)
+}
+
+func (c *ChainConfig) SetEIP213Transition(n *uint64) error {
+	return c.setTransition(fEIP213, n)
+}
+
+func (c *ChainConfig) GetEIP214Transition() *uint64 {
+	return c.getTransition(fEIP214)
+}
+
+func (c *ChainConfig) SetEIP214Transition(n *uint64) error {
+	return c.setTransition(fEIP214, n)
+}
+
+func (c *ChainConfig) GetEIP658Transition() *uint64 {
+	return c.getTransition(fEIP658)
+}
+
+func (c *ChainConfig) SetEIP658Transition(n *uint64) error {
+	return c.setTransition(fEIP658, n)
+}
+
+func (c *ChainConfig) GetEIP145Transition() *uint64 {
+	return c.getTransition(fEIP145)
+}
+
+func (c *ChainConfig) SetEIP145Transition(n *uint64) error {
+	return c.setTransition(fEIP145, n)
+}
+
+func (c *ChainConfig) GetEIP1014Transition() *uint64 {
+	return c.getTransition(fEIP1014)
+}
+
+func (c *ChainConfig) SetEIP1014Transition(n *uint64) error {
+	return c.setTransition(fEIP1014, n)
+}
+
+func (c *ChainConfig) GetEIP1052Transition() *uint64 {
+	return c.getTransition(fEIP1052)
+}
+
+func (c *ChainConfig) SetEIP1052Transition(n *uint64) error {
+	return c.setTransition(fEIP1052, n)
+}
+
+func (c *ChainConfig) GetEIP1283Transition() *uint64 {
+	return c.getTransition(fEIP1283)
+}
+
+func (c *ChainConfig) SetEIP1283Transition(n *uint64) error {
+	return c.setTransition(fEIP1283, n)
+}
+
+func (c *ChainConfig) GetEIP1283DisableTransition() *uint64 {
+	return c.getTransition(fEIP1283Disable)
+}
+
+func (c *ChainConfig) SetEIP1283DisableTransition(n *uint64) error {
+	return c.setTransition(fEIP1283Disable, n)
+}
+
+func (c *ChainConfig) GetEIP1108Transition() *uint64 {
+	return c.getTransition(fEIP1108)
+}
+
+func (c *ChainConfig) SetEIP1108Transition(n *uint64) error {
+	return c.setTransition(fEIP1108, n)
+}
+
+func (c *ChainConfig) GetEIP2200Transition() *uint64 {
+	return c.getTransition(fEIP2200)
+}
+
+func (c *ChainConfig) SetEIP2200Transition(n *uint64) error {
+	return c.setTransition(fEIP2200, n)
+}
+
+func (c *ChainConfig) GetEIP2200DisableTransition() *uint64 {
+	return nil
+}
+
+func (c *ChainConfig) SetEIP2200DisableTransition(n *uint64) error {
+	if n == nil {
+		return nil
+	}
+	return ctypes.ErrUnsupportedConfigFatal
+}
+
+func (c *ChainConfig) GetEIP1344Transition() *uint64 {
+	return c.getTransition(fEIP1344)
+}
+
+func (c *ChainConfig) SetEIP1344Transition(n *uint64) error {
+	return c.setTransition(fEIP1344, n)
+}
+
+func (c *ChainConfig) GetEIP1884Transition() *uint64 {
+	return c.getTransition(fEIP1884)
+}
+
+func (c *ChainConfig) SetEIP1884Transition(n *uint64) error {
+	return c.setTransition(fEIP1884, n)
+}
+
+func (c *ChainConfig) GetEIP2028Transition() *uint64 {
+	return c.getTransition(fEIP2028)
+}
+
+func (c *ChainConfig) SetEIP2028Transition(n *uint64) error {
+	return c.setTransition(fEIP2028, n)
+}
+
+func (c *ChainConfig) GetECIP1080Transition() *uint64 {
+	return nil
+}
+
+func (c *ChainConfig) SetECIP1080Transition(n *uint64) error {
+	if n == nil {
+		return nil
+	}
+	return ctypes.ErrUnsupportedConfigFatal
+}
+
+func (c *ChainConfig) GetEIP1706Transition() *uint64 {
+	return nil
+}
+
+func (c *ChainConfig) SetEIP1706Transition(n *uint64) error {
+	if n == nil {
+		return nil
+	}
+	return ctypes.ErrUnsupportedConfigFatal
+}
+
+func (c *ChainConfig) GetEIP2537Transition() *uint64 {
+	return nil
+}
+
+func (c *ChainConfig) SetEIP2537Transition(n *uint64) error {
+	if n == nil {
+		return nil
+	}
+	return ctypes.ErrUnsupportedConfigFatal
+}
+
+func (c *ChainConfig) GetEIP2718Transition() *uint64 {
+	return c.getTransition(fEIP2718)
+}
+
+func (c *ChainConfig) SetEIP2718Transition(n *uint64) error {
+	return c.setTransition(fEIP2718, n)
+}
+
+func (c *ChainConfig) GetEIP2929Transition() *uint64 {
+	return c.getTransition(fEIP2929)
+}
+
+func (c *ChainConfig) SetEIP2929Transition(n *uint64) error {
+	return c.setTransition(fEIP2929, n)
+}
+
+func (c *ChainConfig) GetEIP2930Transition() *uint64 {
+	return c.getTransition(fEIP2930)
+}
+
+func (c *ChainConfig) SetEIP2930Transition(n *uint64) error {
+	return c.setTransition(fEIP2930, n)
+}
+
+func (c *ChainConfig) GetEIP1559Transition() *uint64 {
+	return c.getTransition(fEIP1559)
+}
+
+func (c *ChainConfig) SetEIP1559Transition(n *uint64) error {
+	return c.setTransition(fEIP1559, n)
+}
+
+func (c *ChainConfig) GetECBP1100Transition() *uint64 {
+	return c.getTransition(fECBP1100)
+}
+
+func (c *ChainConfig) SetECBP1100Transition(n *uint64) error {
+	return c.setTransition(fECBP1100, n)
+}
+
+func (c *ChainConfig) IsEnabled(fn func() *uint64, n *big.Int) bool {
+	f := fn()
+	if f == nil || n == nil {
+		return false
+	}
+	return big.NewInt(int64(*f)).Cmp(n) <= 0
+}
+
+func (c *ChainConfig) GetForkCanonHash(n uint64) common.Hash {
+	return common.Hash{}
+}
+
+func (c *ChainConfig) SetForkCanonHash(n uint64, h common.Hash) error {
+	return ctypes.ErrUnsupportedConfigNoop
+}
+
+func (c *ChainConfig) GetForkCanonHashes() map[uint64]common.Hash {
+	return nil
+}
+
+func (c *ChainConfig) GetConsensusEngineType() ctypes.ConsensusEngineT {
+	if c.Clique != nil {
+		return ctypes.ConsensusEngineT_Clique
+	}
+	return ctypes.ConsensusEngineT_Ethash
+}
+
+func (c *ChainConfig) MustSetConsensusEngineType(t ctypes.ConsensusEngineT) error {
+	switch t {
+	case ctypes.ConsensusEngineT_Ethash:
+		if c.Ethash == nil {
+			c.Ethash = new(EthashConfig)
+		}
+		c.Clique = nil
+		return nil
+	case ctypes.ConsensusEngineT_Clique:
+		if c.Clique == nil {
+			c.Clique = new(CliqueConfig)
+		}
+		c.Ethash = nil
+		return nil
+	default:
+		return ctypes.ErrUnsupportedConfigFatal
+	}
+}
+
+func (c *ChainConfig) GetEthashMinimumDifficulty() *big.Int {
+	if c.GetConsensusEngineType() != ctypes.ConsensusEngineT_Ethash {
+		return nil
+	}
+	return internal.GlobalConfigurator().GetEthashMinimumDifficulty()
+}
+
+func (c *ChainConfig) SetEthashMinimumDifficulty(i *big.Int) error {
+	return internal.GlobalConfigurator().SetEthashMinimumDifficulty(i)
+}
+
+func (c *ChainConfig) GetEthashDifficultyBoundDivisor() *big.Int {
+	if c.GetConsensusEngineType() != ctypes.ConsensusEngineT_Ethash {
+		return nil
+	}
+	return internal.GlobalConfigurator().GetEthashDifficultyBoundDivisor()
+}
+
+func (c *ChainConfig) SetEthashDifficultyBoundDivisor(i *big.Int) error {
+	return internal.GlobalConfigurator().SetEthashDifficultyBoundDivisor(i)
+}
+
+func (c *ChainConfig) GetEthashDurationLimit() *big.Int {
+	if c.GetConsensusEngineType() != ctypes.ConsensusEngineT_Ethash {
+		return nil
+	}
+	return internal.GlobalConfigurator().GetEthashDurationLimit()
+}
+
+func (c *ChainConfig) SetEthashDurationLimit(i *big.Int) error {
+	return internal.GlobalConfigurator().SetEthashDurationLimit(i)
+}
+
+func (c *ChainConfig) GetEthashHomesteadTransition() *uint64 {
+	return c.getTransition(fEthashHomestead)
+}
+
+func (c *ChainConfig) SetEthashHomesteadTransition(n *uint64) error {
+	if c.Ethash == nil {
+		return ctypes.ErrUnsupportedConfigFatal
+	}
+	return c.setTransition(fEthashHomestead, n)
+}
+
+func (c *ChainConfig) GetEthashEIP779Transition() *uint64 {
+	return c.getTransition(fEthashEIP779)
+}
+
+func (c *ChainConfig) SetEthashEIP779Transition(n *uint64) error {
+	if c.Ethash == nil {
+		return ctypes.ErrUnsupportedConfigFatal
+	}
+	return c.setTransition(fEthashEIP779, n)
+}
+
+func (c *ChainConfig) GetEthashEIP649Transition() *uint64 {
+	return c.getTransition(fEthashEIP649)
+}
+
+func (c *ChainConfig) SetEthashEIP649Transition(n *uint64) error {
+	if c.Ethash == nil {
+		return ctypes.ErrUnsupportedConfigFatal
+	}
+	return c.setTransition(fEthashEIP649, n)
+}
+
+func (c *ChainConfig) GetEthashEIP1234Transition() *uint64 {
+	return c.getTransition(fEthashEIP1234)
+}
+
+func (c *ChainConfig) SetEthashEIP1234Transition(n *uint64) error {
+	if c.Ethash == nil {
+		return ctypes.ErrUnsupportedConfigFatal
+	}
+	return c.setTransition(fEthashEIP1234, n)
+}
+
+func (c *ChainConfig) GetEthashEIP2384Transition() *uint64 {
+	return c.getTransition(fEthashEIP2384)
+}
+
+func (c *ChainConfig) SetEthashEIP2384Transition(n *uint64) error {
+	if c.Ethash == nil {
+		return ctypes.ErrUnsupportedConfigFatal
+	}
+	return c.setTransition(fEthashEIP2384, n)
+}
+
+func (c *ChainConfig) GetEthashECIP1010PauseTransition() *uint64 {
+	return c.getTransition(fEthashECIP1010P)
+}
+
+func (c *ChainConfig) SetEthashECIP1010PauseTransition(n *uint64) error {
+	if c.Ethash == nil {
+		return ctypes.ErrUnsupportedConfigFatal
+	}
+	return c.setTransition(fEthashECIP1010P, n)
+}
+
+func (c *ChainConfig) GetEthashECIP1010ContinueTransition() *uint64 {
+	return c.getTransition(fEthashECIP1010C)
+}
+
+func (c *ChainConfig) SetEthashECIP1010ContinueTransition(n *uint64) error {
+	if c.Ethash == nil {
+		return ctypes.ErrUnsupportedConfigFatal
+	}
+	return c.setTransition(fEthashECIP1010C, n)
+}
+
+func (c *ChainConfig) GetEthashECIP1017Transition() *uint64 {
+	return c.getTransition(fEthashECIP1017)
+}
+
+func (c *ChainConfig) SetEthashECIP1017Transition(n *uint64) error {
+	if c.Ethash == nil {
+		return ctypes.ErrUnsupportedConfigFatal
+	}
+	return c.setTransition(fEthashECIP1017, n)
+}
+
+func (c *ChainConfig) GetEthashECIP1017EraRounds() *uint64 {
+	if c.GetConsensusEngineType() != ctypes.ConsensusEngineT_Ethash {
+		return nil
+	}
+	return bigNewU64(c.ECIP1017EraRounds)
+}
+
+func (c *ChainConfig) SetEthashECIP1017EraRounds(n *uint64) error {
+	if c.Ethash == nil {
+		return ctypes.ErrUnsupportedConfigFatal
+	}
+	c.ECIP1017EraRounds = setBig(n)
+	return nil
+}
+
+func (c *ChainConfig) GetEthashEIP100BTransition() *uint64 {
+	return c.getTransition(fEthashEIP100B)
+}
+
+func (c *ChainConfig) SetEthashEIP100BTransition(n *uint64) error {
+	if c.Ethash == nil {
+		return ctypes.ErrUnsupportedConfigFatal
+	}
+	return c.setTransition(fEthashEIP100B, n)
+}
+
+func (c *ChainConfig) GetEthashECIP1041Transition() *uint64 {
+	return c.getTransition(fEthashECIP1041)
+}
+
+func (c *ChainConfig) SetEthashECIP1041Transition(n *uint64) error {
+	if c.Ethash == nil {
+		return ctypes.ErrUnsupportedConfigFatal
+	}
+	return c.setTransition(fEthashECIP1041, n)
+}
+
+func (c *ChainConfig) GetEthashECIP1099Transition() *uint64 {
+	return c.getTransition(fEthashECIP1099)
+}
+
+func (c *ChainConfig) SetEthashECIP1099Transition(n *uint64) error {
+	if c.Ethash == nil {
+		return ctypes.ErrUnsupportedConfigFatal
+	}
+	return c.setTransition(fEthashECIP1099, n)
+}
+
+func (c *ChainConfig) GetEthashDifficultyBombDelaySchedule() ctypes.Uint64BigMapEncodesHex {
+	return nil
+}
+
+func (c *ChainConfig) SetEthashDifficultyBombDelaySchedule(m ctypes.Uint64BigMapEncodesHex) error {
+	return ctypes.ErrUnsupportedConfigNoop
+}
+
+func (c *ChainConfig) GetEthashBlockRewardSchedule() ctypes.Uint64BigMapEncodesHex {
+	return nil
+}
+
+func (c *ChainConfig) SetEthashBlockRewardSchedule(m ctypes.Uint64BigMapEncodesHex) error {
+	return ctypes.ErrUnsupportedConfigNoop
+}
+
+func (c *ChainConfig) GetCliquePeriod() uint64 {
+	if c.Clique == nil {
+		return 0
+	}
+	return c.Clique.BlockPeriodSeconds
+}
+
+func (c *ChainConfig) SetCliquePeriod(n uint64) error {
+	if c.Clique == nil {
+		return ctypes.ErrUnsupportedConfigFatal
+	}
+	c.Clique.BlockPeriodSeconds = n
+	return nil
+}
+
+func (c *ChainConfig) GetCliqueEpoch() uint64 {
+	if c.Clique == nil {
+		return 0
+	}
+	return c.Clique.EpochLength
+}
+
+func (c *ChainConfig) SetCliqueEpoch(n uint64) error {
+	if c.Clique == nil {
+		return ctypes.ErrUnsupportedConfigFatal
+	}
+	c.Clique.EpochLength = n
+	return nil
+}
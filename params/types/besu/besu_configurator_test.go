@@ -0,0 +1,123 @@
+// Copyright 2020 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package besu
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// classicConfig is the config section of Besu's Ethereum Classic genesis.
+const classicConfig = `{
+	"chainId": 61,
+	"homesteadBlock": 1150000,
+	"classicForkBlock": 1920000,
+	"ecip1015Block": 2500000,
+	"diehardBlock": 3000000,
+	"gothamBlock": 5000000,
+	"ecip1041Block": 5900000,
+	"atlantisBlock": 8772000,
+	"aghartaBlock": 9573000,
+	"phoenixBlock": 10500839,
+	"thanosBlock": 11700000,
+	"ecip1017EraRounds": 5000000,
+	"ethash": {}
+}`
+
+func TestChainConfig_DecodeForks(t *testing.T) {
+	c := &ChainConfig{}
+	if err := json.Unmarshal([]byte(classicConfig), c); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		name string
+		fn   func() *uint64
+		want uint64
+	}{
+		{"EIP2", c.GetEIP2Transition, 1150000},
+		{"EIP150", c.GetEIP150Transition, 2500000},
+		{"EIP155", c.GetEIP155Transition, 3000000},
+		{"EIP160", c.GetEIP160Transition, 3000000},
+		{"EIP161abc", c.GetEIP161abcTransition, 8772000},
+		{"EIP170", c.GetEIP170Transition, 8772000},
+		{"EIP140", c.GetEIP140Transition, 8772000},
+		{"EIP1052", c.GetEIP1052Transition, 9573000},
+		{"EIP2200", c.GetEIP2200Transition, 10500839},
+		{"ECIP1010Pause", c.GetEthashECIP1010PauseTransition, 3000000},
+		{"ECIP1010Continue", c.GetEthashECIP1010ContinueTransition, 5000000},
+		{"ECIP1017", c.GetEthashECIP1017Transition, 5000000},
+		{"ECIP1017EraRounds", c.GetEthashECIP1017EraRounds, 5000000},
+		{"ECIP1041", c.GetEthashECIP1041Transition, 5900000},
+		{"ECIP1099", c.GetEthashECIP1099Transition, 11700000},
+	} {
+		if got := tt.fn(); got == nil || *got != tt.want {
+			t.Errorf("%s: got %v, want %d", tt.name, got, tt.want)
+		}
+	}
+	for name, fn := range map[string]func() *uint64{
+		"EIP649":   c.GetEthashEIP649Transition,
+		"EIP1283":  c.GetEIP1283Transition,
+		"EIP779":   c.GetEthashEIP779Transition,
+		"ECBP1100": c.GetECBP1100Transition,
+	} {
+		if got := fn(); got != nil {
+			t.Errorf("%s: got %d, want nil", name, *got)
+		}
+	}
+}
+
+func TestChainConfig_EncodeSchedule(t *testing.T) {
+	c := &ChainConfig{}
+	if err := json.Unmarshal([]byte(classicConfig), c); err != nil {
+		t.Fatal(err)
+	}
+	// Setting an already configured transition must not change the encoding.
+	n := uint64(8772000)
+	if err := c.SetEIP140Transition(&n); err != nil {
+		t.Fatal(err)
+	}
+	n = 11380000
+	if err := c.SetECBP1100Transition(&n); err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, want := make(map[string]interface{}), make(map[string]interface{})
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(classicConfig), &want); err != nil {
+		t.Fatal(err)
+	}
+	want["ecbp1100Block"] = float64(11380000)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("encoding mismatch:\ngot  %s\nwant %v", b, want)
+	}
+
+	// EIP1283 is only enabled by Constantinople, which would also enable
+	// EIP145 et al. ahead of Agharta.
+	n = 9000000
+	if err := c.SetEIP1283Transition(&n); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := json.Marshal(c); err == nil {
+		t.Error("expected error for unencodable schedule")
+	}
+}
@@ -165,6 +165,22 @@ type CoreGethChainConfig struct {
 	// https://github.com/ethereum/EIPs/pull/2537: BLS12-381 curve operations
 	EIP2537FBlock *big.Int `json:"eip2315FBlock,omitempty"`
 
+	// EIP-2718: Typed Transaction Envelope
+	// https://eips.ethereum.org/EIPS/eip-2718
+	EIP2718FBlock *big.Int `json:"eip2718FBlock,omitempty"`
+
+	// EIP-2929: Gas cost increases for state access opcodes
+	// https://eips.ethereum.org/EIPS/eip-2929
+	EIP2929FBlock *big.Int `json:"eip2929FBlock,omitempty"`
+
+	// EIP-2930: Optional access lists
+	// https://eips.ethereum.org/EIPS/eip-2930
+	EIP2930FBlock *big.Int `json:"eip2930FBlock,omitempty"`
+
+	// EIP-1559: Fee market change for ETH 1.0 chain
+	// https://eips.ethereum.org/EIPS/eip-1559
+	EIP1559FBlock *big.Int `json:"eip1559FBlock,omitempty"`
+
 	//EWASMBlock *big.Int `json:"ewasmBlock,omitempty"` // EWASM switch block (nil = no fork, 0 = already activated)
 
 	ECIP1010PauseBlock *big.Int `json:"ecip1010PauseBlock,omitempty"` // ECIP1010 pause HF block
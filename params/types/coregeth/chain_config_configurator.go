@@ -381,6 +381,42 @@ func (c *CoreGethChainConfig) SetEIP2537Transition(n *uint64) error {
 	return nil
 }
 
+func (c *CoreGethChainConfig) GetEIP2718Transition() *uint64 {
+	return bigNewU64(c.EIP2718FBlock)
+}
+
+func (c *CoreGethChainConfig) SetEIP2718Transition(n *uint64) error {
+	c.EIP2718FBlock = setBig(c.EIP2718FBlock, n)
+	return nil
+}
+
+func (c *CoreGethChainConfig) GetEIP2929Transition() *uint64 {
+	return bigNewU64(c.EIP2929FBlock)
+}
+
+func (c *CoreGethChainConfig) SetEIP2929Transition(n *uint64) error {
+	c.EIP2929FBlock = setBig(c.EIP2929FBlock, n)
+	return nil
+}
+
+func (c *CoreGethChainConfig) GetEIP2930Transition() *uint64 {
+	return bigNewU64(c.EIP2930FBlock)
+}
+
+func (c *CoreGethChainConfig) SetEIP2930Transition(n *uint64) error {
+	c.EIP2930FBlock = setBig(c.EIP2930FBlock, n)
+	return nil
+}
+
+func (c *CoreGethChainConfig) GetEIP1559Transition() *uint64 {
+	return bigNewU64(c.EIP1559FBlock)
+}
+
+func (c *CoreGethChainConfig) SetEIP1559Transition(n *uint64) error {
+	c.EIP1559FBlock = setBig(c.EIP1559FBlock, n)
+	return nil
+}
+
 func (c *CoreGethChainConfig) GetECBP1100Transition() *uint64 {
 	return bigNewU64(c.ECBP1100FBlock)
 }
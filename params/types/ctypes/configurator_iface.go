@@ -130,6 +130,14 @@ type ProtocolSpecifier interface {
 	SetEIP1706Transition(n *uint64) error
 	GetEIP2537Transition() *uint64
 	SetEIP2537Transition(n *uint64) error
+	GetEIP2718Transition() *uint64
+	SetEIP2718Transition(n *uint64) error
+	GetEIP2929Transition() *uint64
+	SetEIP2929Transition(n *uint64) error
+	GetEIP2930Transition() *uint64
+	SetEIP2930Transition(n *uint64) error
+	GetEIP1559Transition() *uint64
+	SetEIP1559Transition(n *uint64) error
 	GetECBP1100Transition() *uint64
 	SetECBP1100Transition(n *uint64) error
 }
@@ -416,6 +416,50 @@ func (c *ChainConfig) SetEIP2537Transition(n *uint64) error {
 	return ctypes.ErrUnsupportedConfigFatal
 }
 
+func (c *ChainConfig) GetEIP2718Transition() *uint64 {
+	return nil
+}
+
+func (c *ChainConfig) SetEIP2718Transition(n *uint64) error {
+	if n == nil {
+		return nil
+	}
+	return ctypes.ErrUnsupportedConfigFatal
+}
+
+func (c *ChainConfig) GetEIP2929Transition() *uint64 {
+	return nil
+}
+
+func (c *ChainConfig) SetEIP2929Transition(n *uint64) error {
+	if n == nil {
+		return nil
+	}
+	return ctypes.ErrUnsupportedConfigFatal
+}
+
+func (c *ChainConfig) GetEIP2930Transition() *uint64 {
+	return nil
+}
+
+func (c *ChainConfig) SetEIP2930Transition(n *uint64) error {
+	if n == nil {
+		return nil
+	}
+	return ctypes.ErrUnsupportedConfigFatal
+}
+
+func (c *ChainConfig) GetEIP1559Transition() *uint64 {
+	return nil
+}
+
+func (c *ChainConfig) SetEIP1559Transition(n *uint64) error {
+	if n == nil {
+		return nil
+	}
+	return ctypes.ErrUnsupportedConfigFatal
+}
+
 func (c *ChainConfig) GetECBP1100Transition() *uint64 {
 	return nil
 }
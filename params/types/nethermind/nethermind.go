@@ -0,0 +1,151 @@
+// Copyright 2020 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package nethermind
+
+import (
+	"encoding/json"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/params/types/ctypes"
+	"github.com/ethereum/go-ethereum/params/types/parity"
+)
+
+// NethermindChainSpec is the chain specification format used by Nethermind.
+// It descends from the Parity format, but configures protocol changes by
+// explicit transitions in the params rather than by builtin pricing, and
+// supports the Ethereum Classic extensions.
+type NethermindChainSpec struct {
+	Name    string `json:"name"`
+	Datadir string `json:"dataDir,omitempty"`
+	Engine  struct {
+		Ethash *EthashEngine `json:"Ethash,omitempty"`
+		Clique *CliqueEngine `json:"clique,omitempty"`
+	} `json:"engine"`
+
+	Params struct {
+		AccountStartNonce         *parity.ParityU64 `json:"accountStartNonce,omitempty"`
+		MaximumExtraDataSize      *parity.ParityU64 `json:"maximumExtraDataSize,omitempty"`
+		MinGasLimit               *parity.ParityU64 `json:"minGasLimit,omitempty"`
+		GasLimitBoundDivisor      *parity.ParityU64 `json:"gasLimitBoundDivisor,omitempty"`
+		NetworkID                 *parity.ParityU64 `json:"networkID,omitempty"`
+		ChainID                   *parity.ParityU64 `json:"chainID,omitempty"`
+		MaxCodeSize               *parity.ParityU64 `json:"maxCodeSize,omitempty"`
+		MaxCodeSizeTransition     *parity.ParityU64 `json:"maxCodeSizeTransition,omitempty"`
+		EIP150Transition          *parity.ParityU64 `json:"eip150Transition,omitempty"`
+		EIP152Transition          *parity.ParityU64 `json:"eip152Transition,omitempty"`
+		EIP160Transition          *parity.ParityU64 `json:"eip160Transition,omitempty"`
+		EIP161abcTransition       *parity.ParityU64 `json:"eip161abcTransition,omitempty"`
+		EIP161dTransition         *parity.ParityU64 `json:"eip161dTransition,omitempty"`
+		EIP155Transition          *parity.ParityU64 `json:"eip155Transition,omitempty"`
+		EIP140Transition          *parity.ParityU64 `json:"eip140Transition,omitempty"`
+		EIP211Transition          *parity.ParityU64 `json:"eip211Transition,omitempty"`
+		EIP214Transition          *parity.ParityU64 `json:"eip214Transition,omitempty"`
+		EIP658Transition          *parity.ParityU64 `json:"eip658Transition,omitempty"`
+		EIP145Transition          *parity.ParityU64 `json:"eip145Transition,omitempty"`
+		EIP1014Transition         *parity.ParityU64 `json:"eip1014Transition,omitempty"`
+		EIP1052Transition         *parity.ParityU64 `json:"eip1052Transition,omitempty"`
+		EIP1108Transition         *parity.ParityU64 `json:"eip1108Transition,omitempty"`
+		EIP1283Transition         *parity.ParityU64 `json:"eip1283Transition,omitempty"`
+		EIP1283DisableTransition  *parity.ParityU64 `json:"eip1283DisableTransition,omitempty"`
+		EIP1283ReenableTransition *parity.ParityU64 `json:"eip1283ReenableTransition,omitempty"`
+		EIP1344Transition         *parity.ParityU64 `json:"eip1344Transition,omitempty"`
+		EIP1706Transition         *parity.ParityU64 `json:"eip1706Transition,omitempty"`
+		EIP1884Transition         *parity.ParityU64 `json:"eip1884Transition,omitempty"`
+		EIP2028Transition         *parity.ParityU64 `json:"eip2028Transition,omitempty"`
+		EIP2200Transition         *parity.ParityU64 `json:"eip2200Transition,omitempty"`
+		EIP2537Transition         *parity.ParityU64 `json:"eip2537Transition,omitempty"`
+		EIP2718Transition         *parity.ParityU64 `json:"eip2718Transition,omitempty"`
+		EIP2929Transition         *parity.ParityU64 `json:"eip2929Transition,omitempty"`
+		EIP2930Transition         *parity.ParityU64 `json:"eip2930Transition,omitempty"`
+		EIP1559Transition         *parity.ParityU64 `json:"eip1559Transition,omitempty"`
+
+		// ECBP1100Transition is a core-geth extension; Nethermind does not implement MESS.
+		ECBP1100Transition *parity.ParityU64 `json:"ecbp1100Transition,omitempty"`
+
+		ForkBlock     *parity.ParityU64 `json:"forkBlock,omitempty"`
+		ForkCanonHash *common.Hash      `json:"forkCanonHash,omitempty"`
+	} `json:"params"`
+
+	Genesis struct {
+		Seal struct {
+			Ethereum struct {
+				Nonce   parity.BlockNonce `json:"nonce"`
+				MixHash hexutil.Bytes     `json:"mixHash"`
+			} `json:"ethereum"`
+		} `json:"seal"`
+
+		Difficulty *math.HexOrDecimal256 `json:"difficulty"`
+		Author     common.Address        `json:"author"`
+		Timestamp  math.HexOrDecimal64   `json:"timestamp"`
+		ParentHash common.Hash           `json:"parentHash"`
+		ExtraData  hexutil.Bytes         `json:"extraData"`
+		GasLimit   math.HexOrDecimal64   `json:"gasLimit"`
+	} `json:"genesis"`
+
+	Nodes    []string                                                    `json:"nodes"`
+	Accounts map[common.UnprefixedAddress]*parity.ParityChainSpecAccount `json:"accounts"`
+}
+
+// EthashEngine is the Ethash consensus engine section of a Nethermind chainspec.
+type EthashEngine struct {
+	Params struct {
+		MinimumDifficulty      *math.HexOrDecimal256         `json:"minimumDifficulty"`
+		DifficultyBoundDivisor *math.HexOrDecimal256         `json:"difficultyBoundDivisor"`
+		DurationLimit          *math.HexOrDecimal256         `json:"durationLimit"`
+		BlockReward            ctypes.Uint64BigValOrMapHex   `json:"blockReward,omitempty"`
+		DifficultyBombDelays   ctypes.Uint64BigMapEncodesHex `json:"difficultyBombDelays,omitempty"`
+
+		// Caches.
+		// These inferences require computation.
+		// See ctypes.MapMeetsSpecification for this bespoke logic.
+		eip649Inferred    bool
+		eip649Transition  *parity.ParityU64
+		eip1234Inferred   bool
+		eip1234Transition *parity.ParityU64
+		eip2384Inferred   bool
+		eip2384Transition *parity.ParityU64
+
+		HomesteadTransition *parity.ParityU64 `json:"homesteadTransition,omitempty"`
+		EIP100bTransition   *parity.ParityU64 `json:"eip100bTransition,omitempty"`
+
+		DaoHardforkTransition  *parity.ParityU64 `json:"daoHardforkTransition,omitempty"`
+		DaoHardforkBeneficiary *common.Address   `json:"daoHardforkBeneficiary,omitempty"`
+		DaoHardforkAccounts    []common.Address  `json:"daoHardforkAccounts,omitempty"`
+
+		BombDefuseTransition       *parity.ParityU64 `json:"bombDefuseTransition,omitempty"`
+		ECIP1010PauseTransition    *parity.ParityU64 `json:"ecip1010PauseTransition,omitempty"`
+		ECIP1010ContinueTransition *parity.ParityU64 `json:"ecip1010ContinueTransition,omitempty"`
+		ECIP1017Transition         *parity.ParityU64 `json:"ecip1017Transition,omitempty"`
+		ECIP1017EraRounds          *parity.ParityU64 `json:"ecip1017EraRounds,omitempty"`
+		ECIP1099Transition         *parity.ParityU64 `json:"ecip1099Transition,omitempty"`
+	} `json:"params"`
+}
+
+// CliqueEngine is the Clique consensus engine section of a Nethermind chainspec.
+type CliqueEngine struct {
+	Params struct {
+		Period *parity.ParityU64 `json:"period,omitempty"`
+		Epoch  *parity.ParityU64 `json:"epoch,omitempty"`
+	} `json:"params"`
+}
+
+func (spec *NethermindChainSpec) String() string {
+	j, _ := json.MarshalIndent(spec, "", "    ")
+	return string(j)
+}
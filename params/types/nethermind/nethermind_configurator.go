@@ -0,0 +1,1026 @@
+// Copyright 2020 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package nethermind
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/params/types/ctypes"
+	"github.com/ethereum/go-ethereum/params/types/parity"
+	"github.com/ethereum/go-ethereum/params/vars"
+)
+
+// newHexOrDecimal256 returns a copy of i, or nil.
+func newHexOrDecimal256(i *big.Int) *math.HexOrDecimal256 {
+	if i == nil {
+		return nil
+	}
+	return (*math.HexOrDecimal256)(new(big.Int).Set(i))
+}
+
+func (spec *NethermindChainSpec) ensureEthash() {
+	if spec.Engine.Ethash == nil {
+		spec.Engine.Ethash = new(EthashEngine)
+	}
+}
+
+func (spec *NethermindChainSpec) ensureExistingRewardSchedule() {
+	if spec.Engine.Ethash.Params.BlockReward == nil {
+		spec.Engine.Ethash.Params.BlockReward = ctypes.Uint64BigValOrMapHex{}
+	}
+}
+
+func (spec *NethermindChainSpec) ensureExistingDifficultyDelaySchedule() {
+	if spec.Engine.Ethash.Params.DifficultyBombDelays == nil {
+		spec.Engine.Ethash.Params.DifficultyBombDelays = ctypes.Uint64BigMapEncodesHex{}
+	}
+}
+
+func (spec *NethermindChainSpec) GetAccountStartNonce() *uint64 {
+	return spec.Params.AccountStartNonce.Uint64P()
+}
+
+func (spec *NethermindChainSpec) SetAccountStartNonce(n *uint64) error {
+	if n == nil {
+		return ctypes.ErrUnsupportedConfigFatal
+	}
+	spec.Params.AccountStartNonce = new(parity.ParityU64).SetUint64(n)
+	return nil
+}
+
+func (spec *NethermindChainSpec) GetMaximumExtraDataSize() *uint64 {
+	return spec.Params.MaximumExtraDataSize.Uint64P()
+}
+
+func (spec *NethermindChainSpec) SetMaximumExtraDataSize(n *uint64) error {
+	spec.Params.MaximumExtraDataSize = new(parity.ParityU64).SetUint64(n)
+	return nil
+}
+
+func (spec *NethermindChainSpec) GetMinGasLimit() *uint64 {
+	return spec.Params.MinGasLimit.Uint64P()
+}
+
+func (spec *NethermindChainSpec) SetMinGasLimit(n *uint64) error {
+	spec.Params.MinGasLimit = new(parity.ParityU64).SetUint64(n)
+	return nil
+}
+
+func (spec *NethermindChainSpec) GetGasLimitBoundDivisor() *uint64 {
+	return spec.Params.GasLimitBoundDivisor.Uint64P()
+}
+
+func (spec *NethermindChainSpec) SetGasLimitBoundDivisor(n *uint64) error {
+	spec.Params.GasLimitBoundDivisor = new(parity.ParityU64).SetUint64(n)
+	return nil
+}
+
+func (spec *NethermindChainSpec) GetNetworkID() *uint64 {
+	return spec.Params.NetworkID.Uint64P()
+}
+
+func (spec *NethermindChainSpec) SetNetworkID(n *uint64) error {
+	spec.Params.NetworkID = new(parity.ParityU64).SetUint64(n)
+	return nil
+}
+
+func (spec *NethermindChainSpec) GetChainID() *big.Int {
+	if chainid := spec.Params.ChainID.Big(); chainid != nil {
+		return chainid
+	}
+	return spec.Params.NetworkID.Big()
+}
+
+func (spec *NethermindChainSpec) SetChainID(i *big.Int) error {
+	if i == nil {
+		return nil
+	}
+	u := i.Uint64()
+	spec.Params.ChainID = new(parity.ParityU64).SetUint64(&u)
+	return nil
+}
+
+func (spec *NethermindChainSpec) GetMaxCodeSize() *uint64 {
+	return spec.Params.MaxCodeSize.Uint64P()
+}
+
+func (spec *NethermindChainSpec) SetMaxCodeSize(n *uint64) error {
+	spec.Params.MaxCodeSize = new(parity.ParityU64).SetUint64(n)
+	return nil
+}
+
+// GetEIP2Transition returns the Homestead transition of the Ethash engine.
+// Nethermind treats Homestead as active from genesis for any other engine.
+func (spec *NethermindChainSpec) GetEIP2Transition() *uint64 {
+	if spec.Engine.Ethash == nil {
+		if spec.Engine.Clique != nil {
+			return new(uint64)
+		}
+		return nil
+	}
+	return spec.Engine.Ethash.Params.HomesteadTransition.Uint64P()
+}
+
+func (spec *NethermindChainSpec) SetEIP2Transition(n *uint64) error {
+	return spec.SetEthashHomesteadTransition(n)
+}
+
+func (spec *NethermindChainSpec) GetEIP7Transition() *uint64 {
+	return spec.GetEIP2Transition()
+}
+
+func (spec *NethermindChainSpec) SetEIP7Transition(n *uint64) error {
+	return spec.SetEthashHomesteadTransition(n)
+}
+
+// setEIP140Derived handles the setters of the Byzantium precompiles, which
+// Nethermind activates together with EIP140 and does not configure separately.
+func (spec *NethermindChainSpec) setEIP140Derived(n *uint64) error {
+	have := spec.GetEIP140Transition()
+	if n == nil && have == nil {
+		return nil
+	}
+	if n != nil && have != nil && *n == *have {
+		return nil
+	}
+	return ctypes.ErrUnsupportedConfigFatal
+}
+
+func (spec *NethermindChainSpec) GetEIP150Transition() *uint64 {
+	return spec.Params.EIP150Transition.Uint64P()
+}
+
+func (spec *NethermindChainSpec) SetEIP150Transition(n *uint64) error {
+	spec.Params.EIP150Transition = new(parity.ParityU64).SetUint64(n)
+	return nil
+}
+
+func (spec *NethermindChainSpec) GetEIP152Transition() *uint64 {
+	return spec.Params.EIP152Transition.Uint64P()
+}
+
+func (spec *NethermindChainSpec) SetEIP152Transition(n *uint64) error {
+	spec.Params.EIP152Transition = new(parity.ParityU64).SetUint64(n)
+	return nil
+}
+
+func (spec *NethermindChainSpec) GetEIP160Transition() *uint64 {
+	return spec.Params.EIP160Transition.Uint64P()
+}
+
+func (spec *NethermindChainSpec) SetEIP160Transition(n *uint64) error {
+	spec.Params.EIP160Transition = new(parity.ParityU64).SetUint64(n)
+	return nil
+}
+
+func (spec *NethermindChainSpec) GetEIP161abcTransition() *uint64 {
+	return spec.Params.EIP161abcTransition.Uint64P()
+}
+
+func (spec *NethermindChainSpec) SetEIP161abcTransition(n *uint64) error {
+	spec.Params.EIP161abcTransition = new(parity.ParityU64).SetUint64(n)
+	return nil
+}
+
+func (spec *NethermindChainSpec) GetEIP161dTransition() *uint64 {
+	return spec.Params.EIP161dTransition.Uint64P()
+}
+
+func (spec *NethermindChainSpec) SetEIP161dTransition(n *uint64) error {
+	spec.Params.EIP161dTransition = new(parity.ParityU64).SetUint64(n)
+	return nil
+}
+
+func (spec *NethermindChainSpec) GetEIP170Transition() *uint64 {
+	return spec.Params.MaxCodeSizeTransition.Uint64P()
+}
+
+func (spec *NethermindChainSpec) SetEIP170Transition(n *uint64) error {
+	spec.Params.MaxCodeSizeTransition = new(parity.ParityU64).SetUint64(n)
+	return nil
+}
+
+func (spec *NethermindChainSpec) GetEIP155Transition() *uint64 {
+	return spec.Params.EIP155Transition.Uint64P()
+}
+
+func (spec *NethermindChainSpec) SetEIP155Transition(n *uint64) error {
+	spec.Params.EIP155Transition = new(parity.ParityU64).SetUint64(n)
+	return nil
+}
+
+func (spec *NethermindChainSpec) GetEIP140Transition() *uint64 {
+	return spec.Params.EIP140Transition.Uint64P()
+}
+
+func (spec *NethermindChainSpec) SetEIP140Transition(n *uint64) error {
+	spec.Params.EIP140Transition = new(parity.ParityU64).SetUint64(n)
+	return nil
+}
+
+func (spec *NethermindChainSpec) GetEIP198Transition() *uint64 {
+	return spec.GetEIP140Transition()
+}
+
+func (spec *NethermindChainSpec) SetEIP198Transition(n *uint64) error {
+	return spec.setEIP140Derived(n)
+}
+
+func (spec *NethermindChainSpec) GetEIP211Transition() *uint64 {
+	return spec.Params.EIP211Transition.Uint64P()
+}
+
+func (spec *NethermindChainSpec) SetEIP211Transition(n *uint64) error {
+	spec.Params.EIP211Transition = new(parity.ParityU64).SetUint64(n)
+	return nil
+}
+
+func (spec *NethermindChainSpec) GetEIP212Transition() *uint64 {
+	return spec.GetEIP140Transition()
+}
+
+func (spec *NethermindChainSpec) SetEIP212Transition(n *uint64) error {
+	return spec.setEIP140Derived(n)
+}
+
+func (spec *NethermindChainSpec) GetEIP213Transition() *uint64 {
+	return spec.GetEIP140Transition()
+}
+
+func (spec *NethermindChainSpec) SetEIP213Transition(n *uint64) error {
+	return spec.setEIP140Derived(n)
+}
+
+func (spec *NethermindChainSpec) GetEIP214Transition() *uint64 {
+	return spec.Params.EIP214Transition.Uint64P()
+}
+
+func (spec *NethermindChainSpec) SetEIP214Transition(n *uint64) error {
+	spec.Params.EIP214Transition = new(parity.ParityU64).SetUint64(n)
+	return nil
+}
+
+func (spec *NethermindChainSpec) GetEIP658Transition() *uint64 {
+	return spec.Params.EIP658Transition.Uint64P()
+}
+
+func (spec *NethermindChainSpec) SetEIP658Transition(n *uint64) error {
+	spec.Params.EIP658Transition = new(parity.ParityU64).SetUint64(n)
+	return nil
+}
+
+func (spec *NethermindChainSpec) GetEIP145Transition() *uint64 {
+	return spec.Params.EIP145Transition.Uint64P()
+}
+
+func (spec *NethermindChainSpec) SetEIP145Transition(n *uint64) error {
+	spec.Params.EIP145Transition = new(parity.ParityU64).SetUint64(n)
+	return nil
+}
+
+func (spec *NethermindChainSpec) GetEIP1014Transition() *uint64 {
+	return spec.Params.EIP1014Transition.Uint64P()
+}
+
+func (spec *NethermindChainSpec) SetEIP1014Transition(n *uint64) error {
+	spec.Params.EIP1014Transition = new(parity.ParityU64).SetUint64(n)
+	return nil
+}
+
+func (spec *NethermindChainSpec) GetEIP1052Transition() *uint64 {
+	return spec.Params.EIP1052Transition.Uint64P()
+}
+
+func (spec *NethermindChainSpec) SetEIP1052Transition(n *uint64) error {
+	spec.Params.EIP1052Transition = new(parity.ParityU64).SetUint64(n)
+	return nil
+}
+
+func (spec *NethermindChainSpec) GetEIP1283Transition() *uint64 {
+	return spec.Params.EIP1283Transition.Uint64P()
+}
+
+func (spec *NethermindChainSpec) SetEIP1283Transition(n *uint64) error {
+	spec.Params.EIP1283Transition = new(parity.ParityU64).SetUint64(n)
+	return nil
+}
+
+func (spec *NethermindChainSpec) GetEIP1283DisableTransition() *uint64 {
+	return spec.Params.EIP1283DisableTransition.Uint64P()
+}
+
+func (spec *NethermindChainSpec) SetEIP1283DisableTransition(n *uint64) error {
+	spec.Params.EIP1283DisableTransition = new(parity.ParityU64).SetUint64(n)
+	return nil
+}
+
+func (spec *NethermindChainSpec) GetEIP1108Transition() *uint64 {
+	return spec.Params.EIP1108Transition.Uint64P()
+}
+
+func (spec *NethermindChainSpec) SetEIP1108Transition(n *uint64) error {
+	spec.Params.EIP1108Transition = new(parity.ParityU64).SetUint64(n)
+	return nil
+}
+
+// GetEIP2200Transition falls back to the Parity-style EIP1283 reenable transition.
+func (spec *NethermindChainSpec) GetEIP2200Transition() *uint64 {
+	if spec.Params.EIP2200Transition != nil {
+		return spec.Params.EIP2200Transition.Uint64P()
+	}
+	return spec.Params.EIP1283ReenableTransition.Uint64P()
+}
+
+func (spec *NethermindChainSpec) SetEIP2200Transition(n *uint64) error {
+	spec.Params.EIP2200Transition = new(parity.ParityU64).SetUint64(n)
+	spec.Params.EIP1283ReenableTransition = nil
+	return nil
+}
+
+func (spec *NethermindChainSpec) GetEIP2200DisableTransition() *uint64 {
+	return nil
+}
+
+func (spec *NethermindChainSpec) SetEIP2200DisableTransition(n *uint64) error {
+	if n == nil {
+		return nil
+	}
+	return ctypes.ErrUnsupportedConfigFatal
+}
+
+func (spec *NethermindChainSpec) GetEIP1344Transition() *uint64 {
+	return spec.Params.EIP1344Transition.Uint64P()
+}
+
+func (spec *NethermindChainSpec) SetEIP1344Transition(n *uint64) error {
+	spec.Params.EIP1344Transition = new(parity.ParityU64).SetUint64(n)
+	return nil
+}
+
+func (spec *NethermindChainSpec) GetEIP1884Transition() *uint64 {
+	return spec.Params.EIP1884Transition.Uint64P()
+}
+
+func (spec *NethermindChainSpec) SetEIP1884Transition(n *uint64) error {
+	spec.Params.EIP1884Transition = new(parity.ParityU64).SetUint64(n)
+	return nil
+}
+
+func (spec *NethermindChainSpec) GetEIP2028Transition() *uint64 {
+	return spec.Params.EIP2028Transition.Uint64P()
+}
+
+func (spec *NethermindChainSpec) SetEIP2028Transition(n *uint64) error {
+	spec.Params.EIP2028Transition = new(parity.ParityU64).SetUint64(n)
+	return nil
+}
+
+func (spec *NethermindChainSpec) GetECIP1080Transition() *uint64 {
+	return nil
+}
+
+func (spec *NethermindChainSpec) SetECIP1080Transition(n *uint64) error {
+	if n == nil {
+		return nil
+	}
+	return ctypes.ErrUnsupportedConfigFatal
+}
+
+func (spec *NethermindChainSpec) GetEIP1706Transition() *uint64 {
+	return spec.Params.EIP1706Transition.Uint64P()
+}
+
+func (spec *NethermindChainSpec) SetEIP1706Transition(n *uint64) error {
+	spec.Params.EIP1706Transition = new(parity.ParityU64).SetUint64(n)
+	return nil
+}
+
+func (spec *NethermindChainSpec) GetEIP2537Transition() *uint64 {
+	return spec.Params.EIP2537Transition.Uint64P()
+}
+
+func (spec *NethermindChainSpec) SetEIP2537Transition(n *uint64) error {
+	spec.Params.EIP2537Transition = new(parity.ParityU64).SetUint64(n)
+	return nil
+}
+
+func (spec *NethermindChainSpec) GetEIP2718Transition() *uint64 {
+	return spec.Params.EIP2718Transition.Uint64P()
+}
+
+func (spec *NethermindChainSpec) SetEIP2718Transition(n *uint64) error {
+	spec.Params.EIP2718Transition = new(parity.ParityU64).SetUint64(n)
+	return nil
+}
+
+func (spec *NethermindChainSpec) GetEIP2929Transition() *uint64 {
+	return spec.Params.EIP2929Transition.Uint64P()
+}
+
+func (spec *NethermindChainSpec) SetEIP2929Transition(n *uint64) error {
+	spec.Params.EIP2929Transition = new(parity.ParityU64).SetUint64(n)
+	return nil
+}
+
+func (spec *NethermindChainSpec) GetEIP2930Transition() *uint64 {
+	return spec.Params.EIP2930Transition.Uint64P()
+}
+
+func (spec *NethermindChainSpec) SetEIP2930Transition(n *uint64) error {
+	spec.Params.EIP2930Transition = new(parity.ParityU64).SetUint64(n)
+	return nil
+}
+
+func (spec *NethermindChainSpec) GetEIP1559Transition() *uint64 {
+	return spec.Params.EIP1559Transition.Uint64P()
+}
+
+func (spec *NethermindChainSpec) SetEIP1559Transition(n *uint64) error {
+	spec.Params.EIP1559Transition = new(parity.ParityU64).SetUint64(n)
+	return nil
+}
+
+func (spec *NethermindChainSpec) GetECBP1100Transition() *uint64 {
+	return spec.Params.ECBP1100Transition.Uint64P()
+}
+
+func (spec *NethermindChainSpec) SetECBP1100Transition(n *uint64) error {
+	spec.Params.ECBP1100Transition = new(parity.ParityU64).SetUint64(n)
+	return nil
+}
+
+func (spec *NethermindChainSpec) IsEnabled(fn func() *uint64, n *big.Int) bool {
+	f := fn()
+	if f == nil || n == nil {
+		return false
+	}
+	return big.NewInt(int64(*f)).Cmp(n) <= 0
+}
+
+func (spec *NethermindChainSpec) GetForkCanonHash(n uint64) common.Hash {
+	if spec.Params.ForkBlock == nil || spec.Params.ForkCanonHash == nil {
+		return common.Hash{}
+	}
+	if uint64(*spec.Params.ForkBlock) == n {
+		return *spec.Params.ForkCanonHash
+	}
+	return common.Hash{}
+}
+
+func (spec *NethermindChainSpec) SetForkCanonHash(n uint64, h common.Hash) error {
+	spec.Params.ForkBlock = new(parity.ParityU64).SetUint64(&n)
+	spec.Params.ForkCanonHash = &h
+	return nil
+}
+
+func (spec *NethermindChainSpec) GetForkCanonHashes() map[uint64]common.Hash {
+	if spec.Params.ForkBlock == nil || spec.Params.ForkCanonHash == nil {
+		return nil
+	}
+	return map[uint64]common.Hash{
+		uint64(*spec.Params.ForkBlock): *spec.Params.ForkCanonHash,
+	}
+}
+
+func (spec *NethermindChainSpec) GetConsensusEngineType() ctypes.ConsensusEngineT {
+	if spec.Engine.Clique != nil {
+		return ctypes.ConsensusEngineT_Clique
+	}
+	if spec.Engine.Ethash != nil {
+		return ctypes.ConsensusEngineT_Ethash
+	}
+	return ctypes.ConsensusEngineT_Unknown
+}
+
+func (spec *NethermindChainSpec) MustSetConsensusEngineType(t ctypes.ConsensusEngineT) error {
+	switch t {
+	case ctypes.ConsensusEngineT_Ethash:
+		spec.ensureEthash()
+		spec.Engine.Clique = nil
+		return nil
+	case ctypes.ConsensusEngineT_Clique:
+		if spec.Engine.Clique == nil {
+			spec.Engine.Clique = new(CliqueEngine)
+		}
+		spec.Engine.Ethash = nil
+		return nil
+	default:
+		return ctypes.ErrUnsupportedConfigFatal
+	}
+}
+
+func (spec *NethermindChainSpec) GetEthashMinimumDifficulty() *big.Int {
+	if spec.Engine.Ethash == nil {
+		return nil
+	}
+	return spec.Engine.Ethash.Params.MinimumDifficulty.ToInt()
+}
+
+func (spec *NethermindChainSpec) SetEthashMinimumDifficulty(i *big.Int) error {
+	if i == nil && spec.Engine.Ethash == nil {
+		return nil
+	}
+	spec.ensureEthash()
+	spec.Engine.Ethash.Params.MinimumDifficulty = newHexOrDecimal256(i)
+	return nil
+}
+
+func (spec *NethermindChainSpec) GetEthashDifficultyBoundDivisor() *big.Int {
+	if spec.Engine.Ethash == nil {
+		return nil
+	}
+	return spec.Engine.Ethash.Params.DifficultyBoundDivisor.ToInt()
+}
+
+func (spec *NethermindChainSpec) SetEthashDifficultyBoundDivisor(i *big.Int) error {
+	if i == nil && spec.Engine.Ethash == nil {
+		return nil
+	}
+	spec.ensureEthash()
+	spec.Engine.Ethash.Params.DifficultyBoundDivisor = newHexOrDecimal256(i)
+	return nil
+}
+
+func (spec *NethermindChainSpec) GetEthashDurationLimit() *big.Int {
+	if spec.Engine.Ethash == nil {
+		return nil
+	}
+	return spec.Engine.Ethash.Params.DurationLimit.ToInt()
+}
+
+func (spec *NethermindChainSpec) SetEthashDurationLimit(i *big.Int) error {
+	if i == nil && spec.Engine.Ethash == nil {
+		return nil
+	}
+	spec.ensureEthash()
+	spec.Engine.Ethash.Params.DurationLimit = newHexOrDecimal256(i)
+	return nil
+}
+
+func (spec *NethermindChainSpec) GetEthashHomesteadTransition() *uint64 {
+	if spec.GetConsensusEngineType() != ctypes.ConsensusEngineT_Ethash {
+		return nil
+	}
+	return spec.Engine.Ethash.Params.HomesteadTransition.Uint64P()
+}
+
+func (spec *NethermindChainSpec) SetEthashHomesteadTransition(n *uint64) error {
+	if n == nil && spec.Engine.Ethash == nil {
+		return nil
+	}
+	spec.ensureEthash()
+	spec.Engine.Ethash.Params.HomesteadTransition = new(parity.ParityU64).SetUint64(n)
+	return nil
+}
+
+func (spec *NethermindChainSpec) GetEthashEIP779Transition() *uint64 {
+	if spec.GetConsensusEngineType() != ctypes.ConsensusEngineT_Ethash {
+		return nil
+	}
+	return spec.Engine.Ethash.Params.DaoHardforkTransition.Uint64P()
+}
+
+func (spec *NethermindChainSpec) SetEthashEIP779Transition(n *uint64) error {
+	if n == nil {
+		if spec.Engine.Ethash != nil {
+			spec.Engine.Ethash.Params.DaoHardforkTransition = nil
+			spec.Engine.Ethash.Params.DaoHardforkBeneficiary = nil
+			spec.Engine.Ethash.Params.DaoHardforkAccounts = nil
+		}
+		return nil
+	}
+	spec.ensureEthash()
+	spec.Engine.Ethash.Params.DaoHardforkTransition = new(parity.ParityU64).SetUint64(n)
+	spec.Engine.Ethash.Params.DaoHardforkBeneficiary = &vars.DAORefundContract
+	spec.Engine.Ethash.Params.DaoHardforkAccounts = vars.DAODrainList()
+	return nil
+}
+
+func (spec *NethermindChainSpec) GetEthashEIP649Transition() *uint64 {
+	if spec.GetConsensusEngineType() != ctypes.ConsensusEngineT_Ethash {
+		return nil
+	}
+	params := &spec.Engine.Ethash.Params
+	if params.eip649Inferred {
+		return params.eip649Transition.Uint64P()
+	}
+
+	diffN := ctypes.MapMeetsSpecification(
+		params.DifficultyBombDelays,
+		ctypes.Uint64BigMapEncodesHex(params.BlockReward),
+		vars.EIP649DifficultyBombDelay,
+		vars.EIP649FBlockReward,
+	)
+	params.eip649Transition = new(parity.ParityU64).SetUint64(diffN)
+	params.eip649Inferred = true
+	return diffN
+}
+
+func (spec *NethermindChainSpec) SetEthashEIP649Transition(n *uint64) error {
+	if n == nil && spec.Engine.Ethash == nil {
+		return nil
+	}
+	spec.ensureEthash()
+	spec.Engine.Ethash.Params.eip649Transition = new(parity.ParityU64).SetUint64(n)
+	spec.Engine.Ethash.Params.eip649Inferred = true
+	if n == nil {
+		return nil
+	}
+
+	spec.ensureExistingRewardSchedule()
+	spec.Engine.Ethash.Params.BlockReward[*n] = vars.EIP649FBlockReward
+
+	spec.ensureExistingDifficultyDelaySchedule()
+	spec.Engine.Ethash.Params.DifficultyBombDelays.SetValueTotalForHeight(n, vars.EIP649DifficultyBombDelay)
+
+	return nil
+}
+
+func (spec *NethermindChainSpec) GetEthashEIP1234Transition() *uint64 {
+	if spec.GetConsensusEngineType() != ctypes.ConsensusEngineT_Ethash {
+		return nil
+	}
+	params := &spec.Engine.Ethash.Params
+	if params.eip1234Inferred {
+		return params.eip1234Transition.Uint64P()
+	}
+
+	diffN := ctypes.MapMeetsSpecification(
+		params.DifficultyBombDelays,
+		ctypes.Uint64BigMapEncodesHex(params.BlockReward),
+		vars.EIP1234DifficultyBombDelay,
+		vars.EIP1234FBlockReward,
+	)
+	params.eip1234Transition = new(parity.ParityU64).SetUint64(diffN)
+	params.eip1234Inferred = true
+	return diffN
+}
+
+func (spec *NethermindChainSpec) SetEthashEIP1234Transition(n *uint64) error {
+	if n == nil && spec.Engine.Ethash == nil {
+		return nil
+	}
+	spec.ensureEthash()
+	spec.Engine.Ethash.Params.eip1234Transition = new(parity.ParityU64).SetUint64(n)
+	spec.Engine.Ethash.Params.eip1234Inferred = true
+	if n == nil {
+		return nil
+	}
+
+	spec.ensureExistingRewardSchedule()
+	spec.Engine.Ethash.Params.BlockReward[*n] = vars.EIP1234FBlockReward
+
+	spec.ensureExistingDifficultyDelaySchedule()
+	spec.Engine.Ethash.Params.DifficultyBombDelays.SetValueTotalForHeight(n, vars.EIP1234DifficultyBombDelay)
+
+	return nil
+}
+
+func (spec *NethermindChainSpec) GetEthashEIP2384Transition() *uint64 {
+	if spec.GetConsensusEngineType() != ctypes.ConsensusEngineT_Ethash {
+		return nil
+	}
+	params := &spec.Engine.Ethash.Params
+	if params.eip2384Inferred {
+		return params.eip2384Transition.Uint64P()
+	}
+
+	diffN := ctypes.MapMeetsSpecification(params.DifficultyBombDelays, nil, vars.EIP2384DifficultyBombDelay, nil)
+	params.eip2384Transition = new(parity.ParityU64).SetUint64(diffN)
+	params.eip2384Inferred = true
+	return diffN
+}
+
+func (spec *NethermindChainSpec) SetEthashEIP2384Transition(n *uint64) error {
+	if n == nil && spec.Engine.Ethash == nil {
+		return nil
+	}
+	spec.ensureEthash()
+	spec.Engine.Ethash.Params.eip2384Transition = new(parity.ParityU64).SetUint64(n)
+	spec.Engine.Ethash.Params.eip2384Inferred = true
+	if n == nil {
+		return nil
+	}
+
+	spec.ensureExistingDifficultyDelaySchedule()
+	spec.Engine.Ethash.Params.DifficultyBombDelays.SetValueTotalForHeight(n, vars.EIP2384DifficultyBombDelay)
+
+	return nil
+}
+
+func (spec *NethermindChainSpec) GetEthashECIP1010PauseTransition() *uint64 {
+	if spec.GetConsensusEngineType() != ctypes.ConsensusEngineT_Ethash {
+		return nil
+	}
+	return spec.Engine.Ethash.Params.ECIP1010PauseTransition.Uint64P()
+}
+
+func (spec *NethermindChainSpec) SetEthashECIP1010PauseTransition(n *uint64) error {
+	if n == nil && spec.Engine.Ethash == nil {
+		return nil
+	}
+	spec.ensureEthash()
+	spec.Engine.Ethash.Params.ECIP1010PauseTransition = new(parity.ParityU64).SetUint64(n)
+	return nil
+}
+
+func (spec *NethermindChainSpec) GetEthashECIP1010ContinueTransition() *uint64 {
+	if spec.GetConsensusEngineType() != ctypes.ConsensusEngineT_Ethash {
+		return nil
+	}
+	return spec.Engine.Ethash.Params.ECIP1010ContinueTransition.Uint64P()
+}
+
+func (spec *NethermindChainSpec) SetEthashECIP1010ContinueTransition(n *uint64) error {
+	if n == nil && spec.Engine.Ethash == nil {
+		return nil
+	}
+	spec.ensureEthash()
+	spec.Engine.Ethash.Params.ECIP1010ContinueTransition = new(parity.ParityU64).SetUint64(n)
+	return nil
+}
+
+// GetEthashECIP1017Transition falls back to the era length, which Parity-derived
+// chainspecs use as the transition (the ETC fork happened at block 5m, with 5m rounds).
+func (spec *NethermindChainSpec) GetEthashECIP1017Transition() *uint64 {
+	if spec.GetConsensusEngineType() != ctypes.ConsensusEngineT_Ethash {
+		return nil
+	}
+	if spec.Engine.Ethash.Params.ECIP1017Transition != nil {
+		return spec.Engine.Ethash.Params.ECIP1017Transition.Uint64P()
+	}
+	return spec.Engine.Ethash.Params.ECIP1017EraRounds.Uint64P()
+}
+
+func (spec *NethermindChainSpec) SetEthashECIP1017Transition(n *uint64) error {
+	if n == nil && spec.Engine.Ethash == nil {
+		return nil
+	}
+	spec.ensureEthash()
+	spec.Engine.Ethash.Params.ECIP1017Transition = new(parity.ParityU64).SetUint64(n)
+	return nil
+}
+
+func (spec *NethermindChainSpec) GetEthashECIP1017EraRounds() *uint64 {
+	if spec.GetConsensusEngineType() != ctypes.ConsensusEngineT_Ethash {
+		return nil
+	}
+	return spec.Engine.Ethash.Params.ECIP1017EraRounds.Uint64P()
+}
+
+func (spec *NethermindChainSpec) SetEthashECIP1017EraRounds(n *uint64) error {
+	if n == nil && spec.Engine.Ethash == nil {
+		return nil
+	}
+	spec.ensureEthash()
+	spec.Engine.Ethash.Params.ECIP1017EraRounds = new(parity.ParityU64).SetUint64(n)
+	return nil
+}
+
+func (spec *NethermindChainSpec) GetEthashEIP100BTransition() *uint64 {
+	if spec.GetConsensusEngineType() != ctypes.ConsensusEngineT_Ethash {
+		return nil
+	}
+	return spec.Engine.Ethash.Params.EIP100bTransition.Uint64P()
+}
+
+func (spec *NethermindChainSpec) SetEthashEIP100BTransition(n *uint64) error {
+	if n == nil && spec.Engine.Ethash == nil {
+		return nil
+	}
+	spec.ensureEthash()
+	spec.Engine.Ethash.Params.EIP100bTransition = new(parity.ParityU64).SetUint64(n)
+	return nil
+}
+
+func (spec *NethermindChainSpec) GetEthashECIP1041Transition() *uint64 {
+	if spec.GetConsensusEngineType() != ctypes.ConsensusEngineT_Ethash {
+		return nil
+	}
+	return spec.Engine.Ethash.Params.BombDefuseTransition.Uint64P()
+}
+
+func (spec *NethermindChainSpec) SetEthashECIP1041Transition(n *uint64) error {
+	if n == nil && spec.Engine.Ethash == nil {
+		return nil
+	}
+	spec.ensureEthash()
+	spec.Engine.Ethash.Params.BombDefuseTransition = new(parity.ParityU64).SetUint64(n)
+	return nil
+}
+
+func (spec *NethermindChainSpec) GetEthashECIP1099Transition() *uint64 {
+	if spec.GetConsensusEngineType() != ctypes.ConsensusEngineT_Ethash {
+		return nil
+	}
+	return spec.Engine.Ethash.Params.ECIP1099Transition.Uint64P()
+}
+
+func (spec *NethermindChainSpec) SetEthashECIP1099Transition(n *uint64) error {
+	if n == nil && spec.Engine.Ethash == nil {
+		return nil
+	}
+	spec.ensureEthash()
+	spec.Engine.Ethash.Params.ECIP1099Transition = new(parity.ParityU64).SetUint64(n)
+	return nil
+}
+
+func (spec *NethermindChainSpec) GetEthashDifficultyBombDelaySchedule() ctypes.Uint64BigMapEncodesHex {
+	if spec.GetConsensusEngineType() != ctypes.ConsensusEngineT_Ethash {
+		return nil
+	}
+	return spec.Engine.Ethash.Params.DifficultyBombDelays
+}
+
+func (spec *NethermindChainSpec) SetEthashDifficultyBombDelaySchedule(input ctypes.Uint64BigMapEncodesHex) error {
+	if input == nil && spec.Engine.Ethash == nil {
+		return nil
+	}
+	spec.ensureEthash()
+	spec.Engine.Ethash.Params.DifficultyBombDelays = input
+	return nil
+}
+
+func (spec *NethermindChainSpec) GetEthashBlockRewardSchedule() ctypes.Uint64BigMapEncodesHex {
+	if spec.GetConsensusEngineType() != ctypes.ConsensusEngineT_Ethash {
+		return nil
+	}
+	return ctypes.Uint64BigMapEncodesHex(spec.Engine.Ethash.Params.BlockReward)
+}
+
+func (spec *NethermindChainSpec) SetEthashBlockRewardSchedule(input ctypes.Uint64BigMapEncodesHex) error {
+	if input == nil && spec.Engine.Ethash == nil {
+		return nil
+	}
+	spec.ensureEthash()
+	spec.Engine.Ethash.Params.BlockReward = ctypes.Uint64BigValOrMapHex(input)
+	return nil
+}
+
+func (spec *NethermindChainSpec) GetCliquePeriod() uint64 {
+	if spec.Engine.Clique == nil {
+		return 0
+	}
+	if p := spec.Engine.Clique.Params.Period.Uint64P(); p != nil {
+		return *p
+	}
+	return 0
+}
+
+func (spec *NethermindChainSpec) SetCliquePeriod(n uint64) error {
+	if spec.Engine.Clique == nil {
+		return ctypes.ErrUnsupportedConfigFatal
+	}
+	spec.Engine.Clique.Params.Period = new(parity.ParityU64).SetUint64(&n)
+	return nil
+}
+
+func (spec *NethermindChainSpec) GetCliqueEpoch() uint64 {
+	if spec.Engine.Clique == nil {
+		return 0
+	}
+	if p := spec.Engine.Clique.Params.Epoch.Uint64P(); p != nil {
+		return *p
+	}
+	return 0
+}
+
+func (spec *NethermindChainSpec) SetCliqueEpoch(n uint64) error {
+	if spec.Engine.Clique == nil {
+		return ctypes.ErrUnsupportedConfigFatal
+	}
+	spec.Engine.Clique.Params.Epoch = new(parity.ParityU64).SetUint64(&n)
+	return nil
+}
+
+// GetSealingType returns the genesis sealing type, which is always Ethereum.
+func (spec *NethermindChainSpec) GetSealingType() ctypes.BlockSealingT {
+	return ctypes.BlockSealing_Ethereum
+}
+
+func (spec *NethermindChainSpec) SetSealingType(in ctypes.BlockSealingT) error {
+	if in == ctypes.BlockSealing_Ethereum {
+		return nil
+	}
+	return ctypes.ErrUnsupportedConfigFatal
+}
+
+func (spec *NethermindChainSpec) GetGenesisSealerEthereumNonce() uint64 {
+	return spec.Genesis.Seal.Ethereum.Nonce.Uint64()
+}
+
+func (spec *NethermindChainSpec) SetGenesisSealerEthereumNonce(n uint64) error {
+	spec.Genesis.Seal.Ethereum.Nonce = parity.EncodeNonce(n)
+	return nil
+}
+
+func (spec *NethermindChainSpec) GetGenesisSealerEthereumMixHash() common.Hash {
+	return common.BytesToHash(spec.Genesis.Seal.Ethereum.MixHash)
+}
+
+func (spec *NethermindChainSpec) SetGenesisSealerEthereumMixHash(h common.Hash) error {
+	spec.Genesis.Seal.Ethereum.MixHash = h[:]
+	return nil
+}
+
+func (spec *NethermindChainSpec) GetGenesisDifficulty() *big.Int {
+	return spec.Genesis.Difficulty.ToInt()
+}
+
+func (spec *NethermindChainSpec) SetGenesisDifficulty(i *big.Int) error {
+	spec.Genesis.Difficulty = newHexOrDecimal256(i)
+	return nil
+}
+
+func (spec *NethermindChainSpec) GetGenesisAuthor() common.Address {
+	return spec.Genesis.Author
+}
+
+func (spec *NethermindChainSpec) SetGenesisAuthor(a common.Address) error {
+	spec.Genesis.Author = a
+	return nil
+}
+
+func (spec *NethermindChainSpec) GetGenesisTimestamp() uint64 {
+	return uint64(spec.Genesis.Timestamp)
+}
+
+func (spec *NethermindChainSpec) SetGenesisTimestamp(u uint64) error {
+	spec.Genesis.Timestamp = math.HexOrDecimal64(u)
+	return nil
+}
+
+func (spec *NethermindChainSpec) GetGenesisParentHash() common.Hash {
+	return spec.Genesis.ParentHash
+}
+
+func (spec *NethermindChainSpec) SetGenesisParentHash(h common.Hash) error {
+	spec.Genesis.ParentHash = h
+	return nil
+}
+
+func (spec *NethermindChainSpec) GetGenesisExtraData() []byte {
+	return spec.Genesis.ExtraData
+}
+
+func (spec *NethermindChainSpec) SetGenesisExtraData(b []byte) error {
+	spec.Genesis.ExtraData = b
+	return nil
+}
+
+func (spec *NethermindChainSpec) GetGenesisGasLimit() uint64 {
+	return uint64(spec.Genesis.GasLimit)
+}
+
+func (spec *NethermindChainSpec) SetGenesisGasLimit(u uint64) error {
+	spec.Genesis.GasLimit = math.HexOrDecimal64(u)
+	return nil
+}
+
+// ForEachAccount iterates the genesis allocation. Unfunded Parity-style
+// builtin definitions are skipped, since Nethermind derives its precompiles
+// from the params transitions.
+func (spec *NethermindChainSpec) ForEachAccount(fn func(address common.Address, bal *big.Int, nonce uint64, code []byte, storage map[common.Hash]common.Hash) error) error {
+	for k, v := range spec.Accounts {
+		if v.Builtin != nil && (v.Balance.ToInt() == nil || v.Balance.ToInt().Sign() == 0) {
+			continue
+		}
+		if err := fn(common.Address(k), v.Balance.ToInt(), uint64(v.Nonce), v.Code, v.Storage); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (spec *NethermindChainSpec) UpdateAccount(address common.Address, bal *big.Int, nonce uint64, code []byte, storage map[common.Hash]common.Hash) error {
+	if spec.Accounts == nil {
+		spec.Accounts = make(map[common.UnprefixedAddress]*parity.ParityChainSpecAccount)
+	}
+	addr := common.UnprefixedAddress(address)
+	if _, ok := spec.Accounts[addr]; !ok {
+		spec.Accounts[addr] = &parity.ParityChainSpecAccount{}
+	}
+	if bal != nil {
+		spec.Accounts[addr].Balance = *newHexOrDecimal256(bal)
+	}
+	spec.Accounts[addr].Nonce = math.HexOrDecimal64(nonce)
+	spec.Accounts[addr].Code = code
+	spec.Accounts[addr].Storage = storage
+	return nil
+}
@@ -0,0 +1,77 @@
+// Copyright 2020 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package nethermind
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/params/types/ctypes"
+)
+
+func TestNethermindChainSpec_GetConsensusEngineType(t *testing.T) {
+	spec := new(NethermindChainSpec)
+	if engine := spec.GetConsensusEngineType(); engine != ctypes.ConsensusEngineT_Unknown {
+		t.Error("unwanted engine type", engine)
+	}
+	if err := spec.MustSetConsensusEngineType(ctypes.ConsensusEngineT_Clique); err != nil {
+		t.Fatal(err)
+	}
+	if engine := spec.GetConsensusEngineType(); engine != ctypes.ConsensusEngineT_Clique {
+		t.Error("mismatch engine", engine)
+	}
+	if n := spec.GetEIP2Transition(); n == nil || *n != 0 {
+		t.Error("want homestead at genesis for clique", n)
+	}
+}
+
+func TestNethermindChainSpec_SetEIP140Derived(t *testing.T) {
+	spec := new(NethermindChainSpec)
+	n, m := uint64(42), uint64(43)
+	if err := spec.SetEIP198Transition(nil); err != nil {
+		t.Error(err)
+	}
+	if err := spec.SetEIP140Transition(&n); err != nil {
+		t.Fatal(err)
+	}
+	if err := spec.SetEIP213Transition(&n); err != nil {
+		t.Error(err)
+	}
+	if err := spec.SetEIP212Transition(&m); err != ctypes.ErrUnsupportedConfigFatal {
+		t.Error("want fatal error, got", err)
+	}
+	if got := spec.GetEIP198Transition(); got == nil || *got != n {
+		t.Error("want EIP198 derived from EIP140, got", got)
+	}
+}
+
+func TestNethermindChainSpec_ECIP1017(t *testing.T) {
+	spec := new(NethermindChainSpec)
+	rounds := uint64(5000000)
+	if err := spec.SetEthashECIP1017EraRounds(&rounds); err != nil {
+		t.Fatal(err)
+	}
+	if got := spec.GetEthashECIP1017Transition(); got == nil || *got != rounds {
+		t.Error("want transition to fall back to era rounds, got", got)
+	}
+	zero := uint64(0)
+	if err := spec.SetEthashECIP1017Transition(&zero); err != nil {
+		t.Fatal(err)
+	}
+	if got := spec.GetEthashECIP1017Transition(); got == nil || *got != zero {
+		t.Error("want explicit transition, got", got)
+	}
+}
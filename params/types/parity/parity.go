@@ -107,6 +107,10 @@ type ParityChainSpec struct {
 		EIP1884Transition         *ParityU64 `json:"eip1884Transition,omitempty"`
 		EIP2028Transition         *ParityU64 `json:"eip2028Transition,omitempty"`
 		EIP2537Transition         *ParityU64 `json:"eip2537Transition,omitempty"`
+		EIP2718Transition         *ParityU64 `json:"eip2718Transition,omitempty"`
+		EIP2929Transition         *ParityU64 `json:"eip2929Transition,omitempty"`
+		EIP2930Transition         *ParityU64 `json:"eip2930Transition,omitempty"`
+		EIP1559Transition         *ParityU64 `json:"eip1559Transition,omitempty"`
 		EIP1706Transition         *ParityU64 `json:"-"` // FIXME, when and if i'm implemented in Parity
 		ECIP1080Transition        *ParityU64 `json:"-"` // FIXME, when and if i'm implemented in Parity
 
@@ -624,6 +624,42 @@ func (spec *ParityChainSpec) SetEIP2537Transition(n *uint64) error {
 	return nil
 }
 
+func (spec *ParityChainSpec) GetEIP2718Transition() *uint64 {
+	return spec.Params.EIP2718Transition.Uint64P()
+}
+
+func (spec *ParityChainSpec) SetEIP2718Transition(n *uint64) error {
+	spec.Params.EIP2718Transition = new(ParityU64).SetUint64(n)
+	return nil
+}
+
+func (spec *ParityChainSpec) GetEIP2929Transition() *uint64 {
+	return spec.Params.EIP2929Transition.Uint64P()
+}
+
+func (spec *ParityChainSpec) SetEIP2929Transition(n *uint64) error {
+	spec.Params.EIP2929Transition = new(ParityU64).SetUint64(n)
+	return nil
+}
+
+func (spec *ParityChainSpec) GetEIP2930Transition() *uint64 {
+	return spec.Params.EIP2930Transition.Uint64P()
+}
+
+func (spec *ParityChainSpec) SetEIP2930Transition(n *uint64) error {
+	spec.Params.EIP2930Transition = new(ParityU64).SetUint64(n)
+	return nil
+}
+
+func (spec *ParityChainSpec) GetEIP1559Transition() *uint64 {
+	return spec.Params.EIP1559Transition.Uint64P()
+}
+
+func (spec *ParityChainSpec) SetEIP1559Transition(n *uint64) error {
+	spec.Params.EIP1559Transition = new(ParityU64).SetUint64(n)
+	return nil
+}
+
 func (spec *ParityChainSpec) GetECBP1100Transition() *uint64 {
 	return nil
 }
@@ -266,8 +266,11 @@ var Forks = map[string]ctypes.ChainConfigurator{
 		IstanbulBlock:       big.NewInt(0),
 		YoloV1Block:         big.NewInt(0),
 	},
-	// This specification is subject to change, but is for now identical to YOLOv1
-	// for cross-client testing purposes
+	// Berlin is defined by its own fork block (EIP-2718, EIP-2929, EIP-2930) rather
+	// than as YOLOv1. EIP-2315 and EIP-2537 were dropped from the final Berlin
+	// specification; EIP-2315 is not tied to any fork here in the first place and
+	// can still be tested as "Berlin+2315". The EIP-2929 fixtures live in the
+	// tests submodule, see TestStateEIP2929 for coverage independent of it.
 	"Berlin": &goethereum.ChainConfig{
 		ChainID:             big.NewInt(1),
 		HomesteadBlock:      big.NewInt(0),
@@ -278,7 +281,7 @@ var Forks = map[string]ctypes.ChainConfigurator{
 		ConstantinopleBlock: big.NewInt(0),
 		PetersburgBlock:     big.NewInt(0),
 		IstanbulBlock:       big.NewInt(0),
-		YoloV1Block:         big.NewInt(0),
+		BerlinBlock:         big.NewInt(0),
 	},
 }
 
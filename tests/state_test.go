@@ -19,10 +19,13 @@ package tests
 import (
 	"bufio"
 	"bytes"
+	"encoding/json"
 	"fmt"
+	"math/big"
 	"reflect"
 	"testing"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/vm"
 )
 
@@ -143,3 +146,78 @@ func withTrace(t *testing.T, gasLimit uint64, test func(vm.Config) error) {
 	//t.Logf("EVM output: 0x%x", tracer.Output())
 	//t.Logf("EVM error: %v", tracer.Error())
 }
+
+// eip2929StateTest is a general state test calling a contract which loads the
+// same storage slot twice and reads the balance of the same account twice and
+// of a precompile, without and with (data index 1) an access list.
+const eip2929StateTest = `{
+	"env": {
+		"currentCoinbase": "2adc25665018aa1fe0e6bc666dac8fc2697ff9ba",
+		"currentDifficulty": "0x020000",
+		"currentGasLimit": "0x05f5e100",
+		"currentNumber": "0x01",
+		"currentTimestamp": "0x03e8"
+	},
+	"pre": {
+		"a94f5374fce5edbc8e2a8697c15331677e6ebf0b": {"balance": "0x0de0b6b3a7640000", "code": "0x", "nonce": "0x00", "storage": {}},
+		"095e7baea6a6c7c4c2dfeb977efac326af552d87": {
+			"balance": "0x00",
+			"code": "0x60005460005473000000000000000000000000000000000000aaaa3173000000000000000000000000000000000000aaaa3160013100",
+			"nonce": "0x00",
+			"storage": {"0x00": "0x01"}
+		}
+	},
+	"transaction": {
+		"gasPrice": "0x01",
+		"nonce": "0x00",
+		"to": "0x095e7baea6a6c7c4c2dfeb977efac326af552d87",
+		"data": ["0x", "0x"],
+		"accessLists": [null, [
+			{"address": "0x095e7baea6a6c7c4c2dfeb977efac326af552d87", "storageKeys": ["0x0000000000000000000000000000000000000000000000000000000000000000"]},
+			{"address": "0x000000000000000000000000000000000000aaaa", "storageKeys": []}
+		]],
+		"gasLimit": ["0x0186a0"],
+		"value": ["0x00"],
+		"secretKey": "0x45a915e4d060149eb4365960e6a7a45f334393093061116b197e3240065ff2d8"
+	},
+	"post": {
+		"Istanbul": [{"hash": "0000000000000000000000000000000000000000000000000000000000000000", "logs": "0000000000000000000000000000000000000000000000000000000000000000", "indexes": {"data": 0, "gas": 0, "value": 0}}],
+		"Berlin": [
+			{"hash": "0000000000000000000000000000000000000000000000000000000000000000", "logs": "0000000000000000000000000000000000000000000000000000000000000000", "indexes": {"data": 0, "gas": 0, "value": 0}},
+			{"hash": "0000000000000000000000000000000000000000000000000000000000000000", "logs": "0000000000000000000000000000000000000000000000000000000000000000", "indexes": {"data": 1, "gas": 0, "value": 0}}
+		]
+	}
+}`
+
+// Tests that the Berlin fork charges the EIP-2929 costs of cold and warm state
+// accesses, independently of the fixtures of the tests submodule.
+func TestStateEIP2929(t *testing.T) {
+	var test StateTest
+	if err := json.Unmarshal([]byte(eip2929StateTest), &test); err != nil {
+		t.Fatalf("failed to decode test: %v", err)
+	}
+	var (
+		sender  = common.HexToAddress("0xa94f5374fce5edbc8e2a8697c15331677e6ebf0b")
+		balance = big.NewInt(1000000000000000000)
+	)
+	for _, tt := range []struct {
+		subtest StateSubtest
+		gas     uint64
+	}{
+		// 21000 + 2*3+2*800 (SLOAD) + 3*3+3*700 (BALANCE)
+		{StateSubtest{"Istanbul", 0}, 24715},
+		// 21000 + 2*3+2100+100 (cold, warm SLOAD) + 3*3+2600+100+100 (cold, warm, precompile BALANCE)
+		{StateSubtest{"Berlin", 0}, 26015},
+		// 21000+2*2400+1900 (access list) + 2*3+2*100 (SLOAD) + 3*3+3*100 (BALANCE)
+		{StateSubtest{"Berlin", 1}, 28215},
+	} {
+		_, statedb, _, err := test.RunNoVerify(tt.subtest, vm.Config{}, false)
+		if err != nil {
+			t.Fatalf("%s/%d: failed to run test: %v", tt.subtest.Fork, tt.subtest.Index, err)
+		}
+		used := new(big.Int).Sub(balance, statedb.GetBalance(sender))
+		if used.Uint64() != tt.gas {
+			t.Errorf("%s/%d: gas used mismatch: have %d, want %d", tt.subtest.Fork, tt.subtest.Index, used, tt.gas)
+		}
+	}
+}
@@ -55,6 +55,28 @@ func TestEmptyBug(t *testing.T) {
 	}
 }
 
+// TestSmallRootCommit checks that a root node shorter than 32 bytes, which is
+// embedded rather than hashed, is still hashed and written out on commit.
+func TestSmallRootCommit(t *testing.T) {
+	db := memorydb.New()
+	st := NewStackTrie(db)
+	nt, _ := New(common.Hash{}, NewDatabase(memorydb.New()))
+
+	nt.TryUpdate([]byte{0x01}, []byte{0x02})
+	st.TryUpdate([]byte{0x01}, []byte{0x02})
+
+	root, err := st.Commit()
+	if err != nil {
+		t.Fatalf("failed to commit: %v", err)
+	}
+	if root != nt.Hash() {
+		t.Fatalf("root mismatch: have %x, want %x", root, nt.Hash())
+	}
+	if blob, _ := db.Get(root[:]); len(blob) == 0 {
+		t.Fatalf("root node %x not committed", root)
+	}
+}
+
 func TestValLength56(t *testing.T) {
 	st := NewStackTrie(nil)
 	nt, _ := New(common.Hash{}, NewDatabase(memorydb.New()))